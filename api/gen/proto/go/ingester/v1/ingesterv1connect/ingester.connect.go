@@ -59,6 +59,12 @@ const (
 	// IngesterServiceMergeProfilesPprofProcedure is the fully-qualified name of the IngesterService's
 	// MergeProfilesPprof RPC.
 	IngesterServiceMergeProfilesPprofProcedure = "/ingester.v1.IngesterService/MergeProfilesPprof"
+	// IngesterServiceSelectProfileByIDProcedure is the fully-qualified name of the IngesterService's
+	// SelectProfileByID RPC.
+	IngesterServiceSelectProfileByIDProcedure = "/ingester.v1.IngesterService/SelectProfileByID"
+	// IngesterServiceCardinalityProcedure is the fully-qualified name of the IngesterService's
+	// Cardinality RPC.
+	IngesterServiceCardinalityProcedure = "/ingester.v1.IngesterService/Cardinality"
 )
 
 // IngesterServiceClient is a client for the ingester.v1.IngesterService service.
@@ -72,6 +78,8 @@ type IngesterServiceClient interface {
 	MergeProfilesStacktraces(context.Context) *connect_go.BidiStreamForClient[v12.MergeProfilesStacktracesRequest, v12.MergeProfilesStacktracesResponse]
 	MergeProfilesLabels(context.Context) *connect_go.BidiStreamForClient[v12.MergeProfilesLabelsRequest, v12.MergeProfilesLabelsResponse]
 	MergeProfilesPprof(context.Context) *connect_go.BidiStreamForClient[v12.MergeProfilesPprofRequest, v12.MergeProfilesPprofResponse]
+	SelectProfileByID(context.Context, *connect_go.Request[v12.SelectProfileByIDRequest]) (*connect_go.Response[v12.SelectProfileByIDResponse], error)
+	Cardinality(context.Context, *connect_go.Request[v12.CardinalityRequest]) (*connect_go.Response[v12.CardinalityResponse], error)
 }
 
 // NewIngesterServiceClient constructs a client for the ingester.v1.IngesterService service. By
@@ -129,6 +137,16 @@ func NewIngesterServiceClient(httpClient connect_go.HTTPClient, baseURL string,
 			baseURL+IngesterServiceMergeProfilesPprofProcedure,
 			opts...,
 		),
+		selectProfileByID: connect_go.NewClient[v12.SelectProfileByIDRequest, v12.SelectProfileByIDResponse](
+			httpClient,
+			baseURL+IngesterServiceSelectProfileByIDProcedure,
+			opts...,
+		),
+		cardinality: connect_go.NewClient[v12.CardinalityRequest, v12.CardinalityResponse](
+			httpClient,
+			baseURL+IngesterServiceCardinalityProcedure,
+			opts...,
+		),
 	}
 }
 
@@ -143,6 +161,8 @@ type ingesterServiceClient struct {
 	mergeProfilesStacktraces *connect_go.Client[v12.MergeProfilesStacktracesRequest, v12.MergeProfilesStacktracesResponse]
 	mergeProfilesLabels      *connect_go.Client[v12.MergeProfilesLabelsRequest, v12.MergeProfilesLabelsResponse]
 	mergeProfilesPprof       *connect_go.Client[v12.MergeProfilesPprofRequest, v12.MergeProfilesPprofResponse]
+	selectProfileByID        *connect_go.Client[v12.SelectProfileByIDRequest, v12.SelectProfileByIDResponse]
+	cardinality              *connect_go.Client[v12.CardinalityRequest, v12.CardinalityResponse]
 }
 
 // Push calls ingester.v1.IngesterService.Push.
@@ -190,6 +210,16 @@ func (c *ingesterServiceClient) MergeProfilesPprof(ctx context.Context) *connect
 	return c.mergeProfilesPprof.CallBidiStream(ctx)
 }
 
+// SelectProfileByID calls ingester.v1.IngesterService.SelectProfileByID.
+func (c *ingesterServiceClient) SelectProfileByID(ctx context.Context, req *connect_go.Request[v12.SelectProfileByIDRequest]) (*connect_go.Response[v12.SelectProfileByIDResponse], error) {
+	return c.selectProfileByID.CallUnary(ctx, req)
+}
+
+// Cardinality calls ingester.v1.IngesterService.Cardinality.
+func (c *ingesterServiceClient) Cardinality(ctx context.Context, req *connect_go.Request[v12.CardinalityRequest]) (*connect_go.Response[v12.CardinalityResponse], error) {
+	return c.cardinality.CallUnary(ctx, req)
+}
+
 // IngesterServiceHandler is an implementation of the ingester.v1.IngesterService service.
 type IngesterServiceHandler interface {
 	Push(context.Context, *connect_go.Request[v1.PushRequest]) (*connect_go.Response[v1.PushResponse], error)
@@ -201,6 +231,8 @@ type IngesterServiceHandler interface {
 	MergeProfilesStacktraces(context.Context, *connect_go.BidiStream[v12.MergeProfilesStacktracesRequest, v12.MergeProfilesStacktracesResponse]) error
 	MergeProfilesLabels(context.Context, *connect_go.BidiStream[v12.MergeProfilesLabelsRequest, v12.MergeProfilesLabelsResponse]) error
 	MergeProfilesPprof(context.Context, *connect_go.BidiStream[v12.MergeProfilesPprofRequest, v12.MergeProfilesPprofResponse]) error
+	SelectProfileByID(context.Context, *connect_go.Request[v12.SelectProfileByIDRequest]) (*connect_go.Response[v12.SelectProfileByIDResponse], error)
+	Cardinality(context.Context, *connect_go.Request[v12.CardinalityRequest]) (*connect_go.Response[v12.CardinalityResponse], error)
 }
 
 // NewIngesterServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -254,6 +286,16 @@ func NewIngesterServiceHandler(svc IngesterServiceHandler, opts ...connect_go.Ha
 		svc.MergeProfilesPprof,
 		opts...,
 	)
+	ingesterServiceSelectProfileByIDHandler := connect_go.NewUnaryHandler(
+		IngesterServiceSelectProfileByIDProcedure,
+		svc.SelectProfileByID,
+		opts...,
+	)
+	ingesterServiceCardinalityHandler := connect_go.NewUnaryHandler(
+		IngesterServiceCardinalityProcedure,
+		svc.Cardinality,
+		opts...,
+	)
 	return "/ingester.v1.IngesterService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case IngesterServicePushProcedure:
@@ -274,6 +316,10 @@ func NewIngesterServiceHandler(svc IngesterServiceHandler, opts ...connect_go.Ha
 			ingesterServiceMergeProfilesLabelsHandler.ServeHTTP(w, r)
 		case IngesterServiceMergeProfilesPprofProcedure:
 			ingesterServiceMergeProfilesPprofHandler.ServeHTTP(w, r)
+		case IngesterServiceSelectProfileByIDProcedure:
+			ingesterServiceSelectProfileByIDHandler.ServeHTTP(w, r)
+		case IngesterServiceCardinalityProcedure:
+			ingesterServiceCardinalityHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -318,3 +364,11 @@ func (UnimplementedIngesterServiceHandler) MergeProfilesLabels(context.Context,
 func (UnimplementedIngesterServiceHandler) MergeProfilesPprof(context.Context, *connect_go.BidiStream[v12.MergeProfilesPprofRequest, v12.MergeProfilesPprofResponse]) error {
 	return connect_go.NewError(connect_go.CodeUnimplemented, errors.New("ingester.v1.IngesterService.MergeProfilesPprof is not implemented"))
 }
+
+func (UnimplementedIngesterServiceHandler) SelectProfileByID(context.Context, *connect_go.Request[v12.SelectProfileByIDRequest]) (*connect_go.Response[v12.SelectProfileByIDResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("ingester.v1.IngesterService.SelectProfileByID is not implemented"))
+}
+
+func (UnimplementedIngesterServiceHandler) Cardinality(context.Context, *connect_go.Request[v12.CardinalityRequest]) (*connect_go.Response[v12.CardinalityResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("ingester.v1.IngesterService.Cardinality is not implemented"))
+}
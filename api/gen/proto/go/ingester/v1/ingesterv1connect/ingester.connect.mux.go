@@ -64,4 +64,14 @@ func RegisterIngesterServiceHandler(mux *mux.Router, svc IngesterServiceHandler,
 		svc.MergeProfilesPprof,
 		opts...,
 	))
+	mux.Handle("/ingester.v1.IngesterService/SelectProfileByID", connect_go.NewUnaryHandler(
+		"/ingester.v1.IngesterService/SelectProfileByID",
+		svc.SelectProfileByID,
+		opts...,
+	))
+	mux.Handle("/ingester.v1.IngesterService/Cardinality", connect_go.NewUnaryHandler(
+		"/ingester.v1.IngesterService/Cardinality",
+		svc.Cardinality,
+		opts...,
+	))
 }
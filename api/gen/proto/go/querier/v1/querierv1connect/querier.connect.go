@@ -49,14 +49,41 @@ const (
 	// QuerierServiceSelectMergeStacktracesProcedure is the fully-qualified name of the QuerierService's
 	// SelectMergeStacktraces RPC.
 	QuerierServiceSelectMergeStacktracesProcedure = "/querier.v1.QuerierService/SelectMergeStacktraces"
+	// QuerierServiceSelectMergeSpanProfileProcedure is the fully-qualified name of the QuerierService's
+	// SelectMergeSpanProfile RPC.
+	QuerierServiceSelectMergeSpanProfileProcedure = "/querier.v1.QuerierService/SelectMergeSpanProfile"
 	// QuerierServiceSelectMergeProfileProcedure is the fully-qualified name of the QuerierService's
 	// SelectMergeProfile RPC.
 	QuerierServiceSelectMergeProfileProcedure = "/querier.v1.QuerierService/SelectMergeProfile"
 	// QuerierServiceSelectSeriesProcedure is the fully-qualified name of the QuerierService's
 	// SelectSeries RPC.
 	QuerierServiceSelectSeriesProcedure = "/querier.v1.QuerierService/SelectSeries"
+	// QuerierServiceSelectFunctionTableProcedure is the fully-qualified name of the QuerierService's
+	// SelectFunctionTable RPC.
+	QuerierServiceSelectFunctionTableProcedure = "/querier.v1.QuerierService/SelectFunctionTable"
+	// QuerierServiceSelectFunctionSeriesProcedure is the fully-qualified name of the QuerierService's
+	// SelectFunctionSeries RPC.
+	QuerierServiceSelectFunctionSeriesProcedure = "/querier.v1.QuerierService/SelectFunctionSeries"
+	// QuerierServiceSelectFunctionLinesProcedure is the fully-qualified name of the QuerierService's
+	// SelectFunctionLines RPC.
+	QuerierServiceSelectFunctionLinesProcedure = "/querier.v1.QuerierService/SelectFunctionLines"
+	// QuerierServiceSelectCallGraphProcedure is the fully-qualified name of the QuerierService's
+	// SelectCallGraph RPC.
+	QuerierServiceSelectCallGraphProcedure = "/querier.v1.QuerierService/SelectCallGraph"
+	// QuerierServiceSelectHeatmapProcedure is the fully-qualified name of the QuerierService's
+	// SelectHeatmap RPC.
+	QuerierServiceSelectHeatmapProcedure = "/querier.v1.QuerierService/SelectHeatmap"
+	// QuerierServiceSelectProfileByIDProcedure is the fully-qualified name of the QuerierService's
+	// SelectProfileByID RPC.
+	QuerierServiceSelectProfileByIDProcedure = "/querier.v1.QuerierService/SelectProfileByID"
+	// QuerierServiceCardinalityProcedure is the fully-qualified name of the QuerierService's
+	// Cardinality RPC.
+	QuerierServiceCardinalityProcedure = "/querier.v1.QuerierService/Cardinality"
 	// QuerierServiceDiffProcedure is the fully-qualified name of the QuerierService's Diff RPC.
 	QuerierServiceDiffProcedure = "/querier.v1.QuerierService/Diff"
+	// QuerierServiceDiffReportProcedure is the fully-qualified name of the QuerierService's DiffReport
+	// RPC.
+	QuerierServiceDiffReportProcedure = "/querier.v1.QuerierService/DiffReport"
 )
 
 // QuerierServiceClient is a client for the querier.v1.QuerierService service.
@@ -71,11 +98,30 @@ type QuerierServiceClient interface {
 	Series(context.Context, *connect_go.Request[v1.SeriesRequest]) (*connect_go.Response[v1.SeriesResponse], error)
 	// SelectMergeStacktraces returns matching profiles aggregated in a flamegraph format. It will combine samples from within the same callstack, with each element being grouped by its function name.
 	SelectMergeStacktraces(context.Context, *connect_go.Request[v1.SelectMergeStacktracesRequest]) (*connect_go.Response[v1.SelectMergeStacktracesResponse], error)
+	// SelectMergeSpanProfile returns matching profiles aggregated in a flamegraph format, accounting only for the samples collected in the given spans.
+	SelectMergeSpanProfile(context.Context, *connect_go.Request[v1.SelectMergeSpanProfileRequest]) (*connect_go.Response[v1.SelectMergeSpanProfileResponse], error)
 	// SelectMergeProfile returns matching profiles aggregated in pprof format. It will contain all information stored (so including filenames and line number, if ingested).
 	SelectMergeProfile(context.Context, *connect_go.Request[v1.SelectMergeProfileRequest]) (*connect_go.Response[v12.Profile], error)
 	// SelectSeries returns a time series for the total sum of the requested profiles.
 	SelectSeries(context.Context, *connect_go.Request[v1.SelectSeriesRequest]) (*connect_go.Response[v1.SelectSeriesResponse], error)
+	// SelectFunctionTable returns the self and total values of each function found in the matching profiles, ordered by the self value.
+	SelectFunctionTable(context.Context, *connect_go.Request[v1.SelectFunctionTableRequest]) (*connect_go.Response[v1.SelectFunctionTableResponse], error)
+	// SelectFunctionSeries returns the series of the self or total values of the functions in the matching profiles, one per function and group.
+	SelectFunctionSeries(context.Context, *connect_go.Request[v1.SelectFunctionSeriesRequest]) (*connect_go.Response[v1.SelectSeriesResponse], error)
+	// SelectFunctionLines returns the self and total values of each source line of the function found in the matching profiles, ordered by the file name and line.
+	SelectFunctionLines(context.Context, *connect_go.Request[v1.SelectFunctionLinesRequest]) (*connect_go.Response[v1.SelectFunctionLinesResponse], error)
+	// SelectCallGraph returns the call graph of the matching profiles aggregated by function, both as a graph and in the DOT format.
+	SelectCallGraph(context.Context, *connect_go.Request[v1.SelectCallGraphRequest]) (*connect_go.Response[v1.SelectCallGraphResponse], error)
+	// SelectHeatmap returns the distribution of the profile values over time: the number of profiles within each bin of the time and value axes.
+	SelectHeatmap(context.Context, *connect_go.Request[v1.SelectHeatmapRequest]) (*connect_go.Response[v1.SelectHeatmapResponse], error)
+	// SelectProfileByID returns the stored profile with the given ID in pprof format.
+	SelectProfileByID(context.Context, *connect_go.Request[v1.SelectProfileByIDRequest]) (*connect_go.Response[v12.Profile], error)
+	// Cardinality returns the label and series cardinality statistics of the series matching the request, similar to the Prometheus TSDB status.
+	// The series counts are estimated from the counts of the ingesters and the store-gateways.
+	Cardinality(context.Context, *connect_go.Request[v1.CardinalityRequest]) (*connect_go.Response[v1.CardinalityResponse], error)
 	Diff(context.Context, *connect_go.Request[v1.DiffRequest]) (*connect_go.Response[v1.DiffResponse], error)
+	// DiffReport returns the functions whose share of the total has changed the most between two selections, with a significance score of the change.
+	DiffReport(context.Context, *connect_go.Request[v1.DiffReportRequest]) (*connect_go.Response[v1.DiffReportResponse], error)
 }
 
 // NewQuerierServiceClient constructs a client for the querier.v1.QuerierService service. By
@@ -113,6 +159,11 @@ func NewQuerierServiceClient(httpClient connect_go.HTTPClient, baseURL string, o
 			baseURL+QuerierServiceSelectMergeStacktracesProcedure,
 			opts...,
 		),
+		selectMergeSpanProfile: connect_go.NewClient[v1.SelectMergeSpanProfileRequest, v1.SelectMergeSpanProfileResponse](
+			httpClient,
+			baseURL+QuerierServiceSelectMergeSpanProfileProcedure,
+			opts...,
+		),
 		selectMergeProfile: connect_go.NewClient[v1.SelectMergeProfileRequest, v12.Profile](
 			httpClient,
 			baseURL+QuerierServiceSelectMergeProfileProcedure,
@@ -123,11 +174,51 @@ func NewQuerierServiceClient(httpClient connect_go.HTTPClient, baseURL string, o
 			baseURL+QuerierServiceSelectSeriesProcedure,
 			opts...,
 		),
+		selectFunctionTable: connect_go.NewClient[v1.SelectFunctionTableRequest, v1.SelectFunctionTableResponse](
+			httpClient,
+			baseURL+QuerierServiceSelectFunctionTableProcedure,
+			opts...,
+		),
+		selectFunctionSeries: connect_go.NewClient[v1.SelectFunctionSeriesRequest, v1.SelectSeriesResponse](
+			httpClient,
+			baseURL+QuerierServiceSelectFunctionSeriesProcedure,
+			opts...,
+		),
+		selectFunctionLines: connect_go.NewClient[v1.SelectFunctionLinesRequest, v1.SelectFunctionLinesResponse](
+			httpClient,
+			baseURL+QuerierServiceSelectFunctionLinesProcedure,
+			opts...,
+		),
+		selectCallGraph: connect_go.NewClient[v1.SelectCallGraphRequest, v1.SelectCallGraphResponse](
+			httpClient,
+			baseURL+QuerierServiceSelectCallGraphProcedure,
+			opts...,
+		),
+		selectHeatmap: connect_go.NewClient[v1.SelectHeatmapRequest, v1.SelectHeatmapResponse](
+			httpClient,
+			baseURL+QuerierServiceSelectHeatmapProcedure,
+			opts...,
+		),
+		selectProfileByID: connect_go.NewClient[v1.SelectProfileByIDRequest, v12.Profile](
+			httpClient,
+			baseURL+QuerierServiceSelectProfileByIDProcedure,
+			opts...,
+		),
+		cardinality: connect_go.NewClient[v1.CardinalityRequest, v1.CardinalityResponse](
+			httpClient,
+			baseURL+QuerierServiceCardinalityProcedure,
+			opts...,
+		),
 		diff: connect_go.NewClient[v1.DiffRequest, v1.DiffResponse](
 			httpClient,
 			baseURL+QuerierServiceDiffProcedure,
 			opts...,
 		),
+		diffReport: connect_go.NewClient[v1.DiffReportRequest, v1.DiffReportResponse](
+			httpClient,
+			baseURL+QuerierServiceDiffReportProcedure,
+			opts...,
+		),
 	}
 }
 
@@ -138,9 +229,18 @@ type querierServiceClient struct {
 	labelNames             *connect_go.Client[v11.LabelNamesRequest, v11.LabelNamesResponse]
 	series                 *connect_go.Client[v1.SeriesRequest, v1.SeriesResponse]
 	selectMergeStacktraces *connect_go.Client[v1.SelectMergeStacktracesRequest, v1.SelectMergeStacktracesResponse]
+	selectMergeSpanProfile *connect_go.Client[v1.SelectMergeSpanProfileRequest, v1.SelectMergeSpanProfileResponse]
 	selectMergeProfile     *connect_go.Client[v1.SelectMergeProfileRequest, v12.Profile]
 	selectSeries           *connect_go.Client[v1.SelectSeriesRequest, v1.SelectSeriesResponse]
+	selectFunctionTable    *connect_go.Client[v1.SelectFunctionTableRequest, v1.SelectFunctionTableResponse]
+	selectFunctionSeries   *connect_go.Client[v1.SelectFunctionSeriesRequest, v1.SelectSeriesResponse]
+	selectFunctionLines    *connect_go.Client[v1.SelectFunctionLinesRequest, v1.SelectFunctionLinesResponse]
+	selectCallGraph        *connect_go.Client[v1.SelectCallGraphRequest, v1.SelectCallGraphResponse]
+	selectHeatmap          *connect_go.Client[v1.SelectHeatmapRequest, v1.SelectHeatmapResponse]
+	selectProfileByID      *connect_go.Client[v1.SelectProfileByIDRequest, v12.Profile]
+	cardinality            *connect_go.Client[v1.CardinalityRequest, v1.CardinalityResponse]
 	diff                   *connect_go.Client[v1.DiffRequest, v1.DiffResponse]
+	diffReport             *connect_go.Client[v1.DiffReportRequest, v1.DiffReportResponse]
 }
 
 // ProfileTypes calls querier.v1.QuerierService.ProfileTypes.
@@ -168,6 +268,11 @@ func (c *querierServiceClient) SelectMergeStacktraces(ctx context.Context, req *
 	return c.selectMergeStacktraces.CallUnary(ctx, req)
 }
 
+// SelectMergeSpanProfile calls querier.v1.QuerierService.SelectMergeSpanProfile.
+func (c *querierServiceClient) SelectMergeSpanProfile(ctx context.Context, req *connect_go.Request[v1.SelectMergeSpanProfileRequest]) (*connect_go.Response[v1.SelectMergeSpanProfileResponse], error) {
+	return c.selectMergeSpanProfile.CallUnary(ctx, req)
+}
+
 // SelectMergeProfile calls querier.v1.QuerierService.SelectMergeProfile.
 func (c *querierServiceClient) SelectMergeProfile(ctx context.Context, req *connect_go.Request[v1.SelectMergeProfileRequest]) (*connect_go.Response[v12.Profile], error) {
 	return c.selectMergeProfile.CallUnary(ctx, req)
@@ -178,11 +283,51 @@ func (c *querierServiceClient) SelectSeries(ctx context.Context, req *connect_go
 	return c.selectSeries.CallUnary(ctx, req)
 }
 
+// SelectFunctionTable calls querier.v1.QuerierService.SelectFunctionTable.
+func (c *querierServiceClient) SelectFunctionTable(ctx context.Context, req *connect_go.Request[v1.SelectFunctionTableRequest]) (*connect_go.Response[v1.SelectFunctionTableResponse], error) {
+	return c.selectFunctionTable.CallUnary(ctx, req)
+}
+
+// SelectFunctionSeries calls querier.v1.QuerierService.SelectFunctionSeries.
+func (c *querierServiceClient) SelectFunctionSeries(ctx context.Context, req *connect_go.Request[v1.SelectFunctionSeriesRequest]) (*connect_go.Response[v1.SelectSeriesResponse], error) {
+	return c.selectFunctionSeries.CallUnary(ctx, req)
+}
+
+// SelectFunctionLines calls querier.v1.QuerierService.SelectFunctionLines.
+func (c *querierServiceClient) SelectFunctionLines(ctx context.Context, req *connect_go.Request[v1.SelectFunctionLinesRequest]) (*connect_go.Response[v1.SelectFunctionLinesResponse], error) {
+	return c.selectFunctionLines.CallUnary(ctx, req)
+}
+
+// SelectCallGraph calls querier.v1.QuerierService.SelectCallGraph.
+func (c *querierServiceClient) SelectCallGraph(ctx context.Context, req *connect_go.Request[v1.SelectCallGraphRequest]) (*connect_go.Response[v1.SelectCallGraphResponse], error) {
+	return c.selectCallGraph.CallUnary(ctx, req)
+}
+
+// SelectHeatmap calls querier.v1.QuerierService.SelectHeatmap.
+func (c *querierServiceClient) SelectHeatmap(ctx context.Context, req *connect_go.Request[v1.SelectHeatmapRequest]) (*connect_go.Response[v1.SelectHeatmapResponse], error) {
+	return c.selectHeatmap.CallUnary(ctx, req)
+}
+
+// SelectProfileByID calls querier.v1.QuerierService.SelectProfileByID.
+func (c *querierServiceClient) SelectProfileByID(ctx context.Context, req *connect_go.Request[v1.SelectProfileByIDRequest]) (*connect_go.Response[v12.Profile], error) {
+	return c.selectProfileByID.CallUnary(ctx, req)
+}
+
+// Cardinality calls querier.v1.QuerierService.Cardinality.
+func (c *querierServiceClient) Cardinality(ctx context.Context, req *connect_go.Request[v1.CardinalityRequest]) (*connect_go.Response[v1.CardinalityResponse], error) {
+	return c.cardinality.CallUnary(ctx, req)
+}
+
 // Diff calls querier.v1.QuerierService.Diff.
 func (c *querierServiceClient) Diff(ctx context.Context, req *connect_go.Request[v1.DiffRequest]) (*connect_go.Response[v1.DiffResponse], error) {
 	return c.diff.CallUnary(ctx, req)
 }
 
+// DiffReport calls querier.v1.QuerierService.DiffReport.
+func (c *querierServiceClient) DiffReport(ctx context.Context, req *connect_go.Request[v1.DiffReportRequest]) (*connect_go.Response[v1.DiffReportResponse], error) {
+	return c.diffReport.CallUnary(ctx, req)
+}
+
 // QuerierServiceHandler is an implementation of the querier.v1.QuerierService service.
 type QuerierServiceHandler interface {
 	// ProfileType returns a list of the existing profile types.
@@ -195,11 +340,30 @@ type QuerierServiceHandler interface {
 	Series(context.Context, *connect_go.Request[v1.SeriesRequest]) (*connect_go.Response[v1.SeriesResponse], error)
 	// SelectMergeStacktraces returns matching profiles aggregated in a flamegraph format. It will combine samples from within the same callstack, with each element being grouped by its function name.
 	SelectMergeStacktraces(context.Context, *connect_go.Request[v1.SelectMergeStacktracesRequest]) (*connect_go.Response[v1.SelectMergeStacktracesResponse], error)
+	// SelectMergeSpanProfile returns matching profiles aggregated in a flamegraph format, accounting only for the samples collected in the given spans.
+	SelectMergeSpanProfile(context.Context, *connect_go.Request[v1.SelectMergeSpanProfileRequest]) (*connect_go.Response[v1.SelectMergeSpanProfileResponse], error)
 	// SelectMergeProfile returns matching profiles aggregated in pprof format. It will contain all information stored (so including filenames and line number, if ingested).
 	SelectMergeProfile(context.Context, *connect_go.Request[v1.SelectMergeProfileRequest]) (*connect_go.Response[v12.Profile], error)
 	// SelectSeries returns a time series for the total sum of the requested profiles.
 	SelectSeries(context.Context, *connect_go.Request[v1.SelectSeriesRequest]) (*connect_go.Response[v1.SelectSeriesResponse], error)
+	// SelectFunctionTable returns the self and total values of each function found in the matching profiles, ordered by the self value.
+	SelectFunctionTable(context.Context, *connect_go.Request[v1.SelectFunctionTableRequest]) (*connect_go.Response[v1.SelectFunctionTableResponse], error)
+	// SelectFunctionSeries returns the series of the self or total values of the functions in the matching profiles, one per function and group.
+	SelectFunctionSeries(context.Context, *connect_go.Request[v1.SelectFunctionSeriesRequest]) (*connect_go.Response[v1.SelectSeriesResponse], error)
+	// SelectFunctionLines returns the self and total values of each source line of the function found in the matching profiles, ordered by the file name and line.
+	SelectFunctionLines(context.Context, *connect_go.Request[v1.SelectFunctionLinesRequest]) (*connect_go.Response[v1.SelectFunctionLinesResponse], error)
+	// SelectCallGraph returns the call graph of the matching profiles aggregated by function, both as a graph and in the DOT format.
+	SelectCallGraph(context.Context, *connect_go.Request[v1.SelectCallGraphRequest]) (*connect_go.Response[v1.SelectCallGraphResponse], error)
+	// SelectHeatmap returns the distribution of the profile values over time: the number of profiles within each bin of the time and value axes.
+	SelectHeatmap(context.Context, *connect_go.Request[v1.SelectHeatmapRequest]) (*connect_go.Response[v1.SelectHeatmapResponse], error)
+	// SelectProfileByID returns the stored profile with the given ID in pprof format.
+	SelectProfileByID(context.Context, *connect_go.Request[v1.SelectProfileByIDRequest]) (*connect_go.Response[v12.Profile], error)
+	// Cardinality returns the label and series cardinality statistics of the series matching the request, similar to the Prometheus TSDB status.
+	// The series counts are estimated from the counts of the ingesters and the store-gateways.
+	Cardinality(context.Context, *connect_go.Request[v1.CardinalityRequest]) (*connect_go.Response[v1.CardinalityResponse], error)
 	Diff(context.Context, *connect_go.Request[v1.DiffRequest]) (*connect_go.Response[v1.DiffResponse], error)
+	// DiffReport returns the functions whose share of the total has changed the most between two selections, with a significance score of the change.
+	DiffReport(context.Context, *connect_go.Request[v1.DiffReportRequest]) (*connect_go.Response[v1.DiffReportResponse], error)
 }
 
 // NewQuerierServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -233,6 +397,11 @@ func NewQuerierServiceHandler(svc QuerierServiceHandler, opts ...connect_go.Hand
 		svc.SelectMergeStacktraces,
 		opts...,
 	)
+	querierServiceSelectMergeSpanProfileHandler := connect_go.NewUnaryHandler(
+		QuerierServiceSelectMergeSpanProfileProcedure,
+		svc.SelectMergeSpanProfile,
+		opts...,
+	)
 	querierServiceSelectMergeProfileHandler := connect_go.NewUnaryHandler(
 		QuerierServiceSelectMergeProfileProcedure,
 		svc.SelectMergeProfile,
@@ -243,11 +412,51 @@ func NewQuerierServiceHandler(svc QuerierServiceHandler, opts ...connect_go.Hand
 		svc.SelectSeries,
 		opts...,
 	)
+	querierServiceSelectFunctionTableHandler := connect_go.NewUnaryHandler(
+		QuerierServiceSelectFunctionTableProcedure,
+		svc.SelectFunctionTable,
+		opts...,
+	)
+	querierServiceSelectFunctionSeriesHandler := connect_go.NewUnaryHandler(
+		QuerierServiceSelectFunctionSeriesProcedure,
+		svc.SelectFunctionSeries,
+		opts...,
+	)
+	querierServiceSelectFunctionLinesHandler := connect_go.NewUnaryHandler(
+		QuerierServiceSelectFunctionLinesProcedure,
+		svc.SelectFunctionLines,
+		opts...,
+	)
+	querierServiceSelectCallGraphHandler := connect_go.NewUnaryHandler(
+		QuerierServiceSelectCallGraphProcedure,
+		svc.SelectCallGraph,
+		opts...,
+	)
+	querierServiceSelectHeatmapHandler := connect_go.NewUnaryHandler(
+		QuerierServiceSelectHeatmapProcedure,
+		svc.SelectHeatmap,
+		opts...,
+	)
+	querierServiceSelectProfileByIDHandler := connect_go.NewUnaryHandler(
+		QuerierServiceSelectProfileByIDProcedure,
+		svc.SelectProfileByID,
+		opts...,
+	)
+	querierServiceCardinalityHandler := connect_go.NewUnaryHandler(
+		QuerierServiceCardinalityProcedure,
+		svc.Cardinality,
+		opts...,
+	)
 	querierServiceDiffHandler := connect_go.NewUnaryHandler(
 		QuerierServiceDiffProcedure,
 		svc.Diff,
 		opts...,
 	)
+	querierServiceDiffReportHandler := connect_go.NewUnaryHandler(
+		QuerierServiceDiffReportProcedure,
+		svc.DiffReport,
+		opts...,
+	)
 	return "/querier.v1.QuerierService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case QuerierServiceProfileTypesProcedure:
@@ -260,12 +469,30 @@ func NewQuerierServiceHandler(svc QuerierServiceHandler, opts ...connect_go.Hand
 			querierServiceSeriesHandler.ServeHTTP(w, r)
 		case QuerierServiceSelectMergeStacktracesProcedure:
 			querierServiceSelectMergeStacktracesHandler.ServeHTTP(w, r)
+		case QuerierServiceSelectMergeSpanProfileProcedure:
+			querierServiceSelectMergeSpanProfileHandler.ServeHTTP(w, r)
 		case QuerierServiceSelectMergeProfileProcedure:
 			querierServiceSelectMergeProfileHandler.ServeHTTP(w, r)
 		case QuerierServiceSelectSeriesProcedure:
 			querierServiceSelectSeriesHandler.ServeHTTP(w, r)
+		case QuerierServiceSelectFunctionTableProcedure:
+			querierServiceSelectFunctionTableHandler.ServeHTTP(w, r)
+		case QuerierServiceSelectFunctionSeriesProcedure:
+			querierServiceSelectFunctionSeriesHandler.ServeHTTP(w, r)
+		case QuerierServiceSelectFunctionLinesProcedure:
+			querierServiceSelectFunctionLinesHandler.ServeHTTP(w, r)
+		case QuerierServiceSelectCallGraphProcedure:
+			querierServiceSelectCallGraphHandler.ServeHTTP(w, r)
+		case QuerierServiceSelectHeatmapProcedure:
+			querierServiceSelectHeatmapHandler.ServeHTTP(w, r)
+		case QuerierServiceSelectProfileByIDProcedure:
+			querierServiceSelectProfileByIDHandler.ServeHTTP(w, r)
+		case QuerierServiceCardinalityProcedure:
+			querierServiceCardinalityHandler.ServeHTTP(w, r)
 		case QuerierServiceDiffProcedure:
 			querierServiceDiffHandler.ServeHTTP(w, r)
+		case QuerierServiceDiffReportProcedure:
+			querierServiceDiffReportHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -295,6 +522,10 @@ func (UnimplementedQuerierServiceHandler) SelectMergeStacktraces(context.Context
 	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectMergeStacktraces is not implemented"))
 }
 
+func (UnimplementedQuerierServiceHandler) SelectMergeSpanProfile(context.Context, *connect_go.Request[v1.SelectMergeSpanProfileRequest]) (*connect_go.Response[v1.SelectMergeSpanProfileResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectMergeSpanProfile is not implemented"))
+}
+
 func (UnimplementedQuerierServiceHandler) SelectMergeProfile(context.Context, *connect_go.Request[v1.SelectMergeProfileRequest]) (*connect_go.Response[v12.Profile], error) {
 	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectMergeProfile is not implemented"))
 }
@@ -303,6 +534,38 @@ func (UnimplementedQuerierServiceHandler) SelectSeries(context.Context, *connect
 	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectSeries is not implemented"))
 }
 
+func (UnimplementedQuerierServiceHandler) SelectFunctionTable(context.Context, *connect_go.Request[v1.SelectFunctionTableRequest]) (*connect_go.Response[v1.SelectFunctionTableResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectFunctionTable is not implemented"))
+}
+
+func (UnimplementedQuerierServiceHandler) SelectFunctionSeries(context.Context, *connect_go.Request[v1.SelectFunctionSeriesRequest]) (*connect_go.Response[v1.SelectSeriesResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectFunctionSeries is not implemented"))
+}
+
+func (UnimplementedQuerierServiceHandler) SelectFunctionLines(context.Context, *connect_go.Request[v1.SelectFunctionLinesRequest]) (*connect_go.Response[v1.SelectFunctionLinesResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectFunctionLines is not implemented"))
+}
+
+func (UnimplementedQuerierServiceHandler) SelectCallGraph(context.Context, *connect_go.Request[v1.SelectCallGraphRequest]) (*connect_go.Response[v1.SelectCallGraphResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectCallGraph is not implemented"))
+}
+
+func (UnimplementedQuerierServiceHandler) SelectHeatmap(context.Context, *connect_go.Request[v1.SelectHeatmapRequest]) (*connect_go.Response[v1.SelectHeatmapResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectHeatmap is not implemented"))
+}
+
+func (UnimplementedQuerierServiceHandler) SelectProfileByID(context.Context, *connect_go.Request[v1.SelectProfileByIDRequest]) (*connect_go.Response[v12.Profile], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectProfileByID is not implemented"))
+}
+
+func (UnimplementedQuerierServiceHandler) Cardinality(context.Context, *connect_go.Request[v1.CardinalityRequest]) (*connect_go.Response[v1.CardinalityResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.Cardinality is not implemented"))
+}
+
 func (UnimplementedQuerierServiceHandler) Diff(context.Context, *connect_go.Request[v1.DiffRequest]) (*connect_go.Response[v1.DiffResponse], error) {
 	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.Diff is not implemented"))
 }
+
+func (UnimplementedQuerierServiceHandler) DiffReport(context.Context, *connect_go.Request[v1.DiffReportRequest]) (*connect_go.Response[v1.DiffReportResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("querier.v1.QuerierService.DiffReport is not implemented"))
+}
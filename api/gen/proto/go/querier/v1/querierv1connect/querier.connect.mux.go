@@ -44,6 +44,11 @@ func RegisterQuerierServiceHandler(mux *mux.Router, svc QuerierServiceHandler, o
 		svc.SelectMergeStacktraces,
 		opts...,
 	))
+	mux.Handle("/querier.v1.QuerierService/SelectMergeSpanProfile", connect_go.NewUnaryHandler(
+		"/querier.v1.QuerierService/SelectMergeSpanProfile",
+		svc.SelectMergeSpanProfile,
+		opts...,
+	))
 	mux.Handle("/querier.v1.QuerierService/SelectMergeProfile", connect_go.NewUnaryHandler(
 		"/querier.v1.QuerierService/SelectMergeProfile",
 		svc.SelectMergeProfile,
@@ -54,9 +59,49 @@ func RegisterQuerierServiceHandler(mux *mux.Router, svc QuerierServiceHandler, o
 		svc.SelectSeries,
 		opts...,
 	))
+	mux.Handle("/querier.v1.QuerierService/SelectFunctionTable", connect_go.NewUnaryHandler(
+		"/querier.v1.QuerierService/SelectFunctionTable",
+		svc.SelectFunctionTable,
+		opts...,
+	))
+	mux.Handle("/querier.v1.QuerierService/SelectFunctionSeries", connect_go.NewUnaryHandler(
+		"/querier.v1.QuerierService/SelectFunctionSeries",
+		svc.SelectFunctionSeries,
+		opts...,
+	))
+	mux.Handle("/querier.v1.QuerierService/SelectFunctionLines", connect_go.NewUnaryHandler(
+		"/querier.v1.QuerierService/SelectFunctionLines",
+		svc.SelectFunctionLines,
+		opts...,
+	))
+	mux.Handle("/querier.v1.QuerierService/SelectCallGraph", connect_go.NewUnaryHandler(
+		"/querier.v1.QuerierService/SelectCallGraph",
+		svc.SelectCallGraph,
+		opts...,
+	))
+	mux.Handle("/querier.v1.QuerierService/SelectHeatmap", connect_go.NewUnaryHandler(
+		"/querier.v1.QuerierService/SelectHeatmap",
+		svc.SelectHeatmap,
+		opts...,
+	))
+	mux.Handle("/querier.v1.QuerierService/SelectProfileByID", connect_go.NewUnaryHandler(
+		"/querier.v1.QuerierService/SelectProfileByID",
+		svc.SelectProfileByID,
+		opts...,
+	))
+	mux.Handle("/querier.v1.QuerierService/Cardinality", connect_go.NewUnaryHandler(
+		"/querier.v1.QuerierService/Cardinality",
+		svc.Cardinality,
+		opts...,
+	))
 	mux.Handle("/querier.v1.QuerierService/Diff", connect_go.NewUnaryHandler(
 		"/querier.v1.QuerierService/Diff",
 		svc.Diff,
 		opts...,
 	))
+	mux.Handle("/querier.v1.QuerierService/DiffReport", connect_go.NewUnaryHandler(
+		"/querier.v1.QuerierService/DiffReport",
+		svc.DiffReport,
+		opts...,
+	))
 }
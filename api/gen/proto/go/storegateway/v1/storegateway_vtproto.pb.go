@@ -7,6 +7,7 @@ package storegatewayv1
 import (
 	context "context"
 	v1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	v11 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -33,6 +34,10 @@ type StoreGatewayServiceClient interface {
 	MergeProfilesLabels(ctx context.Context, opts ...grpc.CallOption) (StoreGatewayService_MergeProfilesLabelsClient, error)
 	MergeProfilesPprof(ctx context.Context, opts ...grpc.CallOption) (StoreGatewayService_MergeProfilesPprofClient, error)
 	Series(ctx context.Context, in *v1.SeriesRequest, opts ...grpc.CallOption) (*v1.SeriesResponse, error)
+	LabelValues(ctx context.Context, in *v11.LabelValuesRequest, opts ...grpc.CallOption) (*v11.LabelValuesResponse, error)
+	LabelNames(ctx context.Context, in *v11.LabelNamesRequest, opts ...grpc.CallOption) (*v11.LabelNamesResponse, error)
+	SelectProfileByID(ctx context.Context, in *v1.SelectProfileByIDRequest, opts ...grpc.CallOption) (*v1.SelectProfileByIDResponse, error)
+	Cardinality(ctx context.Context, in *v1.CardinalityRequest, opts ...grpc.CallOption) (*v1.CardinalityResponse, error)
 }
 
 type storeGatewayServiceClient struct {
@@ -145,6 +150,42 @@ func (c *storeGatewayServiceClient) Series(ctx context.Context, in *v1.SeriesReq
 	return out, nil
 }
 
+func (c *storeGatewayServiceClient) LabelValues(ctx context.Context, in *v11.LabelValuesRequest, opts ...grpc.CallOption) (*v11.LabelValuesResponse, error) {
+	out := new(v11.LabelValuesResponse)
+	err := c.cc.Invoke(ctx, "/storegateway.v1.StoreGatewayService/LabelValues", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeGatewayServiceClient) LabelNames(ctx context.Context, in *v11.LabelNamesRequest, opts ...grpc.CallOption) (*v11.LabelNamesResponse, error) {
+	out := new(v11.LabelNamesResponse)
+	err := c.cc.Invoke(ctx, "/storegateway.v1.StoreGatewayService/LabelNames", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeGatewayServiceClient) SelectProfileByID(ctx context.Context, in *v1.SelectProfileByIDRequest, opts ...grpc.CallOption) (*v1.SelectProfileByIDResponse, error) {
+	out := new(v1.SelectProfileByIDResponse)
+	err := c.cc.Invoke(ctx, "/storegateway.v1.StoreGatewayService/SelectProfileByID", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeGatewayServiceClient) Cardinality(ctx context.Context, in *v1.CardinalityRequest, opts ...grpc.CallOption) (*v1.CardinalityResponse, error) {
+	out := new(v1.CardinalityResponse)
+	err := c.cc.Invoke(ctx, "/storegateway.v1.StoreGatewayService/Cardinality", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StoreGatewayServiceServer is the server API for StoreGatewayService service.
 // All implementations must embed UnimplementedStoreGatewayServiceServer
 // for forward compatibility
@@ -153,6 +194,10 @@ type StoreGatewayServiceServer interface {
 	MergeProfilesLabels(StoreGatewayService_MergeProfilesLabelsServer) error
 	MergeProfilesPprof(StoreGatewayService_MergeProfilesPprofServer) error
 	Series(context.Context, *v1.SeriesRequest) (*v1.SeriesResponse, error)
+	LabelValues(context.Context, *v11.LabelValuesRequest) (*v11.LabelValuesResponse, error)
+	LabelNames(context.Context, *v11.LabelNamesRequest) (*v11.LabelNamesResponse, error)
+	SelectProfileByID(context.Context, *v1.SelectProfileByIDRequest) (*v1.SelectProfileByIDResponse, error)
+	Cardinality(context.Context, *v1.CardinalityRequest) (*v1.CardinalityResponse, error)
 	mustEmbedUnimplementedStoreGatewayServiceServer()
 }
 
@@ -172,6 +217,18 @@ func (UnimplementedStoreGatewayServiceServer) MergeProfilesPprof(StoreGatewaySer
 func (UnimplementedStoreGatewayServiceServer) Series(context.Context, *v1.SeriesRequest) (*v1.SeriesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Series not implemented")
 }
+func (UnimplementedStoreGatewayServiceServer) LabelValues(context.Context, *v11.LabelValuesRequest) (*v11.LabelValuesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LabelValues not implemented")
+}
+func (UnimplementedStoreGatewayServiceServer) LabelNames(context.Context, *v11.LabelNamesRequest) (*v11.LabelNamesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LabelNames not implemented")
+}
+func (UnimplementedStoreGatewayServiceServer) SelectProfileByID(context.Context, *v1.SelectProfileByIDRequest) (*v1.SelectProfileByIDResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SelectProfileByID not implemented")
+}
+func (UnimplementedStoreGatewayServiceServer) Cardinality(context.Context, *v1.CardinalityRequest) (*v1.CardinalityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cardinality not implemented")
+}
 func (UnimplementedStoreGatewayServiceServer) mustEmbedUnimplementedStoreGatewayServiceServer() {}
 
 // UnsafeStoreGatewayServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -281,6 +338,78 @@ func _StoreGatewayService_Series_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StoreGatewayService_LabelValues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(v11.LabelValuesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreGatewayServiceServer).LabelValues(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storegateway.v1.StoreGatewayService/LabelValues",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreGatewayServiceServer).LabelValues(ctx, req.(*v11.LabelValuesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StoreGatewayService_LabelNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(v11.LabelNamesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreGatewayServiceServer).LabelNames(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storegateway.v1.StoreGatewayService/LabelNames",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreGatewayServiceServer).LabelNames(ctx, req.(*v11.LabelNamesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StoreGatewayService_SelectProfileByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(v1.SelectProfileByIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreGatewayServiceServer).SelectProfileByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storegateway.v1.StoreGatewayService/SelectProfileByID",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreGatewayServiceServer).SelectProfileByID(ctx, req.(*v1.SelectProfileByIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StoreGatewayService_Cardinality_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(v1.CardinalityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreGatewayServiceServer).Cardinality(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storegateway.v1.StoreGatewayService/Cardinality",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreGatewayServiceServer).Cardinality(ctx, req.(*v1.CardinalityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // StoreGatewayService_ServiceDesc is the grpc.ServiceDesc for StoreGatewayService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -292,6 +421,22 @@ var StoreGatewayService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Series",
 			Handler:    _StoreGatewayService_Series_Handler,
 		},
+		{
+			MethodName: "LabelValues",
+			Handler:    _StoreGatewayService_LabelValues_Handler,
+		},
+		{
+			MethodName: "LabelNames",
+			Handler:    _StoreGatewayService_LabelNames_Handler,
+		},
+		{
+			MethodName: "SelectProfileByID",
+			Handler:    _StoreGatewayService_SelectProfileByID_Handler,
+		},
+		{
+			MethodName: "Cardinality",
+			Handler:    _StoreGatewayService_Cardinality_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -10,6 +10,7 @@ import (
 	connect_go "github.com/bufbuild/connect-go"
 	v1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
 	_ "github.com/grafana/pyroscope/api/gen/proto/go/storegateway/v1"
+	v11 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
 	http "net/http"
 	strings "strings"
 )
@@ -46,6 +47,18 @@ const (
 	// StoreGatewayServiceSeriesProcedure is the fully-qualified name of the StoreGatewayService's
 	// Series RPC.
 	StoreGatewayServiceSeriesProcedure = "/storegateway.v1.StoreGatewayService/Series"
+	// StoreGatewayServiceLabelValuesProcedure is the fully-qualified name of the StoreGatewayService's
+	// LabelValues RPC.
+	StoreGatewayServiceLabelValuesProcedure = "/storegateway.v1.StoreGatewayService/LabelValues"
+	// StoreGatewayServiceLabelNamesProcedure is the fully-qualified name of the StoreGatewayService's
+	// LabelNames RPC.
+	StoreGatewayServiceLabelNamesProcedure = "/storegateway.v1.StoreGatewayService/LabelNames"
+	// StoreGatewayServiceSelectProfileByIDProcedure is the fully-qualified name of the
+	// StoreGatewayService's SelectProfileByID RPC.
+	StoreGatewayServiceSelectProfileByIDProcedure = "/storegateway.v1.StoreGatewayService/SelectProfileByID"
+	// StoreGatewayServiceCardinalityProcedure is the fully-qualified name of the StoreGatewayService's
+	// Cardinality RPC.
+	StoreGatewayServiceCardinalityProcedure = "/storegateway.v1.StoreGatewayService/Cardinality"
 )
 
 // StoreGatewayServiceClient is a client for the storegateway.v1.StoreGatewayService service.
@@ -54,6 +67,10 @@ type StoreGatewayServiceClient interface {
 	MergeProfilesLabels(context.Context) *connect_go.BidiStreamForClient[v1.MergeProfilesLabelsRequest, v1.MergeProfilesLabelsResponse]
 	MergeProfilesPprof(context.Context) *connect_go.BidiStreamForClient[v1.MergeProfilesPprofRequest, v1.MergeProfilesPprofResponse]
 	Series(context.Context, *connect_go.Request[v1.SeriesRequest]) (*connect_go.Response[v1.SeriesResponse], error)
+	LabelValues(context.Context, *connect_go.Request[v11.LabelValuesRequest]) (*connect_go.Response[v11.LabelValuesResponse], error)
+	LabelNames(context.Context, *connect_go.Request[v11.LabelNamesRequest]) (*connect_go.Response[v11.LabelNamesResponse], error)
+	SelectProfileByID(context.Context, *connect_go.Request[v1.SelectProfileByIDRequest]) (*connect_go.Response[v1.SelectProfileByIDResponse], error)
+	Cardinality(context.Context, *connect_go.Request[v1.CardinalityRequest]) (*connect_go.Response[v1.CardinalityResponse], error)
 }
 
 // NewStoreGatewayServiceClient constructs a client for the storegateway.v1.StoreGatewayService
@@ -86,6 +103,26 @@ func NewStoreGatewayServiceClient(httpClient connect_go.HTTPClient, baseURL stri
 			baseURL+StoreGatewayServiceSeriesProcedure,
 			opts...,
 		),
+		labelValues: connect_go.NewClient[v11.LabelValuesRequest, v11.LabelValuesResponse](
+			httpClient,
+			baseURL+StoreGatewayServiceLabelValuesProcedure,
+			opts...,
+		),
+		labelNames: connect_go.NewClient[v11.LabelNamesRequest, v11.LabelNamesResponse](
+			httpClient,
+			baseURL+StoreGatewayServiceLabelNamesProcedure,
+			opts...,
+		),
+		selectProfileByID: connect_go.NewClient[v1.SelectProfileByIDRequest, v1.SelectProfileByIDResponse](
+			httpClient,
+			baseURL+StoreGatewayServiceSelectProfileByIDProcedure,
+			opts...,
+		),
+		cardinality: connect_go.NewClient[v1.CardinalityRequest, v1.CardinalityResponse](
+			httpClient,
+			baseURL+StoreGatewayServiceCardinalityProcedure,
+			opts...,
+		),
 	}
 }
 
@@ -95,6 +132,10 @@ type storeGatewayServiceClient struct {
 	mergeProfilesLabels      *connect_go.Client[v1.MergeProfilesLabelsRequest, v1.MergeProfilesLabelsResponse]
 	mergeProfilesPprof       *connect_go.Client[v1.MergeProfilesPprofRequest, v1.MergeProfilesPprofResponse]
 	series                   *connect_go.Client[v1.SeriesRequest, v1.SeriesResponse]
+	labelValues              *connect_go.Client[v11.LabelValuesRequest, v11.LabelValuesResponse]
+	labelNames               *connect_go.Client[v11.LabelNamesRequest, v11.LabelNamesResponse]
+	selectProfileByID        *connect_go.Client[v1.SelectProfileByIDRequest, v1.SelectProfileByIDResponse]
+	cardinality              *connect_go.Client[v1.CardinalityRequest, v1.CardinalityResponse]
 }
 
 // MergeProfilesStacktraces calls storegateway.v1.StoreGatewayService.MergeProfilesStacktraces.
@@ -117,6 +158,26 @@ func (c *storeGatewayServiceClient) Series(ctx context.Context, req *connect_go.
 	return c.series.CallUnary(ctx, req)
 }
 
+// LabelValues calls storegateway.v1.StoreGatewayService.LabelValues.
+func (c *storeGatewayServiceClient) LabelValues(ctx context.Context, req *connect_go.Request[v11.LabelValuesRequest]) (*connect_go.Response[v11.LabelValuesResponse], error) {
+	return c.labelValues.CallUnary(ctx, req)
+}
+
+// LabelNames calls storegateway.v1.StoreGatewayService.LabelNames.
+func (c *storeGatewayServiceClient) LabelNames(ctx context.Context, req *connect_go.Request[v11.LabelNamesRequest]) (*connect_go.Response[v11.LabelNamesResponse], error) {
+	return c.labelNames.CallUnary(ctx, req)
+}
+
+// SelectProfileByID calls storegateway.v1.StoreGatewayService.SelectProfileByID.
+func (c *storeGatewayServiceClient) SelectProfileByID(ctx context.Context, req *connect_go.Request[v1.SelectProfileByIDRequest]) (*connect_go.Response[v1.SelectProfileByIDResponse], error) {
+	return c.selectProfileByID.CallUnary(ctx, req)
+}
+
+// Cardinality calls storegateway.v1.StoreGatewayService.Cardinality.
+func (c *storeGatewayServiceClient) Cardinality(ctx context.Context, req *connect_go.Request[v1.CardinalityRequest]) (*connect_go.Response[v1.CardinalityResponse], error) {
+	return c.cardinality.CallUnary(ctx, req)
+}
+
 // StoreGatewayServiceHandler is an implementation of the storegateway.v1.StoreGatewayService
 // service.
 type StoreGatewayServiceHandler interface {
@@ -124,6 +185,10 @@ type StoreGatewayServiceHandler interface {
 	MergeProfilesLabels(context.Context, *connect_go.BidiStream[v1.MergeProfilesLabelsRequest, v1.MergeProfilesLabelsResponse]) error
 	MergeProfilesPprof(context.Context, *connect_go.BidiStream[v1.MergeProfilesPprofRequest, v1.MergeProfilesPprofResponse]) error
 	Series(context.Context, *connect_go.Request[v1.SeriesRequest]) (*connect_go.Response[v1.SeriesResponse], error)
+	LabelValues(context.Context, *connect_go.Request[v11.LabelValuesRequest]) (*connect_go.Response[v11.LabelValuesResponse], error)
+	LabelNames(context.Context, *connect_go.Request[v11.LabelNamesRequest]) (*connect_go.Response[v11.LabelNamesResponse], error)
+	SelectProfileByID(context.Context, *connect_go.Request[v1.SelectProfileByIDRequest]) (*connect_go.Response[v1.SelectProfileByIDResponse], error)
+	Cardinality(context.Context, *connect_go.Request[v1.CardinalityRequest]) (*connect_go.Response[v1.CardinalityResponse], error)
 }
 
 // NewStoreGatewayServiceHandler builds an HTTP handler from the service implementation. It returns
@@ -152,6 +217,26 @@ func NewStoreGatewayServiceHandler(svc StoreGatewayServiceHandler, opts ...conne
 		svc.Series,
 		opts...,
 	)
+	storeGatewayServiceLabelValuesHandler := connect_go.NewUnaryHandler(
+		StoreGatewayServiceLabelValuesProcedure,
+		svc.LabelValues,
+		opts...,
+	)
+	storeGatewayServiceLabelNamesHandler := connect_go.NewUnaryHandler(
+		StoreGatewayServiceLabelNamesProcedure,
+		svc.LabelNames,
+		opts...,
+	)
+	storeGatewayServiceSelectProfileByIDHandler := connect_go.NewUnaryHandler(
+		StoreGatewayServiceSelectProfileByIDProcedure,
+		svc.SelectProfileByID,
+		opts...,
+	)
+	storeGatewayServiceCardinalityHandler := connect_go.NewUnaryHandler(
+		StoreGatewayServiceCardinalityProcedure,
+		svc.Cardinality,
+		opts...,
+	)
 	return "/storegateway.v1.StoreGatewayService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case StoreGatewayServiceMergeProfilesStacktracesProcedure:
@@ -162,6 +247,14 @@ func NewStoreGatewayServiceHandler(svc StoreGatewayServiceHandler, opts ...conne
 			storeGatewayServiceMergeProfilesPprofHandler.ServeHTTP(w, r)
 		case StoreGatewayServiceSeriesProcedure:
 			storeGatewayServiceSeriesHandler.ServeHTTP(w, r)
+		case StoreGatewayServiceLabelValuesProcedure:
+			storeGatewayServiceLabelValuesHandler.ServeHTTP(w, r)
+		case StoreGatewayServiceLabelNamesProcedure:
+			storeGatewayServiceLabelNamesHandler.ServeHTTP(w, r)
+		case StoreGatewayServiceSelectProfileByIDProcedure:
+			storeGatewayServiceSelectProfileByIDHandler.ServeHTTP(w, r)
+		case StoreGatewayServiceCardinalityProcedure:
+			storeGatewayServiceCardinalityHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -186,3 +279,19 @@ func (UnimplementedStoreGatewayServiceHandler) MergeProfilesPprof(context.Contex
 func (UnimplementedStoreGatewayServiceHandler) Series(context.Context, *connect_go.Request[v1.SeriesRequest]) (*connect_go.Response[v1.SeriesResponse], error) {
 	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("storegateway.v1.StoreGatewayService.Series is not implemented"))
 }
+
+func (UnimplementedStoreGatewayServiceHandler) LabelValues(context.Context, *connect_go.Request[v11.LabelValuesRequest]) (*connect_go.Response[v11.LabelValuesResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("storegateway.v1.StoreGatewayService.LabelValues is not implemented"))
+}
+
+func (UnimplementedStoreGatewayServiceHandler) LabelNames(context.Context, *connect_go.Request[v11.LabelNamesRequest]) (*connect_go.Response[v11.LabelNamesResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("storegateway.v1.StoreGatewayService.LabelNames is not implemented"))
+}
+
+func (UnimplementedStoreGatewayServiceHandler) SelectProfileByID(context.Context, *connect_go.Request[v1.SelectProfileByIDRequest]) (*connect_go.Response[v1.SelectProfileByIDResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("storegateway.v1.StoreGatewayService.SelectProfileByID is not implemented"))
+}
+
+func (UnimplementedStoreGatewayServiceHandler) Cardinality(context.Context, *connect_go.Request[v1.CardinalityRequest]) (*connect_go.Response[v1.CardinalityResponse], error) {
+	return nil, connect_go.NewError(connect_go.CodeUnimplemented, errors.New("storegateway.v1.StoreGatewayService.Cardinality is not implemented"))
+}
@@ -39,4 +39,24 @@ func RegisterStoreGatewayServiceHandler(mux *mux.Router, svc StoreGatewayService
 		svc.Series,
 		opts...,
 	))
+	mux.Handle("/storegateway.v1.StoreGatewayService/LabelValues", connect_go.NewUnaryHandler(
+		"/storegateway.v1.StoreGatewayService/LabelValues",
+		svc.LabelValues,
+		opts...,
+	))
+	mux.Handle("/storegateway.v1.StoreGatewayService/LabelNames", connect_go.NewUnaryHandler(
+		"/storegateway.v1.StoreGatewayService/LabelNames",
+		svc.LabelNames,
+		opts...,
+	))
+	mux.Handle("/storegateway.v1.StoreGatewayService/SelectProfileByID", connect_go.NewUnaryHandler(
+		"/storegateway.v1.StoreGatewayService/SelectProfileByID",
+		svc.SelectProfileByID,
+		opts...,
+	))
+	mux.Handle("/storegateway.v1.StoreGatewayService/Cardinality", connect_go.NewUnaryHandler(
+		"/storegateway.v1.StoreGatewayService/Cardinality",
+		svc.Cardinality,
+		opts...,
+	))
 }
@@ -54,6 +54,8 @@ func main() {
 	queryMergeParams := addQueryMergeParams(queryMergeCmd)
 	querySeriesCmd := queryCmd.Command("series", "Request series labels.")
 	querySeriesParams := addQuerySeriesParams(querySeriesCmd)
+	queryCardinalityCmd := queryCmd.Command("cardinality", "Request label and series cardinality statistics.")
+	queryCardinalityParams := addQueryCardinalityParams(queryCardinalityCmd)
 
 	uploadCmd := app.Command("upload", "Upload profile(s).")
 	uploadParams := addUploadParams(uploadCmd)
@@ -86,6 +88,10 @@ func main() {
 		if err := querySeries(ctx, querySeriesParams); err != nil {
 			os.Exit(checkError(err))
 		}
+	case queryCardinalityCmd.FullCommand():
+		if err := queryCardinality(ctx, queryCardinalityParams); err != nil {
+			os.Exit(checkError(err))
+		}
 	case uploadCmd.FullCommand():
 		if err := upload(ctx, uploadParams); err != nil {
 			os.Exit(checkError(err))
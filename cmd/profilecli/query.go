@@ -18,6 +18,7 @@ import (
 	"github.com/k0kubun/pp/v3"
 	"github.com/klauspost/compress/gzip"
 	"github.com/mattn/go-isatty"
+	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
 
@@ -314,3 +315,56 @@ func querySeries(ctx context.Context, params *querySeriesParams) (err error) {
 	return nil
 
 }
+
+type queryCardinalityParams struct {
+	*queryParams
+	Limit int32
+}
+
+func addQueryCardinalityParams(queryCmd commander) *queryCardinalityParams {
+	params := new(queryCardinalityParams)
+	params.queryParams = addQueryParams(queryCmd)
+	queryCmd.Flag("limit", "Number of entries to return for each statistic.").Default("10").Int32Var(&params.Limit)
+	return params
+}
+
+func queryCardinality(ctx context.Context, params *queryCardinalityParams) (err error) {
+	from, to, err := params.parseFromTo()
+	if err != nil {
+		return err
+	}
+
+	level.Info(logger).Log("msg", "query cardinality", "url", params.URL, "from", from, "to", to, "query", params.Query, "limit", params.Limit)
+
+	qc := params.phlareClient.queryClient()
+	resp, err := qc.Cardinality(ctx, connect.NewRequest(&querierv1.CardinalityRequest{
+		Start:    from.UnixMilli(),
+		End:      to.UnixMilli(),
+		Matchers: []string{params.Query},
+		Limit:    params.Limit,
+	}))
+	if err != nil {
+		return errors.Wrap(err, "failed to query")
+	}
+
+	fmt.Fprintf(output(ctx), "Total series: %d\n", resp.Msg.TotalSeries)
+	for _, s := range []struct {
+		header []string
+		stats  []*querierv1.CardinalityStat
+	}{
+		{[]string{"Label name", "Values"}, resp.Msg.LabelValueCountByLabelName},
+		{[]string{"Label pair", "Series"}, resp.Msg.SeriesCountByLabelValuePair},
+		{[]string{"Service name", "Series"}, resp.Msg.SeriesCountByServiceName},
+		{[]string{"Profile type", "Series"}, resp.Msg.SeriesCountByProfileType},
+	} {
+		fmt.Fprintln(output(ctx))
+		table := tablewriter.NewWriter(output(ctx))
+		table.SetHeader(s.header)
+		for _, stat := range s.stats {
+			table.Append([]string{stat.Name, strconv.FormatUint(stat.Value, 10)})
+		}
+		table.Render()
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/bufbuild/connect-go"
@@ -11,6 +12,7 @@ import (
 	pushv1 "github.com/grafana/pyroscope/api/gen/proto/go/push/v1"
 	"github.com/grafana/pyroscope/api/gen/proto/go/push/v1/pushv1connect"
 	"github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/og/convert/perf"
 	"github.com/grafana/pyroscope/pkg/pprof"
 )
 
@@ -24,6 +26,7 @@ func (c *phlareClient) pusherClient() pushv1connect.PusherServiceClient {
 type uploadParams struct {
 	*phlareClient
 	paths       []string
+	inputType   string
 	extraLabels map[string]string
 }
 
@@ -36,6 +39,7 @@ func addUploadParams(cmd commander) *uploadParams {
 	params.phlareClient = addPhlareClient(cmd)
 
 	cmd.Arg("path", "Path(s) to profile(s) to upload").Required().ExistingFilesVar(&params.paths)
+	cmd.Flag("input-type", "Format of the profile(s) to upload. For perf.data files, a profile is uploaded for every recorded event; locations are named after the mapped file and the offset, as perf.data has no symbols.").Default("pprof").EnumVar(&params.inputType, "pprof", "perf")
 	cmd.Flag("extra-labels", "Add additional labels to the profile(s)").Default("job=profilecli-upload").StringMapVar(&params.extraLabels)
 	return params
 }
@@ -50,15 +54,28 @@ func upload(ctx context.Context, params *uploadParams) (err error) {
 
 	var (
 		lbl        = model.LabelsFromStrings(lblStrings...)
-		series     = make([]*pushv1.RawProfileSeries, len(params.paths))
+		series     = make([]*pushv1.RawProfileSeries, 0, len(params.paths))
+		paths      = make([]string, 0, len(params.paths))
 		lblBuilder = model.NewLabelsBuilder(lbl)
 	)
-	for idx, path := range params.paths {
+	for _, path := range params.paths {
 		lblBuilder.Reset(lbl)
 
 		data, err := os.ReadFile(path)
 		if err != nil {
-			return nil
+			return err
+		}
+
+		if params.inputType == "perf" {
+			s, err := perfDataSeries(data, lbl)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			for range s {
+				paths = append(paths, path)
+			}
+			series = append(series, s...)
+			continue
 		}
 
 		profile, err := pprof.RawFromBytes(data)
@@ -85,13 +102,14 @@ func upload(ctx context.Context, params *uploadParams) (err error) {
 			lblBuilder.Set(model.LabelNameProfileName, name)
 		}
 
-		series[idx] = &pushv1.RawProfileSeries{
+		series = append(series, &pushv1.RawProfileSeries{
 			Labels: lblBuilder.Labels(),
 			Samples: []*pushv1.RawSample{{
 				ID:         uuid.New().String(),
 				RawProfile: data,
 			}},
-		}
+		})
+		paths = append(paths, path)
 	}
 
 	_, err = pc.Push(ctx, connect.NewRequest(&pushv1.PushRequest{
@@ -103,8 +121,38 @@ func upload(ctx context.Context, params *uploadParams) (err error) {
 	}
 
 	for idx := range series {
-		level.Info(logger).Log("msg", "successfully uploaded profile", "id", series[idx].Samples[0].ID, "labels", model.Labels(series[idx].Labels).ToPrometheusLabels().String(), "path", params.paths[idx])
+		level.Info(logger).Log("msg", "successfully uploaded profile", "id", series[idx].Samples[0].ID, "labels", model.Labels(series[idx].Labels).ToPrometheusLabels().String(), "path", paths[idx])
 	}
 
 	return nil
 }
+
+// perfDataSeries converts the perf.data file to pprof, and returns
+// a series for every event recorded.
+func perfDataSeries(data []byte, lbl model.Labels) ([]*pushv1.RawProfileSeries, error) {
+	profiles, err := perf.ParseData(data)
+	if err != nil {
+		return nil, err
+	}
+	lblBuilder := model.NewLabelsBuilder(lbl)
+	series := make([]*pushv1.RawProfileSeries, 0, len(profiles))
+	for _, p := range profiles {
+		raw, err := p.Profile.MarshalVT()
+		if err != nil {
+			return nil, err
+		}
+		lblBuilder.Reset(lbl)
+		if lbl.Get(model.LabelNameProfileName) == "" {
+			lblBuilder.Set(model.LabelNameProfileName, p.Name)
+		}
+		lblBuilder.Set(perf.LabelNameEvent, p.Event)
+		series = append(series, &pushv1.RawProfileSeries{
+			Labels: lblBuilder.Labels(),
+			Samples: []*pushv1.RawSample{{
+				ID:         uuid.New().String(),
+				RawProfile: raw,
+			}},
+		})
+	}
+	return series, nil
+}
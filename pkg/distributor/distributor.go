@@ -26,6 +26,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/relabel"
 	"go.uber.org/atomic"
 
 	googlev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
@@ -59,8 +60,9 @@ const (
 
 var (
 	ignoredPprofLabels = []string{
-		"profile_id",          // For compatibility with the existing clients.
-		"span_id", "trace_id", // Will be supported in the future.
+		"profile_id",                // For compatibility with the existing clients.
+		phlaremodel.LabelNameSpanID, // Stored per sample.
+		"trace_id",                  // Will be supported in the future.
 	}
 )
 
@@ -121,6 +123,8 @@ type Limits interface {
 	MaxProfileStacktraceDepth(tenantID string) int
 	MaxProfileSymbolValueLength(tenantID string) int
 	MaxSessionsPerSeries(tenantID string) int
+	IngestionRelabelingRules(tenantID string) []*relabel.Config
+	IngestionSampleRelabelingRules(tenantID string) []*relabel.Config
 	validation.ProfileValidationLimits
 }
 
@@ -325,12 +329,22 @@ func (d *Distributor) PushParsed(ctx context.Context, req *distributormodel.Push
 		}
 	}()
 
+	sampleRelabelingRules := d.limits.IngestionSampleRelabelingRules(tenantID)
 	for _, series := range req.Series {
 		s := &distributormodel.ProfileSeries{
 			Labels:  series.Labels,
 			Samples: make([]*distributormodel.ProfileSample, 0, len(series.Samples)),
 		}
 		for _, raw := range series.Samples {
+			if len(sampleRelabelingRules) > 0 {
+				droppedBytes := relabelSampleLabels(raw.Profile.Profile, sampleRelabelingRules)
+				validation.DiscardedBytes.WithLabelValues(string(validation.DroppedByRelabelRules), tenantID).Add(float64(droppedBytes))
+				if droppedBytes > 0 && len(raw.Profile.Sample) == 0 {
+					// All the samples have been dropped.
+					validation.DiscardedProfiles.WithLabelValues(string(validation.DroppedByRelabelRules), tenantID).Add(1)
+					continue
+				}
+			}
 			raw.Profile.Normalize()
 			groups := pprof.GroupSamplesWithoutLabels(raw.Profile.Profile, ignoredPprofLabels...)
 			if len(groups) < 2 {
@@ -359,15 +373,36 @@ func (d *Distributor) PushParsed(ctx context.Context, req *distributormodel.Push
 		}
 	}
 
-	// Validate the labels again and generate tokens for shuffle sharding.
-	keys := make([]uint32, len(profileSeries))
-	for i, series := range profileSeries {
+	// Apply the relabeling rules, validate the labels again
+	// and generate tokens for shuffle sharding.
+	relabelingRules := d.limits.IngestionRelabelingRules(tenantID)
+	keys := make([]uint32, 0, len(profileSeries))
+	kept := profileSeries[:0]
+	for _, series := range profileSeries {
+		if len(relabelingRules) > 0 {
+			var keep bool
+			droppedBytes := labelsSize(series.Labels)
+			if series.Labels, keep = relabelSeriesLabels(series.Labels, relabelingRules); !keep {
+				for _, raw := range series.Samples {
+					droppedBytes += int64(raw.Profile.SizeVT())
+				}
+				validation.DiscardedProfiles.WithLabelValues(string(validation.DroppedByRelabelRules), tenantID).Add(float64(len(series.Samples)))
+				validation.DiscardedBytes.WithLabelValues(string(validation.DroppedByRelabelRules), tenantID).Add(float64(droppedBytes))
+				continue
+			}
+		}
 		if err = validation.ValidateLabels(d.limits, tenantID, series.Labels); err != nil {
 			validation.DiscardedProfiles.WithLabelValues(string(validation.ReasonOf(err)), tenantID).Add(float64(totalProfiles))
 			validation.DiscardedBytes.WithLabelValues(string(validation.ReasonOf(err)), tenantID).Add(float64(totalPushUncompressedBytes))
 			return nil, connect.NewError(connect.CodeInvalidArgument, err)
 		}
-		keys[i] = TokenFor(tenantID, phlaremodel.LabelPairsString(series.Labels))
+		keys = append(keys, TokenFor(tenantID, phlaremodel.LabelPairsString(series.Labels)))
+		kept = append(kept, series)
+	}
+	profileSeries = kept
+	if len(profileSeries) == 0 {
+		// All the series have been dropped by the relabeling rules.
+		return connect.NewResponse(&pushv1.PushResponse{}), nil
 	}
 
 	profiles := make([]*profileTracker, 0, len(profileSeries))
@@ -562,6 +597,15 @@ func (d *Distributor) limitMaxSessionsPerSeries(tenantID string, labels phlaremo
 	return labels
 }
 
+// labelsSize returns the size of the label names and values.
+func labelsSize(ls []*typesv1.LabelPair) int64 {
+	var size int64
+	for _, l := range ls {
+		size += int64(len(l.Name) + len(l.Value))
+	}
+	return size
+}
+
 // mergeSeriesAndSampleLabels merges sample labels with
 // series labels. Series labels take precedence.
 func mergeSeriesAndSampleLabels(p *googlev1.Profile, sl []*typesv1.LabelPair, pl []*googlev1.Label) []*typesv1.LabelPair {
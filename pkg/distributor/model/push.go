@@ -9,6 +9,10 @@ type RawProfileType string
 
 const RawProfileTypePPROF = RawProfileType("pprof")
 const RawProfileTypeJFR = RawProfileType("jfr")
+const RawProfileTypeOTLP = RawProfileType("otlp")
+const RawProfileTypeCPUProfile = RawProfileType("cpuprofile")
+const RawProfileTypePerf = RawProfileType("perf")
+const RawProfileTypeGecko = RawProfileType("gecko")
 
 type PushRequest struct {
 	RawProfileSize int
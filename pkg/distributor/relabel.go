@@ -0,0 +1,105 @@
+package distributor
+
+import (
+	"encoding/binary"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+
+	googlev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+// relabelSeriesLabels applies the relabeling rules to the series labels.
+// The second return value is false, if the series is dropped.
+func relabelSeriesLabels(ls phlaremodel.Labels, rules []*relabel.Config) (phlaremodel.Labels, bool) {
+	res, keep := relabel.Process(ls.ToPrometheusLabels(), rules...)
+	if !keep {
+		return nil, false
+	}
+	relabeled := make(phlaremodel.Labels, 0, len(res))
+	for _, l := range res {
+		relabeled = append(relabeled, &typesv1.LabelPair{Name: l.Name, Value: l.Value})
+	}
+	return relabeled, true
+}
+
+// relabelSampleLabels applies the relabeling rules to the string labels
+// of the profile samples: numeric labels are not affected. Samples
+// dropped by the rules are removed from the profile, and their size
+// is returned.
+func relabelSampleLabels(p *googlev1.Profile, rules []*relabel.Config) (droppedBytes int) {
+	type relabeled struct {
+		labels labels.Labels
+		keep   bool
+	}
+	var (
+		// Samples often share the same set of labels,
+		// therefore the results are cached.
+		cache = make(map[string]relabeled)
+		index map[string]int64
+		key   []byte
+		ls    []labels.Label
+	)
+	stringIndex := func(s string) int64 {
+		if index == nil {
+			index = make(map[string]int64, len(p.StringTable))
+			for i, x := range p.StringTable {
+				if _, ok := index[x]; !ok {
+					index[x] = int64(i)
+				}
+			}
+		}
+		if i, ok := index[s]; ok {
+			return i
+		}
+		i := int64(len(p.StringTable))
+		p.StringTable = append(p.StringTable, s)
+		index[s] = i
+		return i
+	}
+
+	n := 0
+	for _, s := range p.Sample {
+		key = key[:0]
+		for _, l := range s.Label {
+			if l.Str != 0 {
+				key = binary.AppendVarint(key, l.Key)
+				key = binary.AppendVarint(key, l.Str)
+			}
+		}
+		r, ok := cache[string(key)]
+		if !ok {
+			ls = ls[:0]
+			for _, l := range s.Label {
+				if l.Str != 0 {
+					ls = append(ls, labels.Label{Name: p.StringTable[l.Key], Value: p.StringTable[l.Str]})
+				}
+			}
+			r.labels, r.keep = relabel.Process(labels.New(ls...), rules...)
+			cache[string(key)] = r
+		}
+		if !r.keep {
+			droppedBytes += s.SizeVT()
+			continue
+		}
+		sampleLabels := make([]*googlev1.Label, 0, len(s.Label))
+		for _, l := range s.Label {
+			if l.Str == 0 {
+				sampleLabels = append(sampleLabels, l)
+			}
+		}
+		for _, l := range r.labels {
+			sampleLabels = append(sampleLabels, &googlev1.Label{Key: stringIndex(l.Name), Str: stringIndex(l.Value)})
+		}
+		s.Label = sampleLabels
+		p.Sample[n] = s
+		n++
+	}
+	for i := n; i < len(p.Sample); i++ {
+		p.Sample[i] = nil
+	}
+	p.Sample = p.Sample[:n]
+	return droppedBytes
+}
@@ -0,0 +1,201 @@
+package distributor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/ring/client"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	googlev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	pushv1 "github.com/grafana/pyroscope/api/gen/proto/go/push/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/pprof"
+	"github.com/grafana/pyroscope/pkg/tenant"
+	"github.com/grafana/pyroscope/pkg/testhelper"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+func parseRelabelRules(t *testing.T, s string) []*relabel.Config {
+	t.Helper()
+	var rules []*relabel.Config
+	require.NoError(t, yaml.Unmarshal([]byte(s), &rules))
+	return rules
+}
+
+func Test_RelabelSeriesLabels(t *testing.T) {
+	rules := parseRelabelRules(t, `
+- source_labels: [env]
+  regex: dev
+  action: drop
+- source_labels: [pod]
+  target_label: pod_name
+- regex: pod
+  action: labeldrop
+`)
+	ls, keep := relabelSeriesLabels(phlaremodel.LabelsFromStrings(
+		"__name__", "cpu",
+		"env", "prod",
+		"pod", "pod-1",
+		"service_name", "svc",
+	), rules)
+	require.True(t, keep)
+	require.Equal(t, phlaremodel.LabelsFromStrings(
+		"__name__", "cpu",
+		"env", "prod",
+		"pod_name", "pod-1",
+		"service_name", "svc",
+	), ls)
+
+	_, keep = relabelSeriesLabels(phlaremodel.LabelsFromStrings(
+		"__name__", "cpu",
+		"env", "dev",
+		"service_name", "svc",
+	), rules)
+	require.False(t, keep)
+}
+
+func Test_RelabelSampleLabels(t *testing.T) {
+	rules := parseRelabelRules(t, `
+- source_labels: [thread]
+  regex: gc
+  action: drop
+- source_labels: [thread]
+  target_label: thread_name
+- regex: thread
+  action: labeldrop
+`)
+	p := &googlev1.Profile{
+		StringTable: []string{"", "thread", "main", "gc", "bytes"},
+		Sample: []*googlev1.Sample{
+			{Value: []int64{1}, Label: []*googlev1.Label{{Key: 1, Str: 2}, {Key: 4, Num: 10}}},
+			{Value: []int64{2}, Label: []*googlev1.Label{{Key: 1, Str: 3}}},
+			{Value: []int64{3}, Label: []*googlev1.Label{{Key: 1, Str: 2}}},
+			{Value: []int64{4}},
+		},
+	}
+	relabelSampleLabels(p, rules)
+
+	require.Equal(t, []string{"", "thread", "main", "gc", "bytes", "thread_name"}, p.StringTable)
+	require.Equal(t, []*googlev1.Sample{
+		{Value: []int64{1}, Label: []*googlev1.Label{{Key: 4, Num: 10}, {Key: 5, Str: 2}}},
+		{Value: []int64{3}, Label: []*googlev1.Label{{Key: 5, Str: 2}}},
+		{Value: []int64{4}, Label: []*googlev1.Label{}},
+	}, p.Sample)
+}
+
+func Test_Push_Relabeling(t *testing.T) {
+	ing := newFakeIngester(t, false)
+	overrides := validation.MockOverrides(func(defaults *validation.Limits, tenantLimits map[string]*validation.Limits) {
+		l := validation.MockDefaultLimits()
+		l.IngestionRelabelingRules = parseRelabelRules(t, `
+- source_labels: [cluster]
+  regex: dev-.*
+  action: drop
+- source_labels: [cluster]
+  target_label: region
+  regex: (.*)-\d+
+`)
+		tenantLimits["user-1"] = l
+	})
+	d, err := New(Config{DistributorRing: ringConfig}, testhelper.NewMockRing([]ring.InstanceDesc{
+		{Addr: "foo"},
+	}, 3), &poolFactory{func(addr string) (client.PoolClient, error) {
+		return ing, nil
+	}}, overrides, nil, log.NewLogfmtLogger(os.Stdout))
+	require.NoError(t, err)
+
+	series := func(cluster string) *pushv1.RawProfileSeries {
+		return &pushv1.RawProfileSeries{
+			Labels: []*typesv1.LabelPair{
+				{Name: "cluster", Value: cluster},
+				{Name: phlaremodel.LabelNameServiceName, Value: "svc"},
+				{Name: "__name__", Value: "cpu"},
+			},
+			Samples: []*pushv1.RawSample{{RawProfile: emptyCPUPprof()}},
+		}
+	}
+
+	ctx := tenant.InjectTenantID(context.Background(), "user-1")
+	_, err = d.Push(ctx, connect.NewRequest(&pushv1.PushRequest{
+		Series: []*pushv1.RawProfileSeries{series("us-central-1"), series("dev-1")},
+	}))
+	require.NoError(t, err)
+	require.Len(t, ing.requests, 1)
+	// The series is replicated to the same ingester.
+	for _, s := range ing.requests[0].Series {
+		ls := phlaremodel.Labels(s.Labels)
+		require.Equal(t, "us-central-1", ls.Get("cluster"))
+		require.Equal(t, "us-central", ls.Get("region"))
+	}
+
+	// All the series are dropped.
+	_, err = d.Push(ctx, connect.NewRequest(&pushv1.PushRequest{
+		Series: []*pushv1.RawProfileSeries{series("dev-2")},
+	}))
+	require.NoError(t, err)
+	require.Len(t, ing.requests, 1)
+}
+
+func Test_Push_RelabelingOversizedProfile(t *testing.T) {
+	ing := newFakeIngester(t, false)
+	overrides := validation.MockOverrides(func(defaults *validation.Limits, tenantLimits map[string]*validation.Limits) {
+		l := validation.MockDefaultLimits()
+		l.IngestionSampleRelabelingRules = parseRelabelRules(t, `
+- source_labels: [thread]
+  target_label: thread_name
+`)
+		// The profiles split by the sample labels are within the limit.
+		l.MaxProfileSizeBytes = 1024
+		tenantLimits["user-relabel"] = l
+	})
+	d, err := New(Config{DistributorRing: ringConfig}, testhelper.NewMockRing([]ring.InstanceDesc{
+		{Addr: "foo"},
+	}, 3), &poolFactory{func(addr string) (client.PoolClient, error) {
+		return ing, nil
+	}}, overrides, nil, log.NewLogfmtLogger(os.Stdout))
+	require.NoError(t, err)
+
+	p := &googlev1.Profile{
+		StringTable: []string{"", "cpu", "nanoseconds", "thread", "main"},
+		SampleType:  []*googlev1.ValueType{{Type: 1, Unit: 2}},
+		Location:    []*googlev1.Location{{Id: 1, Line: []*googlev1.Line{{FunctionId: 1}}}},
+		Function:    []*googlev1.Function{{Id: 1, Name: 4}},
+	}
+	for i := 0; i < 200; i++ {
+		p.StringTable = append(p.StringTable, fmt.Sprintf("thread-%d", i))
+		p.Sample = append(p.Sample, &googlev1.Sample{
+			LocationId: []uint64{1},
+			Value:      []int64{1},
+			Label:      []*googlev1.Label{{Key: 3, Str: int64(len(p.StringTable) - 1)}},
+		})
+	}
+	var buf bytes.Buffer
+	_, err = pprof.RawFromProto(p).WriteTo(&buf)
+	require.NoError(t, err)
+
+	ctx := tenant.InjectTenantID(context.Background(), "user-relabel")
+	_, err = d.Push(ctx, connect.NewRequest(&pushv1.PushRequest{
+		Series: []*pushv1.RawProfileSeries{
+			{
+				Labels: []*typesv1.LabelPair{
+					{Name: phlaremodel.LabelNameServiceName, Value: "svc"},
+					{Name: "__name__", Value: "cpu"},
+				},
+				Samples: []*pushv1.RawSample{{RawProfile: buf.Bytes()}},
+			},
+		},
+	}))
+	require.Error(t, err)
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+	require.Empty(t, ing.requests)
+}
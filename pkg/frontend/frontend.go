@@ -30,6 +30,7 @@ import (
 	"github.com/grafana/dskit/tenant"
 
 	"github.com/grafana/pyroscope/pkg/frontend/frontendpb"
+	"github.com/grafana/pyroscope/pkg/frontend/resultscache"
 	"github.com/grafana/pyroscope/pkg/querier/stats"
 	"github.com/grafana/pyroscope/pkg/scheduler/schedulerdiscovery"
 	"github.com/grafana/pyroscope/pkg/util/httpgrpc"
@@ -50,9 +51,14 @@ type Config struct {
 	Addr string `yaml:"address" category:"advanced"`
 	Port int    `yaml:"-"`
 
+	QueryStatsEnabled bool `yaml:"query_stats_enabled" category:"advanced"`
+
+	ResultsCache resultscache.Config `yaml:"results_cache" doc:"description=Configures the cache of the results of the query sub-ranges that are not served by ingesters."`
+
 	// This configuration is injected internally.
 	QuerySchedulerDiscovery schedulerdiscovery.Config `yaml:"-"`
 	MaxLoopDuration         time.Duration             `yaml:"-"`
+	QueryStoreAfter         time.Duration             `yaml:"-"`
 }
 
 func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
@@ -61,8 +67,10 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.InfNames = netutil.PrivateNetworkInterfacesWithFallback([]string{"eth0", "en0"}, logger)
 	f.Var((*flagext.StringSlice)(&cfg.InfNames), "query-frontend.instance-interface-names", "List of network interface names to look up when finding the instance IP address. This address is sent to query-scheduler and querier, which uses it to send the query response back to query-frontend.")
 	f.StringVar(&cfg.Addr, "query-frontend.instance-addr", "", "IP address to advertise to the querier (via scheduler) (default is auto-detected from network interfaces).")
+	f.BoolVar(&cfg.QueryStatsEnabled, "query-frontend.query-stats-enabled", false, "True to collect the execution statistics of every query and return them in the X-Pyroscope-Query-Stats response trailer. Otherwise, the statistics are only collected for requests with the X-Pyroscope-Query-Stats header.")
 
 	cfg.GRPCClientConfig.RegisterFlagsWithPrefix("query-frontend.grpc-client-config", f)
+	cfg.ResultsCache.RegisterFlagsWithPrefix("query-frontend.results-cache.", f)
 }
 
 func (cfg *Config) Validate() error {
@@ -70,6 +78,9 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("scheduler address cannot be specified when query-scheduler service discovery mode is set to '%s'", cfg.QuerySchedulerDiscovery.Mode)
 	}
 
+	if err := cfg.ResultsCache.Validate(); err != nil {
+		return err
+	}
 	return cfg.GRPCClientConfig.Validate()
 }
 
@@ -87,6 +98,7 @@ type Frontend struct {
 	requestsCh chan *frontendRequest
 
 	limits                  Limits
+	resultsCache            resultscache.Cache
 	schedulerWorkers        *frontendSchedulerWorkers
 	schedulerWorkersWatcher *services.FailureWatcher
 	requests                *requestsInProgress
@@ -95,6 +107,7 @@ type Frontend struct {
 
 type Limits interface {
 	QuerySplitDuration(string) time.Duration
+	QueryShardingTotalShards(string) int
 	MaxQueryParallelism(string) int
 	MaxQueryLength(tenantID string) time.Duration
 	MaxQueryLookback(tenantID string) time.Duration
@@ -137,10 +150,16 @@ func NewFrontend(cfg Config, limits Limits, log log.Logger, reg prometheus.Regis
 		return nil, err
 	}
 
+	resultsCache, err := resultscache.New(cfg.ResultsCache, log, reg)
+	if err != nil {
+		return nil, err
+	}
+
 	f := &Frontend{
 		cfg:                     cfg,
 		log:                     log,
 		limits:                  limits,
+		resultsCache:            resultsCache,
 		requestsCh:              requestsCh,
 		schedulerWorkers:        schedulerWorkers,
 		schedulerWorkersWatcher: services.NewFailureWatcher(),
@@ -0,0 +1,52 @@
+package frontend
+
+import (
+	"context"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+func (f *Frontend) Cardinality(ctx context.Context, c *connect.Request[querierv1.CardinalityRequest]) (*connect.Response[querierv1.CardinalityResponse], error) {
+	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceCardinalityProcedure)
+
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	// The statistics are always computed over a
+	// time range, the last hour by default.
+	if c.Msg.End == 0 {
+		c.Msg.End = int64(model.Now())
+	}
+	if c.Msg.Start == 0 {
+		c.Msg.Start = int64(model.Time(c.Msg.End).Add(-time.Hour))
+	}
+	if c.Msg.Start > c.Msg.End {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start time must not be after end time"))
+	}
+	interval := model.Interval{
+		Start: model.Time(c.Msg.Start),
+		End:   model.Time(c.Msg.End),
+	}
+	validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, interval, model.Now())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if validated.IsEmpty {
+		return connect.NewResponse(&querierv1.CardinalityResponse{}), nil
+	}
+	c.Msg.Start = int64(validated.Start)
+	c.Msg.End = int64(validated.End)
+
+	return connectgrpc.RoundTripUnary[querierv1.CardinalityRequest, querierv1.CardinalityResponse](ctx, f, c)
+}
@@ -18,6 +18,11 @@ func (f *Frontend) Diff(ctx context.Context,
 	*connect.Response[querierv1.DiffResponse], error,
 ) {
 	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceDiffProcedure)
+	rightReq, err := phlaremodel.DiffRight(c.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 
 	var left, right *phlaremodel.Tree
@@ -32,7 +37,7 @@ func (f *Frontend) Diff(ctx context.Context,
 		return err
 	})
 	g.Go(func() error {
-		resp, err := f.SelectMergeStacktraces(ctx, connect.NewRequest(c.Msg.Right))
+		resp, err := f.SelectMergeStacktraces(ctx, connect.NewRequest(rightReq))
 		if err != nil {
 			return err
 		}
@@ -45,7 +50,7 @@ func (f *Frontend) Diff(ctx context.Context,
 		return nil, err
 	}
 
-	maxNodes := int(math.Max(c.Msg.Left.GetMaxNodes(), c.Msg.Right.GetMaxNodes()))
+	maxNodes := int(math.Max(c.Msg.Left.GetMaxNodes(), rightReq.GetMaxNodes()))
 	diff, err := phlaremodel.NewFlamegraphDiff(left, right, maxNodes)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
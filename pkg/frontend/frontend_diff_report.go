@@ -0,0 +1,44 @@
+package frontend
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/common/model"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+func (f *Frontend) DiffReport(ctx context.Context, c *connect.Request[querierv1.DiffReportRequest]) (*connect.Response[querierv1.DiffReportResponse], error) {
+	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceDiffReportProcedure)
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	baseline, err := phlaremodel.DiffReportBaseline(c.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	c.Msg.Baseline = baseline
+	c.Msg.Offset = 0
+	for _, s := range []*querierv1.SelectMergeStacktracesRequest{c.Msg.Baseline, c.Msg.Comparison} {
+		s.Start -= s.Offset
+		s.End -= s.Offset
+		s.Offset = 0
+		validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, model.Interval{Start: model.Time(s.Start), End: model.Time(s.End)}, model.Now())
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		if validated.IsEmpty {
+			return connect.NewResponse(&querierv1.DiffReportResponse{}), nil
+		}
+		s.Start = int64(validated.Start)
+		s.End = int64(validated.End)
+	}
+	return connectgrpc.RoundTripUnary[querierv1.DiffReportRequest, querierv1.DiffReportResponse](ctx, f, c)
+}
@@ -0,0 +1,88 @@
+package frontend
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+)
+
+// selectSeriesExpression combines the series of the profile types of the
+// expression. Each of the profile types is queried as a regular request,
+// therefore the expression is only evaluated once the sub-query results
+// are merged. The limit only applies to the resulting series.
+func (f *Frontend) selectSeriesExpression(ctx context.Context,
+	c *connect.Request[querierv1.SelectSeriesRequest]) (
+	*connect.Response[querierv1.SelectSeriesResponse], error,
+) {
+	e, err := phlaremodel.ParseProfileTypeExpression(c.Msg.ProfileTypeID, c.Msg.LabelSelector)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	series, err := e.SelectSeries(ctx, func(ctx context.Context, op phlaremodel.ProfileTypeOperand) ([]*typesv1.Series, error) {
+		resp, err := f.SelectSeries(ctx, connectgrpc.CloneRequest(c, &querierv1.SelectSeriesRequest{
+			ProfileTypeID:      op.ProfileTypeID,
+			LabelSelector:      op.LabelSelector,
+			Start:              c.Msg.Start,
+			End:                c.Msg.End,
+			GroupBy:            c.Msg.GroupBy,
+			Step:               c.Msg.Step,
+			Aggregation:        c.Msg.Aggregation,
+			StackTraceSelector: c.Msg.StackTraceSelector,
+			Offset:             c.Msg.Offset,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return resp.Msg.Series, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&querierv1.SelectSeriesResponse{
+		Series: phlaremodel.TopSeries(series, int(c.Msg.GetLimit()), c.Msg.IncludeOther),
+	}), nil
+}
+
+// selectMergeStacktracesExpression combines the flame graphs of the
+// profile types of the expression per node. The operands are requested
+// untruncated, the limit only applies to the resulting flame graph.
+func (f *Frontend) selectMergeStacktracesExpression(ctx context.Context,
+	c *connect.Request[querierv1.SelectMergeStacktracesRequest]) (
+	*connect.Response[querierv1.SelectMergeStacktracesResponse], error,
+) {
+	e, err := phlaremodel.ParseProfileTypeExpression(c.Msg.ProfileTypeID, c.Msg.LabelSelector)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	t, err := e.SelectTree(ctx, func(ctx context.Context, op phlaremodel.ProfileTypeOperand) (*phlaremodel.Tree, error) {
+		noMaxNodes := int64(-1) // A negative limit disables the truncation.
+		resp, err := f.SelectMergeStacktraces(ctx, connectgrpc.CloneRequest(c, &querierv1.SelectMergeStacktracesRequest{
+			ProfileTypeID:      op.ProfileTypeID,
+			LabelSelector:      op.LabelSelector,
+			Start:              c.Msg.Start,
+			End:                c.Msg.End,
+			MaxNodes:           &noMaxNodes,
+			StackTraceSelector: c.Msg.StackTraceSelector,
+			Offset:             c.Msg.Offset,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		m := phlaremodel.NewFlameGraphMerger()
+		if resp.Msg.Flamegraph != nil {
+			m.MergeFlameGraph(resp.Msg.Flamegraph)
+		}
+		return m.Tree(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&querierv1.SelectMergeStacktracesResponse{
+		Flamegraph: phlaremodel.NewFlameGraph(t, c.Msg.GetMaxNodes()),
+	}), nil
+}
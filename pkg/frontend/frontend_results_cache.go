@@ -0,0 +1,81 @@
+package frontend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/go-kit/log/level"
+
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+)
+
+type cacheableMessage[T any] interface {
+	*T
+	MarshalVT() ([]byte, error)
+	UnmarshalVT([]byte) error
+}
+
+// roundTripCached executes the sub-query of the time interval, unless
+// the result is present in the results cache. Results of the intervals
+// that end before the ingesters time window are not expected to change,
+// therefore they are stored in the cache.
+func roundTripCached[Req, Res any, ReqT cacheableMessage[Req], ResT cacheableMessage[Res]](
+	ctx context.Context,
+	f *Frontend,
+	tenantIDs []string,
+	interval TimeInterval,
+	req *connect.Request[Req],
+) (*connect.Response[Res], error) {
+	if !f.isCacheable(interval) {
+		return connectgrpc.RoundTripUnary[Req, Res](ctx, f, req)
+	}
+	key, err := resultsCacheKey(ctx, tenantIDs, ReqT(req.Msg))
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := f.resultsCache.Get(ctx, key); ok {
+		var res Res
+		if err = ResT(&res).UnmarshalVT(b); err == nil {
+			return connect.NewResponse(&res), nil
+		}
+		level.Warn(f.log).Log("msg", "failed to decode cached result", "err", err)
+	}
+	resp, err := connectgrpc.RoundTripUnary[Req, Res](ctx, f, req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ResT(resp.Msg).MarshalVT()
+	if err != nil {
+		level.Warn(f.log).Log("msg", "failed to encode result for caching", "err", err)
+		return resp, nil
+	}
+	f.resultsCache.Set(ctx, key, b)
+	return resp, nil
+}
+
+func (f *Frontend) isCacheable(interval TimeInterval) bool {
+	if f.resultsCache == nil {
+		return false
+	}
+	return interval.End.Before(time.Now().Add(-f.cfg.QueryStoreAfter))
+}
+
+// resultsCacheKey identifies the result by the procedure, tenants,
+// and the request, which includes the query and the time interval.
+func resultsCacheKey(ctx context.Context, tenantIDs []string, req interface{ MarshalVT() ([]byte, error) }) (string, error) {
+	b, err := req.MarshalVT()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, _ = h.Write([]byte(connectgrpc.ProcedureFromContext(ctx)))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(strings.Join(tenantIDs, "|")))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
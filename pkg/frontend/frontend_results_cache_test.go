@@ -0,0 +1,93 @@
+package frontend
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/frontend/resultscache"
+	"github.com/grafana/pyroscope/pkg/scheduler/schedulerpb"
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+)
+
+// setupCachingFrontend creates a frontend with the in-memory results cache.
+// The querier responds to every sub-query with a series labeled with the
+// number of the sub-queries executed so far.
+func setupCachingFrontend(t *testing.T, queryStoreAfter time.Duration) (*Frontend, *atomic.Int64) {
+	const userID = "test"
+	var calls atomic.Int64
+	f, _ := setupFrontend(t, nil, func(f *Frontend, msg *schedulerpb.FrontendToScheduler) *schedulerpb.SchedulerToFrontend {
+		ctx := user.InjectOrgID(context.Background(), userID)
+		resp, err := connectgrpc.HandleUnary(ctx, msg.HttpRequest,
+			func(context.Context, *connect.Request[querierv1.SelectSeriesRequest]) (*connect.Response[querierv1.SelectSeriesResponse], error) {
+				n := calls.Inc()
+				return connect.NewResponse(&querierv1.SelectSeriesResponse{
+					Series: []*typesv1.Series{{Labels: []*typesv1.LabelPair{{Name: "call", Value: strconv.FormatInt(n, 10)}}}},
+				}), nil
+			})
+		require.NoError(t, err)
+		go sendResponseWithDelay(f, 100*time.Millisecond, userID, msg.QueryID, resp)
+		return &schedulerpb.SchedulerToFrontend{Status: schedulerpb.SchedulerToFrontendStatus_OK}
+	})
+	f.resultsCache = resultscache.NewInMemory(1 << 20)
+	f.cfg.QueryStoreAfter = queryStoreAfter
+	return f, &calls
+}
+
+func selectSeriesCached(t *testing.T, f *Frontend, interval TimeInterval) *querierv1.SelectSeriesResponse {
+	ctx := user.InjectOrgID(context.Background(), "test")
+	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceSelectSeriesProcedure)
+	req := connect.NewRequest(&querierv1.SelectSeriesRequest{
+		ProfileTypeID: "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+		LabelSelector: "{}",
+		Start:         interval.Start.UnixMilli(),
+		End:           interval.End.UnixMilli(),
+		Step:          15,
+	})
+	resp, err := roundTripCached[
+		querierv1.SelectSeriesRequest,
+		querierv1.SelectSeriesResponse](ctx, f, []string{"test"}, interval, req)
+	require.NoError(t, err)
+	return resp.Msg
+}
+
+func Test_RoundTripCached(t *testing.T) {
+	end := time.Now().Add(-2 * time.Hour)
+	interval := TimeInterval{Start: end.Add(-time.Hour), End: end}
+	f, calls := setupCachingFrontend(t, time.Hour)
+
+	// A miss executes the sub-query and fills the cache.
+	miss := selectSeriesCached(t, f, interval)
+	require.Equal(t, int64(1), calls.Load())
+
+	// A hit returns the cached result without querying.
+	hit := selectSeriesCached(t, f, interval)
+	require.Equal(t, int64(1), calls.Load())
+	require.Equal(t, miss.String(), hit.String())
+
+	// Results of other intervals are not shared.
+	selectSeriesCached(t, f, TimeInterval{Start: interval.Start, End: interval.End.Add(-time.Minute)})
+	require.Equal(t, int64(2), calls.Load())
+}
+
+func Test_RoundTripCached_QueryStoreAfter(t *testing.T) {
+	end := time.Now().Add(-30 * time.Minute)
+	interval := TimeInterval{Start: end.Add(-time.Hour), End: end}
+	f, calls := setupCachingFrontend(t, time.Hour)
+
+	// The interval ends within the ingesters time window:
+	// the result may change, therefore it is never cached.
+	first := selectSeriesCached(t, f, interval)
+	second := selectSeriesCached(t, f, interval)
+	require.Equal(t, int64(2), calls.Load())
+	require.NotEqual(t, first.String(), second.String())
+}
@@ -0,0 +1,74 @@
+package frontend
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/grafana/dskit/tenant"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/common/model"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+func (f *Frontend) SelectFunctionSeries(ctx context.Context,
+	c *connect.Request[querierv1.SelectFunctionSeriesRequest]) (
+	*connect.Response[querierv1.SelectSeriesResponse], error,
+) {
+	opentracing.SpanFromContext(ctx).
+		SetTag("start", model.Time(c.Msg.Start).Time().String()).
+		SetTag("end", model.Time(c.Msg.End).Time().String()).
+		SetTag("selector", c.Msg.LabelSelector).
+		SetTag("profile_type", c.Msg.ProfileTypeID)
+
+	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceSelectFunctionSeriesProcedure)
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, model.Interval{Start: model.Time(c.Msg.Start), End: model.Time(c.Msg.End)}, model.Now())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if validated.IsEmpty {
+		return connect.NewResponse(&querierv1.SelectSeriesResponse{}), nil
+	}
+	c.Msg.Start = int64(validated.Start)
+	c.Msg.End = int64(validated.End)
+
+	// The values within a step are summed,
+	// therefore the query is always sharded.
+	selectors, err := f.shardLabelSelector(tenantIDs, c.Msg.LabelSelector)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	series, err := f.selectSeriesShards(ctx, tenantIDs, selectors, c.Msg.Start, c.Msg.End, c.Msg.Step, 0,
+		func(ctx context.Context, selector string, r TimeInterval) ([]*typesv1.Series, error) {
+			req := connectgrpc.CloneRequest(c, &querierv1.SelectFunctionSeriesRequest{
+				ProfileTypeID: c.Msg.ProfileTypeID,
+				LabelSelector: selector,
+				Start:         r.Start.UnixMilli(),
+				End:           r.End.UnixMilli(),
+				GroupBy:       c.Msg.GroupBy,
+				Step:          c.Msg.Step,
+				Functions:     c.Msg.Functions,
+				ValueType:     c.Msg.ValueType,
+			})
+			resp, err := roundTripCached[
+				querierv1.SelectFunctionSeriesRequest,
+				querierv1.SelectSeriesResponse](ctx, f, tenantIDs, r, req)
+			if err != nil {
+				return nil, err
+			}
+			return resp.Msg.Series, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&querierv1.SelectSeriesResponse{Series: series}), nil
+}
@@ -0,0 +1,118 @@
+package frontend
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/querier/stats"
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+	validationutil "github.com/grafana/pyroscope/pkg/util/validation"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+func (f *Frontend) SelectHeatmap(ctx context.Context, c *connect.Request[querierv1.SelectHeatmapRequest]) (*connect.Response[querierv1.SelectHeatmapResponse], error) {
+	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceSelectHeatmapProcedure)
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if _, _, err = phlaremodel.HeatmapBuckets(c.Msg); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, model.Interval{Start: model.Time(c.Msg.Start), End: model.Time(c.Msg.End)}, model.Now())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if validated.IsEmpty {
+		return connect.NewResponse(&querierv1.SelectHeatmapResponse{}), nil
+	}
+	c.Msg.Start = int64(validated.Start)
+	c.Msg.End = int64(validated.End)
+
+	selectors, err := f.shardLabelSelector(tenantIDs, c.Msg.LabelSelector)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if len(selectors) == 1 {
+		return connectgrpc.RoundTripUnary[querierv1.SelectHeatmapRequest, querierv1.SelectHeatmapResponse](ctx, f, c)
+	}
+	stats.FromContext(ctx).AddShardedQueries(uint32(len(selectors)))
+
+	// A profile belongs to a single series, therefore the heatmaps of the
+	// series shards can be summed, as long as their value axes match.
+	// If the value range is not specified, it is determined first.
+	if c.Msg.MinValue == nil || c.Msg.MaxValue == nil {
+		heatmaps, err := f.selectHeatmapShards(ctx, c, tenantIDs, selectors)
+		if err != nil {
+			return nil, err
+		}
+		var found bool
+		var minValue, maxValue int64
+		for _, h := range heatmaps {
+			if h.GetMaxDepth() == 0 {
+				continue
+			}
+			if !found || h.MinValue < minValue {
+				minValue = h.MinValue
+			}
+			if !found || h.MaxValue > maxValue {
+				maxValue = h.MaxValue
+			}
+			found = true
+		}
+		if !found {
+			return connect.NewResponse(&querierv1.SelectHeatmapResponse{Heatmap: heatmaps[0]}), nil
+		}
+		c.Msg.MinValue = &minValue
+		c.Msg.MaxValue = &maxValue
+	}
+
+	heatmaps, err := f.selectHeatmapShards(ctx, c, tenantIDs, selectors)
+	if err != nil {
+		return nil, err
+	}
+	h, err := phlaremodel.MergeHeatmaps(heatmaps...)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	return connect.NewResponse(&querierv1.SelectHeatmapResponse{Heatmap: h}), nil
+}
+
+// selectHeatmapShards returns the heatmaps of the series shards.
+func (f *Frontend) selectHeatmapShards(ctx context.Context,
+	c *connect.Request[querierv1.SelectHeatmapRequest],
+	tenantIDs []string,
+	selectors []string,
+) ([]*querierv1.Heatmap, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	if maxConcurrent := validationutil.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueryParallelism); maxConcurrent > 0 {
+		g.SetLimit(maxConcurrent)
+	}
+	heatmaps := make([]*querierv1.Heatmap, len(selectors))
+	for i, selector := range selectors {
+		i, selector := i, selector
+		g.Go(func() error {
+			msg := c.Msg.CloneVT()
+			msg.LabelSelector = selector
+			resp, err := connectgrpc.RoundTripUnary[
+				querierv1.SelectHeatmapRequest,
+				querierv1.SelectHeatmapResponse](ctx, f, connectgrpc.CloneRequest(c, msg))
+			if err != nil {
+				return err
+			}
+			heatmaps[i] = resp.Msg.Heatmap
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return heatmaps, nil
+}
@@ -21,6 +21,9 @@ func (f *Frontend) SelectMergeProfile(ctx context.Context, c *connect.Request[qu
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
+	c.Msg.Start -= c.Msg.Offset
+	c.Msg.End -= c.Msg.Offset
+	c.Msg.Offset = 0
 	validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, model.Interval{Start: model.Time(c.Msg.Start), End: model.Time(c.Msg.End)}, model.Now())
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
@@ -0,0 +1,99 @@
+package frontend
+
+import (
+	"context"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/grafana/dskit/tenant"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/querier/stats"
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+	validationutil "github.com/grafana/pyroscope/pkg/util/validation"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+func (f *Frontend) SelectMergeSpanProfile(ctx context.Context,
+	c *connect.Request[querierv1.SelectMergeSpanProfileRequest]) (
+	*connect.Response[querierv1.SelectMergeSpanProfileResponse], error,
+) {
+	opentracing.SpanFromContext(ctx).
+		SetTag("start", model.Time(c.Msg.Start).Time().String()).
+		SetTag("end", model.Time(c.Msg.End).Time().String()).
+		SetTag("selector", c.Msg.LabelSelector).
+		SetTag("profile_type", c.Msg.ProfileTypeID).
+		SetTag("spans", len(c.Msg.SpanSelector))
+
+	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceSelectMergeSpanProfileProcedure)
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, model.Interval{Start: model.Time(c.Msg.Start), End: model.Time(c.Msg.End)}, model.Now())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if validated.IsEmpty {
+		return connect.NewResponse(&querierv1.SelectMergeSpanProfileResponse{}), nil
+	}
+
+	selectors, err := f.shardLabelSelector(tenantIDs, c.Msg.LabelSelector)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if len(selectors) > 1 {
+		stats.FromContext(ctx).AddShardedQueries(uint32(len(selectors)))
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	if maxConcurrent := validationutil.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueryParallelism); maxConcurrent > 0 {
+		g.SetLimit(maxConcurrent)
+	}
+
+	m := phlaremodel.NewFlameGraphMerger()
+	interval := validationutil.MaxDurationOrZeroPerTenant(tenantIDs, f.limits.QuerySplitDuration)
+	intervals := NewTimeIntervalIterator(time.UnixMilli(int64(validated.Start)), time.UnixMilli(int64(validated.End)), interval)
+
+	for intervals.Next() {
+		r := intervals.At()
+		stats.FromContext(ctx).AddSplitQueries(1)
+		for _, selector := range selectors {
+			selector := selector
+			g.Go(func() error {
+				req := connectgrpc.CloneRequest(c, &querierv1.SelectMergeSpanProfileRequest{
+					ProfileTypeID: c.Msg.ProfileTypeID,
+					LabelSelector: selector,
+					Start:         r.Start.UnixMilli(),
+					End:           r.End.UnixMilli(),
+					MaxNodes:      c.Msg.MaxNodes,
+					SpanSelector:  c.Msg.SpanSelector,
+				})
+				resp, err := roundTripCached[
+					querierv1.SelectMergeSpanProfileRequest,
+					querierv1.SelectMergeSpanProfileResponse](ctx, f, tenantIDs, r, req)
+				if err != nil {
+					return err
+				}
+				m.MergeFlameGraph(resp.Msg.Flamegraph)
+				return nil
+			})
+		}
+	}
+
+	if err = g.Wait(); err != nil {
+		return nil, err
+	}
+
+	t := m.Tree()
+	t.FormatNodeNames(phlaremodel.DropGoTypeParameters)
+	return connect.NewResponse(&querierv1.SelectMergeSpanProfileResponse{
+		Flamegraph: phlaremodel.NewFlameGraph(t, c.Msg.GetMaxNodes()),
+	}), nil
+}
@@ -13,6 +13,7 @@ import (
 	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
 	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
 	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/querier/stats"
 	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
 	validationutil "github.com/grafana/pyroscope/pkg/util/validation"
 	"github.com/grafana/pyroscope/pkg/validation"
@@ -28,12 +29,20 @@ func (f *Frontend) SelectMergeStacktraces(ctx context.Context,
 		SetTag("selector", c.Msg.LabelSelector).
 		SetTag("profile_type", c.Msg.ProfileTypeID)
 
+	if phlaremodel.IsProfileTypeExpression(c.Msg.ProfileTypeID) {
+		return f.selectMergeStacktracesExpression(ctx, c)
+	}
+
 	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceSelectMergeStacktracesProcedure)
 	tenantIDs, err := tenant.TenantIDs(ctx)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
 
+	c.Msg.Start -= c.Msg.Offset
+	c.Msg.End -= c.Msg.Offset
+	c.Msg.Offset = 0
+
 	validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, model.Interval{Start: model.Time(c.Msg.Start), End: model.Time(c.Msg.End)}, model.Now())
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
@@ -42,6 +51,14 @@ func (f *Frontend) SelectMergeStacktraces(ctx context.Context,
 		return connect.NewResponse(&querierv1.SelectMergeStacktracesResponse{}), nil
 	}
 
+	selectors, err := f.shardLabelSelector(tenantIDs, c.Msg.LabelSelector)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if len(selectors) > 1 {
+		stats.FromContext(ctx).AddShardedQueries(uint32(len(selectors)))
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 	if maxConcurrent := validationutil.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueryParallelism); maxConcurrent > 0 {
 		g.SetLimit(maxConcurrent)
@@ -53,23 +70,28 @@ func (f *Frontend) SelectMergeStacktraces(ctx context.Context,
 
 	for intervals.Next() {
 		r := intervals.At()
-		g.Go(func() error {
-			req := connectgrpc.CloneRequest(c, &querierv1.SelectMergeStacktracesRequest{
-				ProfileTypeID: c.Msg.ProfileTypeID,
-				LabelSelector: c.Msg.LabelSelector,
-				Start:         r.Start.UnixMilli(),
-				End:           r.End.UnixMilli(),
-				MaxNodes:      c.Msg.MaxNodes,
+		stats.FromContext(ctx).AddSplitQueries(1)
+		for _, selector := range selectors {
+			selector := selector
+			g.Go(func() error {
+				req := connectgrpc.CloneRequest(c, &querierv1.SelectMergeStacktracesRequest{
+					ProfileTypeID:      c.Msg.ProfileTypeID,
+					LabelSelector:      selector,
+					Start:              r.Start.UnixMilli(),
+					End:                r.End.UnixMilli(),
+					MaxNodes:           c.Msg.MaxNodes,
+					StackTraceSelector: c.Msg.StackTraceSelector,
+				})
+				resp, err := roundTripCached[
+					querierv1.SelectMergeStacktracesRequest,
+					querierv1.SelectMergeStacktracesResponse](ctx, f, tenantIDs, r, req)
+				if err != nil {
+					return err
+				}
+				m.MergeFlameGraph(resp.Msg.Flamegraph)
+				return nil
 			})
-			resp, err := connectgrpc.RoundTripUnary[
-				querierv1.SelectMergeStacktracesRequest,
-				querierv1.SelectMergeStacktracesResponse](ctx, f, req)
-			if err != nil {
-				return err
-			}
-			m.MergeFlameGraph(resp.Msg.Flamegraph)
-			return nil
-		})
+		}
 	}
 
 	if err = g.Wait(); err != nil {
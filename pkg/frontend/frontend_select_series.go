@@ -12,7 +12,9 @@ import (
 
 	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
 	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
 	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/querier/stats"
 	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
 	validationutil "github.com/grafana/pyroscope/pkg/util/validation"
 	"github.com/grafana/pyroscope/pkg/validation"
@@ -28,12 +30,23 @@ func (f *Frontend) SelectSeries(ctx context.Context,
 		SetTag("selector", c.Msg.LabelSelector).
 		SetTag("profile_type", c.Msg.ProfileTypeID)
 
+	if phlaremodel.IsProfileTypeExpression(c.Msg.ProfileTypeID) {
+		return f.selectSeriesExpression(ctx, c)
+	}
+
 	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceSelectSeriesProcedure)
 	tenantIDs, err := tenant.TenantIDs(ctx)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
 
+	// The time range is shifted back by the offset, and the
+	// timestamps of the resulting points are shifted forward.
+	offset := c.Msg.Offset
+	c.Msg.Start -= offset
+	c.Msg.End -= offset
+	c.Msg.Offset = 0
+
 	validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, model.Interval{Start: model.Time(c.Msg.Start), End: model.Time(c.Msg.End)}, model.Now())
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
@@ -44,41 +57,101 @@ func (f *Frontend) SelectSeries(ctx context.Context,
 	c.Msg.Start = int64(validated.Start)
 	c.Msg.End = int64(validated.End)
 
+	selectors := []string{c.Msg.LabelSelector}
+	// Partial results of series shards can only be combined,
+	// if the values within a step are summed.
+	if phlaremodel.IsSummableAggregation(c.Msg.GetAggregation()) {
+		if selectors, err = f.shardLabelSelector(tenantIDs, c.Msg.LabelSelector); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	}
+	series, err := f.selectSeriesShards(ctx, tenantIDs, selectors, c.Msg.Start, c.Msg.End, c.Msg.Step, int(c.Msg.MaxExemplars),
+		func(ctx context.Context, selector string, r TimeInterval) ([]*typesv1.Series, error) {
+			req := connectgrpc.CloneRequest(c, &querierv1.SelectSeriesRequest{
+				ProfileTypeID:      c.Msg.ProfileTypeID,
+				LabelSelector:      selector,
+				Start:              r.Start.UnixMilli(),
+				End:                r.End.UnixMilli(),
+				GroupBy:            c.Msg.GroupBy,
+				Step:               c.Msg.Step,
+				Aggregation:        c.Msg.Aggregation,
+				MaxExemplars:       c.Msg.MaxExemplars,
+				StackTraceSelector: c.Msg.StackTraceSelector,
+			})
+			resp, err := roundTripCached[
+				querierv1.SelectSeriesRequest,
+				querierv1.SelectSeriesResponse](ctx, f, tenantIDs, r, req)
+			if err != nil {
+				return nil, err
+			}
+			return resp.Msg.Series, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// The limit is not propagated to the sub-queries, as the top series
+	// can only be selected once the full time range is merged.
+	series = phlaremodel.TopSeries(series, int(c.Msg.GetLimit()), c.Msg.IncludeOther)
+	series = phlaremodel.ShiftSeries(series, offset)
+	return connect.NewResponse(&querierv1.SelectSeriesResponse{Series: series}), nil
+}
+
+// selectSeriesShards splits the time range by the split interval of the
+// tenants, and queries the series of every label selector shard within
+// every interval.
+func (f *Frontend) selectSeriesShards(ctx context.Context,
+	tenantIDs []string,
+	selectors []string,
+	start, end int64,
+	step float64,
+	maxExemplars int,
+	selectSeries func(ctx context.Context, selector string, r TimeInterval) ([]*typesv1.Series, error),
+) ([]*typesv1.Series, error) {
+	if len(selectors) > 1 {
+		stats.FromContext(ctx).AddShardedQueries(uint32(len(selectors)))
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 	if maxConcurrent := validationutil.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueryParallelism); maxConcurrent > 0 {
 		g.SetLimit(maxConcurrent)
 	}
 
-	m := phlaremodel.NewSeriesMerger(false)
 	interval := validationutil.MaxDurationOrZeroPerTenant(tenantIDs, f.limits.QuerySplitDuration)
-	intervals := NewTimeIntervalIterator(time.UnixMilli(c.Msg.Start), time.UnixMilli(c.Msg.End), interval,
-		WithAlignment(time.Second*time.Duration(c.Msg.Step)))
+	intervals := NewTimeIntervalIterator(time.UnixMilli(start), time.UnixMilli(end), interval,
+		WithAlignment(time.Second*time.Duration(step)))
 
+	// Series of different shards may have the same labels after grouping,
+	// therefore the shard results are summed within each interval.
+	var shardMergers []*phlaremodel.SeriesMerger
 	for intervals.Next() {
 		r := intervals.At()
-		g.Go(func() error {
-			req := connectgrpc.CloneRequest(c, &querierv1.SelectSeriesRequest{
-				ProfileTypeID: c.Msg.ProfileTypeID,
-				LabelSelector: c.Msg.LabelSelector,
-				Start:         r.Start.UnixMilli(),
-				End:           r.End.UnixMilli(),
-				GroupBy:       c.Msg.GroupBy,
-				Step:          c.Msg.Step,
+		stats.FromContext(ctx).AddSplitQueries(1)
+		sm := phlaremodel.NewSeriesMerger(true)
+		shardMergers = append(shardMergers, sm)
+		for _, selector := range selectors {
+			selector := selector
+			g.Go(func() error {
+				series, err := selectSeries(ctx, selector, r)
+				if err != nil {
+					return err
+				}
+				sm.MergeSeries(series)
+				return nil
 			})
-			resp, err := connectgrpc.RoundTripUnary[
-				querierv1.SelectSeriesRequest,
-				querierv1.SelectSeriesResponse](ctx, f, req)
-			if err != nil {
-				return err
-			}
-			m.MergeSeries(resp.Msg.Series)
-			return nil
-		})
+		}
 	}
 
-	if err = g.Wait(); err != nil {
+	if err := g.Wait(); err != nil {
 		return nil, err
 	}
 
-	return connect.NewResponse(&querierv1.SelectSeriesResponse{Series: m.Series()}), nil
+	// Intervals are aligned to the step, therefore every point is aggregated
+	// within a single interval, regardless of the aggregation type: points
+	// with matching timestamps are duplicates.
+	m := phlaremodel.NewSeriesMerger(false)
+	for _, sm := range shardMergers {
+		m.MergeSeries(phlaremodel.TrimExemplars(sm.Series(), maxExemplars))
+	}
+	return m.Series(), nil
 }
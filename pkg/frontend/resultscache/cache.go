@@ -0,0 +1,123 @@
+package resultscache
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	BackendInMemory = "inmemory"
+	BackendDisk     = "disk"
+)
+
+var supportedBackends = []string{BackendInMemory, BackendDisk}
+
+// Cache stores serialized query results.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored for the key, if present.
+	Get(ctx context.Context, key string) ([]byte, bool)
+	// Set stores the value for the key. The call never fails:
+	// a value that can't be stored is simply not cached.
+	Set(ctx context.Context, key string, value []byte)
+}
+
+type Config struct {
+	Backend  string         `yaml:"backend" category:"experimental"`
+	InMemory InMemoryConfig `yaml:"inmemory"`
+	Disk     DiskConfig     `yaml:"disk"`
+}
+
+type InMemoryConfig struct {
+	MaxSizeBytes int64 `yaml:"max_size_bytes" category:"experimental"`
+}
+
+type DiskConfig struct {
+	Directory    string `yaml:"dir" category:"experimental"`
+	MaxSizeBytes int64  `yaml:"max_size_bytes" category:"experimental"`
+}
+
+func (cfg *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Backend, prefix+"backend", "", fmt.Sprintf("Backend of the results cache. Supported values: %v. The cache is disabled, if empty.", supportedBackends))
+	f.Int64Var(&cfg.InMemory.MaxSizeBytes, prefix+"inmemory.max-size-bytes", 256<<20, "Maximum size of the in-memory results cache in bytes.")
+	f.StringVar(&cfg.Disk.Directory, prefix+"disk.dir", "./data/results-cache", "Directory used for the on-disk results cache.")
+	f.Int64Var(&cfg.Disk.MaxSizeBytes, prefix+"disk.max-size-bytes", 1<<30, "Maximum size of the on-disk results cache in bytes.")
+}
+
+func (cfg *Config) Validate() error {
+	switch cfg.Backend {
+	case "":
+		return nil
+	case BackendInMemory:
+		if cfg.InMemory.MaxSizeBytes <= 0 {
+			return fmt.Errorf("results cache: in-memory max size must be positive")
+		}
+	case BackendDisk:
+		if cfg.Disk.Directory == "" {
+			return fmt.Errorf("results cache: directory must be specified")
+		}
+		if cfg.Disk.MaxSizeBytes <= 0 {
+			return fmt.Errorf("results cache: on-disk max size must be positive")
+		}
+	default:
+		return fmt.Errorf("unsupported results cache backend %q, supported values: %v", cfg.Backend, supportedBackends)
+	}
+	return nil
+}
+
+// New creates the results cache for the configured backend.
+// If no backend is configured, New returns nil.
+func New(cfg Config, logger log.Logger, reg prometheus.Registerer) (Cache, error) {
+	var (
+		c   Cache
+		err error
+	)
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case BackendInMemory:
+		c = NewInMemory(cfg.InMemory.MaxSizeBytes)
+	case BackendDisk:
+		if c, err = NewDisk(cfg.Disk.Directory, cfg.Disk.MaxSizeBytes, logger); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported results cache backend %q", cfg.Backend)
+	}
+	return newInstrumentedCache(c, cfg.Backend, reg), nil
+}
+
+type instrumentedCache struct {
+	Cache
+	requests prometheus.Counter
+	hits     prometheus.Counter
+}
+
+func newInstrumentedCache(c Cache, backend string, reg prometheus.Registerer) *instrumentedCache {
+	reg = prometheus.WrapRegistererWith(prometheus.Labels{"backend": backend}, reg)
+	return &instrumentedCache{
+		Cache: c,
+		requests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "pyroscope_query_frontend_results_cache_requests_total",
+			Help: "Total number of requests to the query-frontend results cache.",
+		}),
+		hits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "pyroscope_query_frontend_results_cache_hits_total",
+			Help: "Total number of query-frontend results cache hits.",
+		}),
+	}
+}
+
+func (c *instrumentedCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	c.requests.Inc()
+	v, ok := c.Cache.Get(ctx, key)
+	if ok {
+		c.hits.Inc()
+	}
+	return v, ok
+}
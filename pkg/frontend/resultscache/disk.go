@@ -0,0 +1,151 @@
+package resultscache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const tmpFileSuffix = ".tmp"
+
+type diskCache struct {
+	dir    string
+	logger log.Logger
+
+	mu  sync.Mutex
+	lru *lru
+}
+
+// NewDisk creates a cache that stores values in files in the directory,
+// up to maxSize bytes in total. Files present in the directory are loaded
+// on start, so that the cached results survive restarts.
+func NewDisk(dir string, maxSize int64, logger log.Logger) (Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &diskCache{
+		dir:    dir,
+		logger: logger,
+	}
+	c.lru = newLRU(maxSize, c.removeFile)
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load adds the files present in the directory to the index
+// in the order of modification time, and removes leftovers
+// of interrupted writes.
+func (c *diskCache) load() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(e.Name(), tmpFileSuffix) {
+			_ = os.Remove(filepath.Join(c.dir, e.Name()))
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	for _, f := range files {
+		// Files exceeding the size limit, e.g., after
+		// the limit has been lowered, are removed.
+		e := &lruEntry{key: f.Name(), size: f.Size()}
+		if !c.lru.add(e) {
+			c.removeFile(e)
+		}
+	}
+	return nil
+}
+
+func (c *diskCache) Get(_ context.Context, key string) ([]byte, bool) {
+	name := fileName(key)
+	c.mu.Lock()
+	_, ok := c.lru.get(name)
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	b, err := os.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			level.Warn(c.logger).Log("msg", "failed to read cached result", "err", err)
+		}
+		c.mu.Lock()
+		c.lru.remove(name)
+		c.mu.Unlock()
+		return nil, false
+	}
+	return b, true
+}
+
+func (c *diskCache) Set(_ context.Context, key string, value []byte) {
+	name := fileName(key)
+	// Check the size before writing the file: an entry
+	// larger than the limit would never be evicted.
+	if !c.lru.fits(int64(len(value))) {
+		return
+	}
+	if err := c.writeFile(name, value); err != nil {
+		level.Warn(c.logger).Log("msg", "failed to write cached result", "err", err)
+		return
+	}
+	c.mu.Lock()
+	c.lru.add(&lruEntry{key: name, size: int64(len(value))})
+	c.mu.Unlock()
+}
+
+// writeFile writes the value to a temporary file first,
+// so that readers never observe a partially written file.
+func (c *diskCache) writeFile(name string, value []byte) error {
+	f, err := os.CreateTemp(c.dir, name+".*"+tmpFileSuffix)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(value); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err = f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err = os.Rename(f.Name(), filepath.Join(c.dir, name)); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+func (c *diskCache) removeFile(e *lruEntry) {
+	if err := os.Remove(filepath.Join(c.dir, e.key)); err != nil && !os.IsNotExist(err) {
+		level.Warn(c.logger).Log("msg", "failed to remove cached result", "err", err)
+	}
+}
+
+func fileName(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
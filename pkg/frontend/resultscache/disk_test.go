@@ -0,0 +1,66 @@
+package resultscache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DiskCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c, err := NewDisk(dir, 6, log.NewNopLogger())
+	require.NoError(t, err)
+
+	_, ok := c.Get(ctx, "a")
+	require.False(t, ok)
+
+	c.Set(ctx, "a", []byte("foo"))
+	c.Set(ctx, "b", []byte("bar"))
+	v, ok := c.Get(ctx, "a")
+	require.True(t, ok)
+	require.Equal(t, []byte("foo"), v)
+
+	// "b" is the least recently used entry.
+	c.Set(ctx, "c", []byte("baz"))
+	_, ok = c.Get(ctx, "b")
+	require.False(t, ok)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	// A file removed externally is a cache miss.
+	require.NoError(t, os.Remove(filepath.Join(dir, fileName("c"))))
+	_, ok = c.Get(ctx, "c")
+	require.False(t, ok)
+
+	// Cached results survive restarts, leftovers are removed.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "x"+tmpFileSuffix), []byte("x"), 0o644))
+	c, err = NewDisk(dir, 6, log.NewNopLogger())
+	require.NoError(t, err)
+	v, ok = c.Get(ctx, "a")
+	require.True(t, ok)
+	require.Equal(t, []byte("foo"), v)
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// Entries larger than the limit are not written.
+	c.Set(ctx, "d", []byte("1234567"))
+	_, ok = c.Get(ctx, "d")
+	require.False(t, ok)
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// Files larger than the lowered limit are removed.
+	_, err = NewDisk(dir, 2, log.NewNopLogger())
+	require.NoError(t, err)
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+}
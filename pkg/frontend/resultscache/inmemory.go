@@ -0,0 +1,116 @@
+package resultscache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// lru tracks entries in the least recently used order
+// and evicts them once the total size exceeds the limit.
+// lru is not safe for concurrent use.
+type lru struct {
+	maxSize int64
+	size    int64
+	order   *list.List
+	entries map[string]*list.Element
+	// onEvict is called for every evicted entry.
+	onEvict func(*lruEntry)
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+	size  int64
+}
+
+func newLRU(maxSize int64, onEvict func(*lruEntry)) *lru {
+	return &lru{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		onEvict: onEvict,
+	}
+}
+
+func (c *lru) get(key string) (*lruEntry, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry), true
+}
+
+// add inserts or replaces the entry and evicts the least recently
+// used entries, if the size limit is exceeded. An entry larger than
+// the limit is not added: add reports whether the entry was added.
+func (c *lru) add(x *lruEntry) bool {
+	if !c.fits(x.size) {
+		return false
+	}
+	if e, ok := c.entries[x.key]; ok {
+		c.size -= e.Value.(*lruEntry).size
+		c.order.Remove(e)
+	}
+	c.entries[x.key] = c.order.PushFront(x)
+	c.size += x.size
+	for c.size > c.maxSize {
+		c.evict(c.order.Back())
+	}
+	return true
+}
+
+// fits reports whether an entry of the given size can be added.
+func (c *lru) fits(size int64) bool {
+	return size <= c.maxSize
+}
+
+func (c *lru) remove(key string) {
+	if e, ok := c.entries[key]; ok {
+		c.size -= e.Value.(*lruEntry).size
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+}
+
+func (c *lru) evict(e *list.Element) {
+	x := e.Value.(*lruEntry)
+	c.order.Remove(e)
+	delete(c.entries, x.key)
+	c.size -= x.size
+	if c.onEvict != nil {
+		c.onEvict(x)
+	}
+}
+
+type inMemoryCache struct {
+	mu  sync.Mutex
+	lru *lru
+}
+
+// NewInMemory creates an in-process LRU cache
+// that holds up to maxSize bytes of values.
+func NewInMemory(maxSize int64) Cache {
+	return &inMemoryCache{lru: newLRU(maxSize, nil)}
+}
+
+func (c *inMemoryCache) Get(_ context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	x, ok := c.lru.get(key)
+	if !ok {
+		return nil, false
+	}
+	return x.value, true
+}
+
+func (c *inMemoryCache) Set(_ context.Context, key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.add(&lruEntry{
+		key:   key,
+		value: value,
+		size:  int64(len(key) + len(value)),
+	})
+}
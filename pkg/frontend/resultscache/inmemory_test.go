@@ -0,0 +1,47 @@
+package resultscache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_InMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	// Every entry takes 4 bytes: 1 byte of key and 3 bytes of value.
+	c := NewInMemory(8)
+
+	_, ok := c.Get(ctx, "a")
+	require.False(t, ok)
+
+	c.Set(ctx, "a", []byte("foo"))
+	c.Set(ctx, "b", []byte("bar"))
+	v, ok := c.Get(ctx, "a")
+	require.True(t, ok)
+	require.Equal(t, []byte("foo"), v)
+
+	// "b" is the least recently used entry.
+	c.Set(ctx, "c", []byte("baz"))
+	_, ok = c.Get(ctx, "b")
+	require.False(t, ok)
+	_, ok = c.Get(ctx, "a")
+	require.True(t, ok)
+	_, ok = c.Get(ctx, "c")
+	require.True(t, ok)
+
+	// Replacing an entry does not evict others.
+	c.Set(ctx, "c", []byte("qux"))
+	v, ok = c.Get(ctx, "c")
+	require.True(t, ok)
+	require.Equal(t, []byte("qux"), v)
+	_, ok = c.Get(ctx, "a")
+	require.True(t, ok)
+
+	// Values larger than the cache are not stored.
+	c.Set(ctx, "d", []byte("too large"))
+	_, ok = c.Get(ctx, "d")
+	require.False(t, ok)
+	_, ok = c.Get(ctx, "a")
+	require.True(t, ok)
+}
@@ -0,0 +1,44 @@
+package frontend
+
+import (
+	"strings"
+
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/pyroscope/pkg/phlaredb/sharding"
+	validationutil "github.com/grafana/pyroscope/pkg/util/validation"
+)
+
+// shardLabelSelector returns a label selector for each of the series shards
+// configured for the tenants. Each of the selectors includes the query shard
+// matcher, which ingesters and store-gateways use to select only the series
+// of the shard. If query sharding is disabled, the selector is returned as is.
+func (f *Frontend) shardLabelSelector(tenantIDs []string, selector string) ([]string, error) {
+	shards := validationutil.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.QueryShardingTotalShards)
+	if shards <= 1 {
+		return []string{selector}, nil
+	}
+	matchers, err := parser.ParseMetricSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	s, _, err := sharding.ShardFromMatchers(matchers)
+	if err != nil {
+		return nil, err
+	}
+	if s != nil {
+		// The query is already sharded.
+		return []string{selector}, nil
+	}
+	selectors := make([]string, shards)
+	parts := make([]string, len(matchers)+1)
+	for i, m := range matchers {
+		parts[i] = m.String()
+	}
+	for i := range selectors {
+		shard := sharding.ShardSelector{ShardIndex: uint64(i), ShardCount: uint64(shards)}
+		parts[len(matchers)] = shard.Matcher().String()
+		selectors[i] = "{" + strings.Join(parts, ",") + "}"
+	}
+	return selectors, nil
+}
@@ -0,0 +1,63 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+func Test_shardLabelSelector(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		shards   int
+		selector string
+		expected []string
+	}{
+		{
+			name:     "sharding disabled",
+			selector: `{service_name="foo"}`,
+			expected: []string{`{service_name="foo"}`},
+		},
+		{
+			name:     "single shard",
+			shards:   1,
+			selector: `{service_name="foo"}`,
+			expected: []string{`{service_name="foo"}`},
+		},
+		{
+			name:     "sharded",
+			shards:   3,
+			selector: `{service_name="foo",pod=~"bar.*"}`,
+			expected: []string{
+				`{service_name="foo",pod=~"bar.*",__query_shard__="1_of_3"}`,
+				`{service_name="foo",pod=~"bar.*",__query_shard__="2_of_3"}`,
+				`{service_name="foo",pod=~"bar.*",__query_shard__="3_of_3"}`,
+			},
+		},
+		{
+			name:     "empty selector",
+			shards:   2,
+			selector: `{}`,
+			expected: []string{
+				`{__query_shard__="1_of_2"}`,
+				`{__query_shard__="2_of_2"}`,
+			},
+		},
+		{
+			name:     "already sharded",
+			shards:   2,
+			selector: `{service_name="foo",__query_shard__="1_of_4"}`,
+			expected: []string{`{service_name="foo",__query_shard__="1_of_4"}`},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			f := &Frontend{limits: validation.MockLimits{QueryShardingTotalShardsValue: tc.shards}}
+			actual, err := f.shardLabelSelector([]string{"tenant"}, tc.selector)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
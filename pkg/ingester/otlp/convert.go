@@ -0,0 +1,298 @@
+package otlp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	commonv1 "github.com/grafana/pyroscope/api/gen/proto/go/opentelemetry/proto/common/v1"
+	otlpprofiles "github.com/grafana/pyroscope/api/gen/proto/go/opentelemetry/proto/profiles/v1experimental"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+// convertedProfile is a part of the OTLP profile: the samples
+// of the profile are split by their attributes.
+type convertedProfile struct {
+	labels  phlaremodel.Labels
+	profile *profilev1.Profile
+}
+
+// convertProfile converts the OTLP profile to pprof profiles, one per set
+// of the sample attributes. The attributes are added to the series labels,
+// which take precedence over the given ones.
+func convertProfile(pc *otlpprofiles.ProfileContainer, base phlaremodel.Labels) ([]convertedProfile, error) {
+	src := pc.Profile
+	p, err := convertToPprof(src)
+	if err != nil {
+		return nil, err
+	}
+	// The time range of the container is used,
+	// if the profile does not specify one.
+	if p.TimeNanos == 0 {
+		p.TimeNanos = int64(pc.StartTimeUnixNano)
+	}
+	if p.DurationNanos == 0 && pc.EndTimeUnixNano > pc.StartTimeUnixNano {
+		p.DurationNanos = int64(pc.EndTimeUnixNano - pc.StartTimeUnixNano)
+	}
+
+	base = phlaremodel.NewLabelsBuilder(base).
+		Set(labels.MetricName, profileName(p)).
+		Set(phlaremodel.LabelNameDelta, strconv.FormatBool(isCumulative(src))).
+		Labels()
+
+	type group struct {
+		labels  phlaremodel.Labels
+		samples []*profilev1.Sample
+	}
+	groups := make(map[string]*group)
+	var keys []string
+	for i, s := range src.Sample {
+		lb := phlaremodel.NewLabelsBuilder(base)
+		for _, a := range s.Attributes {
+			if a >= uint64(len(src.AttributeTable)) {
+				return nil, fmt.Errorf("sample %d: attribute index %d out of range", i, a)
+			}
+			setAttribute(lb, src.AttributeTable[a])
+		}
+		lbs := lb.Labels()
+		k := phlaremodel.LabelPairsString(lbs)
+		g, ok := groups[k]
+		if !ok {
+			g = &group{labels: lbs}
+			groups[k] = g
+			keys = append(keys, k)
+		}
+		g.samples = append(g.samples, p.Sample[i])
+	}
+
+	// Every group has a copy of the symbols, as
+	// profiles may be modified during ingestion.
+	p.Sample = nil
+	sort.Strings(keys)
+	profiles := make([]convertedProfile, 0, len(keys))
+	for _, k := range keys {
+		g := groups[k]
+		gp := p.CloneVT()
+		gp.Sample = g.samples
+		profiles = append(profiles, convertedProfile{labels: g.labels, profile: gp})
+	}
+	return profiles, nil
+}
+
+// convertToPprof converts the OTLP profile to pprof. The indices of the
+// mappings, locations, and functions are converted to identifiers.
+func convertToPprof(src *otlpprofiles.Profile) (*profilev1.Profile, error) {
+	dst := &profilev1.Profile{
+		SampleType:        make([]*profilev1.ValueType, len(src.SampleType)),
+		Sample:            make([]*profilev1.Sample, len(src.Sample)),
+		Mapping:           make([]*profilev1.Mapping, len(src.Mapping)),
+		Location:          make([]*profilev1.Location, len(src.Location)),
+		Function:          make([]*profilev1.Function, len(src.Function)),
+		StringTable:       src.StringTable,
+		DropFrames:        src.DropFrames,
+		KeepFrames:        src.KeepFrames,
+		TimeNanos:         src.TimeNanos,
+		DurationNanos:     src.DurationNanos,
+		Period:            src.Period,
+		Comment:           src.Comment,
+		DefaultSampleType: src.DefaultSampleType,
+	}
+	if src.PeriodType != nil {
+		dst.PeriodType = &profilev1.ValueType{Type: src.PeriodType.Type, Unit: src.PeriodType.Unit}
+	}
+	for i, t := range src.SampleType {
+		dst.SampleType[i] = &profilev1.ValueType{Type: t.Type, Unit: t.Unit}
+	}
+
+	for i, m := range src.Mapping {
+		dst.Mapping[i] = &profilev1.Mapping{
+			Id:              uint64(i + 1),
+			MemoryStart:     m.MemoryStart,
+			MemoryLimit:     m.MemoryLimit,
+			FileOffset:      m.FileOffset,
+			Filename:        m.Filename,
+			BuildId:         m.BuildId,
+			HasFunctions:    m.HasFunctions,
+			HasFilenames:    m.HasFilenames,
+			HasLineNumbers:  m.HasLineNumbers,
+			HasInlineFrames: m.HasInlineFrames,
+		}
+	}
+
+	for i, f := range src.Function {
+		dst.Function[i] = &profilev1.Function{
+			Id:         uint64(i + 1),
+			Name:       f.Name,
+			SystemName: f.SystemName,
+			Filename:   f.Filename,
+			StartLine:  f.StartLine,
+		}
+	}
+
+	for i, loc := range src.Location {
+		l := &profilev1.Location{
+			Id:       uint64(i + 1),
+			Address:  loc.Address,
+			IsFolded: loc.IsFolded,
+			Line:     make([]*profilev1.Line, len(loc.Line)),
+		}
+		// The mapping index is only meaningful if there are mappings.
+		if len(src.Mapping) > 0 {
+			if loc.MappingIndex >= uint64(len(src.Mapping)) {
+				return nil, fmt.Errorf("location %d: mapping index %d out of range", i, loc.MappingIndex)
+			}
+			l.MappingId = loc.MappingIndex + 1
+		}
+		for j, line := range loc.Line {
+			if line.FunctionIndex >= uint64(len(src.Function)) {
+				return nil, fmt.Errorf("location %d: function index %d out of range", i, line.FunctionIndex)
+			}
+			l.Line[j] = &profilev1.Line{FunctionId: line.FunctionIndex + 1, Line: line.Line}
+		}
+		dst.Location[i] = l
+	}
+
+	for i, s := range src.Sample {
+		locations, err := sampleLocations(src, s)
+		if err != nil {
+			return nil, fmt.Errorf("sample %d: %w", i, err)
+		}
+		ds := &profilev1.Sample{
+			LocationId: make([]uint64, len(locations)),
+			Value:      s.Value,
+			Label:      make([]*profilev1.Label, len(s.Label)),
+		}
+		for j, idx := range locations {
+			ds.LocationId[j] = idx + 1
+		}
+		for j, l := range s.Label {
+			ds.Label[j] = &profilev1.Label{Key: l.Key, Str: l.Str, Num: l.Num, NumUnit: l.NumUnit}
+		}
+		dst.Sample[i] = ds
+	}
+
+	return dst, nil
+}
+
+// sampleLocations returns the indices of the sample locations, leaf first.
+// The deprecated location_index field takes precedence, if present.
+func sampleLocations(p *otlpprofiles.Profile, s *otlpprofiles.Sample) ([]uint64, error) {
+	locations := s.LocationIndex
+	if len(locations) == 0 && s.LocationsLength > 0 {
+		end := s.LocationsStartIndex + s.LocationsLength
+		if end > uint64(len(p.LocationIndices)) {
+			return nil, fmt.Errorf("locations range [%d, %d) out of range", s.LocationsStartIndex, end)
+		}
+		locations = make([]uint64, s.LocationsLength)
+		for i, idx := range p.LocationIndices[s.LocationsStartIndex:end] {
+			locations[i] = uint64(idx)
+		}
+	}
+	for _, idx := range locations {
+		if idx >= uint64(len(p.Location)) {
+			return nil, fmt.Errorf("location index %d out of range", idx)
+		}
+	}
+	return locations, nil
+}
+
+// isCumulative reports whether the values of the profile are cumulative,
+// in which case the deltas are computed on ingestion.
+func isCumulative(p *otlpprofiles.Profile) bool {
+	for _, t := range p.SampleType {
+		if t.AggregationTemporality == otlpprofiles.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+			return true
+		}
+	}
+	return false
+}
+
+// profileName returns the name of the profile based on its sample types.
+func profileName(p *profilev1.Profile) string {
+	str := func(i int64) string {
+		if i < 0 || i >= int64(len(p.StringTable)) {
+			return ""
+		}
+		return p.StringTable[i]
+	}
+	if p.PeriodType != nil && str(p.PeriodType.Type) == "cpu" {
+		return "process_cpu"
+	}
+	var st string
+	for _, t := range p.SampleType {
+		st = str(t.Type)
+		switch {
+		case st == "wall":
+			return "wall"
+		case strings.Contains(st, "cpu"):
+			return "process_cpu"
+		case strings.Contains(st, "alloc_") || strings.Contains(st, "inuse_") || st == "space" || st == "objects":
+			return "memory"
+		case strings.Contains(st, "mutex_"):
+			return "mutex"
+		case strings.Contains(st, "block_"):
+			return "block"
+		case strings.Contains(st, "goroutines"):
+			return "goroutines"
+		}
+	}
+	return st
+}
+
+// setAttribute adds the attribute to the labels. Attribute names are
+// converted to valid label names, e.g. service.name to service_name.
+func setAttribute(lb *phlaremodel.LabelsBuilder, a *commonv1.KeyValue) {
+	name := labelName(a.Key)
+	if name == "" || !phlaremodel.IsLabelAllowedForIngestion(name) {
+		return
+	}
+	if v, ok := attributeValue(a.Value); ok {
+		lb.Set(name, v)
+	}
+}
+
+func labelName(key string) string {
+	b := []byte(key)
+	for i, c := range b {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	// Label names must not start with a digit.
+	if len(b) > 0 && b[0] >= '0' && b[0] <= '9' {
+		return "_" + string(b)
+	}
+	return string(b)
+}
+
+// attributeValue returns the string representation of the scalar value.
+func attributeValue(v *commonv1.AnyValue) (string, bool) {
+	switch x := v.GetValue().(type) {
+	case *commonv1.AnyValue_StringValue:
+		return x.StringValue, true
+	case *commonv1.AnyValue_BoolValue:
+		return strconv.FormatBool(x.BoolValue), true
+	case *commonv1.AnyValue_IntValue:
+		return strconv.FormatInt(x.IntValue, 10), true
+	case *commonv1.AnyValue_DoubleValue:
+		return strconv.FormatFloat(x.DoubleValue, 'g', -1, 64), true
+	}
+	return "", false
+}
+
+// seriesLabels returns the labels of the resource and profile
+// attributes: the latter take precedence.
+func seriesLabels(resource, profile []*commonv1.KeyValue) phlaremodel.Labels {
+	lb := phlaremodel.NewLabelsBuilder(nil)
+	for _, a := range resource {
+		setAttribute(lb, a)
+	}
+	for _, a := range profile {
+		setAttribute(lb, a)
+	}
+	return lb.Labels()
+}
@@ -0,0 +1,133 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	commonv1 "github.com/grafana/pyroscope/api/gen/proto/go/opentelemetry/proto/common/v1"
+	otlpprofiles "github.com/grafana/pyroscope/api/gen/proto/go/opentelemetry/proto/profiles/v1experimental"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+func stringAttribute(key, value string) *commonv1.KeyValue {
+	return &commonv1.KeyValue{
+		Key:   key,
+		Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func testProfile() *otlpprofiles.Profile {
+	return &otlpprofiles.Profile{
+		StringTable: []string{"", "samples", "count", "cpu", "nanoseconds", "main", "foo", "bar", "main.go"},
+		SampleType:  []*otlpprofiles.ValueType{{Type: 1, Unit: 2}},
+		PeriodType:  &otlpprofiles.ValueType{Type: 3, Unit: 4},
+		Period:      10000000,
+		TimeNanos:   1e9,
+		Function: []*otlpprofiles.Function{
+			{Name: 5, Filename: 8},
+			{Name: 6, Filename: 8},
+			{Name: 7, Filename: 8},
+		},
+		Location: []*otlpprofiles.Location{
+			{Line: []*otlpprofiles.Line{{FunctionIndex: 0, Line: 1}}},
+			{Line: []*otlpprofiles.Line{{FunctionIndex: 1, Line: 2}}},
+			{Line: []*otlpprofiles.Line{{FunctionIndex: 2, Line: 3}}},
+		},
+		LocationIndices: []int64{1, 0, 2, 0},
+		AttributeTable: []*commonv1.KeyValue{
+			stringAttribute("thread.name", "worker"),
+			stringAttribute("service.name", "override"),
+		},
+		Sample: []*otlpprofiles.Sample{
+			{LocationsStartIndex: 0, LocationsLength: 2, Value: []int64{1}},
+			{LocationsStartIndex: 2, LocationsLength: 2, Value: []int64{2}, Attributes: []uint64{0}},
+			{LocationIndex: []uint64{2, 1, 0}, Value: []int64{3}, Attributes: []uint64{0}},
+		},
+	}
+}
+
+func Test_ConvertProfile(t *testing.T) {
+	pc := &otlpprofiles.ProfileContainer{Profile: testProfile()}
+	base := seriesLabels(
+		[]*commonv1.KeyValue{stringAttribute("service.name", "app"), stringAttribute("k8s.pod.name", "pod")},
+		[]*commonv1.KeyValue{stringAttribute("__private", "x")},
+	)
+	profiles, err := convertProfile(pc, base)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+
+	require.Equal(t, phlaremodel.LabelsFromStrings(
+		"__delta__", "false",
+		"__name__", "process_cpu",
+		"k8s_pod_name", "pod",
+		"service_name", "app",
+		"thread_name", "worker",
+	), profiles[0].labels)
+	require.Equal(t, []*profilev1.Sample{
+		{LocationId: []uint64{3, 1}, Value: []int64{2}, Label: []*profilev1.Label{}},
+		{LocationId: []uint64{3, 2, 1}, Value: []int64{3}, Label: []*profilev1.Label{}},
+	}, profiles[0].profile.Sample)
+
+	require.Equal(t, phlaremodel.LabelsFromStrings(
+		"__delta__", "false",
+		"__name__", "process_cpu",
+		"k8s_pod_name", "pod",
+		"service_name", "app",
+	), profiles[1].labels)
+	require.Equal(t, []*profilev1.Sample{
+		{LocationId: []uint64{2, 1}, Value: []int64{1}, Label: []*profilev1.Label{}},
+	}, profiles[1].profile.Sample)
+
+	p := profiles[0].profile
+	require.Equal(t, int64(1e9), p.TimeNanos)
+	require.Equal(t, &profilev1.ValueType{Type: 3, Unit: 4}, p.PeriodType)
+	require.Equal(t, []*profilev1.Location{
+		{Id: 1, Line: []*profilev1.Line{{FunctionId: 1, Line: 1}}},
+		{Id: 2, Line: []*profilev1.Line{{FunctionId: 2, Line: 2}}},
+		{Id: 3, Line: []*profilev1.Line{{FunctionId: 3, Line: 3}}},
+	}, p.Location)
+	require.Equal(t, &profilev1.Function{Id: 2, Name: 6, Filename: 8}, p.Function[1])
+}
+
+func Test_ConvertProfile_SampleAttributesOverride(t *testing.T) {
+	src := testProfile()
+	for _, s := range src.Sample {
+		s.Attributes = []uint64{1}
+	}
+	profiles, err := convertProfile(&otlpprofiles.ProfileContainer{Profile: src}, phlaremodel.LabelsFromStrings("service_name", "app"))
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	require.Equal(t, "override", profiles[0].labels.Get("service_name"))
+}
+
+func Test_ConvertProfile_Cumulative(t *testing.T) {
+	src := testProfile()
+	src.SampleType[0].AggregationTemporality = otlpprofiles.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	profiles, err := convertProfile(&otlpprofiles.ProfileContainer{Profile: src}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "true", profiles[0].labels.Get(phlaremodel.LabelNameDelta))
+}
+
+func Test_ConvertProfile_Invalid(t *testing.T) {
+	for name, modify := range map[string]func(*otlpprofiles.Profile){
+		"location index":  func(p *otlpprofiles.Profile) { p.Sample[2].LocationIndex = []uint64{3} },
+		"locations range": func(p *otlpprofiles.Profile) { p.Sample[0].LocationsLength = 5 },
+		"function index":  func(p *otlpprofiles.Profile) { p.Location[0].Line[0].FunctionIndex = 3 },
+		"attribute index": func(p *otlpprofiles.Profile) { p.Sample[0].Attributes = []uint64{2} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := testProfile()
+			modify(src)
+			_, err := convertProfile(&otlpprofiles.ProfileContainer{Profile: src}, nil)
+			require.Error(t, err)
+		})
+	}
+}
+
+func Test_LabelName(t *testing.T) {
+	require.Equal(t, "service_name", labelName("service.name"))
+	require.Equal(t, "_1st", labelName("1st"))
+	require.Equal(t, "k8s_pod_name", labelName("k8s.pod.name"))
+}
@@ -0,0 +1,175 @@
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	otlpcollector "github.com/grafana/pyroscope/api/gen/proto/go/opentelemetry/proto/collector/profiles/v1experimental"
+	"github.com/grafana/pyroscope/api/gen/proto/go/opentelemetry/proto/collector/profiles/v1experimental/profilesv1experimentalconnect"
+	pushv1 "github.com/grafana/pyroscope/api/gen/proto/go/push/v1"
+	distributormodel "github.com/grafana/pyroscope/pkg/distributor/model"
+	"github.com/grafana/pyroscope/pkg/pprof"
+	"github.com/grafana/pyroscope/pkg/tenant"
+	httputil "github.com/grafana/pyroscope/pkg/util/http"
+)
+
+// HTTPPath is the path of the OTLP/HTTP profiles endpoint.
+const HTTPPath = "/v1experimental/profiles"
+
+type PushService interface {
+	PushParsed(ctx context.Context, req *distributormodel.PushRequest) (*connect.Response[pushv1.PushResponse], error)
+}
+
+type Limits interface {
+	MaxProfileSizeBytes(tenantID string) int
+}
+
+// IngestHandler receives OTLP profiles over gRPC and HTTP/protobuf,
+// and pushes them as pprof profiles.
+type IngestHandler struct {
+	svc    PushService
+	limits Limits
+	log    log.Logger
+}
+
+var _ profilesv1experimentalconnect.ProfilesServiceHandler = (*IngestHandler)(nil)
+
+func NewIngestHandler(svc PushService, limits Limits, logger log.Logger) *IngestHandler {
+	return &IngestHandler{svc: svc, limits: limits, log: logger}
+}
+
+func (h *IngestHandler) Export(ctx context.Context, req *connect.Request[otlpcollector.ExportProfilesServiceRequest]) (*connect.Response[otlpcollector.ExportProfilesServiceResponse], error) {
+	if err := h.export(ctx, req.Msg); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&otlpcollector.ExportProfilesServiceResponse{}), nil
+}
+
+// ServeHTTP implements the OTLP/HTTP transport: both the binary
+// and JSON protobuf encodings are supported. The size of the
+// uncompressed request is limited to the maximum profile size.
+func (h *IngestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := tenant.ExtractTenantIDFromContext(r.Context())
+	if err != nil {
+		httputil.ErrorWithStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	var (
+		unmarshal func([]byte, proto.Message) error
+		marshal   func(proto.Message) ([]byte, error)
+	)
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		httputil.ErrorWithStatus(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+	switch contentType {
+	case "application/x-protobuf":
+		unmarshal, marshal = proto.Unmarshal, proto.Marshal
+	case "application/json":
+		unmarshal, marshal = protojson.Unmarshal, protojson.Marshal
+	default:
+		httputil.ErrorWithStatus(w, fmt.Errorf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gr, err := gzip.NewReader(body)
+		if err != nil {
+			httputil.ErrorWithStatus(w, err, http.StatusBadRequest)
+			return
+		}
+		defer gr.Close()
+		body = gr
+	}
+	if limit := h.limits.MaxProfileSizeBytes(tenantID); limit > 0 {
+		body = http.MaxBytesReader(w, body, int64(limit))
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			httputil.ErrorWithStatus(w, fmt.Errorf("request body too large: the limit is %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		httputil.ErrorWithStatus(w, err, http.StatusBadRequest)
+		return
+	}
+	var req otlpcollector.ExportProfilesServiceRequest
+	if err = unmarshal(b, &req); err != nil {
+		httputil.ErrorWithStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err = h.export(r.Context(), &req); err != nil {
+		httputil.Error(w, err)
+		return
+	}
+	resp, err := marshal(&otlpcollector.ExportProfilesServiceResponse{})
+	if err != nil {
+		httputil.Error(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(resp)
+}
+
+func (h *IngestHandler) export(ctx context.Context, req *otlpcollector.ExportProfilesServiceRequest) error {
+	p := &distributormodel.PushRequest{
+		RawProfileSize: proto.Size(req),
+		RawProfileType: distributormodel.RawProfileTypeOTLP,
+	}
+	// Converted profiles have to be closed after use.
+	defer func() {
+		for _, s := range p.Series {
+			for _, x := range s.Samples {
+				x.Profile.Close()
+			}
+		}
+	}()
+
+	for _, rp := range req.ResourceProfiles {
+		for _, sp := range rp.ScopeProfiles {
+			for _, pc := range sp.Profiles {
+				if pc.Profile == nil {
+					continue
+				}
+				profiles, err := convertProfile(pc, seriesLabels(rp.GetResource().GetAttributes(), pc.Attributes))
+				if err != nil {
+					return connect.NewError(connect.CodeInvalidArgument, err)
+				}
+				for _, cp := range profiles {
+					p.Series = append(p.Series, &distributormodel.ProfileSeries{
+						Labels: cp.labels,
+						Samples: []*distributormodel.ProfileSample{{
+							Profile: pprof.RawFromProto(cp.profile),
+							ID:      uuid.New().String(),
+						}},
+					})
+				}
+			}
+		}
+	}
+
+	if len(p.Series) == 0 {
+		tenantID, _ := tenant.ExtractTenantIDFromContext(ctx)
+		_ = level.Debug(h.log).Log("msg", "empty OTLP profiles request", "orgID", tenantID)
+		return nil
+	}
+	_, err := h.svc.PushParsed(ctx, p)
+	return err
+}
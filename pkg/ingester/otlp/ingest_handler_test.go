@@ -0,0 +1,160 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	otlpcollector "github.com/grafana/pyroscope/api/gen/proto/go/opentelemetry/proto/collector/profiles/v1experimental"
+	commonv1 "github.com/grafana/pyroscope/api/gen/proto/go/opentelemetry/proto/common/v1"
+	otlpprofiles "github.com/grafana/pyroscope/api/gen/proto/go/opentelemetry/proto/profiles/v1experimental"
+	resourcev1 "github.com/grafana/pyroscope/api/gen/proto/go/opentelemetry/proto/resource/v1"
+	pushv1 "github.com/grafana/pyroscope/api/gen/proto/go/push/v1"
+	distributormodel "github.com/grafana/pyroscope/pkg/distributor/model"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/tenant"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+type mockPushService struct {
+	reqs []*distributormodel.PushRequest
+}
+
+func (m *mockPushService) PushParsed(_ context.Context, req *distributormodel.PushRequest) (*connect.Response[pushv1.PushResponse], error) {
+	m.reqs = append(m.reqs, req)
+	return connect.NewResponse(&pushv1.PushResponse{}), nil
+}
+
+func testExportRequest() *otlpcollector.ExportProfilesServiceRequest {
+	return &otlpcollector.ExportProfilesServiceRequest{
+		ResourceProfiles: []*otlpprofiles.ResourceProfiles{{
+			Resource: &resourcev1.Resource{
+				Attributes: []*commonv1.KeyValue{stringAttribute("service.name", "app")},
+			},
+			ScopeProfiles: []*otlpprofiles.ScopeProfiles{{
+				Profiles: []*otlpprofiles.ProfileContainer{{Profile: testProfile()}},
+			}},
+		}},
+	}
+}
+
+func Test_IngestHandler_Export(t *testing.T) {
+	svc := new(mockPushService)
+	h := NewIngestHandler(svc, validation.MockLimits{}, log.NewNopLogger())
+	_, err := h.Export(context.Background(), connect.NewRequest(testExportRequest()))
+	require.NoError(t, err)
+	require.Len(t, svc.reqs, 1)
+
+	req := svc.reqs[0]
+	require.Equal(t, distributormodel.RawProfileTypeOTLP, req.RawProfileType)
+	require.Len(t, req.Series, 2)
+	for _, s := range req.Series {
+		require.Equal(t, "app", phlaremodel.Labels(s.Labels).Get("service_name"))
+		require.Len(t, s.Samples, 1)
+		require.NotEmpty(t, s.Samples[0].ID)
+	}
+}
+
+func Test_IngestHandler_Export_Invalid(t *testing.T) {
+	svc := new(mockPushService)
+	h := NewIngestHandler(svc, validation.MockLimits{}, log.NewNopLogger())
+	req := testExportRequest()
+	req.ResourceProfiles[0].ScopeProfiles[0].Profiles[0].Profile.Sample[0].LocationsLength = 10
+	_, err := h.Export(context.Background(), connect.NewRequest(req))
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+	require.Empty(t, svc.reqs)
+}
+
+func Test_IngestHandler_ServeHTTP(t *testing.T) {
+	for contentType, marshal := range map[string]func(proto.Message) ([]byte, error){
+		"application/x-protobuf":                proto.Marshal,
+		"application/json":                      protojson.Marshal,
+		"application/json; charset=utf-8":       protojson.Marshal,
+		"Application/X-Protobuf; param=ignored": proto.Marshal,
+	} {
+		t.Run(contentType, func(t *testing.T) {
+			svc := new(mockPushService)
+			h := NewIngestHandler(svc, validation.MockLimits{}, log.NewNopLogger())
+			b, err := marshal(testExportRequest())
+			require.NoError(t, err)
+
+			r := newHTTPRequest(bytes.NewReader(b))
+			r.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			mediaType, _, err := mime.ParseMediaType(contentType)
+			require.NoError(t, err)
+			require.Equal(t, mediaType, w.Header().Get("Content-Type"))
+			require.Len(t, svc.reqs, 1)
+			require.Len(t, svc.reqs[0].Series, 2)
+		})
+	}
+}
+
+func Test_IngestHandler_ServeHTTP_UnsupportedContentType(t *testing.T) {
+	h := NewIngestHandler(new(mockPushService), validation.MockLimits{}, log.NewNopLogger())
+	for _, contentType := range []string{"text/plain", "/json", ""} {
+		r := newHTTPRequest(bytes.NewReader(nil))
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusUnsupportedMediaType, w.Code, contentType)
+	}
+}
+
+func Test_IngestHandler_ServeHTTP_MaxProfileSize(t *testing.T) {
+	b, err := proto.Marshal(testExportRequest())
+	require.NoError(t, err)
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	_, err = gw.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+
+	for _, tc := range []struct {
+		name     string
+		limit    int
+		gzip     bool
+		expected int
+	}{
+		{name: "no limit", limit: 0, expected: http.StatusOK},
+		{name: "within the limit", limit: len(b), expected: http.StatusOK},
+		{name: "over the limit", limit: len(b) - 1, expected: http.StatusRequestEntityTooLarge},
+		{name: "uncompressed size over the limit", limit: len(b) - 1, gzip: true, expected: http.StatusRequestEntityTooLarge},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := new(mockPushService)
+			h := NewIngestHandler(svc, validation.MockLimits{MaxProfileSizeBytesValue: tc.limit}, log.NewNopLogger())
+			r := newHTTPRequest(bytes.NewReader(b))
+			if tc.gzip {
+				r = newHTTPRequest(bytes.NewReader(compressed.Bytes()))
+				r.Header.Set("Content-Encoding", "gzip")
+			}
+			r.Header.Set("Content-Type", "application/x-protobuf")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			require.Equal(t, tc.expected, w.Code)
+			if tc.expected != http.StatusOK {
+				require.Empty(t, svc.reqs)
+			}
+		})
+	}
+}
+
+func newHTTPRequest(body io.Reader) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, HTTPPath, body)
+	return r.WithContext(tenant.InjectTenantID(r.Context(), "tenant"))
+}
@@ -18,7 +18,10 @@ import (
 	"github.com/go-kit/log/level"
 
 	"github.com/grafana/pyroscope/pkg/og/agent/types"
+	"github.com/grafana/pyroscope/pkg/og/convert/cpuprofile"
+	"github.com/grafana/pyroscope/pkg/og/convert/gecko"
 	"github.com/grafana/pyroscope/pkg/og/convert/jfr"
+	"github.com/grafana/pyroscope/pkg/og/convert/perf"
 	"github.com/grafana/pyroscope/pkg/og/convert/pprof"
 	"github.com/grafana/pyroscope/pkg/og/convert/profile"
 	"github.com/grafana/pyroscope/pkg/og/ingestion"
@@ -159,6 +162,26 @@ func (h ingestHandler) ingestInputFromRequest(r *http.Request) (*ingestion.Inges
 			RawData: b,
 		}
 
+	case format == "gecko":
+		input.Format = ingestion.FormatGecko
+		threadLabels, _ := strconv.ParseBool(q.Get("threadLabels"))
+		input.Profile = &gecko.RawProfile{
+			RawData:      b,
+			ThreadLabels: threadLabels,
+		}
+
+	case format == "cpuprofile":
+		input.Format = ingestion.FormatCPUProfile
+		input.Profile = &cpuprofile.RawProfile{
+			RawData: b,
+		}
+
+	case format == "perf":
+		input.Format = ingestion.FormatPerf
+		input.Profile = &perf.RawProfile{
+			RawData: b,
+		}
+
 	case strings.Contains(contentType, "multipart/form-data"):
 		input.Profile = &pprof.RawProfile{
 			FormDataContentType: contentType,
@@ -58,3 +58,16 @@ func (i *Ingester) MergeProfilesPprof(ctx context.Context, stream *connect.BidiS
 		return instance.MergeProfilesPprof(ctx, stream)
 	})
 }
+
+func (i *Ingester) SelectProfileByID(ctx context.Context, req *connect.Request[ingestv1.SelectProfileByIDRequest]) (*connect.Response[ingestv1.SelectProfileByIDResponse], error) {
+	return forInstanceUnary(ctx, i, func(instance *instance) (*connect.Response[ingestv1.SelectProfileByIDResponse], error) {
+		return instance.SelectProfileByID(ctx, req)
+	})
+}
+
+// Cardinality returns the number of series per label name and value.
+func (i *Ingester) Cardinality(ctx context.Context, req *connect.Request[ingestv1.CardinalityRequest]) (*connect.Response[ingestv1.CardinalityResponse], error) {
+	return forInstanceUnary(ctx, i, func(instance *instance) (*connect.Response[ingestv1.CardinalityResponse], error) {
+		return instance.Cardinality(ctx, req)
+	})
+}
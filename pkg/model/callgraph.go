@@ -0,0 +1,238 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+)
+
+// The defaults match the ones of "go tool pprof".
+const (
+	DefaultCallGraphNodeFraction = 0.005
+	DefaultCallGraphEdgeFraction = 0.001
+)
+
+type CallGraphOptions struct {
+	// Nodes with the cumulative value below the fraction
+	// of the total are dropped from the graph.
+	NodeFraction float64
+	// Edges with the weight below the fraction
+	// of the total are dropped from the graph.
+	EdgeFraction float64
+}
+
+type callGraphNode struct {
+	name      string
+	self, cum int64
+	kept      bool
+	index     int64
+}
+
+type callGraphEdge struct {
+	from, to *callGraphNode
+	residual bool
+}
+
+// NewCallGraph builds the call graph of the profile, with a node per
+// function. The first sample value is used.
+//
+// Similarly to pprof, the nodes and the edges are pruned by the fractions
+// of the total. A call path through the dropped nodes is represented as
+// a residual edge between the nearest kept caller and callee. Nodes are
+// ordered by the cumulative value, and edges by the weight, descending.
+func NewCallGraph(p *profilev1.Profile, opts CallGraphOptions) *querierv1.CallGraph {
+	nodes := make(map[uint64]*callGraphNode, len(p.Function))
+	for _, fn := range p.Function {
+		nodes[fn.Id] = &callGraphNode{name: p.StringTable[fn.Name]}
+	}
+	locations := make(map[uint64]*profilev1.Location, len(p.Location))
+	for _, loc := range p.Location {
+		locations[loc.Id] = loc
+	}
+	// The frames of the sample, ordered from the leaf.
+	var frames []*callGraphNode
+	sampleFrames := func(s *profilev1.Sample) []*callGraphNode {
+		frames = frames[:0]
+		for _, id := range s.LocationId {
+			loc, ok := locations[id]
+			if !ok {
+				continue
+			}
+			for _, line := range loc.Line {
+				if n, ok := nodes[line.FunctionId]; ok {
+					frames = append(frames, n)
+				}
+			}
+		}
+		return frames
+	}
+
+	var total int64
+	seen := make(map[*callGraphNode]struct{})
+	for _, s := range p.Sample {
+		if len(s.Value) == 0 {
+			continue
+		}
+		v := s.Value[0]
+		total += v
+		frames := sampleFrames(s)
+		if len(frames) == 0 {
+			continue
+		}
+		frames[0].self += v
+		// Recursive calls are accounted once.
+		for _, n := range frames {
+			if _, ok := seen[n]; !ok {
+				seen[n] = struct{}{}
+				n.cum += v
+			}
+		}
+		for k := range seen {
+			delete(seen, k)
+		}
+	}
+
+	g := &querierv1.CallGraph{Total: total}
+	nodeCutoff := int64(math.Abs(float64(total)) * opts.NodeFraction)
+	kept := make([]*callGraphNode, 0, len(nodes))
+	for _, n := range nodes {
+		if n.cum != 0 && abs64(n.cum) >= nodeCutoff {
+			n.kept = true
+			kept = append(kept, n)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		a, b := abs64(kept[i].cum), abs64(kept[j].cum)
+		if a != b {
+			return a > b
+		}
+		return kept[i].name < kept[j].name
+	})
+	g.Nodes = make([]*querierv1.CallGraphNode, len(kept))
+	for i, n := range kept {
+		n.index = int64(i)
+		g.Nodes[i] = &querierv1.CallGraphNode{Name: n.name, Self: n.self, Cumulative: n.cum}
+	}
+
+	edges := make(map[callGraphEdge]int64)
+	seenEdges := make(map[callGraphEdge]struct{})
+	for _, s := range p.Sample {
+		if len(s.Value) == 0 {
+			continue
+		}
+		var callee *callGraphNode
+		var residual bool
+		for _, n := range sampleFrames(s) {
+			if !n.kept {
+				residual = callee != nil
+				continue
+			}
+			if callee != nil {
+				e := callGraphEdge{from: n, to: callee, residual: residual}
+				if _, ok := seenEdges[e]; !ok {
+					seenEdges[e] = struct{}{}
+					edges[e] += s.Value[0]
+				}
+			}
+			callee, residual = n, false
+		}
+		for k := range seenEdges {
+			delete(seenEdges, k)
+		}
+	}
+
+	edgeCutoff := int64(math.Abs(float64(total)) * opts.EdgeFraction)
+	for e, w := range edges {
+		if w != 0 && abs64(w) >= edgeCutoff {
+			g.Edges = append(g.Edges, &querierv1.CallGraphEdge{
+				From:     e.from.index,
+				To:       e.to.index,
+				Weight:   w,
+				Residual: e.residual,
+			})
+		}
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if wa, wb := abs64(a.Weight), abs64(b.Weight); wa != wb {
+			return wa > wb
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return !a.Residual && b.Residual
+	})
+	return g
+}
+
+const (
+	dotBaseFontSize  = 8
+	dotMaxFontGrowth = 16
+	dotMaxPenWidth   = 6
+)
+
+// WriteCallGraphDOT writes the call graph in the Graphviz DOT format,
+// in the style of "go tool pprof -dot": the node font size grows with
+// the self value, and the edge width with the weight. Residual edges
+// are dotted.
+func WriteCallGraphDOT(dst io.Writer, title string, g *querierv1.CallGraph) error {
+	w := bufio.NewWriter(dst)
+	_, _ = fmt.Fprintf(w, "digraph %s {\n", dotQuote(title))
+	_, _ = w.WriteString("node [style=filled fillcolor=\"#f8f8f8\"]\n")
+	_, _ = fmt.Fprintf(w, "subgraph cluster_L { legend [shape=box fontsize=16 label=\"%s\\lTotal: %d\\lShowing %d nodes and %d edges\\l\"] }\n",
+		dotEscape(title), g.Total, len(g.Nodes), len(g.Edges))
+
+	var maxSelf int64
+	for _, n := range g.Nodes {
+		if s := abs64(n.Self); s > maxSelf {
+			maxSelf = s
+		}
+	}
+	for i, n := range g.Nodes {
+		fontSize := dotBaseFontSize
+		if maxSelf > 0 {
+			fontSize += int(math.Ceil(dotMaxFontGrowth * math.Sqrt(float64(abs64(n.Self))/float64(maxSelf))))
+		}
+		label := fmt.Sprintf("%s\n%d (%.2f%%)", n.Name, n.Self, percent(n.Self, g.Total))
+		if n.Cumulative != n.Self {
+			label += fmt.Sprintf("\nof %d (%.2f%%)", n.Cumulative, percent(n.Cumulative, g.Total))
+		}
+		_, _ = fmt.Fprintf(w, "N%d [label=%s shape=box fontsize=%d tooltip=%s]\n",
+			i, dotQuote(label), fontSize, dotQuote(fmt.Sprintf("%s (%d)", n.Name, n.Cumulative)))
+	}
+	for _, e := range g.Edges {
+		penWidth := 1.0
+		if g.Total != 0 {
+			penWidth = math.Max(1, dotMaxPenWidth*math.Abs(float64(e.Weight))/math.Abs(float64(g.Total)))
+		}
+		attrs := fmt.Sprintf("label=\" %d\" weight=%d penwidth=%.2f", e.Weight, int64(math.Max(1, penWidth*10)), penWidth)
+		if e.Residual {
+			attrs += " style=dotted"
+		}
+		_, _ = fmt.Fprintf(w, "N%d -> N%d [%s]\n", e.From, e.To, attrs)
+	}
+	_, _ = w.WriteString("}\n")
+	return w.Flush()
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotEscape(s string) string { return dotEscaper.Replace(s) }
+
+func dotQuote(s string) string { return `"` + dotEscape(s) + `"` }
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
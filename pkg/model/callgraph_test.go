@@ -0,0 +1,116 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+)
+
+func callGraphTestProfile() *profilev1.Profile {
+	p := &profilev1.Profile{
+		StringTable: []string{"", "main", "a", "b", "c", "d"},
+	}
+	for i := 1; i <= 5; i++ {
+		p.Function = append(p.Function, &profilev1.Function{Id: uint64(i), Name: int64(i)})
+		p.Location = append(p.Location, &profilev1.Location{Id: uint64(i), Line: []*profilev1.Line{{FunctionId: uint64(i)}}})
+	}
+	const main, a, b, c, d = 1, 2, 3, 4, 5
+	p.Sample = []*profilev1.Sample{
+		{LocationId: []uint64{b, a, main}, Value: []int64{10}},
+		{LocationId: []uint64{c, a, main}, Value: []int64{5}},
+		{LocationId: []uint64{b, main}, Value: []int64{1}},
+		{LocationId: []uint64{b, d, main}, Value: []int64{1}},
+	}
+	return p
+}
+
+func Test_NewCallGraph(t *testing.T) {
+	nodes := []*querierv1.CallGraphNode{
+		{Name: "main", Self: 0, Cumulative: 17},
+		{Name: "a", Self: 0, Cumulative: 15},
+		{Name: "b", Self: 12, Cumulative: 12},
+		{Name: "c", Self: 5, Cumulative: 5},
+	}
+
+	g := NewCallGraph(callGraphTestProfile(), CallGraphOptions{NodeFraction: 0.2, EdgeFraction: 0.05})
+	require.Equal(t, &querierv1.CallGraph{
+		Total: 17,
+		Nodes: nodes,
+		Edges: []*querierv1.CallGraphEdge{
+			{From: 0, To: 1, Weight: 15},
+			{From: 1, To: 2, Weight: 10},
+			{From: 1, To: 3, Weight: 5},
+			{From: 0, To: 2, Weight: 1},
+			{From: 0, To: 2, Weight: 1, Residual: true},
+		},
+	}, g)
+
+	g = NewCallGraph(callGraphTestProfile(), CallGraphOptions{NodeFraction: 0.2, EdgeFraction: 0.2})
+	require.Equal(t, &querierv1.CallGraph{
+		Total: 17,
+		Nodes: nodes,
+		Edges: []*querierv1.CallGraphEdge{
+			{From: 0, To: 1, Weight: 15},
+			{From: 1, To: 2, Weight: 10},
+			{From: 1, To: 3, Weight: 5},
+		},
+	}, g)
+
+	g = NewCallGraph(callGraphTestProfile(), CallGraphOptions{})
+	require.Len(t, g.Nodes, 5)
+	require.Equal(t, "d", g.Nodes[4].Name)
+}
+
+func Test_NewCallGraph_Recursion(t *testing.T) {
+	p := &profilev1.Profile{
+		StringTable: []string{"", "main", "a"},
+		Function: []*profilev1.Function{
+			{Id: 1, Name: 1},
+			{Id: 2, Name: 2},
+		},
+		Location: []*profilev1.Location{
+			{Id: 1, Line: []*profilev1.Line{{FunctionId: 1}}},
+			{Id: 2, Line: []*profilev1.Line{{FunctionId: 2}}},
+		},
+		Sample: []*profilev1.Sample{
+			{LocationId: []uint64{2, 2, 2, 1}, Value: []int64{3}},
+		},
+	}
+	g := NewCallGraph(p, CallGraphOptions{})
+	require.Equal(t, &querierv1.CallGraph{
+		Total: 3,
+		Nodes: []*querierv1.CallGraphNode{
+			{Name: "a", Self: 3, Cumulative: 3},
+			{Name: "main", Self: 0, Cumulative: 3},
+		},
+		Edges: []*querierv1.CallGraphEdge{
+			{From: 0, To: 0, Weight: 3},
+			{From: 1, To: 0, Weight: 3},
+		},
+	}, g)
+}
+
+func Test_WriteCallGraphDOT(t *testing.T) {
+	g := NewCallGraph(callGraphTestProfile(), CallGraphOptions{NodeFraction: 0.2, EdgeFraction: 0.05})
+	var buf bytes.Buffer
+	require.NoError(t, WriteCallGraphDOT(&buf, `cpu "total"`, g))
+	expected := `digraph "cpu \"total\"" {
+node [style=filled fillcolor="#f8f8f8"]
+subgraph cluster_L { legend [shape=box fontsize=16 label="cpu \"total\"\lTotal: 17\lShowing 4 nodes and 5 edges\l"] }
+N0 [label="main\n0 (0.00%)\nof 17 (100.00%)" shape=box fontsize=8 tooltip="main (17)"]
+N1 [label="a\n0 (0.00%)\nof 15 (88.24%)" shape=box fontsize=8 tooltip="a (15)"]
+N2 [label="b\n12 (70.59%)" shape=box fontsize=24 tooltip="b (12)"]
+N3 [label="c\n5 (29.41%)" shape=box fontsize=19 tooltip="c (5)"]
+N0 -> N1 [label=" 15" weight=52 penwidth=5.29]
+N1 -> N2 [label=" 10" weight=35 penwidth=3.53]
+N1 -> N3 [label=" 5" weight=17 penwidth=1.76]
+N0 -> N2 [label=" 1" weight=10 penwidth=1.00]
+N0 -> N2 [label=" 1" weight=10 penwidth=1.00 style=dotted]
+}
+`
+	require.Equal(t, expected, buf.String())
+}
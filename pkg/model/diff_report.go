@@ -0,0 +1,174 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+)
+
+// DiffReportSelection is the merged tree of a selection
+// and the number of profiles it is made of.
+type DiffReportSelection struct {
+	Tree     *Tree
+	Profiles int64
+	// The sums of squares of the per-profile self and total values
+	// of the functions, used to estimate the variance of the values.
+	SelfSquares  map[string]float64
+	TotalSquares map[string]float64
+}
+
+// DiffReportBaseline returns the baseline of the report request. If the
+// baseline is not specified, it is derived from the comparison shifted
+// back by the offset.
+func DiffReportBaseline(req *querierv1.DiffReportRequest) (*querierv1.SelectMergeStacktracesRequest, error) {
+	if req.Comparison == nil {
+		return nil, errors.New("comparison must be specified")
+	}
+	if req.Baseline != nil {
+		return req.Baseline, nil
+	}
+	if req.Offset == 0 {
+		return nil, errors.New("either baseline or offset must be specified")
+	}
+	baseline := req.Comparison.CloneVT()
+	baseline.Offset += req.Offset
+	return baseline, nil
+}
+
+type functionValues struct {
+	self, total int64
+}
+
+// NewDiffReport returns up to limit functions whose share of the selection
+// total has changed the most between the baseline and the comparison. The
+// significance scores are set by ScoreDiffReport.
+func NewDiffReport(baseline, comparison DiffReportSelection, limit int) *querierv1.DiffReportResponse {
+	for _, s := range []*DiffReportSelection{&baseline, &comparison} {
+		if s.Tree == nil {
+			s.Tree = new(Tree)
+		}
+	}
+	b, c := treeFunctions(baseline.Tree), treeFunctions(comparison.Tree)
+	r := &querierv1.DiffReportResponse{
+		BaselineTotal:      baseline.Tree.Total(),
+		ComparisonTotal:    comparison.Tree.Total(),
+		BaselineProfiles:   baseline.Profiles,
+		ComparisonProfiles: comparison.Profiles,
+	}
+	names := make(map[string]struct{}, len(b)+len(c))
+	for name := range b {
+		names[name] = struct{}{}
+	}
+	for name := range c {
+		names[name] = struct{}{}
+	}
+	r.Entries = make([]*querierv1.DiffReportEntry, 0, len(names))
+	for name := range names {
+		bv, cv := b[name], c[name]
+		e := &querierv1.DiffReportEntry{
+			FunctionName:    name,
+			BaselineSelf:    bv.self,
+			BaselineTotal:   bv.total,
+			ComparisonSelf:  cv.self,
+			ComparisonTotal: cv.total,
+			SelfDelta:       cv.self - bv.self,
+			TotalDelta:      cv.total - bv.total,
+		}
+		e.SelfShareDelta = share(cv.self, r.ComparisonTotal) - share(bv.self, r.BaselineTotal)
+		e.TotalShareDelta = share(cv.total, r.ComparisonTotal) - share(bv.total, r.BaselineTotal)
+		r.Entries = append(r.Entries, e)
+	}
+	sort.Slice(r.Entries, func(i, j int) bool {
+		a, b := diffReportRank(r.Entries[i]), diffReportRank(r.Entries[j])
+		if a != b {
+			return a > b
+		}
+		return r.Entries[i].FunctionName < r.Entries[j].FunctionName
+	})
+	if limit > 0 && len(r.Entries) > limit {
+		r.Entries = r.Entries[:limit]
+	}
+	return r
+}
+
+func diffReportRank(e *querierv1.DiffReportEntry) float64 {
+	return math.Max(math.Abs(e.SelfShareDelta), math.Abs(e.TotalShareDelta))
+}
+
+func share(v, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(v) / float64(total)
+}
+
+// ScoreDiffReport sets the significance scores of the report entries: the
+// z-score of the share change, estimated from the mean and the variance of
+// the per-profile values of the function in each selection.
+//
+// The per-profile values are measured in units of the mean profile total
+// of the selection: then the mean of the values is the share of the
+// function, which makes the score independent of the sample value units.
+func ScoreDiffReport(r *querierv1.DiffReportResponse, baseline, comparison DiffReportSelection) {
+	for _, e := range r.Entries {
+		e.SelfScore = shareChangeScore(e.SelfShareDelta,
+			shareVariance(e.BaselineSelf, baseline.SelfSquares[e.FunctionName], r.BaselineTotal, r.BaselineProfiles),
+			shareVariance(e.ComparisonSelf, comparison.SelfSquares[e.FunctionName], r.ComparisonTotal, r.ComparisonProfiles))
+		e.TotalScore = shareChangeScore(e.TotalShareDelta,
+			shareVariance(e.BaselineTotal, baseline.TotalSquares[e.FunctionName], r.BaselineTotal, r.BaselineProfiles),
+			shareVariance(e.ComparisonTotal, comparison.TotalSquares[e.FunctionName], r.ComparisonTotal, r.ComparisonProfiles))
+	}
+}
+
+// shareVariance returns the variance of the mean of the per-profile
+// values of the function, given the sum and the sum of squares of the
+// values, the selection total, and the number of profiles.
+func shareVariance(sum int64, squares float64, total, profiles int64) float64 {
+	if total <= 0 || profiles <= 0 {
+		return 0
+	}
+	n := float64(profiles)
+	unit := float64(total) / n
+	mean := float64(sum) / n / unit
+	variance := squares/n/(unit*unit) - mean*mean
+	if variance < 0 {
+		// Rounding errors.
+		variance = 0
+	}
+	return variance / n
+}
+
+func shareChangeScore(delta, baselineVariance, comparisonVariance float64) float64 {
+	se := math.Sqrt(baselineVariance + comparisonVariance)
+	if se == 0 {
+		return 0
+	}
+	return delta / se
+}
+
+// treeFunctions returns the self and total values of the tree functions.
+// Recursive calls are accounted once in the total.
+func treeFunctions(t *Tree) map[string]functionValues {
+	m := make(map[string]functionValues)
+	seen := make(map[string]struct{})
+	t.IterateStacks(func(name string, self int64, stack []string) {
+		v := m[name]
+		v.self += self
+		m[name] = v
+		for _, fn := range stack {
+			if _, ok := seen[fn]; ok {
+				continue
+			}
+			seen[fn] = struct{}{}
+			v = m[fn]
+			v.total += self
+			m[fn] = v
+		}
+		for fn := range seen {
+			delete(seen, fn)
+		}
+	})
+	return m
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+)
+
+func Test_NewDiffReport(t *testing.T) {
+	baseline := new(Tree)
+	baseline.InsertStack(50, "main", "a")
+	baseline.InsertStack(50, "main", "b")
+	comparison := new(Tree)
+	comparison.InsertStack(90, "main", "a")
+	comparison.InsertStack(30, "main", "b")
+
+	r := NewDiffReport(
+		DiffReportSelection{Tree: baseline, Profiles: 10},
+		DiffReportSelection{Tree: comparison, Profiles: 12},
+		0,
+	)
+	require.Equal(t, int64(100), r.BaselineTotal)
+	require.Equal(t, int64(120), r.ComparisonTotal)
+	require.Equal(t, int64(10), r.BaselineProfiles)
+	require.Equal(t, int64(12), r.ComparisonProfiles)
+	require.Len(t, r.Entries, 3)
+
+	a, b, main := r.Entries[0], r.Entries[1], r.Entries[2]
+	require.Equal(t, "a", a.FunctionName)
+	require.Equal(t, int64(50), a.BaselineSelf)
+	require.Equal(t, int64(90), a.ComparisonSelf)
+	require.Equal(t, int64(40), a.SelfDelta)
+	require.Equal(t, int64(40), a.TotalDelta)
+	require.Equal(t, 0.25, a.SelfShareDelta)
+	require.Equal(t, 0.25, a.TotalShareDelta)
+
+	require.Equal(t, "b", b.FunctionName)
+	require.Equal(t, int64(-20), b.SelfDelta)
+	require.Equal(t, -0.25, b.SelfShareDelta)
+
+	require.Equal(t, "main", main.FunctionName)
+	require.Equal(t, int64(0), main.BaselineSelf)
+	require.Equal(t, int64(100), main.BaselineTotal)
+	require.Equal(t, int64(120), main.ComparisonTotal)
+	require.Equal(t, 0.0, main.TotalShareDelta)
+
+	r = NewDiffReport(
+		DiffReportSelection{Tree: baseline, Profiles: 10},
+		DiffReportSelection{Tree: comparison},
+		1,
+	)
+	require.Len(t, r.Entries, 1)
+	require.Equal(t, "a", r.Entries[0].FunctionName)
+}
+
+func Test_ScoreDiffReport(t *testing.T) {
+	baseline := new(Tree)
+	baseline.InsertStack(50, "main", "a")
+	baseline.InsertStack(50, "main", "b")
+	comparison := new(Tree)
+	comparison.InsertStack(90, "main", "a")
+	comparison.InsertStack(30, "main", "b")
+
+	// Every baseline profile has the value of 5 of both "a" and "b".
+	b := DiffReportSelection{
+		Tree:         baseline,
+		Profiles:     10,
+		SelfSquares:  map[string]float64{"a": 250, "b": 250},
+		TotalSquares: map[string]float64{"a": 250, "b": 250, "main": 1000},
+	}
+	// Half of the comparison profiles have the value of 15 of "a",
+	// the other half have the value of 5 of "b".
+	c := DiffReportSelection{
+		Tree:         comparison,
+		Profiles:     12,
+		SelfSquares:  map[string]float64{"a": 1350, "b": 150},
+		TotalSquares: map[string]float64{"a": 1350, "b": 150, "main": 1500},
+	}
+	r := NewDiffReport(b, c, 0)
+	ScoreDiffReport(r, b, c)
+
+	a, bb, main := r.Entries[0], r.Entries[1], r.Entries[2]
+	require.Equal(t, "a", a.FunctionName)
+	require.InDelta(t, 1.1547, a.SelfScore, 1e-3)
+	require.Equal(t, a.SelfScore, a.TotalScore)
+	require.Equal(t, "b", bb.FunctionName)
+	require.InDelta(t, -3.4641, bb.SelfScore, 1e-3)
+	require.Equal(t, "main", main.FunctionName)
+	require.Equal(t, 0.0, main.TotalScore)
+
+	// Without the values, the variance is unknown.
+	r = NewDiffReport(DiffReportSelection{Tree: baseline}, DiffReportSelection{Tree: comparison}, 0)
+	ScoreDiffReport(r, DiffReportSelection{}, DiffReportSelection{})
+	require.Equal(t, 0.0, r.Entries[0].SelfScore)
+}
+
+func Test_DiffReportBaseline(t *testing.T) {
+	_, err := DiffReportBaseline(&querierv1.DiffReportRequest{})
+	require.Error(t, err)
+
+	comparison := &querierv1.SelectMergeStacktracesRequest{Start: 10, End: 20}
+	_, err = DiffReportBaseline(&querierv1.DiffReportRequest{Comparison: comparison})
+	require.Error(t, err)
+
+	baseline, err := DiffReportBaseline(&querierv1.DiffReportRequest{Comparison: comparison, Offset: 5})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), baseline.Offset)
+	require.Equal(t, int64(0), comparison.Offset)
+
+	explicit := &querierv1.SelectMergeStacktracesRequest{Start: 1, End: 2}
+	baseline, err = DiffReportBaseline(&querierv1.DiffReportRequest{Comparison: comparison, Baseline: explicit, Offset: 5})
+	require.NoError(t, err)
+	require.Same(t, explicit, baseline)
+}
+
+func Test_treeFunctions(t *testing.T) {
+	tree := new(Tree)
+	tree.InsertStack(3, "main", "a", "b", "a")
+	tree.InsertStack(2, "main", "b")
+	require.Equal(t, map[string]functionValues{
+		"main": {self: 0, total: 5},
+		"a":    {self: 3, total: 3},
+		"b":    {self: 2, total: 5},
+	}, treeFunctions(tree))
+}
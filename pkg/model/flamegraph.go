@@ -124,17 +124,7 @@ func ExportToFlamebearer(fg *querierv1.FlameGraph, profileType *typesv1.ProfileT
 	if fg == nil {
 		fg = &querierv1.FlameGraph{}
 	}
-	unit := metadata.Units(profileType.SampleUnit)
-	sampleRate := uint32(100)
-
-	switch profileType.SampleType {
-	case "inuse_objects", "alloc_objects", "goroutine", "samples":
-		unit = metadata.ObjectsUnits
-	case "cpu":
-		unit = metadata.SamplesUnits
-		sampleRate = uint32(1_000_000_000)
-
-	}
+	md := FlamebearerMetadata(profileType)
 	levels := make([][]int, len(fg.Levels))
 	for i := range levels {
 		levels[i] = lo.Map(fg.Levels[i].Values, func(v int64, i int) int { return int(v) })
@@ -150,14 +140,31 @@ func ExportToFlamebearer(fg *querierv1.FlameGraph, profileType *typesv1.ProfileT
 			},
 			Metadata: flamebearer.FlamebearerMetadataV1{
 				Format:     "single",
-				Units:      unit,
+				Units:      md.Units,
 				Name:       profileType.SampleType,
-				SampleRate: sampleRate,
+				SampleRate: md.SampleRate,
 			},
 		},
 	}
 }
 
+// FlamebearerMetadata returns the units and the sample rate
+// of the flame graph of the given profile type.
+func FlamebearerMetadata(profileType *typesv1.ProfileType) metadata.Metadata {
+	md := metadata.Metadata{
+		Units:      metadata.Units(profileType.SampleUnit),
+		SampleRate: 100,
+	}
+	switch profileType.SampleType {
+	case "inuse_objects", "alloc_objects", "goroutine", "samples":
+		md.Units = metadata.ObjectsUnits
+	case "cpu":
+		md.Units = metadata.SamplesUnits
+		md.SampleRate = 1_000_000_000
+	}
+	return md
+}
+
 func ExportDiffToFlamebearer(fg *querierv1.FlameGraphDiff, profileType *typesv1.ProfileType) *flamebearer.FlamebearerProfile {
 	// Since a normal flamegraph and a diff are so similar, convert it to reuse the export function
 	singleFlamegraph := &querierv1.FlameGraph{
@@ -2,6 +2,7 @@ package model
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 
 	"github.com/grafana/pyroscope/pkg/og/structs/cappedarr"
@@ -11,6 +12,23 @@ import (
 
 const MaxNodes = 8192
 
+// DiffRight returns the right side of the diff request. If the right side
+// is not specified, it is derived from the left one shifted by the offset.
+func DiffRight(req *querierv1.DiffRequest) (*querierv1.SelectMergeStacktracesRequest, error) {
+	if req.Left == nil {
+		return nil, errors.New("left side must be specified")
+	}
+	if req.Right != nil {
+		return req.Right, nil
+	}
+	if req.Offset == 0 {
+		return nil, errors.New("either right side or offset must be specified")
+	}
+	right := req.Left.CloneVT()
+	right.Offset += req.Offset
+	return right, nil
+}
+
 // NewFlamegraphDiff generates a FlameGraphDiff from 2 trees.
 // It also prunes the final tree based on the maxNodes parameter
 // Notice that the resulting FlameGraph can't be used interchangeably with a 'single' Flamegraph
@@ -5,6 +5,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/pkg/testhelper"
 )
 
 func Test_Diff_Tree(t *testing.T) {
@@ -123,3 +127,32 @@ func Test_Diff_No_Common_Root(t *testing.T) {
 	_, err := NewFlamegraphDiff(tr, tr2, 1024)
 	assert.NoError(t, err)
 }
+
+func Test_DiffRight(t *testing.T) {
+	left := &querierv1.SelectMergeStacktracesRequest{
+		LabelSelector: `{app="foo"}`,
+		ProfileTypeID: "memory:inuse_space:bytes:space:byte",
+		Start:         100,
+		End:           200,
+	}
+	right := &querierv1.SelectMergeStacktracesRequest{Start: 0, End: 100}
+
+	actual, err := DiffRight(&querierv1.DiffRequest{Left: left, Right: right, Offset: 10})
+	require.NoError(t, err)
+	require.Same(t, right, actual)
+
+	actual, err = DiffRight(&querierv1.DiffRequest{Left: left, Offset: 10})
+	require.NoError(t, err)
+	expected := left.CloneVT()
+	expected.Offset = 10
+	testhelper.EqualProto(t, expected, actual)
+	require.Zero(t, left.Offset)
+
+	_, err = DiffRight(&querierv1.DiffRequest{Left: left})
+	require.Error(t, err)
+
+	_, err = DiffRight(&querierv1.DiffRequest{Right: right, Offset: 10})
+	require.Error(t, err)
+	_, err = DiffRight(&querierv1.DiffRequest{Offset: 10})
+	require.Error(t, err)
+}
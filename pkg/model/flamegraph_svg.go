@@ -0,0 +1,182 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"sort"
+)
+
+const (
+	svgWidth       = 1200
+	svgFrameHeight = 16
+	svgFontSize    = 12
+	svgFontWidth   = 0.59 * svgFontSize
+	svgTitleHeight = 32
+	svgPadding     = 10
+	// Nodes narrower than that are not rendered.
+	svgMinFrameWidth = 0.1
+)
+
+type svgFrame struct {
+	n, base *node
+	x, w    float64
+	depth   int
+}
+
+// WriteFlameGraphSVG renders the tree as a standalone SVG flame graph,
+// with the root at the top. If the base tree is not nil, the graph is
+// differential: the frames are sized by the tree values and colored by
+// the change of their share relative to the base tree, red for growth
+// and blue for shrinkage.
+func WriteFlameGraphSVG(dst io.Writer, title string, t, base *Tree) error {
+	w := bufio.NewWriter(dst)
+	frames, depth := svgLayout(t, base)
+	width := svgWidth + 2*svgPadding
+	height := svgTitleHeight + (depth+1)*svgFrameHeight + svgPadding
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0" standalone="no"?>
+<svg version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">
+<rect x="0" y="0" width="100%%" height="100%%" fill="#ffffff"/>
+<text x="%d" y="%d" font-family="Verdana, sans-serif" font-size="%d" text-anchor="middle">%s</text>
+`, width, height, width, height, width/2, svgTitleHeight/2+svgFontSize/2, svgFontSize+4, html.EscapeString(title))
+
+	var total, baseTotal int64
+	if len(frames) > 0 {
+		total = frames[0].n.total
+		if frames[0].base != nil {
+			baseTotal = frames[0].base.total
+		}
+	}
+	for _, f := range frames {
+		x := svgPadding + f.x
+		y := svgTitleHeight + f.depth*svgFrameHeight
+		name := f.n.name
+		_, _ = fmt.Fprintf(w, "<g><title>%s</title>", html.EscapeString(svgFrameTitle(f, total, baseTotal, base != nil)))
+		_, _ = fmt.Fprintf(w, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" rx="2" ry="2"/>`,
+			x, y, f.w, svgFrameHeight-1, svgFrameColor(f, total, baseTotal, base != nil))
+		if label := svgFrameLabel(name, f.w); label != "" {
+			_, _ = fmt.Fprintf(w, `<text x="%.1f" y="%d" font-family="Verdana, sans-serif" font-size="%d">%s</text>`,
+				x+3, y+svgFontSize, svgFontSize, html.EscapeString(label))
+		}
+		_, _ = w.WriteString("</g>\n")
+	}
+	_, _ = w.WriteString("</svg>\n")
+	return w.Flush()
+}
+
+// svgLayout returns the frames to render in the depth-first order,
+// starting with the synthetic "total" root, and the maximum depth.
+func svgLayout(t, base *Tree) ([]svgFrame, int) {
+	root := &node{name: "total", children: t.root, total: t.Total()}
+	var baseRoot *node
+	if base != nil {
+		baseRoot = &node{name: "total", children: base.root, total: base.Total()}
+	}
+	if root.total <= 0 {
+		return nil, 0
+	}
+	scale := float64(svgWidth) / float64(root.total)
+	frames := []svgFrame{{n: root, base: baseRoot, w: svgWidth}}
+	var depth int
+	stack := []svgFrame{frames[0]}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		x := f.x
+		children := make([]svgFrame, 0, len(f.n.children))
+		for _, c := range f.n.children {
+			w := float64(c.total) * scale
+			if c.total > 0 && w >= svgMinFrameWidth {
+				cf := svgFrame{n: c, base: findChild(f.base, c.name), x: x, w: w, depth: f.depth + 1}
+				children = append(children, cf)
+				frames = append(frames, cf)
+				if cf.depth > depth {
+					depth = cf.depth
+				}
+			}
+			x += w
+		}
+		for i := len(children) - 1; i >= 0; i-- {
+			stack = append(stack, children[i])
+		}
+	}
+	return frames, depth
+}
+
+func findChild(n *node, name string) *node {
+	if n == nil {
+		return nil
+	}
+	i := sort.Search(len(n.children), func(i int) bool {
+		return n.children[i].name >= name
+	})
+	if i < len(n.children) && n.children[i].name == name {
+		return n.children[i]
+	}
+	return nil
+}
+
+func svgFrameTitle(f svgFrame, total, baseTotal int64, diff bool) string {
+	if !diff {
+		return fmt.Sprintf("%s (%d, %.2f%%)", f.n.name, f.n.total, percent(f.n.total, total))
+	}
+	var baseValue int64
+	if f.base != nil {
+		baseValue = f.base.total
+	}
+	return fmt.Sprintf("%s (base: %d, %.2f%%; value: %d, %.2f%%)", f.n.name,
+		baseValue, percent(baseValue, baseTotal), f.n.total, percent(f.n.total, total))
+}
+
+func svgFrameLabel(name string, width float64) string {
+	n := int((width - 6) / svgFontWidth)
+	if n < 3 {
+		return ""
+	}
+	if r := []rune(name); len(r) > n {
+		return string(r[:n-2]) + ".."
+	}
+	return name
+}
+
+func svgFrameColor(f svgFrame, total, baseTotal int64, diff bool) string {
+	if !diff {
+		// Warm palette, stable for the same name.
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(f.n.name))
+		v := h.Sum32()
+		return fmt.Sprintf("rgb(%d,%d,%d)", 205+v%50, (v>>8)%230, (v>>16)%55)
+	}
+	var baseValue int64
+	if f.base != nil {
+		baseValue = f.base.total
+	}
+	share, baseShare := percent(f.n.total, total), percent(baseValue, baseTotal)
+	if share == baseShare {
+		return "rgb(220,220,220)"
+	}
+	// The relative change of the share, in the [-1, 1] range.
+	d := (share - baseShare) / max64f(share, baseShare)
+	if d > 0 {
+		c := int(220 * (1 - d))
+		return fmt.Sprintf("rgb(255,%d,%d)", c, c)
+	}
+	c := int(220 * (1 + d))
+	return fmt.Sprintf("rgb(%d,%d,255)", c, c)
+}
+
+func percent(v, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(v) * 100 / float64(total)
+}
+
+func max64f(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"sort"
+	"sync"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+type functionTableKey struct {
+	name string
+	file string
+	line int64
+}
+
+type FunctionTableMerger struct {
+	mu      sync.Mutex
+	total   int64
+	entries map[functionTableKey]*typesv1.FunctionTableEntry
+}
+
+func NewFunctionTableMerger() *FunctionTableMerger {
+	return &FunctionTableMerger{
+		entries: make(map[functionTableKey]*typesv1.FunctionTableEntry),
+	}
+}
+
+// MergeFunctionTable adds the function table entries to the resulting
+// table. Entries of the same function (and line, if present) are summed.
+// The call is thread-safe.
+func (m *FunctionTableMerger) MergeFunctionTable(t *typesv1.FunctionTable) {
+	if t == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.total += t.Total
+	for _, e := range t.Entries {
+		k := functionTableKey{name: e.FunctionName, file: e.FileName, line: e.Line}
+		x, ok := m.entries[k]
+		if !ok {
+			m.entries[k] = e.CloneVT()
+			continue
+		}
+		x.Self += e.Self
+		x.Total += e.Total
+	}
+}
+
+// FunctionTable returns the merged function table ordered by the self
+// value, or by the total value if orderByTotal is set. If limit is
+// positive, only the top entries are returned.
+func (m *FunctionTableMerger) FunctionTable(limit int64, orderByTotal bool) *typesv1.FunctionTable {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make([]*typesv1.FunctionTableEntry, 0, len(m.entries))
+	for _, e := range m.entries {
+		entries = append(entries, e)
+	}
+	SortFunctionTableEntries(entries, orderByTotal)
+	if limit > 0 && int64(len(entries)) > limit {
+		entries = entries[:limit]
+	}
+	return &typesv1.FunctionTable{
+		Entries: entries,
+		Total:   m.total,
+	}
+}
+
+// SortFunctionTableEntries orders the entries by the self value, or by the
+// total value if orderByTotal is set, in descending order. Ties are broken
+// by the other value and then by the function location, which makes the
+// order deterministic.
+func SortFunctionTableEntries(entries []*typesv1.FunctionTableEntry, orderByTotal bool) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		x, y := [2]int64{a.Self, a.Total}, [2]int64{b.Self, b.Total}
+		if orderByTotal {
+			x[0], x[1], y[0], y[1] = x[1], x[0], y[1], y[0]
+		}
+		if x[0] != y[0] {
+			return x[0] > y[0]
+		}
+		if x[1] != y[1] {
+			return x[1] > y[1]
+		}
+		if a.FunctionName != b.FunctionName {
+			return a.FunctionName < b.FunctionName
+		}
+		if a.FileName != b.FileName {
+			return a.FileName < b.FileName
+		}
+		return a.Line < b.Line
+	})
+}
+
+// SortFunctionTableEntriesByLine orders the entries by the file name,
+// line, and function name, which is the order of the source code.
+func SortFunctionTableEntriesByLine(entries []*typesv1.FunctionTableEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.FileName != b.FileName {
+			return a.FileName < b.FileName
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.FunctionName < b.FunctionName
+	})
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"testing"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/testhelper"
+)
+
+func Test_FunctionTableMerger(t *testing.T) {
+	in := []*typesv1.FunctionTable{
+		{
+			Entries: []*typesv1.FunctionTableEntry{
+				{FunctionName: "foo", FileName: "foo.go", Self: 1, Total: 5},
+				{FunctionName: "bar", FileName: "bar.go", Self: 2, Total: 2},
+			},
+			Total: 3,
+		},
+		{
+			Entries: []*typesv1.FunctionTableEntry{
+				{FunctionName: "foo", FileName: "foo.go", Self: 1, Total: 1},
+				{FunctionName: "baz", FileName: "baz.go", Self: 4, Total: 4},
+			},
+			Total: 5,
+		},
+	}
+
+	for _, tc := range []struct {
+		name         string
+		limit        int64
+		orderByTotal bool
+		out          *typesv1.FunctionTable
+	}{
+		{
+			name: "order by self",
+			out: &typesv1.FunctionTable{
+				Entries: []*typesv1.FunctionTableEntry{
+					{FunctionName: "baz", FileName: "baz.go", Self: 4, Total: 4},
+					{FunctionName: "foo", FileName: "foo.go", Self: 2, Total: 6},
+					{FunctionName: "bar", FileName: "bar.go", Self: 2, Total: 2},
+				},
+				Total: 8,
+			},
+		},
+		{
+			name:         "order by total",
+			orderByTotal: true,
+			out: &typesv1.FunctionTable{
+				Entries: []*typesv1.FunctionTableEntry{
+					{FunctionName: "foo", FileName: "foo.go", Self: 2, Total: 6},
+					{FunctionName: "baz", FileName: "baz.go", Self: 4, Total: 4},
+					{FunctionName: "bar", FileName: "bar.go", Self: 2, Total: 2},
+				},
+				Total: 8,
+			},
+		},
+		{
+			name:  "limit",
+			limit: 1,
+			out: &typesv1.FunctionTable{
+				Entries: []*typesv1.FunctionTableEntry{
+					{FunctionName: "baz", FileName: "baz.go", Self: 4, Total: 4},
+				},
+				Total: 8,
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewFunctionTableMerger()
+			for _, x := range in {
+				m.MergeFunctionTable(x)
+			}
+			testhelper.EqualProto(t, tc.out, m.FunctionTable(tc.limit, tc.orderByTotal))
+		})
+	}
+}
+
+func Test_SortFunctionTableEntriesByLine(t *testing.T) {
+	entries := []*typesv1.FunctionTableEntry{
+		{FunctionName: "foo", FileName: "foo.go", Line: 20, Self: 1},
+		{FunctionName: "bar", FileName: "bar.go", Line: 30, Self: 2},
+		{FunctionName: "foo", FileName: "foo.go", Line: 10, Self: 3},
+	}
+	SortFunctionTableEntriesByLine(entries)
+	testhelper.EqualProto(t, []*typesv1.FunctionTableEntry{
+		{FunctionName: "bar", FileName: "bar.go", Line: 30, Self: 2},
+		{FunctionName: "foo", FileName: "foo.go", Line: 10, Self: 3},
+		{FunctionName: "foo", FileName: "foo.go", Line: 20, Self: 1},
+	}, entries)
+}
@@ -0,0 +1,204 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/pkg/og/structs/flamebearer"
+)
+
+const (
+	DefaultHeatmapTimeBuckets  = 60
+	DefaultHeatmapValueBuckets = 20
+	// MaxHeatmapBuckets is the maximum number of bins of a heatmap:
+	// the product of the numbers of bins of the time and value axes.
+	MaxHeatmapBuckets = 100000
+)
+
+// HeatmapBuckets returns the numbers of bins of the time and value axes
+// of the requested heatmap. The defaults apply, if they are not set.
+func HeatmapBuckets(req *querierv1.SelectHeatmapRequest) (timeBuckets, valueBuckets int64, err error) {
+	timeBuckets = DefaultHeatmapTimeBuckets
+	if req.TimeBuckets != nil {
+		timeBuckets = *req.TimeBuckets
+	}
+	valueBuckets = DefaultHeatmapValueBuckets
+	if req.ValueBuckets != nil {
+		valueBuckets = *req.ValueBuckets
+	}
+	if timeBuckets <= 0 || valueBuckets <= 0 {
+		return 0, 0, errors.New("the number of buckets must be positive")
+	}
+	if timeBuckets > MaxHeatmapBuckets || valueBuckets > MaxHeatmapBuckets || timeBuckets*valueBuckets > MaxHeatmapBuckets {
+		return 0, 0, fmt.Errorf("the number of buckets %dx%d exceeds the limit of %d", timeBuckets, valueBuckets, MaxHeatmapBuckets)
+	}
+	return timeBuckets, valueBuckets, nil
+}
+
+// HeatmapBuilder bins the profile values by time and value. Unless the
+// value range is set, the boundaries of the value axis are the minimum
+// and maximum values of the profiles.
+type HeatmapBuilder struct {
+	start, end   int64
+	timeBuckets  int64
+	valueBuckets int64
+
+	// Set if the value range is known in advance:
+	// the values are binned as they are added.
+	heatmap *querierv1.Heatmap
+
+	timestamps []int64
+	values     []int64
+}
+
+// NewHeatmapBuilder creates a builder of the heatmap of the [start, end)
+// time range, in milliseconds. The number of bins must be positive.
+func NewHeatmapBuilder(start, end, timeBuckets, valueBuckets int64) *HeatmapBuilder {
+	return &HeatmapBuilder{
+		start:        start,
+		end:          end,
+		timeBuckets:  timeBuckets,
+		valueBuckets: valueBuckets,
+	}
+}
+
+// SetValueRange sets the boundaries of the value axis. Then the profiles
+// are not retained by the builder, and profiles with values outside of
+// the range are ignored. The call must precede any Add calls.
+func (b *HeatmapBuilder) SetValueRange(min, max int64) {
+	b.heatmap = b.newHeatmap()
+	b.heatmap.MinValue = min
+	b.heatmap.MaxValue = max
+}
+
+// Add adds the value of a profile. Profiles outside
+// of the heatmap time range are ignored.
+func (b *HeatmapBuilder) Add(timestamp, value int64) {
+	if timestamp < b.start || timestamp >= b.end {
+		return
+	}
+	if b.heatmap != nil {
+		if value >= b.heatmap.MinValue && value <= b.heatmap.MaxValue {
+			b.add(b.heatmap, timestamp, value)
+		}
+		return
+	}
+	b.timestamps = append(b.timestamps, timestamp)
+	b.values = append(b.values, value)
+}
+
+func (b *HeatmapBuilder) Build() *querierv1.Heatmap {
+	if b.heatmap != nil {
+		setHeatmapDepth(b.heatmap)
+		return b.heatmap
+	}
+	h := b.newHeatmap()
+	if len(b.values) == 0 {
+		return h
+	}
+	h.MinValue, h.MaxValue = b.values[0], b.values[0]
+	for _, v := range b.values {
+		if v < h.MinValue {
+			h.MinValue = v
+		}
+		if v > h.MaxValue {
+			h.MaxValue = v
+		}
+	}
+	for i, ts := range b.timestamps {
+		b.add(h, ts, b.values[i])
+	}
+	setHeatmapDepth(h)
+	return h
+}
+
+func (b *HeatmapBuilder) newHeatmap() *querierv1.Heatmap {
+	h := &querierv1.Heatmap{
+		Columns: make([]*querierv1.HeatmapColumn, b.timeBuckets),
+		Start:   b.start,
+		End:     b.end,
+	}
+	for i := range h.Columns {
+		h.Columns[i] = &querierv1.HeatmapColumn{Counts: make([]uint64, b.valueBuckets)}
+	}
+	return h
+}
+
+func (b *HeatmapBuilder) add(h *querierv1.Heatmap, timestamp, value int64) {
+	x := (timestamp - b.start) * b.timeBuckets / (b.end - b.start)
+	var y int64
+	if width := float64(h.MaxValue-h.MinValue) / float64(b.valueBuckets); width > 0 {
+		y = int64(math.Ceil(float64(value-h.MinValue)/width)) - 1
+	}
+	if y < 0 {
+		y = 0
+	} else if y >= b.valueBuckets {
+		y = b.valueBuckets - 1
+	}
+	h.Columns[x].Counts[y]++
+}
+
+// MergeHeatmaps sums the counts of the heatmaps. The heatmaps must have
+// the same time and value axes, e.g., heatmaps of different series.
+func MergeHeatmaps(heatmaps ...*querierv1.Heatmap) (*querierv1.Heatmap, error) {
+	if len(heatmaps) == 0 {
+		return nil, nil
+	}
+	h := heatmaps[0].CloneVT()
+	for _, x := range heatmaps[1:] {
+		if x.Start != h.Start || x.End != h.End || x.MinValue != h.MinValue || x.MaxValue != h.MaxValue ||
+			len(x.Columns) != len(h.Columns) {
+			return nil, errors.New("heatmap axes do not match")
+		}
+		for i, c := range x.Columns {
+			if len(c.Counts) != len(h.Columns[i].Counts) {
+				return nil, errors.New("heatmap axes do not match")
+			}
+			for j, n := range c.Counts {
+				h.Columns[i].Counts[j] += n
+			}
+		}
+	}
+	setHeatmapDepth(h)
+	return h, nil
+}
+
+func setHeatmapDepth(h *querierv1.Heatmap) {
+	h.MinDepth, h.MaxDepth = 0, 0
+	for _, c := range h.Columns {
+		for _, n := range c.Counts {
+			if n > h.MaxDepth {
+				h.MaxDepth = n
+			}
+			if n > 0 && (h.MinDepth == 0 || n < h.MinDepth) {
+				h.MinDepth = n
+			}
+		}
+	}
+}
+
+// ExportHeatmapToFlamebearer converts the heatmap to the flamebearer one.
+func ExportHeatmapToFlamebearer(h *querierv1.Heatmap) *flamebearer.Heatmap {
+	if h == nil {
+		return nil
+	}
+	fh := &flamebearer.Heatmap{
+		Values:      make([][]uint64, len(h.Columns)),
+		TimeBuckets: int64(len(h.Columns)),
+		StartTime:   h.Start * 1e6,
+		EndTime:     h.End * 1e6,
+		MinValue:    uint64(max(h.MinValue, 0)),
+		MaxValue:    uint64(max(h.MaxValue, 0)),
+		MinDepth:    h.MinDepth,
+		MaxDepth:    h.MaxDepth,
+	}
+	for i, c := range h.Columns {
+		fh.Values[i] = c.Counts
+	}
+	if len(h.Columns) > 0 {
+		fh.ValueBuckets = int64(len(h.Columns[0].Counts))
+	}
+	return fh
+}
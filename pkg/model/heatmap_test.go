@@ -0,0 +1,114 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+)
+
+func Test_HeatmapBuilder(t *testing.T) {
+	b := NewHeatmapBuilder(1000, 5000, 4, 3)
+	b.Add(1000, 10)
+	b.Add(1500, 10)
+	b.Add(1999, 40)
+	b.Add(2000, 20)
+	b.Add(4999, 30)
+	b.Add(4999, 40)
+	// Out of the time range.
+	b.Add(999, 100)
+	b.Add(5000, 100)
+
+	counts := func(c ...uint64) *querierv1.HeatmapColumn {
+		return &querierv1.HeatmapColumn{Counts: c}
+	}
+	require.Equal(t, &querierv1.Heatmap{
+		Columns: []*querierv1.HeatmapColumn{
+			counts(2, 0, 1),
+			counts(1, 0, 0),
+			counts(0, 0, 0),
+			counts(0, 1, 1),
+		},
+		Start:    1000,
+		End:      5000,
+		MinValue: 10,
+		MaxValue: 40,
+		MinDepth: 1,
+		MaxDepth: 2,
+	}, b.Build())
+}
+
+func Test_HeatmapBuilder_SingleValue(t *testing.T) {
+	b := NewHeatmapBuilder(0, 100, 2, 2)
+	b.Add(10, 5)
+	b.Add(60, 5)
+	h := b.Build()
+	require.Equal(t, []uint64{1, 0}, h.Columns[0].Counts)
+	require.Equal(t, []uint64{1, 0}, h.Columns[1].Counts)
+
+	fh := ExportHeatmapToFlamebearer(h)
+	require.Equal(t, int64(2), fh.TimeBuckets)
+	require.Equal(t, int64(2), fh.ValueBuckets)
+	require.Equal(t, int64(100*1e6), fh.EndTime)
+	require.Equal(t, uint64(5), fh.MinValue)
+}
+
+func Test_HeatmapBuilder_Empty(t *testing.T) {
+	h := NewHeatmapBuilder(0, 100, 2, 2).Build()
+	require.Len(t, h.Columns, 2)
+	require.Zero(t, h.MaxDepth)
+}
+
+func Test_HeatmapBuilder_ValueRange(t *testing.T) {
+	// The heatmaps of the two halves of the profiles
+	// sum up to the heatmap of all the profiles.
+	b1 := NewHeatmapBuilder(1000, 5000, 4, 3)
+	b1.SetValueRange(10, 40)
+	b1.Add(1000, 10)
+	b1.Add(1999, 40)
+	b1.Add(4999, 30)
+	// Out of the value range.
+	b1.Add(2000, 50)
+
+	b2 := NewHeatmapBuilder(1000, 5000, 4, 3)
+	b2.SetValueRange(10, 40)
+	b2.Add(1500, 10)
+	b2.Add(2000, 20)
+	b2.Add(4999, 40)
+
+	h, err := MergeHeatmaps(b1.Build(), b2.Build())
+	require.NoError(t, err)
+
+	b := NewHeatmapBuilder(1000, 5000, 4, 3)
+	for _, p := range [][2]int64{{1000, 10}, {1999, 40}, {4999, 30}, {1500, 10}, {2000, 20}, {4999, 40}} {
+		b.Add(p[0], p[1])
+	}
+	require.Equal(t, b.Build(), h)
+
+	_, err = MergeHeatmaps(h, NewHeatmapBuilder(1000, 5000, 4, 2).Build())
+	require.Error(t, err)
+}
+
+func Test_HeatmapBuckets(t *testing.T) {
+	n := func(v int64) *int64 { return &v }
+	timeBuckets, valueBuckets, err := HeatmapBuckets(&querierv1.SelectHeatmapRequest{})
+	require.NoError(t, err)
+	require.Equal(t, int64(DefaultHeatmapTimeBuckets), timeBuckets)
+	require.Equal(t, int64(DefaultHeatmapValueBuckets), valueBuckets)
+
+	timeBuckets, valueBuckets, err = HeatmapBuckets(&querierv1.SelectHeatmapRequest{TimeBuckets: n(1000), ValueBuckets: n(100)})
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), timeBuckets)
+	require.Equal(t, int64(100), valueBuckets)
+
+	for _, req := range []*querierv1.SelectHeatmapRequest{
+		{TimeBuckets: n(0)},
+		{ValueBuckets: n(-1)},
+		{TimeBuckets: n(1000), ValueBuckets: n(101)},
+		{TimeBuckets: n(1 << 32), ValueBuckets: n(1 << 32)},
+	} {
+		_, _, err = HeatmapBuckets(req)
+		require.Error(t, err)
+	}
+}
@@ -31,6 +31,8 @@ const (
 	LabelNameProfileName = pmodel.MetricNameLabel
 	LabelNameServiceName = "service_name"
 	LabelNameSessionID   = "__session_id__"
+	LabelNameOther       = "__other__"
+	LabelNameFunction    = "__function__"
 
 	LabelNameServiceNameK8s = "__meta_kubernetes_pod_annotation_pyroscope_io_service_name"
 
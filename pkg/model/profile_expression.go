@@ -0,0 +1,418 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"golang.org/x/sync/errgroup"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+// ProfileTypeExpression is an arithmetic expression combining the values
+// of several profile types, e.g. the allocated bytes per object:
+//
+//	memory:alloc_space:bytes:space:bytes / memory:alloc_objects:count:space:bytes
+//
+// The operands are profile type IDs, optionally with label matchers that
+// only apply to the operand, and number literals. They are combined with
+// the +, -, * and / operators, either per step of the series, or per node
+// of the flame graph. Values missing on one side are treated as zero, and
+// the values where the divisor is zero are dropped.
+type ProfileTypeExpression struct {
+	root     profileTypeExprNode
+	operands []ProfileTypeOperand
+	index    map[ProfileTypeOperand]int
+}
+
+// ProfileTypeOperand is a profile type selection of the expression.
+type ProfileTypeOperand struct {
+	ProfileTypeID string
+	LabelSelector string
+}
+
+type profileTypeExprNode interface{}
+
+type (
+	exprOperand int
+	exprNumber  float64
+	exprBinary  struct {
+		op       parser.ItemType
+		lhs, rhs profileTypeExprNode
+	}
+)
+
+// IsProfileTypeExpression reports whether the profile type ID is an
+// expression, rather than a single profile type.
+func IsProfileTypeExpression(id string) bool {
+	e, err := ParseProfileTypeExpression(id, "{}")
+	if err != nil {
+		return false
+	}
+	op, ok := e.root.(exprOperand)
+	return !ok || e.operands[op] != ProfileTypeOperand{ProfileTypeID: id, LabelSelector: "{}"}
+}
+
+// ParseProfileTypeExpression parses the expression. The label selector
+// applies to all the profile types of the expression.
+func ParseProfileTypeExpression(expr string, labelSelector string) (*ProfileTypeExpression, error) {
+	selector, err := parser.ParseMetricSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	e := &ProfileTypeExpression{index: make(map[ProfileTypeOperand]int)}
+	if e.root, err = e.build(node, selector); err != nil {
+		return nil, err
+	}
+	if len(e.operands) == 0 {
+		return nil, fmt.Errorf("expression %q must contain a profile type", expr)
+	}
+	return e, nil
+}
+
+func (e *ProfileTypeExpression) build(node parser.Node, selector []*labels.Matcher) (profileTypeExprNode, error) {
+	switch n := node.(type) {
+	case *parser.ParenExpr:
+		return e.build(n.Expr, selector)
+
+	case *parser.NumberLiteral:
+		return exprNumber(n.Val), nil
+
+	case *parser.UnaryExpr:
+		x, err := e.build(n.Expr, selector)
+		if err != nil || n.Op != parser.SUB {
+			return x, err
+		}
+		return &exprBinary{op: parser.SUB, lhs: exprNumber(0), rhs: x}, nil
+
+	case *parser.BinaryExpr:
+		switch n.Op {
+		case parser.ADD, parser.SUB, parser.MUL, parser.DIV:
+		default:
+			return nil, fmt.Errorf("unsupported operator %q", n.Op)
+		}
+		if m := n.VectorMatching; n.ReturnBool || m != nil && (m.On || len(m.MatchingLabels) > 0 || m.Card != parser.CardOneToOne) {
+			return nil, fmt.Errorf("unsupported modifier in %q", n)
+		}
+		lhs, err := e.build(n.LHS, selector)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := e.build(n.RHS, selector)
+		if err != nil {
+			return nil, err
+		}
+		return &exprBinary{op: n.Op, lhs: lhs, rhs: rhs}, nil
+
+	case *parser.VectorSelector:
+		if n.OriginalOffset != 0 || n.Timestamp != nil || n.StartOrEnd != 0 {
+			return nil, fmt.Errorf("unsupported modifier in %q", n)
+		}
+		if _, err := ParseProfileTypeSelector(n.Name); err != nil {
+			return nil, err
+		}
+		matchers := append(make([]*labels.Matcher, 0, len(selector)+len(n.LabelMatchers)), selector...)
+		for _, m := range n.LabelMatchers {
+			if m.Name != labels.MetricName {
+				matchers = append(matchers, m)
+			}
+		}
+		op := ProfileTypeOperand{
+			ProfileTypeID: n.Name,
+			LabelSelector: matchersString(matchers),
+		}
+		i, ok := e.index[op]
+		if !ok {
+			i = len(e.operands)
+			e.index[op] = i
+			e.operands = append(e.operands, op)
+		}
+		return exprOperand(i), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported expression %q", node)
+	}
+}
+
+func matchersString(matchers []*labels.Matcher) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, m := range matchers {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(m.String())
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Operands returns the distinct profile type selections of the expression.
+func (e *ProfileTypeExpression) Operands() []ProfileTypeOperand { return e.operands }
+
+// SelectSeries selects the series of the operands concurrently,
+// and combines them per step. Series are matched by labels.
+func (e *ProfileTypeExpression) SelectSeries(
+	ctx context.Context,
+	selectSeries func(context.Context, ProfileTypeOperand) ([]*typesv1.Series, error),
+) ([]*typesv1.Series, error) {
+	operands, err := selectOperands(ctx, e.operands, selectSeries)
+	if err != nil {
+		return nil, err
+	}
+	v := evalProfileTypeExpr(e.root,
+		func(i int) seriesExprValue { return seriesExprValue{series: operands[i]} },
+		func(x float64) seriesExprValue { return seriesExprValue{scalar: x, isScalar: true} },
+		binarySeriesExpr,
+	)
+	series := make([]*typesv1.Series, 0, len(v.series))
+	for _, s := range v.series {
+		if len(s.Points) > 0 {
+			series = append(series, s)
+		}
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return CompareLabelPairs(series[i].Labels, series[j].Labels) < 0
+	})
+	return series, nil
+}
+
+// SelectTree selects the trees of the operands concurrently, and evaluates
+// the expression per node: the total and self values of a node are combined
+// from the values of the nodes with the same stack in the operand trees. As
+// a ratio of the node totals is not the sum of the ratios of the children,
+// the children of a node are not necessarily narrower than the node. Tree
+// values are integers, therefore the resulting values are rounded. The nodes
+// with a non-positive total are dropped together with their children.
+func (e *ProfileTypeExpression) SelectTree(
+	ctx context.Context,
+	selectTree func(context.Context, ProfileTypeOperand) (*Tree, error),
+) (*Tree, error) {
+	operands, err := selectOperands(ctx, e.operands, selectTree)
+	if err != nil {
+		return nil, err
+	}
+	roots := make([][]*node, len(operands))
+	for i, t := range operands {
+		if t != nil {
+			roots[i] = t.root
+		}
+	}
+	r := &node{}
+	r.children = e.evalTreeNodes(r, roots)
+	return &Tree{root: r.children}, nil
+}
+
+// evalTreeNodes evaluates the expression for the children of the parent
+// node. The nodes of the operands are the children of the operand nodes
+// matching the parent, nil if the operand has no such node.
+func (e *ProfileTypeExpression) evalTreeNodes(parent *node, operands [][]*node) []*node {
+	matched := make(map[string][]*node)
+	names := make([]string, 0, len(operands[0]))
+	for i, nodes := range operands {
+		for _, n := range nodes {
+			m, ok := matched[n.name]
+			if !ok {
+				m = make([]*node, len(operands))
+				matched[n.name] = m
+				names = append(names, n.name)
+			}
+			m[i] = n
+		}
+	}
+	sort.Strings(names)
+	children := make([]*node, 0, len(names))
+	for _, name := range names {
+		m := matched[name]
+		total, ok := e.evalNode(m, func(n *node) int64 { return n.total })
+		if !ok || total <= 0 {
+			continue
+		}
+		self, ok := e.evalNode(m, func(n *node) int64 { return n.self })
+		if !ok || self < 0 {
+			self = 0
+		}
+		n := &node{parent: parent, name: name, total: total, self: self}
+		next := make([][]*node, len(m))
+		for i, o := range m {
+			if o != nil {
+				next[i] = o.children
+			}
+		}
+		n.children = e.evalTreeNodes(n, next)
+		children = append(children, n)
+	}
+	return children
+}
+
+// evalNode evaluates the expression for the values of the operand nodes.
+// The value of a missing node is zero. The result is not valid, if any
+// of the divisors is zero.
+func (e *ProfileTypeExpression) evalNode(nodes []*node, value func(*node) int64) (int64, bool) {
+	v := evalProfileTypeExpr(e.root,
+		func(i int) nodeExprValue {
+			if nodes[i] == nil {
+				return nodeExprValue{valid: true}
+			}
+			return nodeExprValue{x: float64(value(nodes[i])), valid: true}
+		},
+		func(x float64) nodeExprValue { return nodeExprValue{x: x, valid: true} },
+		func(op parser.ItemType, lhs, rhs nodeExprValue) nodeExprValue {
+			x, ok := applyOp(op, lhs.x, rhs.x)
+			return nodeExprValue{x: x, valid: ok && lhs.valid && rhs.valid}
+		},
+	)
+	return int64(math.Round(v.x)), v.valid
+}
+
+func selectOperands[T any](ctx context.Context, operands []ProfileTypeOperand, fn func(context.Context, ProfileTypeOperand) (T, error)) ([]T, error) {
+	results := make([]T, len(operands))
+	g, ctx := errgroup.WithContext(ctx)
+	for i, op := range operands {
+		i, op := i, op
+		g.Go(func() (err error) {
+			results[i], err = fn(ctx, op)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func evalProfileTypeExpr[V any](
+	node profileTypeExprNode,
+	operand func(int) V,
+	number func(float64) V,
+	binary func(op parser.ItemType, lhs, rhs V) V,
+) V {
+	switch n := node.(type) {
+	case exprOperand:
+		return operand(int(n))
+	case exprNumber:
+		return number(float64(n))
+	case *exprBinary:
+		lhs := evalProfileTypeExpr(n.lhs, operand, number, binary)
+		rhs := evalProfileTypeExpr(n.rhs, operand, number, binary)
+		return binary(n.op, lhs, rhs)
+	}
+	panic(fmt.Sprintf("unexpected expression node %T", node))
+}
+
+// applyOp applies the operator. The result is not
+// valid, if the divisor is zero.
+func applyOp(op parser.ItemType, a, b float64) (float64, bool) {
+	switch op {
+	case parser.ADD:
+		return a + b, true
+	case parser.SUB:
+		return a - b, true
+	case parser.MUL:
+		return a * b, true
+	case parser.DIV:
+		if b == 0 {
+			return 0, false
+		}
+		return a / b, true
+	}
+	return 0, false
+}
+
+type seriesExprValue struct {
+	series   []*typesv1.Series
+	scalar   float64
+	isScalar bool
+}
+
+func binarySeriesExpr(op parser.ItemType, lhs, rhs seriesExprValue) seriesExprValue {
+	switch {
+	case lhs.isScalar && rhs.isScalar:
+		x, _ := applyOp(op, lhs.scalar, rhs.scalar)
+		return seriesExprValue{scalar: x, isScalar: true}
+	case lhs.isScalar:
+		return seriesExprValue{series: mapSeries(rhs.series, func(x float64) (float64, bool) {
+			return applyOp(op, lhs.scalar, x)
+		})}
+	case rhs.isScalar:
+		return seriesExprValue{series: mapSeries(lhs.series, func(x float64) (float64, bool) {
+			return applyOp(op, x, rhs.scalar)
+		})}
+	}
+	right := make(map[uint64]*typesv1.Series, len(rhs.series))
+	for _, s := range rhs.series {
+		right[Labels(s.Labels).Hash()] = s
+	}
+	result := make([]*typesv1.Series, 0, len(lhs.series))
+	for _, s := range lhs.series {
+		h := Labels(s.Labels).Hash()
+		r := right[h]
+		delete(right, h)
+		var points []*typesv1.Point
+		if r != nil {
+			points = r.Points
+		}
+		result = append(result, &typesv1.Series{Labels: s.Labels, Points: combinePoints(op, s.Points, points)})
+	}
+	for _, s := range rhs.series {
+		if _, ok := right[Labels(s.Labels).Hash()]; ok {
+			result = append(result, &typesv1.Series{Labels: s.Labels, Points: combinePoints(op, nil, s.Points)})
+		}
+	}
+	return seriesExprValue{series: result}
+}
+
+func mapSeries(series []*typesv1.Series, fn func(float64) (float64, bool)) []*typesv1.Series {
+	result := make([]*typesv1.Series, len(series))
+	for i, s := range series {
+		points := make([]*typesv1.Point, 0, len(s.Points))
+		for _, p := range s.Points {
+			if x, ok := fn(p.Value); ok {
+				points = append(points, &typesv1.Point{Timestamp: p.Timestamp, Value: x})
+			}
+		}
+		result[i] = &typesv1.Series{Labels: s.Labels, Points: points}
+	}
+	return result
+}
+
+// combinePoints combines the points matched by timestamp.
+// Both the slices must be ordered by timestamp.
+func combinePoints(op parser.ItemType, a, b []*typesv1.Point) []*typesv1.Point {
+	points := make([]*typesv1.Point, 0, len(a))
+	add := func(ts int64, x, y float64) {
+		if v, ok := applyOp(op, x, y); ok {
+			points = append(points, &typesv1.Point{Timestamp: ts, Value: v})
+		}
+	}
+	var i, j int
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || i < len(a) && a[i].Timestamp < b[j].Timestamp:
+			add(a[i].Timestamp, a[i].Value, 0)
+			i++
+		case i == len(a) || b[j].Timestamp < a[i].Timestamp:
+			add(b[j].Timestamp, 0, b[j].Value)
+			j++
+		default:
+			add(a[i].Timestamp, a[i].Value, b[j].Value)
+			i++
+			j++
+		}
+	}
+	return points
+}
+
+type nodeExprValue struct {
+	x     float64
+	valid bool
+}
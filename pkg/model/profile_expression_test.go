@@ -0,0 +1,156 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+const (
+	allocSpace   = "memory:alloc_space:bytes:space:bytes"
+	allocObjects = "memory:alloc_objects:count:space:bytes"
+)
+
+func Test_IsProfileTypeExpression(t *testing.T) {
+	for _, tc := range []struct {
+		id       string
+		expected bool
+	}{
+		{id: allocSpace},
+		{id: "foo-bar:cpu:nanoseconds:cpu:nanoseconds"},
+		{id: "invalid"},
+		{id: allocSpace + " /", expected: false},
+		{id: allocSpace + " / " + allocObjects, expected: true},
+		{id: allocSpace + " * 100", expected: true},
+		{id: "(" + allocSpace + ")", expected: true},
+		{id: allocSpace + `{foo="bar"}`, expected: true},
+	} {
+		t.Run(tc.id, func(t *testing.T) {
+			require.Equal(t, tc.expected, IsProfileTypeExpression(tc.id))
+		})
+	}
+}
+
+func Test_ParseProfileTypeExpression(t *testing.T) {
+	e, err := ParseProfileTypeExpression(
+		allocSpace+`{foo="bar"} / `+allocObjects+` + `+allocSpace+`{foo="bar"} * 2`,
+		`{service_name="app"}`,
+	)
+	require.NoError(t, err)
+	require.Equal(t, []ProfileTypeOperand{
+		{ProfileTypeID: allocSpace, LabelSelector: `{service_name="app",foo="bar"}`},
+		{ProfileTypeID: allocObjects, LabelSelector: `{service_name="app"}`},
+	}, e.Operands())
+
+	for _, expr := range []string{
+		"1 + 2",
+		allocSpace + " > 1",
+		allocSpace + " / on(foo) " + allocObjects,
+		allocSpace + " offset 1h",
+		"sum(" + allocSpace + ")",
+		"foo / " + allocSpace,
+	} {
+		_, err = ParseProfileTypeExpression(expr, "{}")
+		require.Error(t, err, expr)
+	}
+}
+
+func Test_ProfileTypeExpression_SelectSeries(t *testing.T) {
+	e, err := ParseProfileTypeExpression(allocSpace+" / "+allocObjects+" * 10", "{}")
+	require.NoError(t, err)
+
+	series := func(lbs string, points ...*typesv1.Point) *typesv1.Series {
+		return &typesv1.Series{Labels: LabelsFromStrings("service_name", lbs), Points: points}
+	}
+	point := func(ts int64, v float64) *typesv1.Point {
+		return &typesv1.Point{Timestamp: ts, Value: v}
+	}
+	operands := map[string][]*typesv1.Series{
+		allocSpace: {
+			series("b", point(1, 100)),
+			series("a", point(1, 100), point(2, 300), point(3, 500)),
+		},
+		allocObjects: {
+			series("a", point(1, 10), point(2, 0), point(4, 10)),
+			series("c", point(1, 10)),
+		},
+	}
+
+	actual, err := e.SelectSeries(context.Background(), func(_ context.Context, op ProfileTypeOperand) ([]*typesv1.Series, error) {
+		return operands[op.ProfileTypeID], nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []*typesv1.Series{
+		series("a", point(1, 100), point(4, 0)),
+		series("c", point(1, 0)),
+	}, actual)
+}
+
+func Test_ProfileTypeExpression_SelectTree(t *testing.T) {
+	e, err := ParseProfileTypeExpression("2 * "+allocSpace+" - "+allocObjects, "{}")
+	require.NoError(t, err)
+
+	a := new(Tree)
+	a.InsertStack(10, "a", "b")
+	a.InsertStack(20, "a", "c")
+	a.InsertStack(5, "a")
+	b := new(Tree)
+	b.InsertStack(4, "a", "b")
+	b.InsertStack(30, "a", "c")
+	b.InsertStack(1, "a", "d")
+	operands := map[string]*Tree{allocSpace: a, allocObjects: b}
+
+	actual, err := e.SelectTree(context.Background(), func(_ context.Context, op ProfileTypeOperand) (*Tree, error) {
+		return operands[op.ProfileTypeID], nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"a 10",
+		"a;b 16",
+		"a;c 10",
+	}, collapsed(actual))
+	require.Equal(t, int64(35), actual.Total())
+}
+
+func Test_ProfileTypeExpression_SelectTree_Ratio(t *testing.T) {
+	e, err := ParseProfileTypeExpression(allocSpace+" / "+allocObjects, "{}")
+	require.NoError(t, err)
+
+	a := new(Tree)
+	a.InsertStack(10, "a", "b")
+	a.InsertStack(20, "a", "c")
+	a.InsertStack(3, "a", "d")
+	a.InsertStack(5, "a")
+	b := new(Tree)
+	b.InsertStack(2, "a", "b")
+	b.InsertStack(2, "a", "c")
+	b.InsertStack(1, "a")
+	operands := map[string]*Tree{allocSpace: a, allocObjects: b}
+
+	actual, err := e.SelectTree(context.Background(), func(_ context.Context, op ProfileTypeOperand) (*Tree, error) {
+		return operands[op.ProfileTypeID], nil
+	})
+	require.NoError(t, err)
+	// The nodes without objects are dropped, and
+	// the node totals are the ratios of the totals.
+	require.Equal(t, []string{
+		"a 5",
+		"a;b 5",
+		"a;c 10",
+	}, collapsed(actual))
+	require.Equal(t, int64(8), actual.Total())
+}
+
+func collapsed(t *Tree) []string {
+	var b bytes.Buffer
+	t.WriteCollapsed(&b)
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	sort.Strings(lines)
+	return lines
+}
@@ -15,10 +15,24 @@ func SumSeries(series ...[]*typesv1.Series) []*typesv1.Series {
 	return m.Series()
 }
 
+// MergeSeries merges series with matching labels. Points with matching
+// timestamps are summed, if the aggregation allows that; otherwise all the
+// points are preserved, so that they can be aggregated later.
+func MergeSeries(aggregation typesv1.TimeSeriesAggregationType, series ...[]*typesv1.Series) []*typesv1.Series {
+	m := NewSeriesMerger(true)
+	m.keepDuplicates = !IsSummableAggregation(aggregation)
+	for _, s := range series {
+		m.MergeSeries(s)
+	}
+	return m.Series()
+}
+
 type SeriesMerger struct {
 	mu     sync.Mutex
 	series map[uint64]*typesv1.Series
 	sum    bool
+
+	keepDuplicates bool
 }
 
 // NewSeriesMerger creates a new series merger. If sum is set, samples
@@ -69,6 +83,9 @@ func (m *SeriesMerger) mergePoints(points []*typesv1.Point) int {
 	sort.Slice(points, func(i, j int) bool {
 		return points[i].Timestamp < points[j].Timestamp
 	})
+	if m.keepDuplicates {
+		return l
+	}
 	var j int
 	for i := 1; i < l; i++ {
 		if points[j].Timestamp != points[i].Timestamp {
@@ -78,7 +95,89 @@ func (m *SeriesMerger) mergePoints(points []*typesv1.Point) int {
 		}
 		if m.sum {
 			points[j].Value += points[i].Value
+			points[j].Exemplars = append(points[j].Exemplars, points[i].Exemplars...)
 		}
 	}
 	return j + 1
 }
+
+// TopSeries returns the limit series with the largest sum of values,
+// ordered by labels. If other is set, the remaining series are summed
+// into a single series labeled with LabelNameOther, which is placed last.
+func TopSeries(series []*typesv1.Series, limit int, other bool) []*typesv1.Series {
+	if limit <= 0 || len(series) <= limit {
+		return series
+	}
+	totals := make(map[*typesv1.Series]float64, len(series))
+	for _, s := range series {
+		var total float64
+		for _, p := range s.Points {
+			total += p.Value
+		}
+		totals[s] = total
+	}
+	sort.Slice(series, func(i, j int) bool {
+		a, b := totals[series[i]], totals[series[j]]
+		if a != b {
+			return a > b
+		}
+		return CompareLabelPairs(series[i].Labels, series[j].Labels) < 0
+	})
+	top, rest := series[:limit], series[limit:]
+	sort.Slice(top, func(i, j int) bool {
+		return CompareLabelPairs(top[i].Labels, top[j].Labels) < 0
+	})
+	if !other {
+		return top
+	}
+	m := NewSeriesMerger(true)
+	for _, s := range rest {
+		m.MergeSeries([]*typesv1.Series{{
+			Labels: Labels{{Name: LabelNameOther, Value: "true"}},
+			Points: s.Points,
+		}})
+	}
+	return append(top, m.Series()...)
+}
+
+// TrimExemplars keeps at most max exemplars with the largest values
+// in each point of the series. If max is zero, exemplars are removed.
+func TrimExemplars(series []*typesv1.Series, max int) []*typesv1.Series {
+	for _, s := range series {
+		for _, p := range s.Points {
+			p.Exemplars = TopExemplars(p.Exemplars, max)
+		}
+	}
+	return series
+}
+
+// TopExemplars returns at most max exemplars with the largest values,
+// ordered by value in descending order.
+func TopExemplars(exemplars []*typesv1.Exemplar, max int) []*typesv1.Exemplar {
+	if max <= 0 {
+		return nil
+	}
+	sort.Slice(exemplars, func(i, j int) bool {
+		if exemplars[i].Value != exemplars[j].Value {
+			return exemplars[i].Value > exemplars[j].Value
+		}
+		return exemplars[i].ProfileId < exemplars[j].ProfileId
+	})
+	if len(exemplars) > max {
+		exemplars = exemplars[:max]
+	}
+	return exemplars
+}
+
+// ShiftSeries shifts the timestamps of the series points by the offset.
+func ShiftSeries(series []*typesv1.Series, offset int64) []*typesv1.Series {
+	if offset == 0 {
+		return series
+	}
+	for _, s := range series {
+		for _, p := range s.Points {
+			p.Timestamp += offset
+		}
+	}
+	return series
+}
@@ -89,3 +89,71 @@ func Test_SeriesMerger_Overlap(t *testing.T) {
 		})
 	}
 }
+
+func Test_TopSeries(t *testing.T) {
+	in := func() []*typesv1.Series {
+		return []*typesv1.Series{
+			{Labels: LabelsFromStrings("pod", "a"), Points: []*typesv1.Point{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 1}}},
+			{Labels: LabelsFromStrings("pod", "b"), Points: []*typesv1.Point{{Timestamp: 1, Value: 5}}},
+			{Labels: LabelsFromStrings("pod", "c"), Points: []*typesv1.Point{{Timestamp: 2, Value: 3}}},
+			{Labels: LabelsFromStrings("pod", "d"), Points: []*typesv1.Point{{Timestamp: 1, Value: 4}, {Timestamp: 3, Value: 1}}},
+		}
+	}
+	for _, tc := range []struct {
+		name  string
+		limit int
+		other bool
+		out   []*typesv1.Series
+	}{
+		{
+			name:  "no limit",
+			limit: 0,
+			out:   in(),
+		},
+		{
+			name:  "limit exceeds series",
+			limit: 5,
+			other: true,
+			out:   in(),
+		},
+		{
+			name:  "top series",
+			limit: 2,
+			out: []*typesv1.Series{
+				{Labels: LabelsFromStrings("pod", "b"), Points: []*typesv1.Point{{Timestamp: 1, Value: 5}}},
+				{Labels: LabelsFromStrings("pod", "d"), Points: []*typesv1.Point{{Timestamp: 1, Value: 4}, {Timestamp: 3, Value: 1}}},
+			},
+		},
+		{
+			name:  "top series with other",
+			limit: 2,
+			other: true,
+			out: []*typesv1.Series{
+				{Labels: LabelsFromStrings("pod", "b"), Points: []*typesv1.Point{{Timestamp: 1, Value: 5}}},
+				{Labels: LabelsFromStrings("pod", "d"), Points: []*typesv1.Point{{Timestamp: 1, Value: 4}, {Timestamp: 3, Value: 1}}},
+				{Labels: LabelsFromStrings(LabelNameOther, "true"), Points: []*typesv1.Point{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 4}}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testhelper.EqualProto(t, tc.out, TopSeries(in(), tc.limit, tc.other))
+		})
+	}
+}
+
+func Test_TrimExemplars(t *testing.T) {
+	m := NewSeriesMerger(true)
+	m.MergeSeries([]*typesv1.Series{{
+		Labels: LabelsFromStrings("foo", "bar"),
+		Points: []*typesv1.Point{{Timestamp: 1, Value: 1, Exemplars: []*typesv1.Exemplar{{ProfileId: "a", Value: 1}}}},
+	}})
+	m.MergeSeries([]*typesv1.Series{{
+		Labels: LabelsFromStrings("foo", "bar"),
+		Points: []*typesv1.Point{{Timestamp: 1, Value: 5, Exemplars: []*typesv1.Exemplar{{ProfileId: "b", Value: 3}, {ProfileId: "c", Value: 2}}}},
+	}})
+
+	testhelper.EqualProto(t, []*typesv1.Series{{
+		Labels: LabelsFromStrings("foo", "bar"),
+		Points: []*typesv1.Point{{Timestamp: 1, Value: 6, Exemplars: []*typesv1.Exemplar{{ProfileId: "b", Value: 3}, {ProfileId: "c", Value: 2}}}},
+	}}, TrimExemplars(m.Series(), 2))
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/grafana/pyroscope/pkg/util"
+)
+
+// LabelNameSpanID is the pprof sample label that refers
+// to the span the sample has been collected in.
+const LabelNameSpanID = "span_id"
+
+// SpanSelector is a set of span IDs.
+type SpanSelector map[uint64]struct{}
+
+func NewSpanSelector(spans []string) (SpanSelector, error) {
+	m := make(SpanSelector, len(spans))
+	for _, s := range spans {
+		id, err := ParseSpanID(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid span id %q: %w", s, err)
+		}
+		m[id] = struct{}{}
+	}
+	return m, nil
+}
+
+// ParseSpanID parses the 8 bytes span ID encoded as a hex string,
+// the same way as in the span_id label. Zero is not a valid span ID.
+func ParseSpanID(s string) (uint64, error) {
+	if len(s) != 16 {
+		return 0, fmt.Errorf("invalid span id length %d", len(s))
+	}
+	var b [8]byte
+	if _, err := hex.Decode(b[:], util.YoloBuf(s)); err != nil {
+		return 0, err
+	}
+	id := binary.LittleEndian.Uint64(b[:])
+	if id == 0 {
+		return 0, fmt.Errorf("invalid span id %s", s)
+	}
+	return id, nil
+}
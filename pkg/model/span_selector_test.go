@@ -0,0 +1,18 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SpanSelector(t *testing.T) {
+	s, err := NewSpanSelector([]string{"0100000000000000", "ffffffffffffffff", "0100000000000000"})
+	require.NoError(t, err)
+	require.Equal(t, SpanSelector{1: {}, 0xffffffffffffffff: {}}, s)
+
+	for _, invalid := range []string{"", "01", "010000000000000g", "0000000000000000"} {
+		_, err = NewSpanSelector([]string{invalid})
+		require.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"math"
+	"sort"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+// IsSummableAggregation reports whether values aggregated with the given
+// aggregation type can be pre-aggregated by summation.
+func IsSummableAggregation(a typesv1.TimeSeriesAggregationType) bool {
+	switch a {
+	case typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_SUM,
+		typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_RATE:
+		return true
+	}
+	return false
+}
+
+// TimeSeriesAggregator aggregates values within a time series step.
+type TimeSeriesAggregator interface {
+	Add(value float64)
+	// GetAndReset returns the aggregated value and resets the aggregator.
+	GetAndReset() float64
+	IsEmpty() bool
+}
+
+// NewTimeSeriesAggregator creates an aggregator of the given type.
+// The step is the step width in milliseconds, which is only used
+// for computing rate.
+func NewTimeSeriesAggregator(a typesv1.TimeSeriesAggregationType, step int64) TimeSeriesAggregator {
+	switch a {
+	case typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_AVERAGE:
+		return &avgAggregator{}
+	case typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_MIN:
+		return &minMaxAggregator{less: func(a, b float64) bool { return a < b }}
+	case typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_MAX:
+		return &minMaxAggregator{less: func(a, b float64) bool { return a > b }}
+	case typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_COUNT:
+		return &countAggregator{}
+	case typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_P50:
+		return &quantileAggregator{q: 0.5}
+	case typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_P95:
+		return &quantileAggregator{q: 0.95}
+	case typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_P99:
+		return &quantileAggregator{q: 0.99}
+	case typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_RATE:
+		return &sumAggregator{div: float64(step) / 1000}
+	default:
+		return &sumAggregator{div: 1}
+	}
+}
+
+type sumAggregator struct {
+	sum float64
+	n   int
+	div float64
+}
+
+func (a *sumAggregator) Add(v float64) {
+	a.sum += v
+	a.n++
+}
+
+func (a *sumAggregator) GetAndReset() float64 {
+	v := a.sum / a.div
+	a.sum, a.n = 0, 0
+	return v
+}
+
+func (a *sumAggregator) IsEmpty() bool { return a.n == 0 }
+
+type avgAggregator struct {
+	sum float64
+	n   int
+}
+
+func (a *avgAggregator) Add(v float64) {
+	a.sum += v
+	a.n++
+}
+
+func (a *avgAggregator) GetAndReset() float64 {
+	v := a.sum / float64(a.n)
+	a.sum, a.n = 0, 0
+	return v
+}
+
+func (a *avgAggregator) IsEmpty() bool { return a.n == 0 }
+
+type minMaxAggregator struct {
+	less func(a, b float64) bool
+	v    float64
+	set  bool
+}
+
+func (a *minMaxAggregator) Add(v float64) {
+	if !a.set || a.less(v, a.v) {
+		a.v = v
+	}
+	a.set = true
+}
+
+func (a *minMaxAggregator) GetAndReset() float64 {
+	v := a.v
+	a.v, a.set = 0, false
+	return v
+}
+
+func (a *minMaxAggregator) IsEmpty() bool { return !a.set }
+
+type countAggregator struct{ n int }
+
+func (a *countAggregator) Add(float64) { a.n++ }
+
+func (a *countAggregator) GetAndReset() float64 {
+	v := float64(a.n)
+	a.n = 0
+	return v
+}
+
+func (a *countAggregator) IsEmpty() bool { return a.n == 0 }
+
+type quantileAggregator struct {
+	q      float64
+	values []float64
+}
+
+func (a *quantileAggregator) Add(v float64) { a.values = append(a.values, v) }
+
+// GetAndReset returns the quantile of the values, interpolating
+// linearly between the nearest ranks.
+func (a *quantileAggregator) GetAndReset() float64 {
+	sort.Float64s(a.values)
+	rank := a.q * float64(len(a.values)-1)
+	lo := math.Floor(rank)
+	hi := math.Ceil(rank)
+	w := rank - lo
+	v := a.values[int(lo)]*(1-w) + a.values[int(hi)]*w
+	a.values = a.values[:0]
+	return v
+}
+
+func (a *quantileAggregator) IsEmpty() bool { return len(a.values) == 0 }
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/testhelper"
+)
+
+func Test_TimeSeriesAggregator(t *testing.T) {
+	values := []float64{4, 1, 3, 2, 10}
+	for _, tc := range []struct {
+		aggregation typesv1.TimeSeriesAggregationType
+		expected    float64
+	}{
+		{typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_SUM, 20},
+		{typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_AVERAGE, 4},
+		{typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_MIN, 1},
+		{typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_MAX, 10},
+		{typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_COUNT, 5},
+		{typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_P50, 3},
+		{typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_P95, 8.8},
+		{typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_P99, 9.76},
+		{typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_RATE, 2},
+	} {
+		tc := tc
+		t.Run(tc.aggregation.String(), func(t *testing.T) {
+			a := NewTimeSeriesAggregator(tc.aggregation, 10000)
+			require.True(t, a.IsEmpty())
+			for _, v := range values {
+				a.Add(v)
+			}
+			require.False(t, a.IsEmpty())
+			require.InDelta(t, tc.expected, a.GetAndReset(), 1e-9)
+			require.True(t, a.IsEmpty())
+			// The aggregator must be reusable after reset.
+			a.Add(7)
+			if tc.aggregation == typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_COUNT {
+				require.Equal(t, float64(1), a.GetAndReset())
+			} else if tc.aggregation == typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_RATE {
+				require.Equal(t, 0.7, a.GetAndReset())
+			} else {
+				require.Equal(t, float64(7), a.GetAndReset())
+			}
+		})
+	}
+}
+
+func Test_MergeSeries(t *testing.T) {
+	in := [][]*typesv1.Series{
+		{
+			{Labels: LabelsFromStrings("foo", "bar"), Points: []*typesv1.Point{{Timestamp: 2, Value: 2}, {Timestamp: 1, Value: 1}}},
+		},
+		{
+			{Labels: LabelsFromStrings("foo", "bar"), Points: []*typesv1.Point{{Timestamp: 1, Value: 3}}},
+		},
+	}
+	clone := func() [][]*typesv1.Series {
+		c := make([][]*typesv1.Series, len(in))
+		for i, s := range in {
+			for _, x := range s {
+				c[i] = append(c[i], x.CloneVT())
+			}
+		}
+		return c
+	}
+
+	testhelper.EqualProto(t, []*typesv1.Series{
+		{Labels: LabelsFromStrings("foo", "bar"), Points: []*typesv1.Point{{Timestamp: 1, Value: 4}, {Timestamp: 2, Value: 2}}},
+	}, MergeSeries(typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_SUM, clone()...))
+
+	actual := MergeSeries(typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_MAX, clone()...)
+	require.Len(t, actual, 1)
+	require.Len(t, actual[0].Points, 3)
+	for i, ts := range []int64{1, 1, 2} {
+		require.Equal(t, ts, actual[0].Points[i].Timestamp)
+	}
+}
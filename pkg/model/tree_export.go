@@ -0,0 +1,185 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/og/storage/tree"
+)
+
+// TreeFromProfile builds the tree of the profile, with a node per
+// function. The first sample value is used.
+func TreeFromProfile(p *profilev1.Profile) *Tree {
+	t := new(Tree)
+	functions := make(map[uint64]string, len(p.Function))
+	for _, fn := range p.Function {
+		functions[fn.Id] = p.StringTable[fn.Name]
+	}
+	locations := make(map[uint64]*profilev1.Location, len(p.Location))
+	for _, loc := range p.Location {
+		locations[loc.Id] = loc
+	}
+	var stack []string
+	for _, s := range p.Sample {
+		if len(s.Value) == 0 || s.Value[0] == 0 {
+			continue
+		}
+		// Locations and their lines are ordered from the leaf,
+		// while the tree expects the stack ordered from the root.
+		stack = stack[:0]
+		for i := len(s.LocationId) - 1; i >= 0; i-- {
+			loc, ok := locations[s.LocationId[i]]
+			if !ok {
+				continue
+			}
+			for j := len(loc.Line) - 1; j >= 0; j-- {
+				if name, ok := functions[loc.Line[j].FunctionId]; ok {
+					stack = append(stack, name)
+				}
+			}
+		}
+		if len(stack) > 0 {
+			t.InsertStack(s.Value[0], stack...)
+		}
+	}
+	return t
+}
+
+// ExportToOgTree converts the tree into the tree used by the
+// pyroscope flame graph writers. Negative values are ignored.
+func ExportToOgTree(t *Tree) *tree.Tree {
+	dst := tree.New()
+	var stack []string
+	t.IterateStacks(func(_ string, self int64, s []string) {
+		if self <= 0 {
+			return
+		}
+		stack = stack[:0]
+		for i := len(s) - 1; i >= 0; i-- {
+			stack = append(stack, s[i])
+		}
+		dst.InsertStackString(stack, uint64(self))
+	})
+	return dst
+}
+
+// WriteCollapsedDiff writes the stacks of both trees in the differential
+// folded format: every line contains the stack and its self values in the
+// base tree and in the tree.
+func WriteCollapsedDiff(dst io.Writer, base, t *Tree) {
+	values := make(map[string]*[2]int64)
+	collect := func(t *Tree, i int) {
+		t.IterateStacks(func(_ string, self int64, stack []string) {
+			k := make([]string, len(stack))
+			for j := range stack {
+				k[len(stack)-1-j] = stack[j]
+			}
+			key := strings.Join(k, ";")
+			v, ok := values[key]
+			if !ok {
+				v = new([2]int64)
+				values[key] = v
+			}
+			v[i] += self
+		})
+	}
+	collect(base, 0)
+	collect(t, 1)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(dst, "%s %d %d\n", k, values[k][0], values[k][1])
+	}
+}
+
+// SpeedscopeProfile is a named tree exported as a speedscope profile.
+type SpeedscopeProfile struct {
+	Name string
+	Tree *Tree
+}
+
+// See https://github.com/jlfwong/speedscope/blob/main/src/lib/file-format-spec.ts
+const speedscopeSchema = "https://www.speedscope.app/file-format-schema.json"
+
+type speedscopeFile struct {
+	Schema   string              `json:"$schema"`
+	Shared   speedscopeShared    `json:"shared"`
+	Profiles []speedscopeProfile `json:"profiles"`
+	Name     string              `json:"name,omitempty"`
+	Exporter string              `json:"exporter"`
+}
+
+type speedscopeShared struct {
+	Frames []speedscopeFrame `json:"frames"`
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+}
+
+type speedscopeProfile struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	StartValue int64   `json:"startValue"`
+	EndValue   int64   `json:"endValue"`
+	Samples    [][]int `json:"samples"`
+	Weights    []int64 `json:"weights"`
+}
+
+// WriteSpeedscope writes the trees as sampled profiles of a single
+// file in the speedscope JSON format.
+func WriteSpeedscope(dst io.Writer, profileType *typesv1.ProfileType, profiles ...SpeedscopeProfile) error {
+	f := speedscopeFile{
+		Schema:   speedscopeSchema,
+		Shared:   speedscopeShared{Frames: []speedscopeFrame{}},
+		Profiles: make([]speedscopeProfile, 0, len(profiles)),
+		Name:     profileType.ID,
+		Exporter: "pyroscope",
+	}
+	frames := make(map[string]int)
+	for _, x := range profiles {
+		p := speedscopeProfile{
+			Type:    "sampled",
+			Name:    x.Name,
+			Unit:    speedscopeUnit(profileType.SampleUnit),
+			Samples: [][]int{},
+			Weights: []int64{},
+		}
+		x.Tree.IterateStacks(func(_ string, self int64, stack []string) {
+			// Samples are ordered from the root.
+			s := make([]int, len(stack))
+			for i, name := range stack {
+				idx, ok := frames[name]
+				if !ok {
+					idx = len(f.Shared.Frames)
+					f.Shared.Frames = append(f.Shared.Frames, speedscopeFrame{Name: name})
+					frames[name] = idx
+				}
+				s[len(stack)-1-i] = idx
+			}
+			p.Samples = append(p.Samples, s)
+			p.Weights = append(p.Weights, self)
+			p.EndValue += self
+		})
+		f.Profiles = append(f.Profiles, p)
+	}
+	return json.NewEncoder(dst).Encode(f)
+}
+
+func speedscopeUnit(unit string) string {
+	switch unit {
+	case "nanoseconds", "microseconds", "milliseconds", "seconds", "bytes":
+		return unit
+	default:
+		return "none"
+	}
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+func exportTestTree() *Tree {
+	t := new(Tree)
+	t.InsertStack(1, "a", "b", "c")
+	t.InsertStack(2, "a", "b", "d")
+	t.InsertStack(3, "a", "e")
+	return t
+}
+
+var exportTestProfileType = &typesv1.ProfileType{
+	ID:         "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+	Name:       "process_cpu",
+	SampleType: "cpu",
+	SampleUnit: "nanoseconds",
+	PeriodType: "cpu",
+	PeriodUnit: "nanoseconds",
+}
+
+func Test_TreeFromProfile(t *testing.T) {
+	p := callGraphTestProfile()
+	// The location of "c" has "d" inlined.
+	p.Location[3].Line = []*profilev1.Line{{FunctionId: 5}, {FunctionId: 4}}
+	p.Sample = append(p.Sample, &profilev1.Sample{LocationId: []uint64{1}, Value: []int64{0}})
+
+	expected := new(Tree)
+	expected.InsertStack(10, "main", "a", "b")
+	expected.InsertStack(5, "main", "a", "c", "d")
+	expected.InsertStack(1, "main", "b")
+	expected.InsertStack(1, "main", "d", "b")
+	require.Equal(t, expected.String(), TreeFromProfile(p).String())
+}
+
+func Test_ExportToOgTree(t *testing.T) {
+	require.Equal(t, "a;e 3\na;b;c 1\na;b;d 2\n", ExportToOgTree(exportTestTree()).Collapsed())
+}
+
+func joinStack(stack []string) string {
+	var b bytes.Buffer
+	for i, s := range stack {
+		if i > 0 {
+			b.WriteByte(';')
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+func Test_WriteCollapsedDiff(t *testing.T) {
+	base := new(Tree)
+	base.InsertStack(5, "a", "e")
+	base.InsertStack(1, "a", "f")
+	var buf bytes.Buffer
+	WriteCollapsedDiff(&buf, base, exportTestTree())
+	require.Equal(t, `a;b;c 0 1
+a;b;d 0 2
+a;e 5 3
+a;f 1 0
+`, buf.String())
+}
+
+func Test_WriteSpeedscope(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteSpeedscope(&buf, exportTestProfileType, SpeedscopeProfile{Name: "cpu", Tree: exportTestTree()}))
+
+	var f speedscopeFile
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &f))
+	require.Equal(t, speedscopeSchema, f.Schema)
+	require.Len(t, f.Profiles, 1)
+	p := f.Profiles[0]
+	require.Equal(t, "nanoseconds", p.Unit)
+	require.Equal(t, int64(6), p.EndValue)
+	require.Len(t, p.Samples, 3)
+
+	stacks := make(map[string]int64)
+	for i, s := range p.Samples {
+		var stack []string
+		for _, idx := range s {
+			stack = append(stack, f.Shared.Frames[idx].Name)
+		}
+		stacks[joinStack(stack)] += p.Weights[i]
+	}
+	require.Equal(t, map[string]int64{
+		"a;b;c": 1,
+		"a;b;d": 2,
+		"a;e":   3,
+	}, stacks)
+}
+
+func Test_WriteFlameGraphSVG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteFlameGraphSVG(&buf, "cpu <test>", exportTestTree(), nil))
+	out := buf.String()
+	require.Contains(t, out, "<svg ")
+	require.Contains(t, out, "cpu &lt;test&gt;")
+	require.Contains(t, out, "<title>total (6, 100.00%)</title>")
+	require.Contains(t, out, "<title>e (3, 50.00%)</title>")
+
+	base := new(Tree)
+	base.InsertStack(3, "a", "e")
+	buf.Reset()
+	require.NoError(t, WriteFlameGraphSVG(&buf, "diff", exportTestTree(), base))
+	out = buf.String()
+	require.Contains(t, out, "<title>e (base: 3, 100.00%; value: 3, 50.00%)</title>")
+	require.Contains(t, out, "<title>a (base: 3, 100.00%; value: 6, 100.00%)</title>")
+	require.Contains(t, out, "<title>b (base: 0, 0.00%; value: 3, 50.00%)</title>")
+}
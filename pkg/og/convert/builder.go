@@ -0,0 +1,133 @@
+package convert
+
+import (
+	"encoding/binary"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	"github.com/grafana/pyroscope/pkg/og/agent/types"
+	"github.com/grafana/pyroscope/pkg/og/ingestion"
+)
+
+// SampleRate returns the sample rate of the profile ingested with the
+// metadata, or the default one, if the rate is not specified.
+func SampleRate(md ingestion.Metadata) uint32 {
+	if md.SampleRate == 0 {
+		return types.DefaultSampleRate
+	}
+	return md.SampleRate
+}
+
+// ProfileBuilder builds a pprof profile from the stack traces of a
+// converted profile. Strings, functions and locations are added to the
+// profile once, and samples with the same stack and labels are merged.
+type ProfileBuilder struct {
+	Profile *profilev1.Profile
+
+	strings   map[string]int64
+	functions map[Function]uint64
+	locations map[Location]uint64
+	samples   map[string]*profilev1.Sample
+	key       []byte
+}
+
+// Function identifies a function of the profile.
+type Function struct {
+	Name       string
+	SystemName string
+	Filename   string
+	StartLine  int64
+}
+
+// Location identifies a location of the profile. Locations
+// only have a single line of the function.
+type Location struct {
+	Function
+	Line      int64
+	MappingID uint64
+	Address   uint64
+}
+
+func NewProfileBuilder() *ProfileBuilder {
+	return &ProfileBuilder{
+		Profile:   &profilev1.Profile{StringTable: []string{""}},
+		strings:   map[string]int64{"": 0},
+		functions: make(map[Function]uint64),
+		locations: make(map[Location]uint64),
+		samples:   make(map[string]*profilev1.Sample),
+	}
+}
+
+// String returns the index of the string in the string table.
+func (b *ProfileBuilder) String(s string) int64 {
+	i, ok := b.strings[s]
+	if !ok {
+		i = int64(len(b.Profile.StringTable))
+		b.Profile.StringTable = append(b.Profile.StringTable, s)
+		b.strings[s] = i
+	}
+	return i
+}
+
+// Function returns the ID of the function.
+func (b *ProfileBuilder) Function(f Function) uint64 {
+	if id, ok := b.functions[f]; ok {
+		return id
+	}
+	id := uint64(len(b.Profile.Function) + 1)
+	b.Profile.Function = append(b.Profile.Function, &profilev1.Function{
+		Id:         id,
+		Name:       b.String(f.Name),
+		SystemName: b.String(f.SystemName),
+		Filename:   b.String(f.Filename),
+		StartLine:  f.StartLine,
+	})
+	b.functions[f] = id
+	return id
+}
+
+// Location returns the ID of the location.
+func (b *ProfileBuilder) Location(l Location) uint64 {
+	if id, ok := b.locations[l]; ok {
+		return id
+	}
+	id := uint64(len(b.Profile.Location) + 1)
+	b.Profile.Location = append(b.Profile.Location, &profilev1.Location{
+		Id:        id,
+		MappingId: l.MappingID,
+		Address:   l.Address,
+		Line:      []*profilev1.Line{{FunctionId: b.Function(l.Function), Line: l.Line}},
+	})
+	b.locations[l] = id
+	return id
+}
+
+// AddSample adds the sample with the stack, leaf first. The values of
+// the samples with the same stack and labels are summed. The stack and
+// the labels are referenced by the sample, and must not be modified.
+func (b *ProfileBuilder) AddSample(stack []uint64, labels []*profilev1.Label, values ...int64) {
+	b.key = b.key[:0]
+	for _, id := range stack {
+		b.key = binary.AppendUvarint(b.key, id)
+	}
+	// Location IDs are never zero.
+	b.key = append(b.key, 0)
+	for _, l := range labels {
+		b.key = binary.AppendVarint(b.key, l.Key)
+		b.key = binary.AppendVarint(b.key, l.Str)
+		b.key = binary.AppendVarint(b.key, l.Num)
+		b.key = binary.AppendVarint(b.key, l.NumUnit)
+	}
+	if s, ok := b.samples[string(b.key)]; ok {
+		for i, v := range values {
+			s.Value[i] += v
+		}
+		return
+	}
+	s := &profilev1.Sample{
+		LocationId: stack,
+		Value:      values,
+		Label:      labels,
+	}
+	b.Profile.Sample = append(b.Profile.Sample, s)
+	b.samples[string(b.key)] = s
+}
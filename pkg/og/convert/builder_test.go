@@ -0,0 +1,34 @@
+package convert
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+)
+
+var _ = Describe("ProfileBuilder", func() {
+	It("adds the strings, functions and locations once", func() {
+		b := NewProfileBuilder()
+		main := Location{Function: Function{Name: "main", Filename: "main.go", StartLine: 1}, Line: 2}
+		Expect(b.Location(main)).To(Equal(uint64(1)))
+		Expect(b.Location(Location{Function: main.Function, Line: 3})).To(Equal(uint64(2)))
+		Expect(b.Location(main)).To(Equal(uint64(1)))
+		Expect(b.Profile.Function).To(HaveLen(1))
+		Expect(b.Profile.StringTable).To(Equal([]string{"", "main", "main.go"}))
+	})
+
+	It("merges the samples with the same stack and labels", func() {
+		b := NewProfileBuilder()
+		label := []*profilev1.Label{{Key: b.String("thread"), Str: b.String("main")}}
+		b.AddSample([]uint64{1, 2}, nil, 1, 10)
+		b.AddSample([]uint64{1, 2}, label, 1, 20)
+		b.AddSample([]uint64{1, 2}, nil, 1, 30)
+		b.AddSample([]uint64{1}, nil, 1, 40)
+		Expect(b.Profile.Sample).To(Equal([]*profilev1.Sample{
+			{LocationId: []uint64{1, 2}, Value: []int64{2, 40}},
+			{LocationId: []uint64{1, 2}, Value: []int64{1, 20}, Label: label},
+			{LocationId: []uint64{1}, Value: []int64{1, 40}},
+		}))
+	})
+})
@@ -0,0 +1,38 @@
+package cpuprofile
+
+// Description of the V8 CPU profile JSON, as produced by Chrome DevTools
+// and the Node.js inspector (.cpuprofile files).
+// See https://chromedevtools.github.io/devtools-protocol/tot/Profiler/#type-Profile
+
+type cpuProfile struct {
+	Nodes []node `json:"nodes"`
+	// Start and end time of the profile in microseconds.
+	StartTime int64 `json:"startTime"`
+	EndTime   int64 `json:"endTime"`
+	// Identifiers of the sampled nodes.
+	Samples []int64 `json:"samples"`
+	// Time intervals between adjacent samples in microseconds.
+	// The first delta is relative to the profile start time.
+	TimeDeltas []int64 `json:"timeDeltas"`
+}
+
+type node struct {
+	ID        int64     `json:"id"`
+	CallFrame callFrame `json:"callFrame"`
+	HitCount  int64     `json:"hitCount"`
+	Children  []int64   `json:"children"`
+}
+
+type callFrame struct {
+	FunctionName string `json:"functionName"`
+	URL          string `json:"url"`
+	// Line and column numbers are 0-based, and -1 if unknown.
+	LineNumber   int64 `json:"lineNumber"`
+	ColumnNumber int64 `json:"columnNumber"`
+}
+
+const (
+	rootFunctionName      = "(root)"
+	idleFunctionName      = "(idle)"
+	anonymousFunctionName = "(anonymous)"
+)
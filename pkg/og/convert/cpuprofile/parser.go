@@ -0,0 +1,209 @@
+package cpuprofile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	distributormodel "github.com/grafana/pyroscope/pkg/distributor/model"
+	"github.com/grafana/pyroscope/pkg/og/convert"
+	"github.com/grafana/pyroscope/pkg/og/ingestion"
+	"github.com/grafana/pyroscope/pkg/og/storage"
+	"github.com/grafana/pyroscope/pkg/pprof"
+)
+
+// RawProfile implements ingestion.RawProfile for the V8 CPU profile format.
+type RawProfile struct {
+	RawData []byte
+}
+
+func (p *RawProfile) Bytes() ([]byte, error) { return p.RawData, nil }
+
+func (p *RawProfile) ContentType() string { return "application/json" }
+
+func (p *RawProfile) Parse(context.Context, storage.Putter, storage.MetricsExporter, ingestion.Metadata) error {
+	return fmt.Errorf("parsing cpuprofile to tree/storage.Putter is not supported")
+}
+
+func (p *RawProfile) ParseToPprof(_ context.Context, md ingestion.Metadata) (*distributormodel.PushRequest, error) {
+	var cp cpuProfile
+	if err := json.Unmarshal(p.RawData, &cp); err != nil {
+		return nil, err
+	}
+	profile, err := toPprof(&cp, md)
+	if err != nil {
+		return nil, err
+	}
+	return &distributormodel.PushRequest{
+		RawProfileSize: len(p.RawData),
+		RawProfileType: distributormodel.RawProfileTypeCPUProfile,
+		Series: []*distributormodel.ProfileSeries{{
+			Labels: convert.SeriesLabels(md, "process_cpu"),
+			Samples: []*distributormodel.ProfileSample{{
+				Profile: pprof.RawFromProto(profile),
+			}},
+		}},
+	}, nil
+}
+
+// toPprof converts the V8 CPU profile to a pprof CPU profile. Every sample
+// is weighted with the time till the next sample. If the profile has no
+// samples, the hit counts of the nodes are used instead.
+func toPprof(cp *cpuProfile, md ingestion.Metadata) (*profilev1.Profile, error) {
+	period := int64(time.Second) / int64(convert.SampleRate(md))
+	if len(cp.Samples) > 0 && cp.EndTime > cp.StartTime {
+		period = (cp.EndTime - cp.StartTime) * int64(time.Microsecond) / int64(len(cp.Samples))
+	}
+
+	b := convert.NewProfileBuilder()
+	b.Profile.TimeNanos = md.StartTime.UnixNano()
+	if cp.EndTime > cp.StartTime {
+		b.Profile.DurationNanos = (cp.EndTime - cp.StartTime) * int64(time.Microsecond)
+	}
+	b.Profile.SampleType = []*profilev1.ValueType{{Type: b.String("cpu"), Unit: b.String("nanoseconds")}}
+	b.Profile.PeriodType = &profilev1.ValueType{Type: b.String("cpu"), Unit: b.String("nanoseconds")}
+	b.Profile.Period = period
+
+	nodes := make(map[int64]*node, len(cp.Nodes))
+	for i := range cp.Nodes {
+		nodes[cp.Nodes[i].ID] = &cp.Nodes[i]
+	}
+	stacks, err := newStackResolver(b, cp.Nodes, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[int64]int64, len(cp.Nodes))
+	if len(cp.Samples) > 0 {
+		weights, err := sampleWeights(cp)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range cp.Samples {
+			if _, ok := nodes[id]; !ok {
+				return nil, fmt.Errorf("sample %d: node %d not found", i, id)
+			}
+			values[id] += weights[i]
+		}
+	} else {
+		for _, n := range cp.Nodes {
+			values[n.ID] += n.HitCount * period
+		}
+	}
+
+	// Samples are added in the order of nodes for the output to be stable.
+	for _, n := range cp.Nodes {
+		v := values[n.ID]
+		if v <= 0 || n.CallFrame.FunctionName == idleFunctionName {
+			continue
+		}
+		stack, err := stacks.stack(n.ID, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(stack) > 0 {
+			b.AddSample(stack, nil, v)
+		}
+	}
+	return b.Profile, nil
+}
+
+// sampleWeights returns the time in nanoseconds attributed to every sample:
+// the interval till the next sample, or till the profile end for the last one.
+func sampleWeights(cp *cpuProfile) ([]int64, error) {
+	if len(cp.TimeDeltas) != len(cp.Samples) {
+		return nil, fmt.Errorf("samples and timeDeltas lengths differ: %d != %d", len(cp.Samples), len(cp.TimeDeltas))
+	}
+	timestamps := make([]int64, len(cp.Samples))
+	t := cp.StartTime
+	for i, d := range cp.TimeDeltas {
+		t += d
+		timestamps[i] = t
+	}
+	weights := make([]int64, len(cp.Samples))
+	for i := range timestamps {
+		end := cp.EndTime
+		if i+1 < len(timestamps) {
+			end = timestamps[i+1]
+		}
+		// Deltas may be negative, as samples are not
+		// guaranteed to be ordered by their timestamps.
+		if d := end - timestamps[i]; d > 0 {
+			weights[i] = d * int64(time.Microsecond)
+		}
+	}
+	return weights, nil
+}
+
+// location returns the location of the call frame.
+func location(b *convert.ProfileBuilder, cf callFrame) uint64 {
+	name := cf.FunctionName
+	if name == "" {
+		name = anonymousFunctionName
+	}
+	// Line numbers are 0-based in V8 and 1-based in pprof.
+	line := cf.LineNumber + 1
+	if line < 0 {
+		line = 0
+	}
+	return b.Location(convert.Location{
+		Function: convert.Function{Name: name, Filename: cf.URL, StartLine: line},
+		Line:     line,
+	})
+}
+
+// stackResolver resolves the stack traces of the nodes, leaf first.
+// The root node is not included.
+type stackResolver struct {
+	builder *convert.ProfileBuilder
+	nodes   map[int64]*node
+	parents map[int64]int64
+	stacks  map[int64][]uint64
+}
+
+func newStackResolver(b *convert.ProfileBuilder, list []node, nodes map[int64]*node) (*stackResolver, error) {
+	parents := make(map[int64]int64, len(list))
+	for _, n := range list {
+		for _, c := range n.Children {
+			if _, ok := nodes[c]; !ok {
+				return nil, fmt.Errorf("node %d: child %d not found", n.ID, c)
+			}
+			if _, ok := parents[c]; ok {
+				return nil, fmt.Errorf("node %d has multiple parents", c)
+			}
+			parents[c] = n.ID
+		}
+	}
+	return &stackResolver{
+		builder: b,
+		nodes:   nodes,
+		parents: parents,
+		stacks:  make(map[int64][]uint64, len(list)),
+	}, nil
+}
+
+func (r *stackResolver) stack(id int64, depth int) ([]uint64, error) {
+	if s, ok := r.stacks[id]; ok {
+		return s, nil
+	}
+	if depth > len(r.nodes) {
+		return nil, fmt.Errorf("node %d: cycle detected", id)
+	}
+	var parent []uint64
+	if p, ok := r.parents[id]; ok {
+		var err error
+		if parent, err = r.stack(p, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	s := parent
+	if n := r.nodes[id]; n.CallFrame.FunctionName != rootFunctionName {
+		s = make([]uint64, 0, len(parent)+1)
+		s = append(s, location(r.builder, n.CallFrame))
+		s = append(s, parent...)
+	}
+	r.stacks[id] = s
+	return s, nil
+}
@@ -0,0 +1,75 @@
+package cpuprofile
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/og/convert/testhelper"
+	"github.com/grafana/pyroscope/pkg/og/ingestion"
+)
+
+func Test_ParseToPprof(t *testing.T) {
+	data, err := os.ReadFile("testdata/simple.cpuprofile")
+	require.NoError(t, err)
+	req := testhelper.ParseToPprof(t, &RawProfile{RawData: data}, "nodespy")
+	require.Len(t, req.Series, 1)
+	require.Equal(t, "process_cpu", phlaremodel.Labels(req.Series[0].Labels).Get("__name__"))
+
+	p := req.Series[0].Samples[0].Profile
+	require.Equal(t, int64(7*time.Millisecond), p.DurationNanos)
+	require.Equal(t, int64(7*time.Millisecond/6), p.Period)
+	require.Equal(t, map[string][]int64{
+		"main":                {int64(time.Millisecond)},
+		"main;(anonymous)":    {int64(3 * time.Millisecond)},
+		"main;compute":        {int64(time.Millisecond)},
+		"(garbage collector)": {int64(time.Millisecond)},
+	}, testhelper.Stacks(p.Profile, ""))
+
+	fn := p.Function[1]
+	require.Equal(t, "(anonymous)", p.StringTable[fn.Name])
+	require.Equal(t, "file:///app/index.js", p.StringTable[fn.Filename])
+	require.Equal(t, int64(20), fn.StartLine)
+}
+
+func Test_Convert_HitCount(t *testing.T) {
+	cp := &cpuProfile{
+		Nodes: []node{
+			{ID: 1, CallFrame: callFrame{FunctionName: "(root)"}, Children: []int64{2}},
+			{ID: 2, CallFrame: callFrame{FunctionName: "main", LineNumber: -1}, HitCount: 3},
+		},
+	}
+	p, err := toPprof(cp, ingestion.Metadata{SampleRate: 100})
+	require.NoError(t, err)
+	require.Equal(t, []*profilev1.Sample{{LocationId: []uint64{1}, Value: []int64{30 * int64(time.Millisecond)}}}, p.Sample)
+	require.Equal(t, int64(0), p.Function[0].StartLine)
+}
+
+func Test_Convert_Invalid(t *testing.T) {
+	for name, cp := range map[string]*cpuProfile{
+		"unknown child": {
+			Nodes: []node{{ID: 1, Children: []int64{2}}},
+		},
+		"unknown sample": {
+			Nodes:      []node{{ID: 1}},
+			Samples:    []int64{2},
+			TimeDeltas: []int64{1},
+		},
+		"time deltas": {
+			Nodes:   []node{{ID: 1}},
+			Samples: []int64{1},
+		},
+		"cycle": {
+			Nodes: []node{{ID: 1, Children: []int64{2}}, {ID: 2, HitCount: 1, Children: []int64{1}}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := toPprof(cp, ingestion.Metadata{})
+			require.Error(t, err)
+		})
+	}
+}
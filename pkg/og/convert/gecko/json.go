@@ -0,0 +1,79 @@
+package gecko
+
+// Description of the processed profile JSON of the Firefox Profiler, as
+// produced by the profiler itself (when uploaded or downloaded) and tools
+// like samply. Tables are stored as structs of arrays; null references
+// are represented with nil pointers.
+// See https://github.com/firefox-devtools/profiler/blob/main/docs-developer/CHANGELOG-formats.md
+
+type geckoProfile struct {
+	Meta    meta     `json:"meta"`
+	Threads []thread `json:"threads"`
+	// Newer versions of the format share the strings among the threads.
+	Shared struct {
+		StringArray []string `json:"stringArray"`
+	} `json:"shared"`
+}
+
+type meta struct {
+	// Sampling interval in milliseconds.
+	Interval float64 `json:"interval"`
+}
+
+type thread struct {
+	Name       string     `json:"name"`
+	Samples    samples    `json:"samples"`
+	StackTable stackTable `json:"stackTable"`
+	FrameTable frameTable `json:"frameTable"`
+	FuncTable  funcTable  `json:"funcTable"`
+	// Older versions of the format store the strings per thread.
+	StringArray []string `json:"stringArray"`
+}
+
+// The raw Gecko format, returned by the profiler of Firefox itself,
+// stores the tables as a schema and an array of rows, and is not
+// supported: the schema is only decoded to detect it.
+type rawTable struct {
+	Schema map[string]int `json:"schema"`
+}
+
+type samples struct {
+	rawTable
+	Stack []*int64 `json:"stack"`
+	// Sample timestamps are given either as absolute
+	// values or as deltas, both in milliseconds.
+	Time       []float64 `json:"time"`
+	TimeDeltas []float64 `json:"timeDeltas"`
+	Weight     []float64 `json:"weight"`
+	WeightType string    `json:"weightType"`
+}
+
+type stackTable struct {
+	rawTable
+	Frame  []int64  `json:"frame"`
+	Prefix []*int64 `json:"prefix"`
+}
+
+type frameTable struct {
+	rawTable
+	Func []int64  `json:"func"`
+	Line []*int64 `json:"line"`
+}
+
+type funcTable struct {
+	Name       []int64  `json:"name"`
+	FileName   []*int64 `json:"fileName"`
+	LineNumber []*int64 `json:"lineNumber"`
+}
+
+// isRaw reports whether the thread tables are in the raw Gecko format.
+func (t *thread) isRaw() bool {
+	return t.Samples.Schema != nil || t.StackTable.Schema != nil || t.FrameTable.Schema != nil
+}
+
+// Weight types of the samples. By default, samples are
+// weighted in the number of sampling intervals.
+const (
+	weightTypeTracingMs = "tracing-ms"
+	weightTypeBytes     = "bytes"
+)
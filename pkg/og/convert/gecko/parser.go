@@ -0,0 +1,293 @@
+package gecko
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	distributormodel "github.com/grafana/pyroscope/pkg/distributor/model"
+	"github.com/grafana/pyroscope/pkg/og/convert"
+	"github.com/grafana/pyroscope/pkg/og/ingestion"
+	"github.com/grafana/pyroscope/pkg/og/storage"
+	"github.com/grafana/pyroscope/pkg/pprof"
+)
+
+// LabelNameThread is the sample label holding the name of the thread.
+const LabelNameThread = "thread_name"
+
+// RawProfile implements ingestion.RawProfile for the processed profile
+// format of the Firefox Profiler.
+type RawProfile struct {
+	RawData []byte
+	// ThreadLabels enables labeling of the samples
+	// with the name of the thread they belong to.
+	ThreadLabels bool
+}
+
+func (p *RawProfile) Bytes() ([]byte, error) { return p.RawData, nil }
+
+func (p *RawProfile) ContentType() string { return "application/json" }
+
+func (p *RawProfile) Parse(context.Context, storage.Putter, storage.MetricsExporter, ingestion.Metadata) error {
+	return fmt.Errorf("parsing gecko profile to tree/storage.Putter is not supported")
+}
+
+func (p *RawProfile) ParseToPprof(_ context.Context, md ingestion.Metadata) (*distributormodel.PushRequest, error) {
+	var gp geckoProfile
+	if err := json.Unmarshal(p.RawData, &gp); err != nil {
+		return nil, err
+	}
+	name, err := profileName(&gp)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := toPprof(&gp, md, p.ThreadLabels)
+	if err != nil {
+		return nil, err
+	}
+	return &distributormodel.PushRequest{
+		RawProfileSize: len(p.RawData),
+		RawProfileType: distributormodel.RawProfileTypeGecko,
+		Series: []*distributormodel.ProfileSeries{{
+			Labels: convert.SeriesLabels(md, name),
+			Samples: []*distributormodel.ProfileSample{{
+				Profile: pprof.RawFromProto(profile),
+			}},
+		}},
+	}, nil
+}
+
+// profileName returns the name of the profile: allocation
+// profiles are weighted in bytes, anything else is CPU time.
+// The samples of all the threads must be weighted in the same
+// unit, as they are merged into a single profile.
+func profileName(gp *geckoProfile) (string, error) {
+	var name string
+	for i := range gp.Threads {
+		t := &gp.Threads[i]
+		if len(t.Samples.Stack) == 0 {
+			continue
+		}
+		n := "process_cpu"
+		if t.Samples.WeightType == weightTypeBytes {
+			n = "memory"
+		}
+		if name != "" && n != name {
+			return "", fmt.Errorf("thread %d (%s): weight type %q can't be mixed with the weight types of the other threads", i, t.Name, t.Samples.WeightType)
+		}
+		name = n
+	}
+	if name == "" {
+		name = "process_cpu"
+	}
+	return name, nil
+}
+
+// toPprof converts the samples of all the threads to a single pprof
+// profile. Samples are weighted with the sampling interval, unless
+// the weight type is tracing-ms or bytes.
+func toPprof(gp *geckoProfile, md ingestion.Metadata, threadLabels bool) (*profilev1.Profile, error) {
+	if len(gp.Threads) == 0 {
+		return nil, fmt.Errorf("no threads found")
+	}
+	for i := range gp.Threads {
+		if t := &gp.Threads[i]; t.isRaw() {
+			return nil, fmt.Errorf("thread %d (%s): the raw gecko format is not supported, the profile must be processed by the Firefox Profiler", i, t.Name)
+		}
+	}
+	name, err := profileName(gp)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := time.Duration(gp.Meta.Interval * float64(time.Millisecond))
+	if interval <= 0 {
+		interval = time.Second / time.Duration(convert.SampleRate(md))
+	}
+
+	b := convert.NewProfileBuilder()
+	b.Profile.TimeNanos = md.StartTime.UnixNano()
+	valueType := &profilev1.ValueType{Type: b.String("cpu"), Unit: b.String("nanoseconds")}
+	if name == "memory" {
+		valueType = &profilev1.ValueType{Type: b.String("alloc_space"), Unit: b.String("bytes")}
+	} else {
+		b.Profile.Period = int64(interval)
+	}
+	b.Profile.SampleType = []*profilev1.ValueType{valueType}
+	b.Profile.PeriodType = valueType
+
+	var minTime, maxTime float64 = math.MaxFloat64, 0
+	for i := range gp.Threads {
+		t := &gp.Threads[i]
+		strings := t.StringArray
+		if len(strings) == 0 {
+			strings = gp.Shared.StringArray
+		}
+		c := &threadConverter{
+			builder: b,
+			thread:  t,
+			strings: strings,
+			stacks:  make(map[int64][]uint64),
+		}
+		if threadLabels && t.Name != "" {
+			c.label = []*profilev1.Label{{Key: b.String(LabelNameThread), Str: b.String(t.Name)}}
+		}
+		if err := c.convert(interval); err != nil {
+			return nil, fmt.Errorf("thread %d (%s): %w", i, t.Name, err)
+		}
+		if n := len(c.times); n > 0 {
+			minTime = math.Min(minTime, c.times[0])
+			maxTime = math.Max(maxTime, c.times[n-1])
+		}
+	}
+	if maxTime > minTime {
+		b.Profile.DurationNanos = int64((maxTime - minTime) * float64(time.Millisecond))
+	}
+	return b.Profile, nil
+}
+
+type threadConverter struct {
+	builder *convert.ProfileBuilder
+	thread  *thread
+	strings []string
+	label   []*profilev1.Label
+	// Resolved stacks of the samples by the stack table index.
+	stacks map[int64][]uint64
+	// Sample timestamps in milliseconds.
+	times []float64
+}
+
+func (c *threadConverter) convert(interval time.Duration) error {
+	s := &c.thread.Samples
+	if s.Weight != nil && len(s.Weight) != len(s.Stack) {
+		return fmt.Errorf("samples: weight length %d != %d", len(s.Weight), len(s.Stack))
+	}
+	if err := c.resolveTimes(); err != nil {
+		return err
+	}
+	st := &c.thread.StackTable
+	if len(st.Prefix) != len(st.Frame) {
+		return fmt.Errorf("stackTable: prefix length %d != %d", len(st.Prefix), len(st.Frame))
+	}
+	for i, stack := range s.Stack {
+		if stack == nil {
+			// The thread is idle.
+			continue
+		}
+		weight := 1.0
+		if s.Weight != nil {
+			weight = s.Weight[i]
+		}
+		var v int64
+		switch s.WeightType {
+		case weightTypeBytes:
+			v = int64(weight)
+		case weightTypeTracingMs:
+			v = int64(weight * float64(time.Millisecond))
+		default:
+			v = int64(weight * float64(interval))
+		}
+		if v <= 0 {
+			continue
+		}
+		locs, err := c.stack(*stack)
+		if err != nil {
+			return fmt.Errorf("sample %d: %w", i, err)
+		}
+		c.builder.AddSample(locs, c.label, v)
+	}
+	return nil
+}
+
+func (c *threadConverter) resolveTimes() error {
+	s := &c.thread.Samples
+	switch {
+	case s.Time != nil:
+		if len(s.Time) != len(s.Stack) {
+			return fmt.Errorf("samples: time length %d != %d", len(s.Time), len(s.Stack))
+		}
+		c.times = s.Time
+	case s.TimeDeltas != nil:
+		if len(s.TimeDeltas) != len(s.Stack) {
+			return fmt.Errorf("samples: timeDeltas length %d != %d", len(s.TimeDeltas), len(s.Stack))
+		}
+		c.times = make([]float64, len(s.TimeDeltas))
+		var t float64
+		for i, d := range s.TimeDeltas {
+			t += d
+			c.times[i] = t
+		}
+	}
+	return nil
+}
+
+// stack resolves the stack of the stack table, leaf first. The prefix
+// of a stack always precedes it in the table, which guarantees that
+// the stacks are acyclic.
+func (c *threadConverter) stack(i int64) ([]uint64, error) {
+	st := &c.thread.StackTable
+	if i < 0 || i >= int64(len(st.Frame)) {
+		return nil, fmt.Errorf("stack %d not found", i)
+	}
+	if s, ok := c.stacks[i]; ok {
+		return s, nil
+	}
+	var s []uint64
+	for j := i; ; {
+		loc, err := c.location(st.Frame[j])
+		if err != nil {
+			return nil, fmt.Errorf("stack %d: %w", j, err)
+		}
+		s = append(s, loc)
+		p := st.Prefix[j]
+		if p == nil {
+			break
+		}
+		if *p < 0 || *p >= j {
+			return nil, fmt.Errorf("stack %d: invalid prefix %d", j, *p)
+		}
+		j = *p
+	}
+	c.stacks[i] = s
+	return s, nil
+}
+
+func (c *threadConverter) location(frame int64) (uint64, error) {
+	ft := &c.thread.FrameTable
+	if frame < 0 || frame >= int64(len(ft.Func)) {
+		return 0, fmt.Errorf("frame %d not found", frame)
+	}
+	fn := ft.Func[frame]
+	t := &c.thread.FuncTable
+	if fn < 0 || fn >= int64(len(t.Name)) {
+		return 0, fmt.Errorf("frame %d: func %d not found", frame, fn)
+	}
+	name, err := c.string(t.Name[fn])
+	if err != nil {
+		return 0, fmt.Errorf("func %d: %w", fn, err)
+	}
+	l := convert.Location{Function: convert.Function{Name: name}}
+	if fn < int64(len(t.FileName)) && t.FileName[fn] != nil {
+		if l.Filename, err = c.string(*t.FileName[fn]); err != nil {
+			return 0, fmt.Errorf("func %d: %w", fn, err)
+		}
+	}
+	if fn < int64(len(t.LineNumber)) && t.LineNumber[fn] != nil {
+		l.StartLine = *t.LineNumber[fn]
+	}
+	l.Line = l.StartLine
+	if frame < int64(len(ft.Line)) && ft.Line[frame] != nil {
+		l.Line = *ft.Line[frame]
+	}
+	return c.builder.Location(l), nil
+}
+
+func (c *threadConverter) string(i int64) (string, error) {
+	if i < 0 || i >= int64(len(c.strings)) {
+		return "", fmt.Errorf("string %d not found", i)
+	}
+	return c.strings[i], nil
+}
@@ -0,0 +1,164 @@
+package gecko
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/og/convert/testhelper"
+	"github.com/grafana/pyroscope/pkg/og/ingestion"
+	"github.com/grafana/pyroscope/pkg/og/storage/segment"
+)
+
+func Test_ParseToPprof(t *testing.T) {
+	data, err := os.ReadFile("testdata/simple.json")
+	require.NoError(t, err)
+	req := testhelper.ParseToPprof(t, &RawProfile{RawData: data}, "samply")
+	require.Len(t, req.Series, 1)
+	require.Equal(t, "process_cpu", phlaremodel.Labels(req.Series[0].Labels).Get("__name__"))
+
+	p := req.Series[0].Samples[0].Profile
+	require.Equal(t, int64(3*time.Millisecond), p.DurationNanos)
+	require.Equal(t, int64(time.Millisecond), p.Period)
+	require.Equal(t, map[string][]int64{
+		"main":      {int64(time.Millisecond)},
+		"main;work": {int64(2 * time.Millisecond)},
+		"run":       {int64(3 * time.Millisecond)},
+	}, testhelper.Stacks(p.Profile, ""))
+
+	fn := p.Function[1]
+	require.Equal(t, "work", p.StringTable[fn.Name])
+	require.Equal(t, "app.js", p.StringTable[fn.Filename])
+	require.Equal(t, int64(10), fn.StartLine)
+	require.Equal(t, int64(12), p.Location[1].Line[0].Line)
+}
+
+func Test_ParseToPprof_ThreadLabels(t *testing.T) {
+	data, err := os.ReadFile("testdata/simple.json")
+	require.NoError(t, err)
+	req := testhelper.ParseToPprof(t, &RawProfile{RawData: data, ThreadLabels: true}, "samply")
+
+	p := req.Series[0].Samples[0].Profile.Profile
+	require.Equal(t, map[string][]int64{
+		"GeckoMain/main":      {int64(time.Millisecond)},
+		"GeckoMain/main;work": {int64(2 * time.Millisecond)},
+		"Worker/run":          {int64(3 * time.Millisecond)},
+	}, testhelper.Stacks(p, LabelNameThread))
+}
+
+func Test_Convert_Weights(t *testing.T) {
+	gp := &geckoProfile{
+		Threads: []thread{{
+			Samples: samples{
+				Stack:      []*int64{ptr(0), ptr(0)},
+				Weight:     []float64{512, 1024},
+				WeightType: weightTypeBytes,
+			},
+			StackTable:  stackTable{Frame: []int64{0}, Prefix: []*int64{nil}},
+			FrameTable:  frameTable{Func: []int64{0}},
+			FuncTable:   funcTable{Name: []int64{0}},
+			StringArray: []string{"malloc"},
+		}},
+	}
+	name, err := profileName(gp)
+	require.NoError(t, err)
+	require.Equal(t, "memory", name)
+	p, err := toPprof(gp, ingestion.Metadata{}, false)
+	require.NoError(t, err)
+	require.Equal(t, "alloc_space", p.StringTable[p.SampleType[0].Type])
+	require.Equal(t, []*profilev1.Sample{{LocationId: []uint64{1}, Value: []int64{1536}}}, p.Sample)
+
+	gp.Threads[0].Samples.WeightType = weightTypeTracingMs
+	gp.Threads[0].Samples.Weight = []float64{0.5, 1}
+	p, err = toPprof(gp, ingestion.Metadata{}, false)
+	require.NoError(t, err)
+	require.Equal(t, "cpu", p.StringTable[p.SampleType[0].Type])
+	require.Equal(t, int64(10*time.Millisecond), p.Period)
+	require.Equal(t, []int64{int64(1500 * time.Microsecond)}, p.Sample[0].Value)
+}
+
+func Test_Convert_MixedWeightTypes(t *testing.T) {
+	th := func(weightType string, stack ...*int64) thread {
+		return thread{
+			Samples:     samples{Stack: stack, WeightType: weightType},
+			StackTable:  stackTable{Frame: []int64{0}, Prefix: []*int64{nil}},
+			FrameTable:  frameTable{Func: []int64{0}},
+			FuncTable:   funcTable{Name: []int64{0}},
+			StringArray: []string{"main"},
+		}
+	}
+	// Threads without samples are ignored.
+	gp := &geckoProfile{Threads: []thread{th(weightTypeBytes, ptr(0)), th("samples")}}
+	name, err := profileName(gp)
+	require.NoError(t, err)
+	require.Equal(t, "memory", name)
+
+	gp = &geckoProfile{Threads: []thread{th(weightTypeTracingMs, ptr(0)), th("", ptr(0))}}
+	name, err = profileName(gp)
+	require.NoError(t, err)
+	require.Equal(t, "process_cpu", name)
+
+	gp.Threads = append(gp.Threads, th(weightTypeBytes, ptr(0)))
+	_, err = profileName(gp)
+	require.Error(t, err)
+	_, err = toPprof(gp, ingestion.Metadata{}, false)
+	require.Error(t, err)
+}
+
+func Test_ParseToPprof_Raw(t *testing.T) {
+	key, err := segment.ParseKey("my-app")
+	require.NoError(t, err)
+	md := ingestion.Metadata{Key: key}
+	for expected, data := range map[string]string{
+		"raw gecko format is not supported": `{"meta":{"interval":1,"version":27},"threads":[{"name":"GeckoMain",` +
+			`"samples":{"schema":{"stack":0,"time":1},"data":[[1,0]]},` +
+			`"stackTable":{"schema":{"prefix":0,"frame":1},"data":[[null,0],[0,1]]},` +
+			`"frameTable":{"schema":{"location":0},"data":[[0],[1]]},` +
+			`"stringTable":["main","work"]}]}`,
+		"no threads found": `{"meta":{"interval":1}}`,
+	} {
+		t.Run(expected, func(t *testing.T) {
+			_, err := (&RawProfile{RawData: []byte(data)}).ParseToPprof(context.Background(), md)
+			require.ErrorContains(t, err, expected)
+		})
+	}
+}
+
+func Test_Convert_Invalid(t *testing.T) {
+	valid := func() thread {
+		return thread{
+			Samples:     samples{Stack: []*int64{ptr(1)}},
+			StackTable:  stackTable{Frame: []int64{0, 0}, Prefix: []*int64{nil, ptr(0)}},
+			FrameTable:  frameTable{Func: []int64{0}},
+			FuncTable:   funcTable{Name: []int64{0}},
+			StringArray: []string{"main"},
+		}
+	}
+	_, err := toPprof(&geckoProfile{Threads: []thread{valid()}}, ingestion.Metadata{}, false)
+	require.NoError(t, err)
+
+	for name, fn := range map[string]func(*thread){
+		"unknown stack":  func(t *thread) { t.Samples.Stack[0] = ptr(2) },
+		"unknown frame":  func(t *thread) { t.StackTable.Frame[1] = 1 },
+		"unknown func":   func(t *thread) { t.FrameTable.Func[0] = 1 },
+		"unknown string": func(t *thread) { t.FuncTable.Name[0] = 1 },
+		"prefix cycle":   func(t *thread) { t.StackTable.Prefix[1] = ptr(1) },
+		"prefix length":  func(t *thread) { t.StackTable.Prefix = nil },
+		"time length":    func(t *thread) { t.Samples.Time = []float64{1, 2} },
+		"weight length":  func(t *thread) { t.Samples.Weight = []float64{} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			th := valid()
+			fn(&th)
+			_, err := toPprof(&geckoProfile{Threads: []thread{th}}, ingestion.Metadata{}, false)
+			require.Error(t, err)
+		})
+	}
+}
+
+func ptr(v int64) *int64 { return &v }
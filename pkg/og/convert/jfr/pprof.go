@@ -5,10 +5,10 @@ import (
 
 	"github.com/grafana/jfr-parser/parser"
 	"github.com/grafana/jfr-parser/parser/types"
-	"github.com/prometheus/prometheus/model/labels"
 
 	v1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
-	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/og/convert"
+	"github.com/grafana/pyroscope/pkg/og/ingestion"
 	"github.com/grafana/pyroscope/pkg/og/storage"
 	"github.com/grafana/pyroscope/pkg/og/storage/segment"
 	"github.com/grafana/pyroscope/pkg/og/storage/tree"
@@ -43,10 +43,11 @@ func newJfrPprofBuilders(p *parser.Parser, jfrLabels *LabelsSnapshot, piOriginal
 	res := &jfrPprofBuilders{
 		timeNanos:     st,
 		durationNanos: et - st,
-		labels:        make([]*v1.LabelPair, 0, len(piOriginal.Key.Labels())+5),
-		period:        period,
-		appName:       piOriginal.Key.AppName(),
-		spyName:       piOriginal.SpyName,
+		metadata: ingestion.Metadata{
+			Key:     piOriginal.Key,
+			SpyName: piOriginal.SpyName,
+		},
+		period: period,
 
 		parser: p,
 		cache: tree.NewLabelsCache[testhelper.ProfileBuilder](func() *testhelper.ProfileBuilder {
@@ -56,16 +57,6 @@ func newJfrPprofBuilders(p *parser.Parser, jfrLabels *LabelsSnapshot, piOriginal
 		baseline:  make(map[uint64]labelsWithHash),
 		jfrLabels: jfrLabels,
 	}
-	for k, v := range piOriginal.Key.Labels() {
-		if !phlaremodel.IsLabelAllowedForIngestion(k) {
-			continue
-		}
-		res.labels = append(res.labels, &v1.LabelPair{
-			Name:  k,
-			Value: v,
-		})
-	}
-
 	return res
 }
 
@@ -76,9 +67,7 @@ type labelsWithHash struct {
 type jfrPprofBuilders struct {
 	timeNanos     int64
 	durationNanos int64
-	labels        []*v1.LabelPair
-	appName       string
-	spyName       string
+	metadata      ingestion.Metadata
 
 	parser *parser.Parser
 	cache  tree.LabelsCache[testhelper.ProfileBuilder]
@@ -200,45 +189,23 @@ func (b *jfrPprofBuilders) build(event string) *model.PushRequest {
 				e.Value.AddSampleType("live", "count")
 				metric = "memory"
 			}
-			ls := make([]*v1.LabelPair, 0, len(e.Labels)+len(b.labels)+5)
-			ls = append(ls, &v1.LabelPair{
-				Name:  labels.MetricName,
-				Value: metric,
-			}, &v1.LabelPair{
-				Name:  phlaremodel.LabelNameDelta,
-				Value: "false",
-			}, &v1.LabelPair{
+			extra := make([]*v1.LabelPair, 0, len(e.Labels)+1)
+			extra = append(extra, &v1.LabelPair{
 				Name:  "jfr_event",
 				Value: event,
-			}, &v1.LabelPair{
-				Name:  "pyroscope_spy",
-				Value: b.spyName,
 			})
-			for _, v := range b.labels {
-				ls = append(ls, v)
-			}
 			for _, label := range e.Labels {
 				ks, ok := b.jfrLabels.Strings[label.Key]
 				if !ok {
 					continue
 				}
 				vs, ok := b.jfrLabels.Strings[label.Str]
-				ls = append(ls, &v1.LabelPair{
+				extra = append(extra, &v1.LabelPair{
 					Name:  ks,
 					Value: vs,
 				})
 			}
-			serviceNameLabelName := "service_name"
-			for _, label := range ls {
-				if label.Name == serviceNameLabelName {
-					serviceNameLabelName = "app_name"
-					break
-				}
-			}
-			ls = append(ls, &v1.LabelPair{
-				Name:  serviceNameLabelName,
-				Value: b.appName,
-			})
+			ls := convert.SeriesLabels(b.metadata, metric, extra...)
 			profiles = append(profiles, &model.ProfileSeries{
 				Labels: ls,
 				Samples: []*model.ProfileSample{
@@ -0,0 +1,49 @@
+package convert
+
+import (
+	"github.com/prometheus/prometheus/model/labels"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/og/ingestion"
+)
+
+// SeriesLabels returns the series labels of a profile ingested with the
+// metadata: the metric name, the spy name, the extra labels and the key
+// labels allowed for ingestion. The application name is added as the
+// service_name label, or as app_name, if service_name is already set.
+func SeriesLabels(md ingestion.Metadata, metricName string, extra ...*typesv1.LabelPair) []*typesv1.LabelPair {
+	keyLabels := md.Key.Labels()
+	ls := make([]*typesv1.LabelPair, 0, len(keyLabels)+len(extra)+4)
+	ls = append(ls, &typesv1.LabelPair{
+		Name:  labels.MetricName,
+		Value: metricName,
+	}, &typesv1.LabelPair{
+		Name:  phlaremodel.LabelNameDelta,
+		Value: "false",
+	}, &typesv1.LabelPair{
+		Name:  "pyroscope_spy",
+		Value: md.SpyName,
+	})
+	ls = append(ls, extra...)
+	for k, v := range keyLabels {
+		if !phlaremodel.IsLabelAllowedForIngestion(k) {
+			continue
+		}
+		ls = append(ls, &typesv1.LabelPair{
+			Name:  k,
+			Value: v,
+		})
+	}
+	serviceNameLabelName := phlaremodel.LabelNameServiceName
+	for _, l := range ls {
+		if l.Name == phlaremodel.LabelNameServiceName {
+			serviceNameLabelName = "app_name"
+			break
+		}
+	}
+	return append(ls, &typesv1.LabelPair{
+		Name:  serviceNameLabelName,
+		Value: md.Key.AppName(),
+	})
+}
@@ -0,0 +1,43 @@
+package convert
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/og/ingestion"
+	"github.com/grafana/pyroscope/pkg/og/storage/segment"
+)
+
+var _ = Describe("SeriesLabels", func() {
+	seriesLabels := func(key string, extra ...*typesv1.LabelPair) phlaremodel.Labels {
+		k, err := segment.ParseKey(key)
+		Expect(err).ToNot(HaveOccurred())
+		return SeriesLabels(ingestion.Metadata{Key: k, SpyName: "spy"}, "process_cpu", extra...)
+	}
+
+	It("adds the application name as service_name", func() {
+		ls := seriesLabels("app{env=dev,__private__=1}", &typesv1.LabelPair{Name: "event", Value: "cycles"})
+		Expect(ls.Get("__name__")).To(Equal("process_cpu"))
+		Expect(ls.Get(phlaremodel.LabelNameDelta)).To(Equal("false"))
+		Expect(ls.Get("pyroscope_spy")).To(Equal("spy"))
+		Expect(ls.Get("event")).To(Equal("cycles"))
+		Expect(ls.Get("env")).To(Equal("dev"))
+		Expect(ls.Get("__private__")).To(BeEmpty())
+		Expect(ls.Get(phlaremodel.LabelNameServiceName)).To(Equal("app"))
+		Expect(ls.Get("app_name")).To(BeEmpty())
+	})
+
+	It("adds the application name as app_name, if service_name is set", func() {
+		ls := seriesLabels("app{service_name=svc}")
+		Expect(ls.Get(phlaremodel.LabelNameServiceName)).To(Equal("svc"))
+		Expect(ls.Get("app_name")).To(Equal("app"))
+	})
+
+	It("adds the application name as app_name, if an extra label is service_name", func() {
+		ls := seriesLabels("app", &typesv1.LabelPair{Name: phlaremodel.LabelNameServiceName, Value: "svc"})
+		Expect(ls.Get(phlaremodel.LabelNameServiceName)).To(Equal("svc"))
+		Expect(ls.Get("app_name")).To(Equal("app"))
+	})
+})
@@ -0,0 +1,610 @@
+package perf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Parsing of the binary perf.data file format produced by `perf record`.
+// See https://github.com/torvalds/linux/blob/master/tools/perf/Documentation/perf.data-file-format.txt
+
+const (
+	fileHeaderSize   = 104
+	fileSectionSize  = 16
+	eventHeaderSize  = 8
+	minEventAttrSize = 64
+
+	// Feature bits of the file header.
+	featureBuildID   = 2
+	featureEventDesc = 12
+	maxFeatures      = 256
+)
+
+// Record types.
+const (
+	recordMmap   = 1
+	recordComm   = 3
+	recordFork   = 7
+	recordSample = 9
+	recordMmap2  = 10
+)
+
+// Misc field flags of the record header.
+const (
+	miscCPUModeMask    = 7
+	miscKernel         = 1
+	miscCommExec       = 1 << 13
+	miscMmapBuildID    = 1 << 14
+	miscBuildIDSize    = 1 << 15
+	maxBuildIDSize     = 20
+	kernelPID          = ^uint32(0)
+	kernelMmapFilename = "[kernel.kallsyms]"
+)
+
+// Sample type bits of the event attributes.
+const (
+	sampleIP         = 1 << 0
+	sampleTID        = 1 << 1
+	sampleTime       = 1 << 2
+	sampleAddr       = 1 << 3
+	sampleRead       = 1 << 4
+	sampleCallchain  = 1 << 5
+	sampleID         = 1 << 6
+	sampleCPU        = 1 << 7
+	samplePeriod     = 1 << 8
+	sampleStreamID   = 1 << 9
+	sampleIdentifier = 1 << 16
+)
+
+// Read format bits of the event attributes.
+const (
+	readTotalTimeEnabled = 1 << 0
+	readTotalTimeRunning = 1 << 1
+	readID               = 1 << 2
+	readGroup            = 1 << 3
+	readLost             = 1 << 4
+)
+
+// Callchain context markers.
+const (
+	contextHV          = ^uint64(32 - 1)
+	contextKernel      = ^uint64(128 - 1)
+	contextUser        = ^uint64(512 - 1)
+	contextGuest       = ^uint64(2048 - 1)
+	contextGuestKernel = ^uint64(2176 - 1)
+	contextGuestUser   = ^uint64(2560 - 1)
+	contextMax         = ^uint64(4095 - 1)
+)
+
+const attrFlagFreq = 1 << 10
+
+type fileSection struct {
+	offset uint64
+	size   uint64
+}
+
+// eventAttr is a subset of perf_event_attr.
+type eventAttr struct {
+	typ          uint32
+	config       uint64
+	samplePeriod uint64
+	sampleType   uint64
+	readFormat   uint64
+	flags        uint64
+}
+
+type event struct {
+	attr eventAttr
+	name string
+	ids  []uint64
+}
+
+type dataFile struct {
+	order    binary.ByteOrder
+	data     []byte
+	events   []*event
+	byID     map[uint64]*event
+	records  []byte
+	buildIDs map[string]string
+}
+
+// IsPerfData reports whether the buffer holds a perf.data file.
+func IsPerfData(buf []byte) bool {
+	return len(buf) >= 8 && (string(buf[:8]) == "PERFILE2" || string(buf[:8]) == "2ELIFREP")
+}
+
+func parseDataFile(data []byte) (*dataFile, error) {
+	if len(data) < 16 || !IsPerfData(data) {
+		return nil, fmt.Errorf("not a perf.data file")
+	}
+	f := &dataFile{
+		order:    binary.LittleEndian,
+		data:     data,
+		byID:     make(map[uint64]*event),
+		buildIDs: make(map[string]string),
+	}
+	if string(data[:8]) != "PERFILE2" {
+		f.order = binary.BigEndian
+	}
+	if f.order.Uint64(data[8:]) != fileHeaderSize {
+		// The header of files written in the pipe mode is 16 bytes.
+		return nil, fmt.Errorf("unsupported perf.data header size %d", f.order.Uint64(data[8:]))
+	}
+	if len(data) < fileHeaderSize {
+		return nil, fmt.Errorf("perf.data header is truncated")
+	}
+	attrSize := f.order.Uint64(data[16:])
+	attrs := f.section(data[24:])
+	records := f.section(data[40:])
+	var err error
+	if err = f.parseAttrs(attrs, attrSize); err != nil {
+		return nil, err
+	}
+	if f.records, err = f.sectionData(records); err != nil {
+		return nil, fmt.Errorf("data section: %w", err)
+	}
+	if err = f.parseFeatures(data[72:104], records.offset+records.size); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *dataFile) section(b []byte) fileSection {
+	return fileSection{offset: f.order.Uint64(b), size: f.order.Uint64(b[8:])}
+}
+
+func (f *dataFile) sectionData(s fileSection) ([]byte, error) {
+	if s.offset > uint64(len(f.data)) || s.size > uint64(len(f.data))-s.offset {
+		return nil, fmt.Errorf("section [%d, %d) is out of range", s.offset, s.offset+s.size)
+	}
+	return f.data[s.offset : s.offset+s.size], nil
+}
+
+func (f *dataFile) parseAttrs(s fileSection, attrSize uint64) error {
+	b, err := f.sectionData(s)
+	if err != nil {
+		return fmt.Errorf("attrs section: %w", err)
+	}
+	if attrSize < minEventAttrSize+fileSectionSize {
+		return fmt.Errorf("invalid attr size %d", attrSize)
+	}
+	for ; uint64(len(b)) >= attrSize; b = b[attrSize:] {
+		e := &event{attr: f.parseAttr(b)}
+		ids, err := f.sectionData(f.section(b[attrSize-fileSectionSize:]))
+		if err != nil {
+			return fmt.Errorf("attr ids section: %w", err)
+		}
+		for ; len(ids) >= 8; ids = ids[8:] {
+			id := f.order.Uint64(ids)
+			e.ids = append(e.ids, id)
+			f.byID[id] = e
+		}
+		e.name = defaultEventName(e.attr)
+		f.events = append(f.events, e)
+	}
+	if len(f.events) == 0 {
+		return fmt.Errorf("no events found")
+	}
+	return nil
+}
+
+func (f *dataFile) parseAttr(b []byte) eventAttr {
+	return eventAttr{
+		typ:          f.order.Uint32(b),
+		config:       f.order.Uint64(b[8:]),
+		samplePeriod: f.order.Uint64(b[16:]),
+		sampleType:   f.order.Uint64(b[24:]),
+		readFormat:   f.order.Uint64(b[32:]),
+		flags:        f.order.Uint64(b[40:]),
+	}
+}
+
+// parseFeatures parses the feature sections that follow the data section,
+// one per feature bit set in the header. Unknown features are skipped.
+func (f *dataFile) parseFeatures(bits []byte, offset uint64) error {
+	for bit := 0; bit < maxFeatures; bit++ {
+		// The bitmap is an array of 64-bit words.
+		if f.order.Uint64(bits[bit/64*8:])&(1<<(bit%64)) == 0 {
+			continue
+		}
+		if offset+fileSectionSize > uint64(len(f.data)) {
+			return fmt.Errorf("feature sections are truncated")
+		}
+		s := f.section(f.data[offset:])
+		offset += fileSectionSize
+		b, err := f.sectionData(s)
+		if err != nil {
+			return fmt.Errorf("feature %d section: %w", bit, err)
+		}
+		switch bit {
+		case featureBuildID:
+			err = f.parseBuildIDs(b)
+		case featureEventDesc:
+			err = f.parseEventDesc(b)
+		}
+		if err != nil {
+			return fmt.Errorf("feature %d section: %w", bit, err)
+		}
+	}
+	return nil
+}
+
+func (f *dataFile) parseBuildIDs(b []byte) error {
+	const buildIDOffset = eventHeaderSize + 4
+	for len(b) > 0 {
+		if len(b) < eventHeaderSize {
+			return fmt.Errorf("build id record is truncated")
+		}
+		misc := f.order.Uint16(b[4:])
+		size := int(f.order.Uint16(b[6:]))
+		if size < buildIDOffset+24 || size > len(b) {
+			return fmt.Errorf("invalid build id record size %d", size)
+		}
+		r := b[:size]
+		b = b[size:]
+		n := maxBuildIDSize
+		if misc&miscBuildIDSize != 0 {
+			if n = int(r[buildIDOffset+maxBuildIDSize]); n > maxBuildIDSize {
+				n = maxBuildIDSize
+			}
+		}
+		f.buildIDs[cString(r[buildIDOffset+24:])] = fmt.Sprintf("%x", r[buildIDOffset:buildIDOffset+n])
+	}
+	return nil
+}
+
+func (f *dataFile) parseEventDesc(b []byte) error {
+	if len(b) < 8 {
+		return fmt.Errorf("event description is truncated")
+	}
+	nr := int(f.order.Uint32(b))
+	attrSize := int(f.order.Uint32(b[4:]))
+	b = b[8:]
+	for i := 0; i < nr; i++ {
+		if len(b) < attrSize+8 {
+			return fmt.Errorf("event description is truncated")
+		}
+		b = b[attrSize:]
+		nrIDs := int(f.order.Uint32(b))
+		n := int(f.order.Uint32(b[4:]))
+		b = b[8:]
+		if n > len(b) || nrIDs*8 > len(b)-n {
+			return fmt.Errorf("event description is truncated")
+		}
+		name := eventName(cString(b[:n]))
+		b = b[n:]
+		// Events are described in the order of the attributes,
+		// however, the identifiers are used, if present.
+		var matched bool
+		for ; nrIDs > 0; nrIDs-- {
+			if e, ok := f.byID[f.order.Uint64(b)]; ok {
+				e.name = name
+				matched = true
+			}
+			b = b[8:]
+		}
+		if !matched && i < len(f.events) {
+			f.events[i].name = name
+		}
+	}
+	return nil
+}
+
+// buildID returns the build ID of the file, if known.
+func (f *dataFile) buildID(filename string) string {
+	if id, ok := f.buildIDs[filename]; ok {
+		return id
+	}
+	// The kernel mapping is named after the relocation
+	// reference symbol, e.g. [kernel.kallsyms]_text.
+	if strings.HasPrefix(filename, kernelMmapFilename) {
+		return f.buildIDs[kernelMmapFilename]
+	}
+	return ""
+}
+
+type recordHeader struct {
+	typ  uint32
+	misc uint16
+}
+
+// forEachRecord calls fn for every record of the data section
+// with the record header and the record body.
+func (f *dataFile) forEachRecord(fn func(recordHeader, []byte) error) error {
+	b := f.records
+	for len(b) > 0 {
+		if len(b) < eventHeaderSize {
+			return fmt.Errorf("record header is truncated")
+		}
+		h := recordHeader{typ: f.order.Uint32(b), misc: f.order.Uint16(b[4:])}
+		size := int(f.order.Uint16(b[6:]))
+		if size < eventHeaderSize || size > len(b) {
+			return fmt.Errorf("invalid record size %d", size)
+		}
+		if err := fn(h, b[eventHeaderSize:size]); err != nil {
+			return err
+		}
+		b = b[size:]
+	}
+	return nil
+}
+
+type mmapRecord struct {
+	pid     uint32
+	start   uint64
+	limit   uint64
+	pgoff   uint64
+	file    string
+	buildID string
+	kernel  bool
+}
+
+func (f *dataFile) parseMmap(h recordHeader, b []byte) (mmapRecord, error) {
+	const (
+		mmapFilenameOffset  = 32
+		mmap2FilenameOffset = mmapFilenameOffset + 32
+		mmap2BuildIDOffset  = mmapFilenameOffset + 4
+	)
+	offset := mmapFilenameOffset
+	if h.typ == recordMmap2 {
+		offset = mmap2FilenameOffset
+	}
+	if len(b) < offset {
+		return mmapRecord{}, fmt.Errorf("mmap record is truncated")
+	}
+	m := mmapRecord{
+		pid:   f.order.Uint32(b),
+		start: f.order.Uint64(b[8:]),
+		pgoff: f.order.Uint64(b[24:]),
+		file:  cString(b[offset:]),
+	}
+	m.limit = m.start + f.order.Uint64(b[16:])
+	m.kernel = m.pid == kernelPID || int(h.misc&miscCPUModeMask) == miscKernel
+	if h.typ == recordMmap2 && h.misc&miscMmapBuildID != 0 {
+		n := int(b[mmapFilenameOffset])
+		if n > maxBuildIDSize {
+			n = maxBuildIDSize
+		}
+		m.buildID = fmt.Sprintf("%x", b[mmap2BuildIDOffset:mmap2BuildIDOffset+n])
+	}
+	if m.buildID == "" {
+		m.buildID = f.buildID(m.file)
+	}
+	return m, nil
+}
+
+type commRecord struct {
+	pid  uint32
+	tid  uint32
+	comm string
+	exec bool
+}
+
+func (f *dataFile) parseComm(h recordHeader, b []byte) (commRecord, error) {
+	if len(b) < 8 {
+		return commRecord{}, fmt.Errorf("comm record is truncated")
+	}
+	return commRecord{
+		pid:  f.order.Uint32(b),
+		tid:  f.order.Uint32(b[4:]),
+		comm: cString(b[8:]),
+		exec: h.misc&miscCommExec != 0,
+	}, nil
+}
+
+type forkRecord struct {
+	pid  uint32
+	ppid uint32
+}
+
+func (f *dataFile) parseFork(b []byte) (forkRecord, error) {
+	if len(b) < 8 {
+		return forkRecord{}, fmt.Errorf("fork record is truncated")
+	}
+	return forkRecord{pid: f.order.Uint32(b), ppid: f.order.Uint32(b[4:])}, nil
+}
+
+type sampleRecord struct {
+	event     *event
+	kernel    bool
+	pid       uint32
+	time      uint64
+	hasTime   bool
+	ip        uint64
+	period    uint64
+	callchain []uint64
+}
+
+// parseSample parses the sample fields up to the callchain:
+// the following fields are not used.
+func (f *dataFile) parseSample(h recordHeader, b []byte) (s sampleRecord, err error) {
+	s.kernel = int(h.misc&miscCPUModeMask) == miscKernel
+	// The layout of the sample depends on the event, which can be only
+	// identified by the identifier field, if there are multiple events.
+	s.event = f.events[0]
+	sampleType := s.event.attr.sampleType
+	if sampleType&sampleIdentifier != 0 {
+		if len(b) < 8 {
+			return s, fmt.Errorf("sample record is truncated")
+		}
+		if e, ok := f.byID[f.order.Uint64(b)]; ok {
+			s.event, sampleType = e, e.attr.sampleType
+		}
+	}
+
+	r := reader{order: f.order, b: b}
+	if sampleType&sampleIdentifier != 0 {
+		r.skip(8)
+	}
+	if sampleType&sampleIP != 0 {
+		s.ip = r.uint64()
+	}
+	if sampleType&sampleTID != 0 {
+		s.pid = r.uint32()
+		r.skip(4)
+	}
+	if sampleType&sampleTime != 0 {
+		s.time, s.hasTime = r.uint64(), true
+	}
+	if sampleType&sampleAddr != 0 {
+		r.skip(8)
+	}
+	if sampleType&sampleID != 0 {
+		id := r.uint64()
+		if e, ok := f.byID[id]; ok && sampleType&sampleIdentifier == 0 {
+			s.event = e
+		}
+	}
+	if sampleType&sampleStreamID != 0 {
+		r.skip(8)
+	}
+	if sampleType&sampleCPU != 0 {
+		r.skip(8)
+	}
+	s.period = 1
+	if sampleType&samplePeriod != 0 {
+		s.period = r.uint64()
+	} else if s.event.attr.flags&attrFlagFreq == 0 && s.event.attr.samplePeriod > 0 {
+		s.period = s.event.attr.samplePeriod
+	}
+	if sampleType&sampleRead != 0 {
+		r.skipRead(s.event.attr.readFormat)
+	}
+	if sampleType&sampleCallchain != 0 {
+		n := r.uint64()
+		if r.err == nil && n > uint64(len(r.b)/8) {
+			return s, fmt.Errorf("sample callchain is truncated")
+		}
+		s.callchain = make([]uint64, n)
+		for i := range s.callchain {
+			s.callchain[i] = r.uint64()
+		}
+	}
+	if r.err != nil {
+		return s, fmt.Errorf("sample record: %w", r.err)
+	}
+	return s, nil
+}
+
+type reader struct {
+	order binary.ByteOrder
+	b     []byte
+	err   error
+}
+
+func (r *reader) skip(n int) {
+	if r.err != nil {
+		return
+	}
+	if len(r.b) < n {
+		r.err = fmt.Errorf("record is truncated")
+		r.b = nil
+		return
+	}
+	r.b = r.b[n:]
+}
+
+func (r *reader) uint64() uint64 {
+	b := r.b
+	if r.skip(8); r.err != nil {
+		return 0
+	}
+	return r.order.Uint64(b)
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.b
+	if r.skip(4); r.err != nil {
+		return 0
+	}
+	return r.order.Uint32(b)
+}
+
+func (r *reader) skipRead(format uint64) {
+	var n int
+	for _, f := range []uint64{readID, readLost} {
+		if format&f != 0 {
+			n++
+		}
+	}
+	if format&readGroup == 0 {
+		// value, time_enabled, time_running, id, lost.
+		r.skip(8)
+	} else {
+		// nr, time_enabled, time_running, values[nr].
+		nr := r.uint64()
+		if r.err == nil && nr > uint64(len(r.b)) {
+			r.err = fmt.Errorf("record is truncated")
+			return
+		}
+		n = int(nr) * (n + 1)
+	}
+	for _, f := range []uint64{readTotalTimeEnabled, readTotalTimeRunning} {
+		if format&f != 0 {
+			r.skip(8)
+		}
+	}
+	r.skip(n * 8)
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// eventName trims the modifiers of the event name, e.g. cycles:u.
+func eventName(name string) string {
+	if i := strings.IndexByte(name, ':'); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+const (
+	typeHardware = 0
+	typeSoftware = 1
+)
+
+var (
+	hardwareEventNames = []string{
+		"cycles",
+		"instructions",
+		"cache-references",
+		"cache-misses",
+		"branch-instructions",
+		"branch-misses",
+		"bus-cycles",
+		"stalled-cycles-frontend",
+		"stalled-cycles-backend",
+		"ref-cycles",
+	}
+	softwareEventNames = []string{
+		"cpu-clock",
+		"task-clock",
+		"page-faults",
+		"context-switches",
+		"cpu-migrations",
+		"minor-faults",
+		"major-faults",
+		"alignment-faults",
+		"emulation-faults",
+	}
+)
+
+func defaultEventName(attr eventAttr) string {
+	var names []string
+	switch attr.typ {
+	case typeHardware:
+		names = hardwareEventNames
+	case typeSoftware:
+		names = softwareEventNames
+	}
+	if attr.config < uint64(len(names)) {
+		return names[attr.config]
+	}
+	return fmt.Sprintf("type%d-config%d", attr.typ, attr.config)
+}
@@ -0,0 +1,280 @@
+package perf
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/og/convert/testhelper"
+)
+
+const testSampleType = sampleIdentifier | sampleIP | sampleTID | sampleTime | samplePeriod | sampleCallchain
+
+// perfDataBuilder writes a little-endian perf.data file.
+type perfDataBuilder struct {
+	attrs    []eventAttr
+	ids      [][]uint64
+	records  []byte
+	buildIDs []byte
+	descs    []string
+}
+
+func (b *perfDataBuilder) event(attr eventAttr, desc string, ids ...uint64) {
+	b.attrs = append(b.attrs, attr)
+	b.ids = append(b.ids, ids)
+	b.descs = append(b.descs, desc)
+}
+
+func (b *perfDataBuilder) record(typ uint32, misc uint16, body []byte) {
+	for len(body)%8 != 0 {
+		body = append(body, 0)
+	}
+	h := make([]byte, eventHeaderSize)
+	binary.LittleEndian.PutUint32(h, typ)
+	binary.LittleEndian.PutUint16(h[4:], misc)
+	binary.LittleEndian.PutUint16(h[6:], uint16(eventHeaderSize+len(body)))
+	b.records = append(append(b.records, h...), body...)
+}
+
+func (b *perfDataBuilder) mmap2(pid uint32, misc uint16, start, size, pgoff uint64, buildID []byte, file string) {
+	body := u32(nil, pid, pid)
+	body = u64(body, start, size, pgoff)
+	ids := make([]byte, 24)
+	if buildID != nil {
+		misc |= miscMmapBuildID
+		ids[0] = byte(len(buildID))
+		copy(ids[4:], buildID)
+	}
+	body = append(body, ids...)
+	body = u32(body, 5, 2)
+	b.record(recordMmap2, misc, append(body, file+"\x00"...))
+}
+
+func (b *perfDataBuilder) mmap(pid uint32, start, size, pgoff uint64, file string) {
+	body := u32(nil, pid, pid)
+	body = u64(body, start, size, pgoff)
+	b.record(recordMmap, 2, append(body, file+"\x00"...))
+}
+
+func (b *perfDataBuilder) comm(pid, tid uint32, comm string) {
+	b.record(recordComm, 0, append(u32(nil, pid, tid), comm+"\x00"...))
+}
+
+func (b *perfDataBuilder) fork(pid, ppid uint32) {
+	b.record(recordFork, 0, u64(u32(nil, pid, ppid, pid, ppid), 0))
+}
+
+func (b *perfDataBuilder) sample(id uint64, pid uint32, ts, period uint64, callchain ...uint64) {
+	var ip uint64
+	if len(callchain) > 1 {
+		ip = callchain[1]
+	}
+	body := u64(nil, id, ip)
+	body = u32(body, pid, pid)
+	body = u64(body, ts, period, uint64(len(callchain)))
+	b.record(recordSample, 2, u64(body, callchain...))
+}
+
+func (b *perfDataBuilder) buildID(file string, id []byte) {
+	body := u32(nil, ^uint32(0))
+	x := make([]byte, 24)
+	copy(x, id)
+	x[maxBuildIDSize] = byte(len(id))
+	body = append(body, x...)
+	body = append(body, file+"\x00"...)
+	for (len(body)+eventHeaderSize)%8 != 0 {
+		body = append(body, 0)
+	}
+	h := make([]byte, eventHeaderSize)
+	binary.LittleEndian.PutUint16(h[4:], miscBuildIDSize)
+	binary.LittleEndian.PutUint16(h[6:], uint16(eventHeaderSize+len(body)))
+	b.buildIDs = append(append(b.buildIDs, h...), body...)
+}
+
+func (b *perfDataBuilder) bytes() []byte {
+	const attrSize = minEventAttrSize + fileSectionSize
+	attrsOffset := uint64(fileHeaderSize)
+	idsOffset := attrsOffset + uint64(len(b.attrs)*attrSize)
+
+	var attrs, ids []byte
+	for i, a := range b.attrs {
+		attr := u32(nil, a.typ, minEventAttrSize)
+		attr = u64(attr, a.config, a.samplePeriod, a.sampleType, a.readFormat, a.flags)
+		attr = append(attr, make([]byte, minEventAttrSize-len(attr))...)
+		attr = u64(attr, idsOffset+uint64(len(ids)), uint64(len(b.ids[i])*8))
+		attrs = append(attrs, attr...)
+		ids = u64(ids, b.ids[i]...)
+	}
+
+	var desc []byte
+	desc = u32(desc, uint32(len(b.attrs)), minEventAttrSize)
+	for i := range b.attrs {
+		desc = append(desc, make([]byte, minEventAttrSize)...)
+		name := []byte(b.descs[i] + "\x00")
+		for len(name)%8 != 0 {
+			name = append(name, 0)
+		}
+		desc = u32(desc, uint32(len(b.ids[i])), uint32(len(name)))
+		desc = append(desc, name...)
+		desc = u64(desc, b.ids[i]...)
+	}
+
+	dataOffset := idsOffset + uint64(len(ids))
+	featuresOffset := dataOffset + uint64(len(b.records))
+	buildIDsOffset := featuresOffset + 2*fileSectionSize
+	descOffset := buildIDsOffset + uint64(len(b.buildIDs))
+
+	buf := []byte("PERFILE2")
+	buf = u64(buf, fileHeaderSize, attrSize)
+	buf = u64(buf, attrsOffset, uint64(len(attrs)))
+	buf = u64(buf, dataOffset, uint64(len(b.records)))
+	buf = u64(buf, 0, 0)
+	buf = u64(buf, 1<<featureBuildID|1<<featureEventDesc, 0, 0, 0)
+	buf = append(append(append(buf, attrs...), ids...), b.records...)
+	buf = u64(buf, buildIDsOffset, uint64(len(b.buildIDs)))
+	buf = u64(buf, descOffset, uint64(len(desc)))
+	return append(append(buf, b.buildIDs...), desc...)
+}
+
+func u64(b []byte, v ...uint64) []byte {
+	for _, x := range v {
+		b = binary.LittleEndian.AppendUint64(b, x)
+	}
+	return b
+}
+
+func u32(b []byte, v ...uint32) []byte {
+	for _, x := range v {
+		b = binary.LittleEndian.AppendUint32(b, x)
+	}
+	return b
+}
+
+const (
+	kernelStart = 0xffffffff81000000
+	appStart    = 0x400000
+	libcStart   = 0x7f0000000000
+)
+
+func testPerfData() []byte {
+	var b perfDataBuilder
+	b.event(eventAttr{typ: typeSoftware, config: 0, samplePeriod: 1000000, sampleType: testSampleType}, "cpu-clock:u", 1, 2)
+	b.event(eventAttr{typ: typeHardware, config: 0, samplePeriod: 99, sampleType: testSampleType, flags: attrFlagFreq}, "cycles", 3)
+
+	b.mmap2(kernelPID, miscKernel, kernelStart, 0x1000000, 0, nil, "[kernel.kallsyms]_text")
+	b.comm(100, 100, "app")
+	b.mmap2(100, 2, appStart, 0x10000, 0, []byte{0xde, 0xad, 0xbe, 0xef}, "/usr/bin/app")
+	b.mmap(100, libcStart, 0x200000, 0x1000, "/lib/libc.so")
+	// The second mapping of libc overlaps the first one.
+	b.mmap(100, libcStart+0x100000, 0x1000, 0, "/lib/ld.so")
+	b.fork(200, 100)
+
+	b.sample(1, 100, 1000, 1000000, contextKernel, kernelStart+0x10, contextUser, libcStart+0x20, appStart+0x30)
+	b.sample(2, 100, 2000, 1000000, contextKernel, kernelStart+0x10, contextUser, libcStart+0x20, appStart+0x30)
+	b.sample(1, 200, 3000, 1000000, contextUser, appStart+0x40, 0)
+	b.sample(3, 100, 5000, 12345, contextUser, libcStart+0x100010, contextGuest, 0x1234)
+	b.sample(3, 300, 6000, 1, contextUser, 0x1000)
+
+	b.buildID("[kernel.kallsyms]", []byte{0x01, 0x02})
+	b.buildID("/lib/libc.so", []byte{0x0a, 0x0b})
+	return b.bytes()
+}
+
+func TestParseData(t *testing.T) {
+	profiles, err := ParseData(testPerfData())
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+
+	cpu := profiles[0]
+	require.Equal(t, "cpu-clock", cpu.Event)
+	require.Equal(t, "process_cpu", cpu.Name)
+	p := cpu.Profile
+	require.Equal(t, []string{"samples/count", "cpu/nanoseconds"}, sampleTypes(p))
+	require.Equal(t, int64(1000000), p.Period)
+	require.Equal(t, int64(2000), p.DurationNanos)
+	require.Equal(t, map[string][]int64{
+		"app;/usr/bin/app+0x30;/lib/libc.so+0x1020;[kernel.kallsyms]_text+0x10": {2, 2000000},
+		"app;/usr/bin/app+0x40": {1, 1000000},
+	}, testhelper.Stacks(p, ""))
+
+	mappings := make(map[string]*profilev1.Mapping)
+	for _, m := range p.Mapping {
+		mappings[p.StringTable[m.Filename]] = m
+	}
+	require.Len(t, mappings, 3)
+	require.Equal(t, "0102", p.StringTable[mappings["[kernel.kallsyms]_text"].BuildId])
+	require.Equal(t, "deadbeef", p.StringTable[mappings["/usr/bin/app"].BuildId])
+	libc := mappings["/lib/libc.so"]
+	require.Equal(t, "0a0b", p.StringTable[libc.BuildId])
+	require.Equal(t, uint64(libcStart), libc.MemoryStart)
+	require.Equal(t, uint64(libcStart+0x100000), libc.MemoryLimit)
+	require.Equal(t, uint64(0x1000), libc.FileOffset)
+
+	cycles := profiles[1]
+	require.Equal(t, "cycles", cycles.Event)
+	require.Equal(t, "perf_cycles", cycles.Name)
+	p = cycles.Profile
+	require.Equal(t, []string{"samples/count", "cycles/count"}, sampleTypes(p))
+	require.Equal(t, int64(0), p.Period)
+	require.Equal(t, map[string][]int64{
+		"app;/lib/ld.so+0x10": {1, 12345},
+		"[unknown]":           {1, 1},
+	}, testhelper.Stacks(p, ""))
+}
+
+func TestParseData_Invalid(t *testing.T) {
+	data := testPerfData()
+	for name, b := range map[string][]byte{
+		"empty":         nil,
+		"magic":         append([]byte("PERFILE1"), data[8:]...),
+		"header":        data[:64],
+		"truncated":     data[:fileHeaderSize+200],
+		"invalid attrs": append(append(append([]byte{}, data[:16]...), u64(nil, 8)...), data[24:]...),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseData(b)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestAddressSpace(t *testing.T) {
+	var s addressSpace
+	s.insert(mmapRecord{start: 0x1000, limit: 0x5000, pgoff: 0x100, file: "a"})
+	s.insert(mmapRecord{start: 0x2000, limit: 0x3000, file: "b"})
+	s.insert(mmapRecord{start: 0x6000, limit: 0x7000, file: "c"})
+	require.Equal(t, addressSpace{
+		{start: 0x1000, limit: 0x2000, pgoff: 0x100, file: "a"},
+		{start: 0x2000, limit: 0x3000, file: "b"},
+		{start: 0x3000, limit: 0x5000, pgoff: 0x2100, file: "a"},
+		{start: 0x6000, limit: 0x7000, file: "c"},
+	}, s)
+	require.Equal(t, "b", s.lookup(0x2fff).file)
+	require.Equal(t, "a", s.lookup(0x3000).file)
+	require.Nil(t, s.lookup(0x5000))
+	require.Nil(t, s.lookup(0x100))
+}
+
+func TestRawProfile_ParseToPprof(t *testing.T) {
+	req := testhelper.ParseToPprof(t, &RawProfile{RawData: testPerfData()}, "perf")
+	require.Len(t, req.Series, 2)
+	for i, x := range []struct{ event, name string }{
+		{"cpu-clock", "process_cpu"},
+		{"cycles", "perf_cycles"},
+	} {
+		ls := phlaremodel.Labels(req.Series[i].Labels)
+		require.Equal(t, x.name, ls.Get("__name__"))
+		require.Equal(t, x.event, ls.Get(LabelNameEvent))
+	}
+}
+
+func sampleTypes(p *profilev1.Profile) []string {
+	var types []string
+	for _, t := range p.SampleType {
+		types = append(types, p.StringTable[t.Type]+"/"+p.StringTable[t.Unit])
+	}
+	return types
+}
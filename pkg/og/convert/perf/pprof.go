@@ -0,0 +1,324 @@
+package perf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	"github.com/grafana/pyroscope/pkg/og/convert"
+)
+
+// DataProfile is the profile of a single event recorded in perf.data.
+type DataProfile struct {
+	Event string
+	// Name is the profile name derived from the event:
+	// process_cpu for the CPU clock events, e.g. perf_cycles otherwise.
+	Name    string
+	Profile *profilev1.Profile
+}
+
+// ParseData parses the perf.data file and returns a pprof profile for every
+// event that has samples.
+//
+// perf.data doesn't include the symbol tables of the sampled binaries,
+// therefore locations can't be symbolized: every location is named after
+// the mapped file and the offset within it, e.g. /usr/lib/libc.so.6+0x2a1c0,
+// or [unknown], if the address is not mapped. The locations keep the
+// addresses and the mappings, identified by the build ID, if available.
+func ParseData(data []byte) ([]*DataProfile, error) {
+	f, err := parseDataFile(data)
+	if err != nil {
+		return nil, err
+	}
+	c := &dataConverter{
+		file:      f,
+		processes: make(map[uint32]*process),
+		builders:  make(map[*event]*dataProfileBuilder),
+	}
+	if err = f.forEachRecord(c.record); err != nil {
+		return nil, err
+	}
+	profiles := make([]*DataProfile, 0, len(c.builders))
+	for _, e := range f.events {
+		if b, ok := c.builders[e]; ok {
+			profiles = append(profiles, &DataProfile{Event: e.name, Name: profileName(e), Profile: b.build()})
+		}
+	}
+	return profiles, nil
+}
+
+// isCPUClock reports whether the event samples the CPU time.
+func isCPUClock(e *event) bool {
+	return e.attr.typ == typeSoftware && (e.name == "cpu-clock" || e.name == "task-clock")
+}
+
+func profileName(e *event) string {
+	if isCPUClock(e) {
+		return "process_cpu"
+	}
+	return "perf_" + sampleTypeName(e)
+}
+
+// sampleTypeName returns the event name as a valid metric name suffix.
+func sampleTypeName(e *event) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, e.name)
+}
+
+type dataConverter struct {
+	file      *dataFile
+	kernel    addressSpace
+	processes map[uint32]*process
+	builders  map[*event]*dataProfileBuilder
+}
+
+type process struct {
+	comm string
+	maps addressSpace
+}
+
+func (c *dataConverter) process(pid uint32) *process {
+	p, ok := c.processes[pid]
+	if !ok {
+		p = new(process)
+		c.processes[pid] = p
+	}
+	return p
+}
+
+func (c *dataConverter) record(h recordHeader, b []byte) error {
+	switch h.typ {
+	case recordMmap, recordMmap2:
+		m, err := c.file.parseMmap(h, b)
+		if err != nil {
+			return err
+		}
+		if m.kernel {
+			c.kernel.insert(m)
+		} else {
+			c.process(m.pid).maps.insert(m)
+		}
+
+	case recordComm:
+		r, err := c.file.parseComm(h, b)
+		if err != nil {
+			return err
+		}
+		// Only the main thread names the process.
+		if r.pid != r.tid && !r.exec {
+			return nil
+		}
+		p := c.process(r.pid)
+		p.comm = r.comm
+		if r.exec {
+			p.maps = nil
+		}
+
+	case recordFork:
+		r, err := c.file.parseFork(b)
+		if err != nil {
+			return err
+		}
+		if r.pid == r.ppid {
+			// A new thread shares the address space.
+			return nil
+		}
+		parent := c.process(r.ppid)
+		child := c.process(r.pid)
+		child.comm = parent.comm
+		child.maps = append(addressSpace(nil), parent.maps...)
+
+	case recordSample:
+		s, err := c.file.parseSample(h, b)
+		if err != nil {
+			return err
+		}
+		c.sample(&s)
+	}
+	return nil
+}
+
+func (c *dataConverter) sample(s *sampleRecord) {
+	b, ok := c.builders[s.event]
+	if !ok {
+		b = newDataProfileBuilder(s.event)
+		c.builders[s.event] = b
+	}
+	p := c.process(s.pid)
+	stack := make([]uint64, 0, len(s.callchain)+1)
+	if len(s.callchain) == 0 {
+		stack = append(stack, b.location(c.lookup(p, s.ip, s.kernel), s.ip))
+	}
+	kernel := s.kernel
+	var guest bool
+	for _, ip := range s.callchain {
+		if ip >= contextMax {
+			switch ip {
+			case contextKernel, contextHV:
+				kernel, guest = true, false
+			case contextUser:
+				kernel, guest = false, false
+			case contextGuest, contextGuestKernel, contextGuestUser:
+				guest = true
+			}
+			continue
+		}
+		if guest || ip == 0 {
+			continue
+		}
+		stack = append(stack, b.location(c.lookup(p, ip, kernel), ip))
+	}
+	if p.comm != "" {
+		stack = append(stack, b.processLocation(p.comm))
+	}
+	b.add(stack, s)
+}
+
+func (c *dataConverter) lookup(p *process, addr uint64, kernel bool) *mmapRecord {
+	if kernel {
+		return c.kernel.lookup(addr)
+	}
+	return p.maps.lookup(addr)
+}
+
+// addressSpace is a list of non-overlapping mappings ordered by address.
+type addressSpace []mmapRecord
+
+func (s addressSpace) lookup(addr uint64) *mmapRecord {
+	i := sort.Search(len(s), func(i int) bool { return s[i].limit > addr })
+	if i < len(s) && s[i].start <= addr {
+		return &s[i]
+	}
+	return nil
+}
+
+// insert adds the mapping to the address space. Mappings it
+// overlaps are trimmed or removed, as the new one replaces them.
+func (s *addressSpace) insert(m mmapRecord) {
+	if m.limit <= m.start {
+		return
+	}
+	maps := make(addressSpace, 0, len(*s)+2)
+	for _, x := range *s {
+		if x.limit <= m.start || x.start >= m.limit {
+			maps = append(maps, x)
+			continue
+		}
+		if x.start < m.start {
+			head := x
+			head.limit = m.start
+			maps = append(maps, head)
+		}
+		if x.limit > m.limit {
+			tail := x
+			tail.pgoff += m.limit - x.start
+			tail.start = m.limit
+			maps = append(maps, tail)
+		}
+	}
+	maps = append(maps, m)
+	sort.Slice(maps, func(i, j int) bool { return maps[i].start < maps[j].start })
+	*s = maps
+}
+
+type dataProfileBuilder struct {
+	*convert.ProfileBuilder
+	mappings map[mappingKey]uint64
+	minTime  uint64
+	maxTime  uint64
+}
+
+type mappingKey struct {
+	file    string
+	start   uint64
+	limit   uint64
+	pgoff   uint64
+	buildID string
+}
+
+func newDataProfileBuilder(e *event) *dataProfileBuilder {
+	b := &dataProfileBuilder{
+		ProfileBuilder: convert.NewProfileBuilder(),
+		mappings:       make(map[mappingKey]uint64),
+	}
+	valueType := &profilev1.ValueType{Type: b.String(sampleTypeName(e)), Unit: b.String("count")}
+	if isCPUClock(e) {
+		valueType = &profilev1.ValueType{Type: b.String("cpu"), Unit: b.String("nanoseconds")}
+	}
+	b.Profile.SampleType = []*profilev1.ValueType{
+		{Type: b.String("samples"), Unit: b.String("count")},
+		valueType,
+	}
+	b.Profile.PeriodType = valueType
+	if e.attr.flags&attrFlagFreq == 0 {
+		b.Profile.Period = int64(e.attr.samplePeriod)
+	}
+	return b
+}
+
+func (b *dataProfileBuilder) mapping(m *mmapRecord) uint64 {
+	if m == nil {
+		return 0
+	}
+	k := mappingKey{file: m.file, start: m.start, limit: m.limit, pgoff: m.pgoff, buildID: m.buildID}
+	if id, ok := b.mappings[k]; ok {
+		return id
+	}
+	id := uint64(len(b.Profile.Mapping) + 1)
+	b.Profile.Mapping = append(b.Profile.Mapping, &profilev1.Mapping{
+		Id:          id,
+		MemoryStart: m.start,
+		MemoryLimit: m.limit,
+		FileOffset:  m.pgoff,
+		Filename:    b.String(m.file),
+		BuildId:     b.String(m.buildID),
+	})
+	b.mappings[k] = id
+	return id
+}
+
+func (b *dataProfileBuilder) location(m *mmapRecord, addr uint64) uint64 {
+	// The name of the mapped file and the offset within
+	// it is the only symbol information available.
+	name := "[unknown]"
+	if m != nil {
+		name = fmt.Sprintf("%s+%#x", m.file, addr-m.start+m.pgoff)
+	}
+	return b.Location(convert.Location{
+		Function:  convert.Function{Name: name, SystemName: name},
+		MappingID: b.mapping(m),
+		Address:   addr,
+	})
+}
+
+// processLocation returns the location of the synthetic root
+// frame of the process stacks, named after the process.
+func (b *dataProfileBuilder) processLocation(comm string) uint64 {
+	return b.Location(convert.Location{Function: convert.Function{Name: comm, SystemName: comm}})
+}
+
+// add adds the sample with the stack, leaf first. Samples
+// with the same stack are merged.
+func (b *dataProfileBuilder) add(stack []uint64, s *sampleRecord) {
+	if s.hasTime {
+		if b.minTime == 0 || s.time < b.minTime {
+			b.minTime = s.time
+		}
+		if s.time > b.maxTime {
+			b.maxTime = s.time
+		}
+	}
+	b.AddSample(stack, nil, 1, int64(s.period))
+}
+
+func (b *dataProfileBuilder) build() *profilev1.Profile {
+	// Sample timestamps are taken from the monotonic
+	// clock and can't be converted to the wall time.
+	b.Profile.DurationNanos = int64(b.maxTime - b.minTime)
+	return b.Profile
+}
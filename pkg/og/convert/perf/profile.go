@@ -0,0 +1,54 @@
+package perf
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	distributormodel "github.com/grafana/pyroscope/pkg/distributor/model"
+	"github.com/grafana/pyroscope/pkg/og/convert"
+	"github.com/grafana/pyroscope/pkg/og/ingestion"
+	"github.com/grafana/pyroscope/pkg/og/storage"
+	"github.com/grafana/pyroscope/pkg/pprof"
+)
+
+// LabelNameEvent is the label holding the name of the perf event.
+const LabelNameEvent = "perf_event"
+
+// RawProfile implements ingestion.RawProfile for the perf.data format.
+type RawProfile struct {
+	RawData []byte
+}
+
+func (p *RawProfile) Bytes() ([]byte, error) { return p.RawData, nil }
+
+func (p *RawProfile) ContentType() string { return "application/octet-stream" }
+
+func (p *RawProfile) Parse(context.Context, storage.Putter, storage.MetricsExporter, ingestion.Metadata) error {
+	return fmt.Errorf("parsing perf.data to tree/storage.Putter is not supported")
+}
+
+func (p *RawProfile) ParseToPprof(_ context.Context, md ingestion.Metadata) (*distributormodel.PushRequest, error) {
+	profiles, err := ParseData(p.RawData)
+	if err != nil {
+		return nil, err
+	}
+	req := &distributormodel.PushRequest{
+		RawProfileSize: len(p.RawData),
+		RawProfileType: distributormodel.RawProfileTypePerf,
+		Series:         make([]*distributormodel.ProfileSeries, 0, len(profiles)),
+	}
+	for _, x := range profiles {
+		x.Profile.TimeNanos = md.StartTime.UnixNano()
+		req.Series = append(req.Series, &distributormodel.ProfileSeries{
+			Labels: convert.SeriesLabels(md, x.Name, &v1.LabelPair{
+				Name:  LabelNameEvent,
+				Value: x.Event,
+			}),
+			Samples: []*distributormodel.ProfileSample{{
+				Profile: pprof.RawFromProto(x.Profile),
+			}},
+		})
+	}
+	return req, nil
+}
@@ -0,0 +1,70 @@
+package testhelper
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	distributormodel "github.com/grafana/pyroscope/pkg/distributor/model"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/og/ingestion"
+	"github.com/grafana/pyroscope/pkg/og/storage/segment"
+)
+
+// StartTime is the start time of the profiles converted with ParseToPprof.
+var StartTime = time.Unix(1, 0)
+
+// ParseToPprof converts the profile ingested by the spy with the key
+// my-app{env=dev}, and checks the labels common to all the series.
+func ParseToPprof(t *testing.T, p ingestion.ParseableToPprof, spyName string) *distributormodel.PushRequest {
+	t.Helper()
+	key, err := segment.ParseKey("my-app{env=dev}")
+	require.NoError(t, err)
+	md := ingestion.Metadata{Key: key, SpyName: spyName, StartTime: StartTime, SampleRate: 100}
+	req, err := p.ParseToPprof(context.Background(), md)
+	require.NoError(t, err)
+	require.NotEmpty(t, req.Series)
+	for _, s := range req.Series {
+		ls := phlaremodel.Labels(s.Labels)
+		require.Equal(t, "my-app", ls.Get(phlaremodel.LabelNameServiceName))
+		require.Equal(t, "dev", ls.Get("env"))
+		require.Equal(t, spyName, ls.Get("pyroscope_spy"))
+		for _, x := range s.Samples {
+			require.Equal(t, StartTime.UnixNano(), x.Profile.TimeNanos)
+		}
+	}
+	return req
+}
+
+// Stacks renders the sample stacks root first, and sums the values of the
+// samples with the same stack. The stacks are prefixed with the value of
+// the label, if the label is specified.
+func Stacks(p *profilev1.Profile, label string) map[string][]int64 {
+	stacks := make(map[string][]int64)
+	for _, s := range p.Sample {
+		frames := make([]string, 0, len(s.LocationId))
+		for i := len(s.LocationId) - 1; i >= 0; i-- {
+			loc := p.Location[s.LocationId[i]-1]
+			frames = append(frames, p.StringTable[p.Function[loc.Line[0].FunctionId-1].Name])
+		}
+		stack := strings.Join(frames, ";")
+		for _, l := range s.Label {
+			if label != "" && p.StringTable[l.Key] == label {
+				stack = p.StringTable[l.Str] + "/" + stack
+			}
+		}
+		values, ok := stacks[stack]
+		if !ok {
+			values = make([]int64, len(s.Value))
+			stacks[stack] = values
+		}
+		for i, v := range s.Value {
+			values[i] += v
+		}
+	}
+	return stacks
+}
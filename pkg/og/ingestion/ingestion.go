@@ -32,6 +32,9 @@ const (
   FormatLines      Format = "lines"
   FormatGroups     Format = "groups"
   FormatSpeedscope Format = "speedscope"
+  FormatCPUProfile Format = "cpuprofile"
+  FormatPerf       Format = "perf"
+  FormatGecko      Format = "gecko"
 )
 
 type RawProfile interface {
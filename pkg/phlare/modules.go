@@ -30,6 +30,7 @@ import (
 	"google.golang.org/protobuf/encoding/protojson"
 	"gopkg.in/yaml.v3"
 
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
 	statusv1 "github.com/grafana/pyroscope/api/gen/proto/go/status/v1"
 	"github.com/grafana/pyroscope/pkg/distributor"
 	"github.com/grafana/pyroscope/pkg/frontend"
@@ -39,6 +40,7 @@ import (
 	phlarecontext "github.com/grafana/pyroscope/pkg/phlare/context"
 	"github.com/grafana/pyroscope/pkg/querier"
 	"github.com/grafana/pyroscope/pkg/querier/worker"
+	"github.com/grafana/pyroscope/pkg/ruler"
 	"github.com/grafana/pyroscope/pkg/scheduler"
 	"github.com/grafana/pyroscope/pkg/storegateway"
 	"github.com/grafana/pyroscope/pkg/usagestats"
@@ -67,6 +69,7 @@ const (
 	RuntimeConfig     string = "runtime-config"
 	Overrides         string = "overrides"
 	OverridesExporter string = "overrides-exporter"
+	Ruler             string = "ruler"
 
 	// QueryFrontendTripperware string = "query-frontend-tripperware"
 	// Compactor                string = "compactor"
@@ -90,6 +93,8 @@ func (f *Phlare) initQueryFrontend() (services.Service, error) {
 		f.Cfg.Frontend.Port = f.Cfg.Server.HTTPListenPort
 	}
 
+	f.Cfg.Frontend.QueryStoreAfter = f.Cfg.Querier.QueryStoreAfter
+
 	frontendSvc, err := frontend.NewFrontend(f.Cfg.Frontend, f.Overrides, log.With(f.logger, "component", "frontend"), f.reg)
 	if err != nil {
 		return nil, err
@@ -258,7 +263,7 @@ func (f *Phlare) initDistributor() (services.Service, error) {
 		return nil, err
 	}
 
-	f.API.RegisterDistributor(d)
+	f.API.RegisterDistributor(d, f.Overrides)
 	return d, nil
 }
 
@@ -358,6 +363,36 @@ func (f *Phlare) initStoreGateway() (serv services.Service, err error) {
 	return svc, nil
 }
 
+func (f *Phlare) initRuler() (services.Service, error) {
+	if err := f.Cfg.Ruler.Validate(); err != nil {
+		return nil, err
+	}
+
+	b := f.storageBucket
+	if b == nil {
+		fs, err := filesystem.NewBucket(f.Cfg.PhlareDB.DataPath)
+		if err != nil {
+			return nil, err
+		}
+		b = fs
+	}
+
+	addr := f.Cfg.Ruler.QueryAddress
+	if addr == "" {
+		addr = fmt.Sprintf("http://localhost:%d", f.Cfg.Server.HTTPListenPort)
+	}
+	querierClient := querierv1connect.NewQuerierServiceClient(util.InstrumentedHTTPClient(), addr, f.auth)
+
+	return ruler.New(
+		f.Cfg.Ruler,
+		ruler.NewRuleStore(b),
+		querierClient,
+		ruler.NewRemoteWriter(f.Cfg.Ruler.RemoteWrite),
+		log.With(f.logger, "component", "ruler"),
+		f.reg,
+	), nil
+}
+
 var objstoreTracerMiddleware = middleware.Func(func(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
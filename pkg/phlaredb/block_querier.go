@@ -17,6 +17,7 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/gogo/status"
 	"github.com/google/pprof/profile"
+	"github.com/google/uuid"
 	"github.com/grafana/dskit/multierror"
 	"github.com/grafana/dskit/runutil"
 	"github.com/oklog/ulid"
@@ -25,6 +26,7 @@ import (
 	"github.com/parquet-go/parquet-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/samber/lo"
@@ -41,8 +43,10 @@ import (
 	"github.com/grafana/pyroscope/pkg/phlaredb/block"
 	"github.com/grafana/pyroscope/pkg/phlaredb/query"
 	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
+	"github.com/grafana/pyroscope/pkg/phlaredb/sharding"
 	"github.com/grafana/pyroscope/pkg/phlaredb/symdb"
 	"github.com/grafana/pyroscope/pkg/phlaredb/tsdb/index"
+	"github.com/grafana/pyroscope/pkg/querier/stats"
 	"github.com/grafana/pyroscope/pkg/util"
 )
 
@@ -384,10 +388,23 @@ type Profile interface {
 type Querier interface {
 	Bounds() (model.Time, model.Time)
 	SelectMatchingProfiles(ctx context.Context, params *ingestv1.SelectProfilesRequest) (iter.Iterator[Profile], error)
-	MergeByStacktraces(ctx context.Context, rows iter.Iterator[Profile]) (*phlaremodel.Tree, error)
-	MergeByLabels(ctx context.Context, rows iter.Iterator[Profile], by ...string) ([]*typesv1.Series, error)
-	MergePprof(ctx context.Context, rows iter.Iterator[Profile]) (*profile.Profile, error)
+	MergeByStacktraces(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector) (*phlaremodel.Tree, error)
+	// MergeBySpans merges the samples that belong to the given spans.
+	MergeBySpans(ctx context.Context, rows iter.Iterator[Profile], spans phlaremodel.SpanSelector) (*phlaremodel.Tree, error)
+	MergeByLabels(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector, exemplars bool, by ...string) ([]*typesv1.Series, error)
+	// MergeFunctionSeries is like MergeByLabels, but the series
+	// are split by function, and the values are the function values.
+	MergeFunctionSeries(ctx context.Context, rows iter.Iterator[Profile], fs *typesv1.FunctionSeriesSelector, by ...string) ([]*typesv1.Series, error)
+	MergePprof(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector) (*profile.Profile, error)
+	MergeFunctionTable(ctx context.Context, rows iter.Iterator[Profile], byLine bool) (*typesv1.FunctionTable, error)
+	// MergeFunctionLines breaks down the values of the function by the source line.
+	MergeFunctionLines(ctx context.Context, rows iter.Iterator[Profile], function string) (*typesv1.FunctionTable, error)
+	// SelectProfileByID selects the profile with the given ID among the
+	// profiles matching the request. The profile is nil, if not found.
+	SelectProfileByID(ctx context.Context, id uuid.UUID, params *ingestv1.SelectProfilesRequest) (Profile, error)
 	Series(ctx context.Context, params *ingestv1.SeriesRequest) ([]*typesv1.Labels, error)
+	LabelValues(ctx context.Context, params *typesv1.LabelValuesRequest) ([]string, error)
+	LabelNames(ctx context.Context, params *typesv1.LabelNamesRequest) ([]string, error)
 	Open(ctx context.Context) error
 	// Sorts profiles for retrieval.
 	Sort([]Profile) []Profile
@@ -441,8 +458,27 @@ func (queriers Queriers) ForTimeRange(_ context.Context, start, end model.Time)
 
 type BlockGetter func(ctx context.Context, start, end model.Time) (Queriers, error)
 
+// selectProfilesMatchers returns the matchers of the request selector and
+// the profile type. The query shard matcher is removed from the selector
+// and returned separately, if present.
+func selectProfilesMatchers(params *ingestv1.SelectProfilesRequest) ([]*labels.Matcher, *sharding.ShardSelector, error) {
+	matchers, err := parser.ParseMetricSelector(params.LabelSelector)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "failed to parse label selectors: "+err.Error())
+	}
+	if params.Type == nil {
+		return nil, nil, errors.New("no profileType given")
+	}
+	shard, matchers, err := sharding.RemoveShardFromMatchers(matchers)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return append(matchers, phlaremodel.SelectorFromProfileType(params.Type)), shard, nil
+}
+
 // SelectMatchingProfiles returns a list iterator of profiles matching the given request.
 func SelectMatchingProfiles(ctx context.Context, request *ingestv1.SelectProfilesRequest, queriers Queriers) ([]iter.Iterator[Profile], error) {
+	stats.FromContext(ctx).AddBlocksQueried(uint64(len(queriers)))
 	g, ctx := errgroup.WithContext(ctx)
 	iters := make([]iter.Iterator[Profile], len(queriers))
 
@@ -493,6 +529,13 @@ func MergeProfilesStacktraces(ctx context.Context, stream *connect.BidiStream[in
 		otlog.String("profile_id", request.Type.ID),
 	)
 
+	var spans phlaremodel.SpanSelector
+	if len(r.SpanSelector) > 0 {
+		if spans, err = phlaremodel.NewSpanSelector(r.SpanSelector); err != nil {
+			return connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	}
+
 	queriers, err := blockGetter(ctx, model.Time(request.Start), model.Time(request.End))
 	if err != nil {
 		return err
@@ -512,6 +555,10 @@ func MergeProfilesStacktraces(ctx context.Context, stream *connect.BidiStream[in
 		return err
 	}
 
+	if r.Format == ingestv1.StacktracesMergeFormat_MERGE_FORMAT_FUNCTION_TABLE {
+		return mergeFunctionTable(ctx, stream, queriers, selectedProfiles, r.FunctionTableByLine, r.FunctionName)
+	}
+
 	var m sync.Mutex
 	t := new(phlaremodel.Tree)
 	g, ctx := errgroup.WithContext(ctx)
@@ -525,7 +572,14 @@ func MergeProfilesStacktraces(ctx context.Context, stream *connect.BidiStream[in
 		// Sort profiles for better read locality.
 		// Merge async the result so we can continue streaming profiles.
 		g.Go(util.RecoverPanic(func() error {
-			merge, err := querier.MergeByStacktraces(ctx, iter.NewSliceIterator(querier.Sort(selectedProfiles[i])))
+			var merge *phlaremodel.Tree
+			var err error
+			profiles := iter.NewSliceIterator(querier.Sort(selectedProfiles[i]))
+			if spans != nil {
+				merge, err = querier.MergeBySpans(ctx, profiles, spans)
+			} else {
+				merge, err = querier.MergeByStacktraces(ctx, profiles, r.StackTraceSelector)
+			}
 			if err != nil {
 				return err
 			}
@@ -570,6 +624,75 @@ func MergeProfilesStacktraces(ctx context.Context, stream *connect.BidiStream[in
 	return nil
 }
 
+// mergeFunctionTable merges the selected profiles into a function table
+// and sends it to the client, as the result of MergeProfilesStacktraces.
+// If the function name is specified, the table only contains the lines
+// of the function.
+func mergeFunctionTable(
+	ctx context.Context,
+	stream *connect.BidiStream[ingestv1.MergeProfilesStacktracesRequest, ingestv1.MergeProfilesStacktracesResponse],
+	queriers Queriers,
+	selectedProfiles [][]Profile,
+	byLine bool,
+	function string,
+) error {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "mergeFunctionTable")
+	defer sp.Finish()
+
+	m := phlaremodel.NewFunctionTableMerger()
+	g, ctx := errgroup.WithContext(ctx)
+	for i, querier := range queriers {
+		querier := querier
+		i := i
+		if len(selectedProfiles[i]) == 0 {
+			continue
+		}
+		// Sort profiles for better read locality.
+		// Merge async the result so we can continue streaming profiles.
+		g.Go(util.RecoverPanic(func() error {
+			var merge *typesv1.FunctionTable
+			var err error
+			profiles := iter.NewSliceIterator(querier.Sort(selectedProfiles[i]))
+			if function != "" {
+				merge, err = querier.MergeFunctionLines(ctx, profiles, function)
+			} else {
+				merge, err = querier.MergeFunctionTable(ctx, profiles, byLine)
+			}
+			if err != nil {
+				return err
+			}
+			m.MergeFunctionTable(merge)
+			return nil
+		}))
+	}
+
+	// Signals the end of the profile streaming by sending an empty response.
+	// This allows the client to not block other streaming ingesters.
+	if err := stream.Send(&ingestv1.MergeProfilesStacktracesResponse{}); err != nil {
+		return err
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	// sends the final result to the client.
+	err := stream.Send(&ingestv1.MergeProfilesStacktracesResponse{
+		Result: &ingestv1.MergeProfilesStacktracesResult{
+			Format:        ingestv1.StacktracesMergeFormat_MERGE_FORMAT_FUNCTION_TABLE,
+			FunctionTable: m.FunctionTable(0, false),
+		},
+	})
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return connect.NewError(connect.CodeCanceled, errors.New("client closed stream"))
+		}
+		return err
+	}
+
+	return nil
+}
+
 func MergeProfilesLabels(ctx context.Context, stream *connect.BidiStream[ingestv1.MergeProfilesLabelsRequest, ingestv1.MergeProfilesLabelsResponse], blockGetter BlockGetter) error {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeProfilesLabels")
 	defer sp.Finish()
@@ -632,9 +755,14 @@ func MergeProfilesLabels(ctx context.Context, stream *connect.BidiStream[ingestv
 		// Sort profiles for better read locality.
 		// And merge async the result for each queriers.
 		g.Go(util.RecoverPanic(func() error {
-			merge, err := querier.MergeByLabels(ctx,
-				iter.NewSliceIterator(querier.Sort(selectedProfiles[i])),
-				by...)
+			var merge []*typesv1.Series
+			var err error
+			profiles := iter.NewSliceIterator(querier.Sort(selectedProfiles[i]))
+			if r.FunctionSeries != nil {
+				merge, err = querier.MergeFunctionSeries(ctx, profiles, r.FunctionSeries, by...)
+			} else {
+				merge, err = querier.MergeByLabels(ctx, profiles, r.StackTraceSelector, r.MaxExemplars > 0, by...)
+			}
 			if err != nil {
 				return err
 			}
@@ -652,7 +780,7 @@ func MergeProfilesLabels(ctx context.Context, stream *connect.BidiStream[ingestv
 
 	// sends the final result to the client.
 	err = stream.Send(&ingestv1.MergeProfilesLabelsResponse{
-		Series: phlaremodel.SumSeries(result...),
+		Series: phlaremodel.TrimExemplars(phlaremodel.MergeSeries(r.GetAggregation(), result...), int(r.MaxExemplars)),
 	})
 	if err != nil {
 		if errors.Is(err, io.EOF) {
@@ -718,7 +846,7 @@ func MergeProfilesPprof(ctx context.Context, stream *connect.BidiStream[ingestv1
 		// Sort profiles for better read locality.
 		// Merge async the result so we can continue streaming profiles.
 		g.Go(util.RecoverPanic(func() error {
-			merge, err := querier.MergePprof(ctx, iter.NewSliceIterator(querier.Sort(selectedProfiles[i])))
+			merge, err := querier.MergePprof(ctx, iter.NewSliceIterator(querier.Sort(selectedProfiles[i])), r.StackTraceSelector)
 			if err != nil {
 				return err
 			}
@@ -769,6 +897,101 @@ func MergeProfilesPprof(ctx context.Context, stream *connect.BidiStream[ingestv1
 	return nil
 }
 
+// SelectProfileByID looks up the profile with the given ID among the
+// profiles of the requested type and time range. The response result
+// is empty, if the profile is not found.
+func SelectProfileByID(ctx context.Context, req *ingestv1.SelectProfileByIDRequest, blockGetter BlockGetter) (*ingestv1.SelectProfileByIDResponse, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectProfileByID")
+	defer sp.Finish()
+	if req.Type == nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("missing profile type"))
+	}
+	id, err := uuid.Parse(req.ProfileId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.Wrap(err, "invalid profile ID"))
+	}
+	sp.LogFields(
+		otlog.String("start", model.Time(req.Start).Time().String()),
+		otlog.String("end", model.Time(req.End).Time().String()),
+		otlog.String("profile_id", req.ProfileId),
+		otlog.String("profile_type", req.Type.ID),
+	)
+
+	queriers, err := blockGetter(ctx, model.Time(req.Start), model.Time(req.End))
+	if err != nil {
+		return nil, err
+	}
+	params := &ingestv1.SelectProfilesRequest{
+		LabelSelector: "{}",
+		Type:          req.Type,
+		Start:         req.Start,
+		End:           req.End,
+	}
+	for _, q := range queriers {
+		p, err := q.SelectProfileByID(ctx, id, params)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			continue
+		}
+		pp, err := q.MergePprof(ctx, iter.NewSliceIterator([]Profile{p}), nil)
+		if err != nil {
+			return nil, err
+		}
+		phlaremodel.SetProfileMetadata(pp, req.Type)
+		pp.TimeNanos = p.Timestamp().UnixNano()
+		// connect go already handles compression.
+		var buf bytes.Buffer
+		if err = pp.WriteUncompressed(&buf); err != nil {
+			return nil, err
+		}
+		return &ingestv1.SelectProfileByIDResponse{Result: buf.Bytes()}, nil
+	}
+	return &ingestv1.SelectProfileByIDResponse{}, nil
+}
+
+type columnIterFunc func(ctx context.Context, columnName string, predicate query.Predicate, alias string) query.Iterator
+
+// selectProfileByID looks up the profile with the given ID in the parquet
+// profiles table. The ID predicate is pushed down to the ID column, which
+// skips the row groups and pages that can't contain the ID based on their
+// statistics. Once the row is found, the profiles matching the request are
+// only selected at the profile timestamp, to retrieve the profile series.
+func selectProfileByID(ctx context.Context, q Querier, columnIter columnIterFunc, id uuid.UUID, params *ingestv1.SelectProfilesRequest) (Profile, error) {
+	it := query.NewBinaryJoinIterator(0,
+		columnIter(ctx, "ID", query.NewStringInPredicate([]string{string(id[:])}), "ID"),
+		columnIter(ctx, "TimeNanos", query.NewIntBetweenPredicate(model.Time(params.Start).UnixNano(), model.Time(params.End).UnixNano()), "TimeNanos"),
+	)
+	defer it.Close()
+	if !it.Next() {
+		return nil, it.Err()
+	}
+	res := it.At()
+	rowNum := res.RowNumber[0]
+	buf := res.Columns(make([][]parquet.Value, 1), "TimeNanos")
+	if len(buf[0]) != 1 {
+		return nil, fmt.Errorf("expected a single timestamp for profile %s, got %d", id, len(buf[0]))
+	}
+
+	// The time range is specified in milliseconds.
+	ts := model.TimeFromUnixNano(buf[0][0].Int64())
+	params = params.CloneVT()
+	params.Start = int64(ts)
+	params.End = int64(ts) + 1
+	profiles, err := q.SelectMatchingProfiles(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer profiles.Close()
+	for profiles.Next() {
+		if p, ok := profiles.At().(BlockProfile); ok && p.RowNum == rowNum {
+			return p, nil
+		}
+	}
+	return nil, profiles.Err()
+}
+
 func Series(ctx context.Context, req *ingestv1.SeriesRequest, blockGetter BlockGetter) (*ingestv1.SeriesResponse, error) {
 	queriers, err := blockGetter(ctx, model.Time(req.Start), model.Time(req.End))
 	if err != nil {
@@ -811,6 +1034,150 @@ func Series(ctx context.Context, req *ingestv1.SeriesRequest, blockGetter BlockG
 	}, nil
 }
 
+// LabelValues returns the label values for the given label name found in the
+// queriers overlapping the requested time range.
+func LabelValues(ctx context.Context, req *typesv1.LabelValuesRequest, blockGetter BlockGetter) (*typesv1.LabelValuesResponse, error) {
+	queriers, err := blockGetter(ctx, model.Time(req.Start), model.Time(req.End))
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := forQueriers(ctx, queriers, func(ctx context.Context, q Querier) ([]string, error) {
+		return q.LabelValues(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &typesv1.LabelValuesResponse{Names: values}, nil
+}
+
+// LabelNames returns the label names found in the queriers overlapping the
+// requested time range.
+func LabelNames(ctx context.Context, req *typesv1.LabelNamesRequest, blockGetter BlockGetter) (*typesv1.LabelNamesResponse, error) {
+	queriers, err := blockGetter(ctx, model.Time(req.Start), model.Time(req.End))
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := forQueriers(ctx, queriers, func(ctx context.Context, q Querier) ([]string, error) {
+		return q.LabelNames(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &typesv1.LabelNamesResponse{Names: names}, nil
+}
+
+// Cardinality counts the series matching the request, found in the
+// queriers overlapping the requested time range, per label name and value.
+// A series stored in multiple blocks is counted once: the label sets of
+// a block are dropped once counted, only the fingerprints are retained.
+func Cardinality(ctx context.Context, req *ingestv1.CardinalityRequest, blockGetter BlockGetter) (*ingestv1.CardinalityResponse, error) {
+	queriers, err := blockGetter(ctx, model.Time(req.Start), model.Time(req.End))
+	if err != nil {
+		return nil, err
+	}
+
+	type labelPair struct{ name, value string }
+	var (
+		lock   sync.Mutex
+		seen   = make(map[uint64]struct{})
+		counts = make(map[labelPair]uint64)
+	)
+	group, ctx := errgroup.WithContext(ctx)
+
+	const concurrentQueryLimit = 50
+	group.SetLimit(concurrentQueryLimit)
+
+	for _, q := range queriers {
+		q := q
+		group.Go(util.RecoverPanic(func() error {
+			labelsSet, err := q.Series(ctx, &ingestv1.SeriesRequest{
+				Matchers: req.Matchers,
+				Start:    req.Start,
+				End:      req.End,
+			})
+			if err != nil {
+				return err
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			for _, ls := range labelsSet {
+				fp := phlaremodel.Labels(ls.Labels).Hash()
+				if _, ok := seen[fp]; ok {
+					continue
+				}
+				seen[fp] = struct{}{}
+				for _, l := range ls.Labels {
+					counts[labelPair{name: l.Name, value: l.Value}]++
+				}
+			}
+			return nil
+		}))
+	}
+	if err = group.Wait(); err != nil {
+		return nil, err
+	}
+
+	res := &ingestv1.CardinalityResponse{
+		TotalSeries:            uint64(len(seen)),
+		SeriesCountByLabelPair: make([]*ingestv1.LabelPairSeriesCount, 0, len(counts)),
+	}
+	for p, n := range counts {
+		res.SeriesCountByLabelPair = append(res.SeriesCountByLabelPair, &ingestv1.LabelPairSeriesCount{
+			Name:        p.name,
+			Value:       p.value,
+			SeriesCount: n,
+		})
+	}
+	sort.Slice(res.SeriesCountByLabelPair, func(i, j int) bool {
+		a, b := res.SeriesCountByLabelPair[i], res.SeriesCountByLabelPair[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Value < b.Value
+	})
+	return res, nil
+}
+
+// forQueriers runs fn concurrently for all the queriers and returns the
+// sorted, deduplicated union of the results.
+func forQueriers(ctx context.Context, queriers Queriers, fn func(context.Context, Querier) ([]string, error)) ([]string, error) {
+	var (
+		lock   sync.Mutex
+		unique = make(map[string]struct{})
+	)
+	group, ctx := errgroup.WithContext(ctx)
+
+	const concurrentQueryLimit = 50
+	group.SetLimit(concurrentQueryLimit)
+
+	for _, q := range queriers {
+		q := q
+		group.Go(util.RecoverPanic(func() error {
+			values, err := fn(ctx, q)
+			if err != nil {
+				return err
+			}
+
+			lock.Lock()
+			for _, v := range values {
+				unique[v] = struct{}{}
+			}
+			lock.Unlock()
+			return nil
+		}))
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	result := lo.Keys(unique)
+	sort.Strings(result)
+	return result, nil
+}
+
 var maxBlockProfile Profile = BlockProfile{
 	ts: model.Time(math.MaxInt64),
 }
@@ -858,14 +1225,10 @@ func (b *singleBlockQuerier) SelectMatchingProfiles(ctx context.Context, params
 	if err := b.Open(ctx); err != nil {
 		return nil, err
 	}
-	matchers, err := parser.ParseMetricSelector(params.LabelSelector)
+	matchers, shard, err := selectProfilesMatchers(params)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, "failed to parse label selectors: "+err.Error())
-	}
-	if params.Type == nil {
-		return nil, errors.New("no profileType given")
+		return nil, err
 	}
-	matchers = append(matchers, phlaremodel.SelectorFromProfileType(params.Type))
 
 	postings, err := PostingsForMatchers(b.index, nil, matchers...)
 	if err != nil {
@@ -884,6 +1247,9 @@ func (b *singleBlockQuerier) SelectMatchingProfiles(ctx context.Context, params
 		if err != nil {
 			return nil, err
 		}
+		if shard != nil && !shard.Match(fp) {
+			continue
+		}
 		if lblsExisting, exists := lblsPerRef[int64(chks[0].SeriesIndex)]; exists {
 			// Compare to check if there is a clash
 			if phlaremodel.CompareLabelPairs(lbls, lblsExisting.lbs) != 0 {
@@ -897,6 +1263,7 @@ func (b *singleBlockQuerier) SelectMatchingProfiles(ctx context.Context, params
 			lbls = make(phlaremodel.Labels, 0, 6)
 		}
 	}
+	stats.FromContext(ctx).AddSeriesMatched(uint64(len(lblsPerRef)))
 
 	var buf [][]parquet.Value
 
@@ -920,10 +1287,23 @@ func (b *singleBlockQuerier) SelectMatchingProfiles(ctx context.Context, params
 	iters := make([]iter.Iterator[Profile], 0, len(lblsPerRef))
 	defer pIt.Close()
 
+	var (
+		rowGroups        = b.profiles.file.RowGroups()
+		rowGroupsTouched uint64
+		rowGroupEnd      int64
+	)
 	currSeriesIndex := int64(-1)
 	var currentSeriesSlice []Profile
 	for pIt.Next() {
 		res := pIt.At()
+		// Rows are iterated in the ascending order.
+		if rowNum := res.RowNumber[0]; rowNum >= rowGroupEnd {
+			for rowNum >= rowGroupEnd && len(rowGroups) > 0 {
+				rowGroupEnd += rowGroups[0].NumRows()
+				rowGroups = rowGroups[1:]
+			}
+			rowGroupsTouched++
+		}
 		buf = res.Columns(buf, "SeriesIndex", "TimeNanos", "StacktracePartition")
 		seriesIndex := buf[0][0].Int64()
 		if seriesIndex != currSeriesIndex {
@@ -945,6 +1325,7 @@ func (b *singleBlockQuerier) SelectMatchingProfiles(ctx context.Context, params
 	if len(currentSeriesSlice) > 0 {
 		iters = append(iters, iter.NewSliceIterator(currentSeriesSlice))
 	}
+	stats.FromContext(ctx).AddRowGroupsTouched(rowGroupsTouched)
 
 	return iter.NewMergeIterator(maxBlockProfile, false, iters...), nil
 }
@@ -1014,6 +1395,98 @@ func (b *singleBlockQuerier) Series(ctx context.Context, params *ingestv1.Series
 	return labelsSets, nil
 }
 
+// LabelValues returns the values of the given label name in this block.
+//
+// Note: Like Series, it does not take the requested time range into account
+// beyond the block selection.
+func (b *singleBlockQuerier) LabelValues(ctx context.Context, params *typesv1.LabelValuesRequest) ([]string, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "LabelValues Block")
+	defer sp.Finish()
+
+	if err := b.Open(ctx); err != nil {
+		return nil, err
+	}
+
+	selectors, err := parseSelectors(params.Matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	if selectors.matchesAll() {
+		return b.index.LabelValues(params.Name)
+	}
+
+	values := make(map[string]struct{})
+	for _, matchers := range selectors {
+		postings, err := PostingsForMatchers(b.index, nil, matchers...)
+		if err != nil {
+			return nil, err
+		}
+		for postings.Next() {
+			value, err := b.index.LabelValueFor(postings.At(), params.Name)
+			if err != nil {
+				if err == storage.ErrNotFound {
+					continue
+				}
+				return nil, err
+			}
+			values[value] = struct{}{}
+		}
+		if err = postings.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return lo.Keys(values), nil
+}
+
+// LabelNames returns the label names present in this block.
+//
+// Note: Like Series, it does not take the requested time range into account
+// beyond the block selection.
+func (b *singleBlockQuerier) LabelNames(ctx context.Context, params *typesv1.LabelNamesRequest) ([]string, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "LabelNames Block")
+	defer sp.Finish()
+
+	if err := b.Open(ctx); err != nil {
+		return nil, err
+	}
+
+	selectors, err := parseSelectors(params.Matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	if selectors.matchesAll() {
+		return b.index.LabelNames()
+	}
+
+	names := make(map[string]struct{})
+	for _, matchers := range selectors {
+		postings, err := PostingsForMatchers(b.index, nil, matchers...)
+		if err != nil {
+			return nil, err
+		}
+		var refs []storage.SeriesRef
+		for postings.Next() {
+			refs = append(refs, postings.At())
+		}
+		if err = postings.Err(); err != nil {
+			return nil, err
+		}
+		if len(refs) == 0 {
+			continue
+		}
+		matched, err := b.index.LabelNamesFor(refs...)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range matched {
+			names[name] = struct{}{}
+		}
+	}
+	return lo.Keys(names), nil
+}
+
 func (b *singleBlockQuerier) getUniqueLabelsSets(postings index.Postings, names []string, fingerprints *map[uint64]struct{}) ([]*typesv1.Labels, error) {
 	var labelsSets []*typesv1.Labels
 	for postings.Next() {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -136,7 +137,7 @@ func TestBlockCompatability(t *testing.T) {
 				pcIt := &profileCounter{Iterator: it}
 
 				// TODO: It would be nice actually comparing the whole profile, but at present the result is not deterministic.
-				_, err = q.MergePprof(ctx, pcIt)
+				_, err = q.MergePprof(ctx, pcIt, nil)
 				require.NoError(t, err)
 
 				profileCount += pcIt.count
@@ -658,6 +659,83 @@ func Test_singleBlockQuerier_Series(t *testing.T) {
 	})
 }
 
+func Test_singleBlockQuerier_LabelValues(t *testing.T) {
+	ctx := context.Background()
+	reader, err := index.NewFileReader("testdata/01HA2V3CPSZ9E0HMQNNHH89WSS/index.tsdb")
+	assert.NoError(t, err)
+
+	q := &singleBlockQuerier{
+		metrics: newBlocksMetrics(nil),
+		meta:    &block.Meta{ULID: ulid.MustParse("01HA2V3CPSZ9E0HMQNNHH89WSS")},
+		opened:  true, // Skip trying to open the block.
+		index:   reader,
+	}
+
+	t.Run("get all values", func(t *testing.T) {
+		got, err := q.LabelValues(ctx, &typesv1.LabelValuesRequest{Name: "__name__"})
+		assert.NoError(t, err)
+		sort.Strings(got)
+		assert.Equal(t, []string{"block", "goroutine", "memory", "mutex", "process_cpu"}, got)
+	})
+
+	t.Run("get values with matcher", func(t *testing.T) {
+		got, err := q.LabelValues(ctx, &typesv1.LabelValuesRequest{
+			Name:     "__type__",
+			Matchers: []string{`{__name__="memory"}`},
+		})
+		assert.NoError(t, err)
+		sort.Strings(got)
+		assert.Equal(t, []string{"alloc_objects", "alloc_space", "inuse_objects", "inuse_space"}, got)
+	})
+
+	t.Run("get values of missing label", func(t *testing.T) {
+		got, err := q.LabelValues(ctx, &typesv1.LabelValuesRequest{
+			Name:     "missing",
+			Matchers: []string{`{__name__="memory"}`},
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+}
+
+func Test_singleBlockQuerier_LabelNames(t *testing.T) {
+	ctx := context.Background()
+	reader, err := index.NewFileReader("testdata/01HA2V3CPSZ9E0HMQNNHH89WSS/index.tsdb")
+	assert.NoError(t, err)
+
+	q := &singleBlockQuerier{
+		metrics: newBlocksMetrics(nil),
+		meta:    &block.Meta{ULID: ulid.MustParse("01HA2V3CPSZ9E0HMQNNHH89WSS")},
+		opened:  true, // Skip trying to open the block.
+		index:   reader,
+	}
+
+	t.Run("get all names", func(t *testing.T) {
+		want, err := q.index.LabelNames()
+		assert.NoError(t, err)
+		got, err := q.LabelNames(ctx, &typesv1.LabelNamesRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("get names with matcher", func(t *testing.T) {
+		got, err := q.LabelNames(ctx, &typesv1.LabelNamesRequest{
+			Matchers: []string{`{__name__="memory"}`},
+		})
+		assert.NoError(t, err)
+		assert.Contains(t, got, "__type__")
+		assert.NotContains(t, got, "foo")
+	})
+
+	t.Run("get names with no match", func(t *testing.T) {
+		got, err := q.LabelNames(ctx, &typesv1.LabelNamesRequest{
+			Matchers: []string{`{__name__="missing"}`},
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+}
+
 func Benchmark_singleBlockQuerier_Series(b *testing.B) {
 	ctx := context.Background()
 	reader, err := index.NewFileReader("testdata/01HA2V3CPSZ9E0HMQNNHH89WSS/index.tsdb")
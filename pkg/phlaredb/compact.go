@@ -466,8 +466,12 @@ func newProfileRowIterator(s BlockReader) (*profileRowIterator, error) {
 	if err != nil {
 		return nil, err
 	}
+	rowGroups, err := profileRowGroups(s)
+	if err != nil {
+		return nil, err
+	}
 	// todo close once https://github.com/grafana/pyroscope/issues/2172 is done.
-	reader := parquet.MultiRowGroup(s.Profiles()...).Rows()
+	reader := parquet.MultiRowGroup(rowGroups...).Rows()
 	return &profileRowIterator{
 		profiles:         phlareparquet.NewBufferedRowReaderIterator(reader, 1024),
 		blockReader:      s,
@@ -478,6 +482,26 @@ func newProfileRowIterator(s BlockReader) (*profileRowIterator, error) {
 	}, nil
 }
 
+// profileRowGroups returns the profile row groups of the block. Row groups
+// written before sample span IDs were stored are converted to the current
+// schema, so that the rows of all the blocks have the same columns.
+func profileRowGroups(s BlockReader) ([]parquet.RowGroup, error) {
+	rowGroups := s.Profiles()
+	converted := make([]parquet.RowGroup, len(rowGroups))
+	for i, rg := range rowGroups {
+		if _, ok := rg.Schema().Lookup("Samples", "list", "element", "SpanID"); ok {
+			converted[i] = rg
+			continue
+		}
+		conv, err := parquet.Convert(schemav1.ProfilesSchema, rg.Schema())
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = parquet.ConvertRowGroup(rg, conv)
+	}
+	return converted, nil
+}
+
 func (p *profileRowIterator) At() profileRow {
 	return p.currentRow
 }
@@ -69,7 +69,7 @@ func TestCompact(t *testing.T) {
 	}
 	it, err := querier.SelectMatchingProfiles(ctx, matchAll)
 	require.NoError(t, err)
-	series, err := querier.MergeByLabels(ctx, it, "job")
+	series, err := querier.MergeByLabels(ctx, it, nil, false, "job")
 	require.NoError(t, err)
 	require.Equal(t, []*typesv1.Series{
 		{Labels: phlaremodel.LabelsFromStrings("job", "a"), Points: []*typesv1.Point{{Value: float64(1), Timestamp: int64(1000)}}},
@@ -79,7 +79,7 @@ func TestCompact(t *testing.T) {
 
 	it, err = querier.SelectMatchingProfiles(ctx, matchAll)
 	require.NoError(t, err)
-	res, err := querier.MergeByStacktraces(ctx, it)
+	res, err := querier.MergeByStacktraces(ctx, it, nil)
 	require.NoError(t, err)
 	require.NotNil(t, res)
 
@@ -169,7 +169,7 @@ func TestCompactWithSplitting(t *testing.T) {
 	// Then we query 2 different shards and verify we have a subset of series.
 	it, err = queriers[0].SelectMatchingProfiles(ctx, matchAll)
 	require.NoError(t, err)
-	seriesResult, err := queriers[0].MergeByLabels(context.Background(), it, "job")
+	seriesResult, err := queriers[0].MergeByLabels(context.Background(), it, nil, false, "job")
 	require.NoError(t, err)
 	require.Equal(t,
 		[]*typesv1.Series{
@@ -181,7 +181,7 @@ func TestCompactWithSplitting(t *testing.T) {
 
 	it, err = queriers[1].SelectMatchingProfiles(ctx, matchAll)
 	require.NoError(t, err)
-	seriesResult, err = queriers[1].MergeByLabels(context.Background(), it, "job")
+	seriesResult, err = queriers[1].MergeByLabels(context.Background(), it, nil, false, "job")
 	require.NoError(t, err)
 	require.Equal(t,
 		[]*typesv1.Series{
@@ -194,7 +194,7 @@ func TestCompactWithSplitting(t *testing.T) {
 	// Finally test some stacktraces resolution.
 	it, err = queriers[1].SelectMatchingProfiles(ctx, matchAll)
 	require.NoError(t, err)
-	res, err := queriers[1].MergeByStacktraces(ctx, it)
+	res, err := queriers[1].MergeByStacktraces(ctx, it, nil)
 	require.NoError(t, err)
 
 	expected := new(phlaremodel.Tree)
@@ -0,0 +1,182 @@
+package phlaredb
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/common/model"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/iter"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/phlaredb/query"
+	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
+	"github.com/grafana/pyroscope/pkg/phlaredb/symdb"
+)
+
+// mergeFunctionSeries adds a point per profile and function
+// to the series of the function the profile belongs to.
+func mergeFunctionSeries(
+	ctx context.Context,
+	profileSource Source,
+	symbols symdb.SymbolsReader,
+	rows iter.Iterator[Profile],
+	m seriesByLabels,
+	fs *typesv1.FunctionSeriesSelector,
+	by ...string,
+) error {
+	multiRows, err := iter.CloneN(rows, 2)
+	if err != nil {
+		return err
+	}
+	it := query.NewMultiRepeatedPageIterator(
+		repeatedColumnIter(ctx, profileSource, "Samples.list.element.StacktraceID", multiRows[0]),
+		repeatedColumnIter(ctx, profileSource, "Samples.list.element.Value", multiRows[1]),
+	)
+	defer it.Close()
+
+	selection := newFunctionSelection(symbols, fs)
+	defer selection.release()
+	b := newFunctionSeriesBuilder(m, fs.Functions, by)
+	var samples schemav1.Samples
+
+	for it.Next() {
+		values := it.At()
+		samples.StacktraceIDs = samples.StacktraceIDs[:0]
+		samples.Values = samples.Values[:0]
+		for i := range values.Values[0] {
+			samples.StacktraceIDs = append(samples.StacktraceIDs, uint32(values.Values[0][i].Int64()))
+			samples.Values = append(samples.Values, uint64(values.Values[1][i].Int64()))
+		}
+		v, err := selection.values(ctx, values.Row.StacktracePartition(), samples)
+		if err != nil {
+			return err
+		}
+		b.add(values.Row, v)
+	}
+	return it.Err()
+}
+
+// functionSelection computes the values of the functions in the profile
+// samples. The functions a stack trace is accounted to are only
+// determined once per partition.
+type functionSelection struct {
+	symbols    symdb.SymbolsReader
+	fs         *typesv1.FunctionSeriesSelector
+	partitions map[uint64]*functionPartition
+	buf        []int64
+}
+
+type functionPartition struct {
+	reader    symdb.PartitionReader
+	functions map[uint32][]int
+	resolved  map[uint32]struct{}
+}
+
+func newFunctionSelection(symbols symdb.SymbolsReader, fs *typesv1.FunctionSeriesSelector) *functionSelection {
+	return &functionSelection{
+		symbols:    symbols,
+		fs:         fs,
+		partitions: make(map[uint64]*functionPartition),
+		buf:        make([]int64, len(fs.Functions)),
+	}
+}
+
+// values returns the value of each of the functions in the samples.
+// The slice is only valid until the next call.
+func (s *functionSelection) values(ctx context.Context, partition uint64, samples schemav1.Samples) ([]int64, error) {
+	p, ok := s.partitions[partition]
+	if !ok {
+		r, err := s.symbols.Partition(ctx, partition)
+		if err != nil {
+			return nil, err
+		}
+		p = &functionPartition{
+			reader:    r,
+			functions: make(map[uint32][]int),
+			resolved:  make(map[uint32]struct{}),
+		}
+		s.partitions[partition] = p
+	}
+	var unknown []uint32
+	for _, sid := range samples.StacktraceIDs {
+		if _, ok = p.resolved[sid]; !ok {
+			p.resolved[sid] = struct{}{}
+			unknown = append(unknown, sid)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+		selected, err := p.reader.Symbols().SelectFunctions(ctx, s.fs, unknown)
+		if err != nil {
+			return nil, err
+		}
+		for sid, functions := range selected {
+			p.functions[sid] = functions
+		}
+	}
+	for i := range s.buf {
+		s.buf[i] = 0
+	}
+	for i, sid := range samples.StacktraceIDs {
+		for _, f := range p.functions[sid] {
+			s.buf[f] += int64(samples.Values[i])
+		}
+	}
+	return s.buf, nil
+}
+
+func (s *functionSelection) release() {
+	for _, p := range s.partitions {
+		p.reader.Release()
+	}
+}
+
+// functionSeriesBuilder adds the function values of the profiles to the
+// series grouped by the labels, and split by function.
+type functionSeriesBuilder struct {
+	m                   seriesByLabels
+	functions           []string
+	by                  []string
+	labelsByFingerprint map[model.Fingerprint]string
+	labelBuf            []byte
+}
+
+func newFunctionSeriesBuilder(m seriesByLabels, functions []string, by []string) *functionSeriesBuilder {
+	return &functionSeriesBuilder{
+		m:                   m,
+		functions:           functions,
+		by:                  by,
+		labelsByFingerprint: make(map[model.Fingerprint]string),
+		labelBuf:            make([]byte, 0, 1024),
+	}
+}
+
+func (b *functionSeriesBuilder) add(p Profile, values []int64) {
+	labelsByString, ok := b.labelsByFingerprint[p.Fingerprint()]
+	if !ok {
+		b.labelBuf = p.Labels().BytesWithLabels(b.labelBuf, b.by...)
+		labelsByString = string(b.labelBuf)
+		b.labelsByFingerprint[p.Fingerprint()] = labelsByString
+	}
+	for i, function := range b.functions {
+		// The labels string starts with a separator, which
+		// can't be present in the function index.
+		key := strconv.Itoa(i) + labelsByString
+		series, ok := b.m[key]
+		if !ok {
+			lbs := append(p.Labels().WithLabels(b.by...), &typesv1.LabelPair{
+				Name:  phlaremodel.LabelNameFunction,
+				Value: function,
+			})
+			sort.Sort(lbs)
+			series = &typesv1.Series{Labels: lbs}
+			b.m[key] = series
+		}
+		series.Points = append(series.Points, &typesv1.Point{
+			Timestamp: int64(p.Timestamp()),
+			Value:     float64(values[i]),
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/bufbuild/connect-go"
 	"github.com/go-kit/log/level"
 	"github.com/google/pprof/profile"
+	"github.com/google/uuid"
 	"github.com/opentracing/opentracing-go"
 	"github.com/parquet-go/parquet-go"
 	"github.com/pkg/errors"
@@ -19,6 +20,7 @@ import (
 	"github.com/grafana/pyroscope/pkg/phlaredb/query"
 	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
 	"github.com/grafana/pyroscope/pkg/phlaredb/symdb"
+	"github.com/grafana/pyroscope/pkg/querier/stats"
 )
 
 type headOnDiskQuerier struct {
@@ -94,6 +96,11 @@ func (q *headOnDiskQuerier) SelectMatchingProfiles(ctx context.Context, params *
 	if err := pIt.Err(); err != nil {
 		return nil, errors.Wrap(pIt.Err(), "iterator error")
 	}
+	s := stats.FromContext(ctx)
+	s.AddSeriesMatched(uint64(len(labelsPerFP)))
+	if len(profiles) > 0 {
+		s.AddRowGroupsTouched(1)
+	}
 
 	// Sort profiles by time, the slice is already sorted by series order
 	sort.Slice(profiles, func(i, j int) bool {
@@ -108,10 +115,10 @@ func (q *headOnDiskQuerier) Bounds() (model.Time, model.Time) {
 	return q.head.Bounds()
 }
 
-func (q *headOnDiskQuerier) MergeByStacktraces(ctx context.Context, rows iter.Iterator[Profile]) (*phlaremodel.Tree, error) {
+func (q *headOnDiskQuerier) MergeByStacktraces(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector) (*phlaremodel.Tree, error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeByStacktraces")
 	defer sp.Finish()
-	r := symdb.NewResolver(ctx, q.head.symdb)
+	r := symdb.NewResolver(ctx, q.head.symdb, symdb.WithStackTraceSelector(sts))
 	defer r.Release()
 	if err := mergeByStacktraces(ctx, q.rowGroup(), rows, r); err != nil {
 		return nil, err
@@ -119,35 +126,97 @@ func (q *headOnDiskQuerier) MergeByStacktraces(ctx context.Context, rows iter.It
 	return r.Tree()
 }
 
-func (q *headOnDiskQuerier) MergePprof(ctx context.Context, rows iter.Iterator[Profile]) (*profile.Profile, error) {
-	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergePprof")
+func (q *headOnDiskQuerier) MergeBySpans(ctx context.Context, rows iter.Iterator[Profile], spans phlaremodel.SpanSelector) (*phlaremodel.Tree, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeBySpans - HeadOnDisk")
 	defer sp.Finish()
 	r := symdb.NewResolver(ctx, q.head.symdb)
 	defer r.Release()
+	if err := mergeBySpans(ctx, q.rowGroup(), rows, r, spans); err != nil {
+		return nil, err
+	}
+	return r.Tree()
+}
+
+func (q *headOnDiskQuerier) MergePprof(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector) (*profile.Profile, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergePprof")
+	defer sp.Finish()
+	r := symdb.NewResolver(ctx, q.head.symdb, symdb.WithStackTraceSelector(sts))
+	defer r.Release()
 	if err := mergeByStacktraces(ctx, q.rowGroup(), rows, r); err != nil {
 		return nil, err
 	}
 	return r.Profile()
 }
 
-func (q *headOnDiskQuerier) MergeByLabels(ctx context.Context, rows iter.Iterator[Profile], by ...string) ([]*typesv1.Series, error) {
+func (q *headOnDiskQuerier) MergeFunctionTable(ctx context.Context, rows iter.Iterator[Profile], byLine bool) (*typesv1.FunctionTable, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeFunctionTable - HeadOnDisk")
+	defer sp.Finish()
+	r := symdb.NewResolver(ctx, q.head.symdb)
+	defer r.Release()
+	if err := mergeByStacktraces(ctx, q.rowGroup(), rows, r); err != nil {
+		return nil, err
+	}
+	return r.FunctionTable(byLine)
+}
+
+func (q *headOnDiskQuerier) MergeFunctionLines(ctx context.Context, rows iter.Iterator[Profile], function string) (*typesv1.FunctionTable, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeFunctionLines - HeadOnDisk")
+	defer sp.Finish()
+	r := symdb.NewResolver(ctx, q.head.symdb)
+	defer r.Release()
+	if err := mergeByStacktraces(ctx, q.rowGroup(), rows, r); err != nil {
+		return nil, err
+	}
+	return r.FunctionLines(function)
+}
+
+func (q *headOnDiskQuerier) MergeByLabels(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector, exemplars bool, by ...string) ([]*typesv1.Series, error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeByLabels - HeadOnDisk")
 	defer sp.Finish()
 
 	seriesByLabels := make(seriesByLabels)
 
-	if err := mergeByLabels(ctx, q.rowGroup(), "TotalValue", rows, seriesByLabels, by...); err != nil {
+	if err := mergeByLabels(ctx, q.rowGroup(), q.head.symdb, "TotalValue", rows, seriesByLabels, sts, exemplars, by...); err != nil {
+		return nil, err
+	}
+
+	return seriesByLabels.normalize(), nil
+}
+
+func (q *headOnDiskQuerier) MergeFunctionSeries(ctx context.Context, rows iter.Iterator[Profile], fs *typesv1.FunctionSeriesSelector, by ...string) ([]*typesv1.Series, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeFunctionSeries - HeadOnDisk")
+	defer sp.Finish()
+
+	seriesByLabels := make(seriesByLabels)
+
+	if err := mergeFunctionSeries(ctx, q.rowGroup(), q.head.symdb, rows, seriesByLabels, fs, by...); err != nil {
 		return nil, err
 	}
 
 	return seriesByLabels.normalize(), nil
 }
 
+func (q *headOnDiskQuerier) SelectProfileByID(ctx context.Context, id uuid.UUID, params *ingestv1.SelectProfilesRequest) (Profile, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectProfileByID - HeadOnDisk")
+	defer sp.Finish()
+	return selectProfileByID(ctx, q, q.rowGroup().columnIter, id, params)
+}
+
 func (q *headOnDiskQuerier) Series(ctx context.Context, params *ingestv1.SeriesRequest) ([]*typesv1.Labels, error) {
 	// The TSDB is kept in memory until the head block is flushed to disk.
 	return []*typesv1.Labels{}, nil
 }
 
+func (q *headOnDiskQuerier) LabelValues(ctx context.Context, params *typesv1.LabelValuesRequest) ([]string, error) {
+	// The TSDB is kept in memory until the head block is flushed to disk.
+	return []string{}, nil
+}
+
+func (q *headOnDiskQuerier) LabelNames(ctx context.Context, params *typesv1.LabelNamesRequest) ([]string, error) {
+	// The TSDB is kept in memory until the head block is flushed to disk.
+	return []string{}, nil
+}
+
 func (q *headOnDiskQuerier) Sort(in []Profile) []Profile {
 	var rowI, rowJ int64
 	sort.Slice(in, func(i, j int) bool {
@@ -187,6 +256,7 @@ func (q *headInMemoryQuerier) SelectMatchingProfiles(ctx context.Context, params
 		end   = model.Time(params.End)
 	)
 
+	stats.FromContext(ctx).AddSeriesMatched(uint64(len(ids)))
 	iters := make([]iter.Iterator[Profile], 0, len(ids))
 	index.mutex.RLock()
 	defer index.mutex.RUnlock()
@@ -217,10 +287,10 @@ func (q *headInMemoryQuerier) Bounds() (model.Time, model.Time) {
 	return q.head.Bounds()
 }
 
-func (q *headInMemoryQuerier) MergeByStacktraces(ctx context.Context, rows iter.Iterator[Profile]) (*phlaremodel.Tree, error) {
+func (q *headInMemoryQuerier) MergeByStacktraces(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector) (*phlaremodel.Tree, error) {
 	sp, _ := opentracing.StartSpanFromContext(ctx, "MergeByStacktraces - HeadInMemory")
 	defer sp.Finish()
-	r := symdb.NewResolver(ctx, q.head.symdb)
+	r := symdb.NewResolver(ctx, q.head.symdb, symdb.WithStackTraceSelector(sts))
 	defer r.Release()
 	for rows.Next() {
 		p, ok := rows.At().(ProfileWithLabels)
@@ -235,11 +305,38 @@ func (q *headInMemoryQuerier) MergeByStacktraces(ctx context.Context, rows iter.
 	return r.Tree()
 }
 
-func (q *headInMemoryQuerier) MergePprof(ctx context.Context, rows iter.Iterator[Profile]) (*profile.Profile, error) {
-	sp, _ := opentracing.StartSpanFromContext(ctx, "MergePprof - HeadInMemory")
+func (q *headInMemoryQuerier) MergeBySpans(ctx context.Context, rows iter.Iterator[Profile], spans phlaremodel.SpanSelector) (*phlaremodel.Tree, error) {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "MergeBySpans - HeadInMemory")
 	defer sp.Finish()
 	r := symdb.NewResolver(ctx, q.head.symdb)
 	defer r.Release()
+	for rows.Next() {
+		p, ok := rows.At().(ProfileWithLabels)
+		if !ok {
+			return nil, errors.New("expected ProfileWithLabels")
+		}
+		samples := p.Samples()
+		if len(samples.Spans) == 0 {
+			continue
+		}
+		partition := r.Partition(p.StacktracePartition())
+		for i, span := range samples.Spans {
+			if _, ok = spans[span]; ok {
+				partition[samples.StacktraceIDs[i]] += int64(samples.Values[i])
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return r.Tree()
+}
+
+func (q *headInMemoryQuerier) MergePprof(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector) (*profile.Profile, error) {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "MergePprof - HeadInMemory")
+	defer sp.Finish()
+	r := symdb.NewResolver(ctx, q.head.symdb, symdb.WithStackTraceSelector(sts))
+	defer r.Release()
 	for rows.Next() {
 		p, ok := rows.At().(ProfileWithLabels)
 		if !ok {
@@ -253,13 +350,54 @@ func (q *headInMemoryQuerier) MergePprof(ctx context.Context, rows iter.Iterator
 	return r.Profile()
 }
 
-func (q *headInMemoryQuerier) MergeByLabels(ctx context.Context, rows iter.Iterator[Profile], by ...string) ([]*typesv1.Series, error) {
+func (q *headInMemoryQuerier) MergeFunctionTable(ctx context.Context, rows iter.Iterator[Profile], byLine bool) (*typesv1.FunctionTable, error) {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "MergeFunctionTable - HeadInMemory")
+	defer sp.Finish()
+	r := symdb.NewResolver(ctx, q.head.symdb)
+	defer r.Release()
+	for rows.Next() {
+		p, ok := rows.At().(ProfileWithLabels)
+		if !ok {
+			return nil, errors.New("expected ProfileWithLabels")
+		}
+		r.AddSamples(p.StacktracePartition(), p.Samples())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return r.FunctionTable(byLine)
+}
+
+func (q *headInMemoryQuerier) MergeFunctionLines(ctx context.Context, rows iter.Iterator[Profile], function string) (*typesv1.FunctionTable, error) {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "MergeFunctionLines - HeadInMemory")
+	defer sp.Finish()
+	r := symdb.NewResolver(ctx, q.head.symdb)
+	defer r.Release()
+	for rows.Next() {
+		p, ok := rows.At().(ProfileWithLabels)
+		if !ok {
+			return nil, errors.New("expected ProfileWithLabels")
+		}
+		r.AddSamples(p.StacktracePartition(), p.Samples())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return r.FunctionLines(function)
+}
+
+func (q *headInMemoryQuerier) MergeByLabels(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector, exemplars bool, by ...string) ([]*typesv1.Series, error) {
 	sp, _ := opentracing.StartSpanFromContext(ctx, "MergeByLabels - HeadInMemory")
 	defer sp.Finish()
 
 	labelsByFingerprint := map[model.Fingerprint]string{}
 	seriesByLabels := make(seriesByLabels)
 	labelBuf := make([]byte, 0, 1024)
+	var selection *stacktraceSelection
+	if !symdb.IsStackTraceSelectorEmpty(sts) {
+		selection = newStacktraceSelection(q.head.symdb, sts)
+		defer selection.release()
+	}
 
 	for rows.Next() {
 		p, ok := rows.At().(ProfileWithLabels)
@@ -267,6 +405,25 @@ func (q *headInMemoryQuerier) MergeByLabels(ctx context.Context, rows iter.Itera
 			return nil, errors.New("expected ProfileWithLabels")
 		}
 
+		total := p.Total()
+		if selection != nil {
+			var err error
+			if total, err = selection.total(ctx, p.StacktracePartition(), p.Samples()); err != nil {
+				return nil, err
+			}
+		}
+		point := &typesv1.Point{
+			Timestamp: int64(p.Timestamp()),
+			Value:     float64(total),
+		}
+		if exemplars {
+			point.Exemplars = []*typesv1.Exemplar{{
+				ProfileId: p.profile.ID.String(),
+				Timestamp: point.Timestamp,
+				Value:     total,
+				Labels:    p.Labels(),
+			}}
+		}
 		labelsByString, ok := labelsByFingerprint[p.fp]
 		if !ok {
 			labelBuf = p.Labels().BytesWithLabels(labelBuf, by...)
@@ -275,22 +432,40 @@ func (q *headInMemoryQuerier) MergeByLabels(ctx context.Context, rows iter.Itera
 			if _, ok := seriesByLabels[labelsByString]; !ok {
 				seriesByLabels[labelsByString] = &typesv1.Series{
 					Labels: p.Labels().WithLabels(by...),
-					Points: []*typesv1.Point{
-						{
-							Timestamp: int64(p.Timestamp()),
-							Value:     float64(p.Total()),
-						},
-					},
+					Points: []*typesv1.Point{point},
 				}
 				continue
 			}
 		}
 		series := seriesByLabels[labelsByString]
-		series.Points = append(series.Points, &typesv1.Point{
-			Timestamp: int64(p.Timestamp()),
-			Value:     float64(p.Total()),
-		})
+		series.Points = append(series.Points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return seriesByLabels.normalize(), nil
+}
+
+func (q *headInMemoryQuerier) MergeFunctionSeries(ctx context.Context, rows iter.Iterator[Profile], fs *typesv1.FunctionSeriesSelector, by ...string) ([]*typesv1.Series, error) {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "MergeFunctionSeries - HeadInMemory")
+	defer sp.Finish()
 
+	seriesByLabels := make(seriesByLabels)
+	selection := newFunctionSelection(q.head.symdb, fs)
+	defer selection.release()
+	b := newFunctionSeriesBuilder(seriesByLabels, fs.Functions, by)
+
+	for rows.Next() {
+		p, ok := rows.At().(ProfileWithLabels)
+		if !ok {
+			return nil, errors.New("expected ProfileWithLabels")
+		}
+		values, err := selection.values(ctx, p.StacktracePartition(), p.Samples())
+		if err != nil {
+			return nil, err
+		}
+		b.add(p, values)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -299,6 +474,22 @@ func (q *headInMemoryQuerier) MergeByLabels(ctx context.Context, rows iter.Itera
 	return seriesByLabels.normalize(), nil
 }
 
+func (q *headInMemoryQuerier) SelectProfileByID(ctx context.Context, id uuid.UUID, params *ingestv1.SelectProfilesRequest) (Profile, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectProfileByID - HeadInMemory")
+	defer sp.Finish()
+	profiles, err := q.SelectMatchingProfiles(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer profiles.Close()
+	for profiles.Next() {
+		if p, ok := profiles.At().(ProfileWithLabels); ok && p.profile.ID == id {
+			return p, nil
+		}
+	}
+	return nil, profiles.Err()
+}
+
 func (q *headInMemoryQuerier) Series(ctx context.Context, params *ingestv1.SeriesRequest) ([]*typesv1.Labels, error) {
 	res, err := q.head.Series(ctx, connect.NewRequest(params))
 	if err != nil {
@@ -307,6 +498,22 @@ func (q *headInMemoryQuerier) Series(ctx context.Context, params *ingestv1.Serie
 	return res.Msg.LabelsSet, nil
 }
 
+func (q *headInMemoryQuerier) LabelValues(ctx context.Context, params *typesv1.LabelValuesRequest) ([]string, error) {
+	res, err := q.head.LabelValues(ctx, connect.NewRequest(params))
+	if err != nil {
+		return nil, err
+	}
+	return res.Msg.Names, nil
+}
+
+func (q *headInMemoryQuerier) LabelNames(ctx context.Context, params *typesv1.LabelNamesRequest) ([]string, error) {
+	res, err := q.head.LabelNames(ctx, connect.NewRequest(params))
+	if err != nil {
+		return nil, err
+	}
+	return res.Msg.Names, nil
+}
+
 func (q *headInMemoryQuerier) Sort(in []Profile) []Profile {
 	return in
 }
@@ -31,6 +31,7 @@ import (
 	phlareobj "github.com/grafana/pyroscope/pkg/objstore"
 	phlarecontext "github.com/grafana/pyroscope/pkg/phlare/context"
 	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	"github.com/grafana/pyroscope/pkg/querier/stats"
 )
 
 type Config struct {
@@ -309,17 +310,49 @@ func (f *PhlareDB) flushHead(ctx context.Context, reason flushReason) {
 }
 
 // LabelValues returns the possible label values for a given label name.
+// Requests without a time range are served from the head only.
 func (f *PhlareDB) LabelValues(ctx context.Context, req *connect.Request[typesv1.LabelValuesRequest]) (resp *connect.Response[typesv1.LabelValuesResponse], err error) {
-	return withHeadForQuery(f, func(head *Head) (*connect.Response[typesv1.LabelValuesResponse], error) {
-		return head.LabelValues(ctx, req)
-	})
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "PhlareDB LabelValues")
+	defer sp.Finish()
+
+	legacyRequest := req.Msg.Start == 0 || req.Msg.End == 0
+	if legacyRequest {
+		return withHeadForQuery(f, func(head *Head) (*connect.Response[typesv1.LabelValuesResponse], error) {
+			return head.LabelValues(ctx, req)
+		})
+	}
+
+	f.headLock.RLock()
+	defer f.headLock.RUnlock()
+
+	res, err := LabelValues(ctx, req.Msg, f.queriers().ForTimeRange)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(res), nil
 }
 
 // LabelNames returns the possible label names.
+// Requests without a time range are served from the head only.
 func (f *PhlareDB) LabelNames(ctx context.Context, req *connect.Request[typesv1.LabelNamesRequest]) (resp *connect.Response[typesv1.LabelNamesResponse], err error) {
-	return withHeadForQuery(f, func(head *Head) (*connect.Response[typesv1.LabelNamesResponse], error) {
-		return head.LabelNames(ctx, req)
-	})
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "PhlareDB LabelNames")
+	defer sp.Finish()
+
+	legacyRequest := req.Msg.Start == 0 || req.Msg.End == 0
+	if legacyRequest {
+		return withHeadForQuery(f, func(head *Head) (*connect.Response[typesv1.LabelNamesResponse], error) {
+			return head.LabelNames(ctx, req)
+		})
+	}
+
+	f.headLock.RLock()
+	defer f.headLock.RUnlock()
+
+	res, err := LabelNames(ctx, req.Msg, f.queriers().ForTimeRange)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(res), nil
 }
 
 // ProfileTypes returns the possible profile types.
@@ -353,6 +386,22 @@ func (f *PhlareDB) Series(ctx context.Context, req *connect.Request[ingestv1.Ser
 	return connect.NewResponse(res), nil
 }
 
+// Cardinality returns the number of series per label name and value
+// for the given set of matchers.
+func (f *PhlareDB) Cardinality(ctx context.Context, req *connect.Request[ingestv1.CardinalityRequest]) (*connect.Response[ingestv1.CardinalityResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "PhareDB Cardinality")
+	defer sp.Finish()
+
+	f.headLock.RLock()
+	defer f.headLock.RUnlock()
+
+	res, err := Cardinality(ctx, req.Msg, f.queriers().ForTimeRange)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(res), nil
+}
+
 func (f *PhlareDB) MergeProfilesStacktraces(ctx context.Context, stream *connect.BidiStream[ingestv1.MergeProfilesStacktracesRequest, ingestv1.MergeProfilesStacktracesResponse]) error {
 	f.headLock.RLock()
 	defer f.headLock.RUnlock()
@@ -371,6 +420,17 @@ func (f *PhlareDB) MergeProfilesPprof(ctx context.Context, stream *connect.BidiS
 	return MergeProfilesPprof(ctx, stream, f.queriers().ForTimeRange)
 }
 
+func (f *PhlareDB) SelectProfileByID(ctx context.Context, req *connect.Request[ingestv1.SelectProfileByIDRequest]) (*connect.Response[ingestv1.SelectProfileByIDResponse], error) {
+	f.headLock.RLock()
+	defer f.headLock.RUnlock()
+
+	res, err := SelectProfileByID(ctx, req.Msg, f.queriers().ForTimeRange)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(res), nil
+}
+
 type BidiServerMerge[Res any, Req any] interface {
 	Send(Res) error
 	Receive() (Req, error)
@@ -427,6 +487,7 @@ func filterProfiles[B BidiServerMerge[Res, Req],
 			otlog.Int("batch_requested_size", batchProfileSize),
 		)
 		defer sp.Finish()
+		stats.FromContext(ctx).AddProfilesScanned(uint64(len(batch)))
 
 		seriesByFP := map[model.Fingerprint]labelWithIndex{}
 		selectProfileResult.LabelsSets = selectProfileResult.LabelsSets[:0]
@@ -112,6 +112,22 @@ func (i *ingesterHandlerPhlareDB) MergeProfilesPprof(ctx context.Context, stream
 	return MergeProfilesPprof(ctx, stream, i.ForTimeRange)
 }
 
+func (i *ingesterHandlerPhlareDB) SelectProfileByID(ctx context.Context, req *connect.Request[ingestv1.SelectProfileByIDRequest]) (*connect.Response[ingestv1.SelectProfileByIDResponse], error) {
+	res, err := SelectProfileByID(ctx, req.Msg, i.ForTimeRange)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(res), nil
+}
+
+func (i *ingesterHandlerPhlareDB) Cardinality(ctx context.Context, req *connect.Request[ingestv1.CardinalityRequest]) (*connect.Response[ingestv1.CardinalityResponse], error) {
+	res, err := Cardinality(ctx, req.Msg, i.ForTimeRange)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(res), nil
+}
+
 func (i *ingesterHandlerPhlareDB) Push(context.Context, *connect.Request[pushv1.PushRequest]) (*connect.Response[pushv1.PushResponse], error) {
 	return nil, errors.New("not implemented")
 }
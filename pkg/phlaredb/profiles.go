@@ -6,16 +6,12 @@ import (
 	"sort"
 	"sync"
 
-	"github.com/gogo/status"
 	"github.com/opentracing/opentracing-go"
-	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
-	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/samber/lo"
 	"go.uber.org/atomic"
-	"google.golang.org/grpc/codes"
 
 	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
 	"github.com/grafana/pyroscope/pkg/iter"
@@ -211,14 +207,10 @@ func (pi *profilesIndex) Add(ps *schemav1.InMemoryProfile, lbs phlaremodel.Label
 func (pi *profilesIndex) selectMatchingFPs(ctx context.Context, params *ingestv1.SelectProfilesRequest) ([]model.Fingerprint, error) {
 	sp, _ := opentracing.StartSpanFromContext(ctx, "selectMatchingFPs - Index")
 	defer sp.Finish()
-	selectors, err := parser.ParseMetricSelector(params.LabelSelector)
+	selectors, shard, err := selectProfilesMatchers(params)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, "failed to parse label selectors: "+err.Error())
-	}
-	if params.Type == nil {
-		return nil, errors.New("no profileType given")
+		return nil, err
 	}
-	selectors = append(selectors, phlaremodel.SelectorFromProfileType(params.Type))
 
 	filters, matchers := SplitFiltersAndMatchers(selectors)
 	ids, err := pi.ix.Lookup(matchers, nil)
@@ -233,6 +225,9 @@ func (pi *profilesIndex) selectMatchingFPs(ctx context.Context, params *ingestv1
 	var idx int
 outer:
 	for _, fp := range ids {
+		if shard != nil && !shard.Match(uint64(fp)) {
+			continue
+		}
 		profile, ok := pi.profilesPerFP[fp]
 		if !ok {
 			// If a profile labels is missing here, it has already been flushed
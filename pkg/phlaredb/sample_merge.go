@@ -2,25 +2,29 @@ package phlaredb
 
 import (
 	"context"
+	"fmt"
 	"sort"
 
 	"github.com/google/pprof/profile"
+	"github.com/google/uuid"
 	"github.com/opentracing/opentracing-go"
 	"github.com/parquet-go/parquet-go"
 	"github.com/prometheus/common/model"
 	"github.com/samber/lo"
 
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
 	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
 	"github.com/grafana/pyroscope/pkg/iter"
 	phlaremodel "github.com/grafana/pyroscope/pkg/model"
 	"github.com/grafana/pyroscope/pkg/phlaredb/query"
+	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
 	"github.com/grafana/pyroscope/pkg/phlaredb/symdb"
 )
 
-func (b *singleBlockQuerier) MergeByStacktraces(ctx context.Context, rows iter.Iterator[Profile]) (*phlaremodel.Tree, error) {
+func (b *singleBlockQuerier) MergeByStacktraces(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector) (*phlaremodel.Tree, error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeByStacktraces - Block")
 	defer sp.Finish()
-	r := symdb.NewResolver(ctx, b.symbols)
+	r := symdb.NewResolver(ctx, b.symbols, symdb.WithStackTraceSelector(sts))
 	defer r.Release()
 	if err := mergeByStacktraces(ctx, b.profiles.file, rows, r); err != nil {
 		return nil, err
@@ -28,18 +32,51 @@ func (b *singleBlockQuerier) MergeByStacktraces(ctx context.Context, rows iter.I
 	return r.Tree()
 }
 
-func (b *singleBlockQuerier) MergePprof(ctx context.Context, rows iter.Iterator[Profile]) (*profile.Profile, error) {
-	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeByStacktraces - Block")
+func (b *singleBlockQuerier) MergeBySpans(ctx context.Context, rows iter.Iterator[Profile], spans phlaremodel.SpanSelector) (*phlaremodel.Tree, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeBySpans - Block")
 	defer sp.Finish()
 	r := symdb.NewResolver(ctx, b.symbols)
 	defer r.Release()
+	if err := mergeBySpans(ctx, b.profiles.file, rows, r, spans); err != nil {
+		return nil, err
+	}
+	return r.Tree()
+}
+
+func (b *singleBlockQuerier) MergePprof(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector) (*profile.Profile, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeByStacktraces - Block")
+	defer sp.Finish()
+	r := symdb.NewResolver(ctx, b.symbols, symdb.WithStackTraceSelector(sts))
+	defer r.Release()
 	if err := mergeByStacktraces(ctx, b.profiles.file, rows, r); err != nil {
 		return nil, err
 	}
 	return r.Profile()
 }
 
-func (b *singleBlockQuerier) MergeByLabels(ctx context.Context, rows iter.Iterator[Profile], by ...string) ([]*typesv1.Series, error) {
+func (b *singleBlockQuerier) MergeFunctionTable(ctx context.Context, rows iter.Iterator[Profile], byLine bool) (*typesv1.FunctionTable, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeFunctionTable - Block")
+	defer sp.Finish()
+	r := symdb.NewResolver(ctx, b.symbols)
+	defer r.Release()
+	if err := mergeByStacktraces(ctx, b.profiles.file, rows, r); err != nil {
+		return nil, err
+	}
+	return r.FunctionTable(byLine)
+}
+
+func (b *singleBlockQuerier) MergeFunctionLines(ctx context.Context, rows iter.Iterator[Profile], function string) (*typesv1.FunctionTable, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeFunctionLines - Block")
+	defer sp.Finish()
+	r := symdb.NewResolver(ctx, b.symbols)
+	defer r.Release()
+	if err := mergeByStacktraces(ctx, b.profiles.file, rows, r); err != nil {
+		return nil, err
+	}
+	return r.FunctionLines(function)
+}
+
+func (b *singleBlockQuerier) MergeByLabels(ctx context.Context, rows iter.Iterator[Profile], sts *typesv1.StackTraceSelector, exemplars bool, by ...string) ([]*typesv1.Series, error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeByLabels - Block")
 	defer sp.Finish()
 
@@ -48,12 +85,32 @@ func (b *singleBlockQuerier) MergeByLabels(ctx context.Context, rows iter.Iterat
 	if b.meta.Version == 1 {
 		columnName = "Samples.list.element.Value"
 	}
-	if err := mergeByLabels(ctx, b.profiles.file, columnName, rows, m, by...); err != nil {
+	if err := mergeByLabels(ctx, b.profiles.file, b.symbols, columnName, rows, m, sts, exemplars, by...); err != nil {
 		return nil, err
 	}
 	return m.normalize(), nil
 }
 
+func (b *singleBlockQuerier) MergeFunctionSeries(ctx context.Context, rows iter.Iterator[Profile], fs *typesv1.FunctionSeriesSelector, by ...string) ([]*typesv1.Series, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "MergeFunctionSeries - Block")
+	defer sp.Finish()
+
+	m := make(seriesByLabels)
+	if err := mergeFunctionSeries(ctx, b.profiles.file, b.symbols, rows, m, fs, by...); err != nil {
+		return nil, err
+	}
+	return m.normalize(), nil
+}
+
+func (b *singleBlockQuerier) SelectProfileByID(ctx context.Context, id uuid.UUID, params *ingestv1.SelectProfilesRequest) (Profile, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectProfileByID - Block")
+	defer sp.Finish()
+	if err := b.Open(ctx); err != nil {
+		return nil, err
+	}
+	return selectProfileByID(ctx, b, b.profiles.columnIter, id, params)
+}
+
 type Source interface {
 	Schema() *parquet.Schema
 	RowGroups() []parquet.RowGroup
@@ -82,6 +139,41 @@ func mergeByStacktraces(ctx context.Context, profileSource Source, rows iter.Ite
 	return it.Err()
 }
 
+// mergeBySpans is like mergeByStacktraces, but only accounts
+// for the samples that belong to the given spans.
+func mergeBySpans(ctx context.Context, profileSource Source, rows iter.Iterator[Profile], r *symdb.Resolver, spans phlaremodel.SpanSelector) error {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "mergeBySpans")
+	defer sp.Finish()
+	if _, found := profileSource.Schema().Lookup("Samples", "list", "element", "SpanID"); !found {
+		// The profiles have been written before span IDs were
+		// stored, therefore none of the samples has a span.
+		return nil
+	}
+	multiRows, err := iter.CloneN(rows, 3)
+	if err != nil {
+		return err
+	}
+	it := query.NewMultiRepeatedPageIterator(
+		repeatedColumnIter(ctx, profileSource, "Samples.list.element.StacktraceID", multiRows[0]),
+		repeatedColumnIter(ctx, profileSource, "Samples.list.element.Value", multiRows[1]),
+		repeatedColumnIter(ctx, profileSource, "Samples.list.element.SpanID", multiRows[2]),
+	)
+	defer it.Close()
+	for it.Next() {
+		values := it.At().Values
+		p := r.Partition(it.At().Row.StacktracePartition())
+		for i := 0; i < len(values[0]); i++ {
+			if values[2][i].IsNull() {
+				continue
+			}
+			if _, ok := spans[values[2][i].Uint64()]; ok {
+				p[uint32(values[0][i].Int64())] += values[1][i].Int64()
+			}
+		}
+	}
+	return it.Err()
+}
+
 type seriesByLabels map[string]*typesv1.Series
 
 func (m seriesByLabels) normalize() []*typesv1.Series {
@@ -98,20 +190,80 @@ func (m seriesByLabels) normalize() []*typesv1.Series {
 	return result
 }
 
-func mergeByLabels(ctx context.Context, profileSource Source, columnName string, rows iter.Iterator[Profile], m seriesByLabels, by ...string) error {
-	it := repeatedColumnIter(ctx, profileSource, columnName, rows)
-
+// mergeByLabels adds a point per profile to the series the profile belongs
+// to. If exemplars are requested, each point refers to its profile. If the
+// stack trace selector is specified, only the matching samples are accounted.
+func mergeByLabels(
+	ctx context.Context,
+	profileSource Source,
+	symbols symdb.SymbolsReader,
+	columnName string,
+	rows iter.Iterator[Profile],
+	m seriesByLabels,
+	sts *typesv1.StackTraceSelector,
+	exemplars bool,
+	by ...string,
+) error {
+	columns := []string{columnName}
+	var selection *stacktraceSelection
+	if !symdb.IsStackTraceSelectorEmpty(sts) {
+		selection = newStacktraceSelection(symbols, sts)
+		defer selection.release()
+		columns = []string{"Samples.list.element.StacktraceID", "Samples.list.element.Value"}
+	}
+	idColumn := len(columns)
+	if exemplars {
+		columns = append(columns, "ID")
+	}
+	multiRows, err := iter.CloneN(rows, len(columns))
+	if err != nil {
+		return err
+	}
+	iters := make([]iter.Iterator[*query.RepeatedRow[Profile]], len(columns))
+	for i, c := range columns {
+		iters[i] = repeatedColumnIter(ctx, profileSource, c, multiRows[i])
+	}
+	it := query.NewMultiRepeatedPageIterator(iters...)
 	defer it.Close()
 
 	labelsByFingerprint := map[model.Fingerprint]string{}
 	labelBuf := make([]byte, 0, 1024)
+	var samples schemav1.Samples
 
 	for it.Next() {
 		values := it.At()
 		p := values.Row
 		var total int64
-		for _, e := range values.Values {
-			total += e.Int64()
+		if selection != nil {
+			samples.StacktraceIDs = samples.StacktraceIDs[:0]
+			samples.Values = samples.Values[:0]
+			for i := range values.Values[0] {
+				samples.StacktraceIDs = append(samples.StacktraceIDs, uint32(values.Values[0][i].Int64()))
+				samples.Values = append(samples.Values, uint64(values.Values[1][i].Int64()))
+			}
+			if total, err = selection.total(ctx, p.StacktracePartition(), samples); err != nil {
+				return err
+			}
+		} else {
+			for _, e := range values.Values[0] {
+				total += e.Int64()
+			}
+		}
+		point := &typesv1.Point{
+			Timestamp: int64(p.Timestamp()),
+			Value:     float64(total),
+		}
+		if exemplars {
+			id, err := profileID(values.Values[idColumn])
+			if err != nil {
+				return err
+			}
+			point.Exemplars = []*typesv1.Exemplar{{
+				ProfileId: id.String(),
+				Timestamp: point.Timestamp,
+				Value:     total,
+				Labels:    p.Labels(),
+			}}
 		}
 		labelsByString, ok := labelsByFingerprint[p.Fingerprint()]
 		if !ok {
@@ -121,21 +273,85 @@ func mergeByLabels(ctx context.Context, profileSource Source, columnName string,
 			if _, ok := m[labelsByString]; !ok {
 				m[labelsByString] = &typesv1.Series{
 					Labels: p.Labels().WithLabels(by...),
-					Points: []*typesv1.Point{
-						{
-							Timestamp: int64(p.Timestamp()),
-							Value:     float64(total),
-						},
-					},
+					Points: []*typesv1.Point{point},
 				}
 				continue
 			}
 		}
 		series := m[labelsByString]
-		series.Points = append(series.Points, &typesv1.Point{
-			Timestamp: int64(p.Timestamp()),
-			Value:     float64(total),
-		})
+		series.Points = append(series.Points, point)
 	}
 	return it.Err()
 }
+
+// stacktraceSelection computes the total value of the profile samples
+// with stack traces matching the selector. Whether a stack trace matches
+// is only determined once per partition.
+type stacktraceSelection struct {
+	symbols    symdb.SymbolsReader
+	sts        *typesv1.StackTraceSelector
+	partitions map[uint64]*selectedPartition
+}
+
+type selectedPartition struct {
+	reader  symdb.PartitionReader
+	matches map[uint32]bool
+}
+
+func newStacktraceSelection(symbols symdb.SymbolsReader, sts *typesv1.StackTraceSelector) *stacktraceSelection {
+	return &stacktraceSelection{
+		symbols:    symbols,
+		sts:        sts,
+		partitions: make(map[uint64]*selectedPartition),
+	}
+}
+
+func (s *stacktraceSelection) total(ctx context.Context, partition uint64, samples schemav1.Samples) (int64, error) {
+	p, ok := s.partitions[partition]
+	if !ok {
+		r, err := s.symbols.Partition(ctx, partition)
+		if err != nil {
+			return 0, err
+		}
+		p = &selectedPartition{reader: r, matches: make(map[uint32]bool)}
+		s.partitions[partition] = p
+	}
+	var unknown []uint32
+	for _, sid := range samples.StacktraceIDs {
+		if _, ok = p.matches[sid]; !ok {
+			p.matches[sid] = false
+			unknown = append(unknown, sid)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+		selected, err := p.reader.Symbols().SelectStacktraces(ctx, s.sts, unknown)
+		if err != nil {
+			return 0, err
+		}
+		for sid := range selected {
+			p.matches[sid] = true
+		}
+	}
+	var total int64
+	for i, sid := range samples.StacktraceIDs {
+		if p.matches[sid] {
+			total += int64(samples.Values[i])
+		}
+	}
+	return total, nil
+}
+
+func (s *stacktraceSelection) release() {
+	for _, p := range s.partitions {
+		p.reader.Release()
+	}
+}
+
+// profileID decodes the profile ID of a row from its single ID column value.
+func profileID(values []parquet.Value) (uuid.UUID, error) {
+	if len(values) != 1 {
+		return uuid.Nil, fmt.Errorf("expected a single profile ID value, got %d", len(values))
+	}
+	return uuid.FromBytes(values[0].ByteArray())
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/grafana/pyroscope/pkg/iter"
 	phlaremodel "github.com/grafana/pyroscope/pkg/model"
 	"github.com/grafana/pyroscope/pkg/objstore/providers/filesystem"
+	"github.com/grafana/pyroscope/pkg/phlaredb/sharding"
 	"github.com/grafana/pyroscope/pkg/pprof"
 	pprofth "github.com/grafana/pyroscope/pkg/pprof/testhelper"
 	"github.com/grafana/pyroscope/pkg/testhelper"
@@ -125,7 +126,7 @@ func TestMergeSampleByStacktraces(t *testing.T) {
 			})
 			require.NoError(t, err)
 
-			r, err := q.queriers[0].MergeByStacktraces(ctx, profiles)
+			r, err := q.queriers[0].MergeByStacktraces(ctx, profiles, nil)
 			require.NoError(t, err)
 			require.Equal(t, expected.String(), r.String())
 		})
@@ -220,7 +221,7 @@ func TestHeadMergeSampleByStacktraces(t *testing.T) {
 				End:   int64(model.TimeFromUnixNano(int64(1 * time.Minute))),
 			})
 			require.NoError(t, err)
-			r, err := db.head.Queriers()[0].MergeByStacktraces(ctx, profiles)
+			r, err := db.head.Queriers()[0].MergeByStacktraces(ctx, profiles, nil)
 			require.NoError(t, err)
 			require.Equal(t, expected.String(), r.String())
 		})
@@ -343,7 +344,7 @@ func TestMergeSampleByLabels(t *testing.T) {
 			require.NoError(t, err)
 
 			q.queriers[0].Sort(profiles)
-			series, err := q.queriers[0].MergeByLabels(ctx, iter.NewSliceIterator(profiles), tc.by...)
+			series, err := q.queriers[0].MergeByLabels(ctx, iter.NewSliceIterator(profiles), nil, false, tc.by...)
 			require.NoError(t, err)
 
 			testhelper.EqualProto(t, tc.expected, series)
@@ -458,7 +459,7 @@ func TestHeadMergeSampleByLabels(t *testing.T) {
 			require.NoError(t, err)
 
 			db.head.Sort(profiles)
-			series, err := db.head.Queriers()[0].MergeByLabels(ctx, iter.NewSliceIterator(profiles), tc.by...)
+			series, err := db.head.Queriers()[0].MergeByLabels(ctx, iter.NewSliceIterator(profiles), nil, false, tc.by...)
 			require.NoError(t, err)
 
 			testhelper.EqualProto(t, tc.expected, series)
@@ -507,7 +508,7 @@ func TestMergePprof(t *testing.T) {
 	require.NoError(t, err)
 
 	q.queriers[0].Sort(profiles)
-	result, err := q.queriers[0].MergePprof(ctx, iter.NewSliceIterator(profiles))
+	result, err := q.queriers[0].MergePprof(ctx, iter.NewSliceIterator(profiles), nil)
 	require.NoError(t, err)
 
 	data, err := proto.Marshal(generateProfile(t, 1))
@@ -552,7 +553,7 @@ func TestHeadMergePprof(t *testing.T) {
 	require.NoError(t, err)
 
 	db.head.Sort(profiles)
-	result, err := db.head.Queriers()[0].MergePprof(ctx, iter.NewSliceIterator(profiles))
+	result, err := db.head.Queriers()[0].MergePprof(ctx, iter.NewSliceIterator(profiles), nil)
 	require.NoError(t, err)
 
 	data, err := proto.Marshal(generateProfile(t, 1))
@@ -565,6 +566,255 @@ func TestHeadMergePprof(t *testing.T) {
 	compareProfile(t, expected.Compact(), result)
 }
 
+// ingestedProfile is a process_cpu profile generated by generateProfile.
+type ingestedProfile struct {
+	ts     int
+	id     uuid.UUID // Random, if not set.
+	labels []*typesv1.LabelPair
+}
+
+// testHeadAndBlock ingests the profiles, and runs the test against the
+// queriers of the head, and then against the querier of the block the
+// head is flushed to.
+func testHeadAndBlock(t *testing.T, profiles []ingestedProfile, test func(t *testing.T, ctx context.Context, queriers Queriers)) {
+	ctx := testContext(t)
+	db, err := New(ctx, Config{
+		DataPath:         contextDataDir(ctx),
+		MaxBlockDuration: time.Duration(100000) * time.Minute, // we will manually flush
+	}, NoLimit, ctx.localBucketClient)
+	require.NoError(t, err)
+
+	for _, p := range profiles {
+		id := p.id
+		if id == uuid.Nil {
+			id = uuid.New()
+		}
+		labels := append([]*typesv1.LabelPair{{Name: model.MetricNameLabel, Value: "process_cpu"}}, p.labels...)
+		require.NoError(t, db.Ingest(ctx, generateProfile(t, p.ts), id, labels...))
+	}
+
+	t.Run("head", func(t *testing.T) {
+		test(t, ctx, db.head.Queriers())
+	})
+
+	require.NoError(t, db.Flush(context.Background()))
+	b, err := filesystem.NewBucket(filepath.Join(contextDataDir(ctx), PathLocal))
+	require.NoError(t, err)
+	q := NewBlockQuerier(context.Background(), b)
+	require.NoError(t, q.Sync(context.Background()))
+
+	t.Run("block", func(t *testing.T) {
+		test(t, ctx, Queriers{q.queriers[0]})
+	})
+}
+
+var (
+	testProfileType = &typesv1.ProfileType{
+		Name:       "process_cpu",
+		SampleType: "cpu",
+		SampleUnit: "nanoseconds",
+		PeriodType: "cpu",
+		PeriodUnit: "nanoseconds",
+	}
+	testStart = int64(model.TimeFromUnixNano(0))
+	testEnd   = int64(model.TimeFromUnixNano(int64(1 * time.Minute)))
+)
+
+// selectProfiles returns the sorted profiles of the querier
+// matching the selector within the first minute.
+func selectProfiles(t *testing.T, ctx context.Context, q Querier, selector string) []Profile {
+	t.Helper()
+	it, err := q.SelectMatchingProfiles(ctx, &ingestv1.SelectProfilesRequest{
+		LabelSelector: selector,
+		Type:          testProfileType,
+		Start:         testStart,
+		End:           testEnd,
+	})
+	require.NoError(t, err)
+	profiles, err := iter.Slice(it)
+	require.NoError(t, err)
+	return q.Sort(profiles)
+}
+
+func TestMergeFunctionTable(t *testing.T) {
+	profiles := []ingestedProfile{{ts: 0}, {ts: 1000}, {ts: 2000}}
+	testHeadAndBlock(t, profiles, func(t *testing.T, ctx context.Context, queriers Queriers) {
+		q := queriers[0]
+		result, err := q.MergeFunctionTable(ctx, iter.NewSliceIterator(selectProfiles(t, ctx, q, "{}")), true)
+		require.NoError(t, err)
+		phlaremodel.SortFunctionTableEntries(result.Entries, false)
+		testhelper.EqualProto(t, &typesv1.FunctionTable{
+			Entries: []*typesv1.FunctionTableEntry{
+				{FunctionName: "bar", FileName: "bar.go", Line: 200, Self: 9, Total: 12},
+				{FunctionName: "foo", FileName: "foo.go", Line: 100, Self: 9, Total: 9},
+			},
+			Total: 18,
+		}, result)
+	})
+}
+
+func TestMergeFunctionLines(t *testing.T) {
+	profiles := []ingestedProfile{{ts: 0}, {ts: 1000}, {ts: 2000}}
+	testHeadAndBlock(t, profiles, func(t *testing.T, ctx context.Context, queriers Queriers) {
+		q := queriers[0]
+		result, err := q.MergeFunctionLines(ctx, iter.NewSliceIterator(selectProfiles(t, ctx, q, "{}")), "bar")
+		require.NoError(t, err)
+		testhelper.EqualProto(t, &typesv1.FunctionTable{
+			Entries: []*typesv1.FunctionTableEntry{
+				{FunctionName: "bar", FileName: "bar.go", Line: 200, Self: 9, Total: 12},
+			},
+			Total: 18,
+		}, result)
+	})
+}
+
+func TestMergeFunctionSeries(t *testing.T) {
+	profiles := []ingestedProfile{{ts: 0}, {ts: int(time.Second)}, {ts: 2 * int(time.Second)}}
+	series := func(function string, value float64) *typesv1.Series {
+		return &typesv1.Series{
+			Labels: []*typesv1.LabelPair{{Name: phlaremodel.LabelNameFunction, Value: function}},
+			Points: []*typesv1.Point{{Timestamp: 0, Value: value}, {Timestamp: 1000, Value: value}, {Timestamp: 2000, Value: value}},
+		}
+	}
+	testHeadAndBlock(t, profiles, func(t *testing.T, ctx context.Context, queriers Queriers) {
+		for _, tc := range []struct {
+			valueType typesv1.FunctionValueType
+			expected  []*typesv1.Series
+		}{
+			{
+				valueType: typesv1.FunctionValueType_FUNCTION_VALUE_TYPE_SELF,
+				expected:  []*typesv1.Series{series("bar", 3), series("foo", 3)},
+			},
+			{
+				valueType: typesv1.FunctionValueType_FUNCTION_VALUE_TYPE_TOTAL,
+				expected:  []*typesv1.Series{series("bar", 4), series("foo", 3)},
+			},
+		} {
+			q := queriers[0]
+			fs := &typesv1.FunctionSeriesSelector{Functions: []string{"foo", "bar"}, ValueType: tc.valueType}
+			result, err := q.MergeFunctionSeries(ctx, iter.NewSliceIterator(selectProfiles(t, ctx, q, "{}")), fs)
+			require.NoError(t, err)
+			testhelper.EqualProto(t, tc.expected, result)
+		}
+	})
+}
+
+func TestSelectMatchingProfilesSharded(t *testing.T) {
+	const series = 16
+	profiles := make([]ingestedProfile, series)
+	for i := range profiles {
+		profiles[i] = ingestedProfile{
+			ts:     i * 1000,
+			labels: []*typesv1.LabelPair{{Name: "pod", Value: fmt.Sprintf("pod-%d", i)}},
+		}
+	}
+	// Every series must be selected by exactly one shard.
+	testHeadAndBlock(t, profiles, func(t *testing.T, ctx context.Context, queriers Queriers) {
+		const shards = 4
+		pods := make(map[string]int)
+		for i := 0; i < shards; i++ {
+			selector := fmt.Sprintf(`{pod=~"pod-.*", %s="%d_of_%d"}`, sharding.ShardLabel, i+1, shards)
+			it, err := queriers.SelectMatchingProfiles(ctx, &ingestv1.SelectProfilesRequest{
+				LabelSelector: selector,
+				Type:          testProfileType,
+				Start:         testStart,
+				End:           testEnd,
+			})
+			require.NoError(t, err)
+			selected, err := iter.Slice(it)
+			require.NoError(t, err)
+			require.Less(t, len(selected), series)
+			for _, p := range selected {
+				pods[p.Labels().Get("pod")]++
+			}
+		}
+		require.Len(t, pods, series)
+		for pod, n := range pods {
+			require.Equal(t, 1, n, pod)
+		}
+	})
+}
+
+func TestSelectProfileByID(t *testing.T) {
+	profiles := make([]ingestedProfile, 3)
+	for i := range profiles {
+		profiles[i] = ingestedProfile{ts: (i + 1) * int(time.Second), id: uuid.New()}
+	}
+	testHeadAndBlock(t, profiles, func(t *testing.T, ctx context.Context, queriers Queriers) {
+		for i, ingested := range profiles {
+			resp, err := SelectProfileByID(ctx, &ingestv1.SelectProfileByIDRequest{
+				ProfileId: ingested.id.String(),
+				Type:      testProfileType,
+				Start:     testStart,
+				End:       testEnd,
+			}, queriers.ForTimeRange)
+			require.NoError(t, err)
+			p, err := profile.ParseUncompressed(resp.Result)
+			require.NoError(t, err)
+			require.Equal(t, int64((i+1)*int(time.Second)), p.TimeNanos)
+			require.NotEmpty(t, p.Sample)
+		}
+
+		resp, err := SelectProfileByID(ctx, &ingestv1.SelectProfileByIDRequest{
+			ProfileId: uuid.New().String(),
+			Type:      testProfileType,
+			Start:     testStart,
+			End:       testEnd,
+		}, queriers.ForTimeRange)
+		require.NoError(t, err)
+		require.Empty(t, resp.Result)
+
+		// The profile IDs are the exemplars of the series.
+		q := queriers[0]
+		series, err := q.MergeByLabels(ctx, iter.NewSliceIterator(selectProfiles(t, ctx, q, "{}")), nil, true)
+		require.NoError(t, err)
+		require.Len(t, series, 1)
+		require.Len(t, series[0].Points, len(profiles))
+		for i, p := range series[0].Points {
+			require.Len(t, p.Exemplars, 1)
+			require.Equal(t, profiles[i].id.String(), p.Exemplars[0].ProfileId)
+			require.Equal(t, p.Timestamp, p.Exemplars[0].Timestamp)
+			require.Equal(t, int64(p.Value), p.Exemplars[0].Value)
+			require.Equal(t, "process_cpu", phlaremodel.Labels(p.Exemplars[0].Labels).Get(model.MetricNameLabel))
+		}
+	})
+}
+
+func TestCardinality(t *testing.T) {
+	var profiles []ingestedProfile
+	for i, pod := range []string{"a", "a", "b"} {
+		profiles = append(profiles, ingestedProfile{
+			ts:     (i + 1) * int(time.Second),
+			labels: []*typesv1.LabelPair{{Name: "pod", Value: pod}},
+		})
+	}
+	testHeadAndBlock(t, profiles, func(t *testing.T, ctx context.Context, queriers Queriers) {
+		// The series of multiple queriers are only counted once.
+		queriers = append(queriers, queriers...)
+		resp, err := Cardinality(ctx, &ingestv1.CardinalityRequest{
+			Start: testStart,
+			End:   testEnd,
+		}, queriers.ForTimeRange)
+		require.NoError(t, err)
+		counts := make(map[string]uint64)
+		for _, p := range resp.SeriesCountByLabelPair {
+			counts[p.Name+"="+p.Value] = p.SeriesCount
+		}
+		require.Equal(t, uint64(2), resp.TotalSeries)
+		require.Equal(t, uint64(2), counts[model.MetricNameLabel+"=process_cpu"])
+		require.Equal(t, uint64(1), counts["pod=a"])
+		require.Equal(t, uint64(1), counts["pod=b"])
+
+		resp, err = Cardinality(ctx, &ingestv1.CardinalityRequest{
+			Matchers: []string{`{pod="b"}`},
+			Start:    testStart,
+			End:      testEnd,
+		}, queriers.ForTimeRange)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), resp.TotalSeries)
+	})
+}
+
 func generateProfile(t *testing.T, ts int) *googlev1.Profile {
 	t.Helper()
 
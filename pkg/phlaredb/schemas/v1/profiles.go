@@ -27,6 +27,7 @@ var (
 		phlareparquet.NewGroupField("StacktraceID", parquet.Encoded(parquet.Uint(64), &parquet.DeltaBinaryPacked)),
 		phlareparquet.NewGroupField("Value", parquet.Encoded(parquet.Int(64), &parquet.DeltaBinaryPacked)),
 		phlareparquet.NewGroupField("Labels", pprofLabels),
+		phlareparquet.NewGroupField("SpanID", parquet.Optional(parquet.Encoded(parquet.Uint(64), &parquet.RLEDictionary))),
 	}
 	ProfilesSchema = parquet.NewSchema("Profile", phlareparquet.Group{
 		phlareparquet.NewGroupField("ID", parquet.UUID()),
@@ -81,6 +82,7 @@ type Sample struct {
 	StacktraceID uint64             `parquet:",delta"`
 	Value        int64              `parquet:",delta"`
 	Labels       []*profilev1.Label `parquet:",list"`
+	SpanID       uint64             `parquet:",optional"`
 }
 
 type Profile struct {
@@ -246,6 +248,10 @@ type InMemoryProfile struct {
 type Samples struct {
 	StacktraceIDs []uint32
 	Values        []uint64
+	// Span IDs of the samples, if any: either empty or of
+	// the same length as the stack traces. Zero means that
+	// the sample does not belong to any span.
+	Spans []uint64
 }
 
 func NewSamples(size int) Samples {
@@ -289,50 +295,76 @@ func (s Samples) Clone() Samples {
 func trimDuplicateSamples(samples Samples) Samples {
 	sort.Sort(samples)
 	n := 0
+	spans := len(samples.Spans) > 0
 	for j := 1; j < len(samples.StacktraceIDs); j++ {
-		if samples.StacktraceIDs[n] == samples.StacktraceIDs[j] {
+		if samples.StacktraceIDs[n] == samples.StacktraceIDs[j] && (!spans || samples.Spans[n] == samples.Spans[j]) {
 			samples.Values[n] += samples.Values[j]
 		} else {
 			n++
 			samples.StacktraceIDs[n] = samples.StacktraceIDs[j]
 			samples.Values[n] = samples.Values[j]
+			if spans {
+				samples.Spans[n] = samples.Spans[j]
+			}
 		}
 	}
-	return Samples{
+	s := Samples{
 		StacktraceIDs: samples.StacktraceIDs[:n+1],
 		Values:        samples.Values[:n+1],
 	}
+	if spans {
+		s.Spans = samples.Spans[:n+1]
+	}
+	return s
 }
 
 func trimZeroAndNegativeSamples(samples Samples) Samples {
 	n := 0
+	spans := len(samples.Spans) > 0
 	for j, v := range samples.Values {
 		if v > 0 {
 			samples.Values[n] = v
 			samples.StacktraceIDs[n] = samples.StacktraceIDs[j]
+			if spans {
+				samples.Spans[n] = samples.Spans[j]
+			}
 			n++
 		}
 	}
-	return Samples{
+	s := Samples{
 		StacktraceIDs: samples.StacktraceIDs[:n],
 		Values:        samples.Values[:n],
 	}
+	if spans {
+		s.Spans = samples.Spans[:n]
+	}
+	return s
 }
 
 func cloneSamples(samples Samples) Samples {
-	return Samples{
+	s := Samples{
 		StacktraceIDs: copySlice(samples.StacktraceIDs),
 		Values:        copySlice(samples.Values),
 	}
+	if len(samples.Spans) > 0 {
+		s.Spans = copySlice(samples.Spans)
+	}
+	return s
 }
 
 func (s Samples) Less(i, j int) bool {
-	return s.StacktraceIDs[i] < s.StacktraceIDs[j]
+	if s.StacktraceIDs[i] != s.StacktraceIDs[j] || len(s.Spans) == 0 {
+		return s.StacktraceIDs[i] < s.StacktraceIDs[j]
+	}
+	return s.Spans[i] < s.Spans[j]
 }
 
 func (s Samples) Swap(i, j int) {
 	s.StacktraceIDs[i], s.StacktraceIDs[j] = s.StacktraceIDs[j], s.StacktraceIDs[i]
 	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+	if len(s.Spans) > 0 {
+		s.Spans[i], s.Spans[j] = s.Spans[j], s.Spans[i]
+	}
 }
 
 func (s Samples) Len() int {
@@ -387,7 +419,9 @@ const profileSize = uint64(unsafe.Sizeof(InMemoryProfile{}))
 func (p InMemoryProfile) Size() uint64 {
 	size := profileSize + uint64(cap(p.Comments)*8)
 	// 4 bytes for stacktrace id and 8 bytes for each stacktrace value
-	return size + uint64(cap(p.Samples.StacktraceIDs)*(4+8))
+	size += uint64(cap(p.Samples.StacktraceIDs) * (4 + 8))
+	// 8 bytes for each span id
+	return size + uint64(cap(p.Samples.Spans)*8)
 }
 
 func (p InMemoryProfile) Timestamp() model.Time {
@@ -422,7 +456,7 @@ func deconstructMemoryProfile(imp InMemoryProfile, row parquet.Row) parquet.Row
 			col++
 			return col
 		}
-		totalCols = 8 + (6 * len(imp.Samples.StacktraceIDs)) + len(imp.Comments)
+		totalCols = 8 + (7 * len(imp.Samples.StacktraceIDs)) + len(imp.Comments)
 	)
 	if cap(row) < totalCols {
 		row = make(parquet.Row, 0, totalCols)
@@ -467,6 +501,21 @@ func deconstructMemoryProfile(imp InMemoryProfile, row parquet.Row) parquet.Row
 			row = append(row, parquet.Value{}.Level(repetition, 1, col))
 		}
 	}
+	newCol()
+	repetition = -1
+	if len(imp.Samples.Values) == 0 {
+		row = append(row, parquet.Value{}.Level(0, 0, col))
+	}
+	for i := range imp.Samples.Values {
+		if repetition < 1 {
+			repetition++
+		}
+		if len(imp.Samples.Spans) == 0 || imp.Samples.Spans[i] == 0 {
+			row = append(row, parquet.Value{}.Level(repetition, 1, col))
+		} else {
+			row = append(row, parquet.Int64Value(int64(imp.Samples.Spans[i])).Level(repetition, 2, col))
+		}
+	}
 	if imp.DropFrames == 0 {
 		row = append(row, parquet.Value{}.Level(0, 0, newCol()))
 	} else {
@@ -95,6 +95,24 @@ func TestRoundtripProfile(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, expected, actual)
 	})
+
+	t.Run("Spans", func(t *testing.T) {
+		profiles := generateProfiles(10)
+		inMemoryProfiles := generateMemoryProfiles(10)
+		for i, p := range profiles {
+			inMemoryProfiles[i].Samples.Spans = make([]uint64, len(p.Samples))
+			// Every other sample has no span.
+			for j := 0; j < len(p.Samples); j += 2 {
+				p.Samples[j].SpanID = uint64(j + 1)
+				inMemoryProfiles[i].Samples.Spans[j] = uint64(j + 1)
+			}
+		}
+		expected, err := phlareparquet.ReadAll(NewProfilesRowReader(profiles))
+		require.NoError(t, err)
+		actual, err := phlareparquet.ReadAll(NewInMemoryProfilesRowReader(inMemoryProfiles))
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
 }
 
 func TestCompactSamples(t *testing.T) {
@@ -121,6 +139,16 @@ func TestCompactSamples(t *testing.T) {
 		StacktraceIDs: []uint32{1, 2, 3},
 		Values:        []uint64{1, 2, 3},
 	})
+
+	require.Equal(t, Samples{
+		StacktraceIDs: []uint32{1, 2, 1, 2, 1, 3},
+		Values:        []uint64{1, 1, 1, 1, 1, 0},
+		Spans:         []uint64{0, 7, 8, 7, 0, 8},
+	}.Compact(true), Samples{
+		StacktraceIDs: []uint32{1, 1, 2},
+		Values:        []uint64{2, 1, 2},
+		Spans:         []uint64{0, 8, 7},
+	})
 }
 
 func BenchmarkRowReader(b *testing.B) {
@@ -44,6 +44,11 @@ func (shard ShardSelector) Matcher() *labels.Matcher {
 	return labels.MustNewMatcher(labels.MatchEqual, ShardLabel, shard.LabelValue())
 }
 
+// Match reports whether the series with the given fingerprint belongs to the shard.
+func (shard ShardSelector) Match(fingerprint uint64) bool {
+	return fingerprint%shard.ShardCount == shard.ShardIndex
+}
+
 // ShardFromMatchers extracts a ShardSelector and the index it was pulled from the matcher list.
 func ShardFromMatchers(matchers []*labels.Matcher) (shard *ShardSelector, idx int, err error) {
 	for i, matcher := range matchers {
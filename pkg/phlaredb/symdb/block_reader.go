@@ -21,6 +21,7 @@ import (
 	parquetobj "github.com/grafana/pyroscope/pkg/objstore/parquet"
 	"github.com/grafana/pyroscope/pkg/phlaredb/block"
 	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
+	"github.com/grafana/pyroscope/pkg/querier/stats"
 )
 
 type Reader struct {
@@ -367,6 +368,7 @@ func (c *stacktraceChunkReader) fetch(ctx context.Context) (err error) {
 	if err != nil {
 		return err
 	}
+	stats.FromContext(ctx).AddFetchedSymbolBytes(uint64(c.header.Size))
 	defer func() {
 		err = multierror.New(err, rc.Close()).Err()
 	}()
@@ -13,6 +13,7 @@ import (
 	"github.com/grafana/pyroscope/pkg/iter"
 	parquetobj "github.com/grafana/pyroscope/pkg/objstore/parquet"
 	pparquet "github.com/grafana/pyroscope/pkg/parquet"
+	"github.com/grafana/pyroscope/pkg/querier/stats"
 )
 
 func (r *Reader) Load(ctx context.Context) error {
@@ -50,6 +51,7 @@ func (r *Reader) loadStacktraces(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	stats.FromContext(ctx).AddFetchedSymbolBytes(uint64(size))
 	defer func() {
 		err = multierror.New(err, rc.Close()).Err()
 	}()
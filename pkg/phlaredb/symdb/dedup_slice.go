@@ -12,6 +12,7 @@ import (
 	"go.uber.org/atomic"
 
 	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	"github.com/grafana/pyroscope/pkg/model"
 	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
 )
 
@@ -75,7 +76,7 @@ func (p *PartitionWriter) WriteProfileSymbols(profile *profilev1.Profile) []sche
 	}
 
 	p.locations.ingest(locs, rewrites)
-	samplesPerType := p.convertSamples(rewrites, profile.Sample)
+	samplesPerType := p.convertSamples(rewrites, profile.Sample, profile.StringTable)
 
 	profiles := make([]schemav1.InMemoryProfile, len(samplesPerType))
 	for idxType := range samplesPerType {
@@ -94,7 +95,7 @@ func (p *PartitionWriter) WriteProfileSymbols(profile *profilev1.Profile) []sche
 	return profiles
 }
 
-func (p *PartitionWriter) convertSamples(r *rewriter, in []*profilev1.Sample) []schemav1.Samples {
+func (p *PartitionWriter) convertSamples(r *rewriter, in []*profilev1.Sample, stringTable []string) []schemav1.Samples {
 	if len(in) == 0 {
 		return nil
 	}
@@ -104,6 +105,7 @@ func (p *PartitionWriter) convertSamples(r *rewriter, in []*profilev1.Sample) []
 		out            = make([]schemav1.Samples, len(in[0].Value))
 		stacktraces    = make([]*schemav1.Stacktrace, len(in))
 		stacktracesIds = uint32SlicePool.Get()
+		spans          = sampleSpans(in, stringTable)
 	)
 
 	for idxType := range out {
@@ -111,6 +113,9 @@ func (p *PartitionWriter) convertSamples(r *rewriter, in []*profilev1.Sample) []
 			Values:        make([]uint64, len(in)),
 			StacktraceIDs: make([]uint32, len(in)),
 		}
+		if spans != nil {
+			out[idxType].Spans = copySlice(spans)
+		}
 	}
 
 	for idxSample := range in {
@@ -153,6 +158,32 @@ func (p *PartitionWriter) convertSamples(r *rewriter, in []*profilev1.Sample) []
 	return out
 }
 
+// sampleSpans returns the span IDs of the samples, if
+// any of the samples has a valid span ID label.
+func sampleSpans(in []*profilev1.Sample, stringTable []string) []uint64 {
+	var spans []uint64
+	for idxSample, s := range in {
+		for _, l := range s.Label {
+			if l.Key < 0 || l.Key >= int64(len(stringTable)) || stringTable[l.Key] != model.LabelNameSpanID {
+				continue
+			}
+			if l.Str < 0 || l.Str >= int64(len(stringTable)) {
+				break
+			}
+			id, err := model.ParseSpanID(stringTable[l.Str])
+			if err != nil {
+				break
+			}
+			if spans == nil {
+				spans = make([]uint64, len(in))
+			}
+			spans[idxSample] = id
+			break
+		}
+	}
+	return spans
+}
+
 func copySlice[T any](in []T) []T {
 	out := make([]T, len(in))
 	copy(out, in)
@@ -0,0 +1,21 @@
+package symdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	googlev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+)
+
+func Test_sampleSpans(t *testing.T) {
+	stringTable := []string{"", "span_id", "0100000000000000", "foo", "bar", "invalid"}
+	samples := []*googlev1.Sample{
+		{Label: []*googlev1.Label{{Key: 3, Str: 4}, {Key: 1, Str: 2}}},
+		{Label: []*googlev1.Label{{Key: 3, Str: 4}}},
+		{Label: []*googlev1.Label{{Key: 1, Str: 5}}},
+		{},
+	}
+	require.Equal(t, []uint64{1, 0, 0, 0}, sampleSpans(samples, stringTable))
+	require.Nil(t, sampleSpans(samples[1:], stringTable))
+}
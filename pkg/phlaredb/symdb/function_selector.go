@@ -0,0 +1,75 @@
+package symdb
+
+import (
+	"context"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+// SelectFunctions returns the indices of the selector functions each
+// of the stack traces is accounted to: the leaf function for the self
+// value, or all the functions present in the stack trace for the total
+// value. Stack traces without any of the functions are omitted. The stack
+// traces must be ordered in the ascending order; the slice is not modified.
+func (r *Symbols) SelectFunctions(ctx context.Context, fs *typesv1.FunctionSeriesSelector, stacktraces []uint32) (map[uint32][]int, error) {
+	s := functionSelector{
+		symbols:   r,
+		self:      fs.GetValueType() == typesv1.FunctionValueType_FUNCTION_VALUE_TYPE_SELF,
+		functions: make(map[string]int, len(fs.GetFunctions())),
+		selected:  make(map[uint32][]int),
+	}
+	for i, name := range fs.GetFunctions() {
+		if _, ok := s.functions[name]; !ok {
+			s.functions[name] = i
+		}
+	}
+	ids := make([]uint32, len(stacktraces))
+	copy(ids, stacktraces)
+	if err := r.Stacktraces.ResolveStacktraceLocations(ctx, &s, ids); err != nil {
+		return nil, err
+	}
+	return s.selected, nil
+}
+
+type functionSelector struct {
+	symbols   *Symbols
+	self      bool
+	functions map[string]int
+	selected  map[uint32][]int
+}
+
+func (s *functionSelector) InsertStacktrace(stacktraceID uint32, locations []int32) {
+	if len(locations) == 0 {
+		return
+	}
+	var selected []int
+	// The first line of the first location is the leaf of the stack trace.
+	for _, loc := range locations {
+		for _, line := range s.symbols.Locations[loc].Line {
+			f := s.symbols.Functions[line.FunctionId]
+			if i, ok := s.functions[s.symbols.Strings[f.Name]]; ok && !containsIndex(selected, i) {
+				selected = append(selected, i)
+			}
+			if s.self {
+				s.add(stacktraceID, selected)
+				return
+			}
+		}
+	}
+	s.add(stacktraceID, selected)
+}
+
+func (s *functionSelector) add(stacktraceID uint32, selected []int) {
+	if len(selected) > 0 {
+		s.selected[stacktraceID] = selected
+	}
+}
+
+func containsIndex(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package symdb
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+func Test_memory_Symbols_SelectFunctions(t *testing.T) {
+	s := newMemSuite(t, [][]string{{"testdata/profile.pb.gz"}})
+	r := NewResolver(context.Background(), s.db)
+	defer r.Release()
+	samples := s.indexed[0][0].Samples
+	r.AddSamples(0, samples)
+	p, err := r.Profile()
+	require.NoError(t, err)
+
+	// The leaf function of the first sample, and the root function.
+	first := p.Sample[0].Location
+	functions := []string{
+		first[0].Line[0].Function.Name,
+		first[len(first)-1].Line[len(first[len(first)-1].Line)-1].Function.Name,
+	}
+	expectedSelf := make([]int64, len(functions))
+	expectedTotal := make([]int64, len(functions))
+	for _, sample := range p.Sample {
+		for i, name := range functions {
+			if sample.Location[0].Line[0].Function.Name == name {
+				expectedSelf[i] += sample.Value[0]
+			}
+		match:
+			for _, loc := range sample.Location {
+				for _, line := range loc.Line {
+					if line.Function.Name == name {
+						expectedTotal[i] += sample.Value[0]
+						break match
+					}
+				}
+			}
+		}
+	}
+
+	pr, err := s.db.Partition(context.Background(), 0)
+	require.NoError(t, err)
+	defer pr.Release()
+	stacktraces := make([]uint32, len(samples.StacktraceIDs))
+	copy(stacktraces, samples.StacktraceIDs)
+	sort.Slice(stacktraces, func(i, j int) bool { return stacktraces[i] < stacktraces[j] })
+
+	for _, tc := range []struct {
+		valueType typesv1.FunctionValueType
+		expected  []int64
+	}{
+		{valueType: typesv1.FunctionValueType_FUNCTION_VALUE_TYPE_SELF, expected: expectedSelf},
+		{valueType: typesv1.FunctionValueType_FUNCTION_VALUE_TYPE_TOTAL, expected: expectedTotal},
+	} {
+		fs := &typesv1.FunctionSeriesSelector{Functions: functions, ValueType: tc.valueType}
+		selected, err := pr.Symbols().SelectFunctions(context.Background(), fs, stacktraces)
+		require.NoError(t, err)
+		actual := make([]int64, len(functions))
+		for i, sid := range samples.StacktraceIDs {
+			for _, f := range selected[sid] {
+				actual[f] += int64(samples.Values[i])
+			}
+		}
+		require.Equal(t, tc.expected, actual, tc.valueType.String())
+	}
+}
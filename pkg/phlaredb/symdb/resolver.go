@@ -9,8 +9,10 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"golang.org/x/sync/errgroup"
 
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
 	"github.com/grafana/pyroscope/pkg/model"
 	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
+	"github.com/grafana/pyroscope/pkg/querier/stats"
 )
 
 // Resolver converts stack trace samples to one of the profile
@@ -29,6 +31,8 @@ type Resolver struct {
 	c int
 	m sync.Mutex
 	p map[uint64]*lazyPartition
+
+	sts *typesv1.StackTraceSelector
 }
 
 type ResolverOption func(*Resolver)
@@ -41,18 +45,29 @@ func WithMaxConcurrent(n int) ResolverOption {
 	}
 }
 
+// WithStackTraceSelector specifies the stack trace selector: only
+// the samples with matching stack traces are included in the result.
+func WithStackTraceSelector(sts *typesv1.StackTraceSelector) ResolverOption {
+	return func(r *Resolver) {
+		r.sts = sts
+	}
+}
+
 type lazyPartition struct {
 	samples map[uint32]int64
 	c       chan *Symbols
 	done    chan struct{}
 }
 
-func NewResolver(ctx context.Context, s SymbolsReader) *Resolver {
+func NewResolver(ctx context.Context, s SymbolsReader, opts ...ResolverOption) *Resolver {
 	r := Resolver{
 		s: s,
 		c: runtime.GOMAXPROCS(-1),
 		p: make(map[uint64]*lazyPartition),
 	}
+	for _, opt := range opts {
+		opt(&r)
+	}
 	r.span, r.ctx = opentracing.StartSpanFromContext(ctx, "NewResolver")
 	r.g, r.ctx = errgroup.WithContext(ctx)
 	return &r
@@ -95,6 +110,7 @@ func (r *Resolver) Partition(partition uint64) map[uint32]int64 {
 			return err
 		}
 		defer pr.Release()
+		stats.FromContext(r.ctx).AddSymdbPartitionsResolved(1)
 		select {
 		case <-r.ctx.Done():
 			return r.ctx.Err()
@@ -124,7 +140,10 @@ func (r *Resolver) Tree() (*model.Tree, error) {
 			case <-ctx.Done():
 				return ctx.Err()
 			case symbols := <-p.c:
-				samples := schemav1.NewSamplesFromMap(p.samples)
+				samples, err := r.samples(ctx, symbols, p.samples)
+				if err != nil {
+					return err
+				}
 				rt, err := symbols.Tree(ctx, samples)
 				if err != nil {
 					return err
@@ -161,7 +180,10 @@ func (r *Resolver) Profile() (*profile.Profile, error) {
 			case <-ctx.Done():
 				return ctx.Err()
 			case symbols := <-p.c:
-				samples := schemav1.NewSamplesFromMap(p.samples)
+				samples, err := r.samples(ctx, symbols, p.samples)
+				if err != nil {
+					return err
+				}
 				rp, err := symbols.Profile(ctx, samples)
 				if err != nil {
 					return err
@@ -180,6 +202,75 @@ func (r *Resolver) Profile() (*profile.Profile, error) {
 	return profile.Merge(profiles)
 }
 
+// FunctionTable returns the self and total values of every function
+// found in the stack traces. If byLine is set, the values are
+// additionally broken down by the source line.
+func (r *Resolver) FunctionTable(byLine bool) (*typesv1.FunctionTable, error) {
+	span, ctx := opentracing.StartSpanFromContext(r.ctx, "Resolver.FunctionTable")
+	defer span.Finish()
+	return r.functionTable(ctx, func(ctx context.Context, symbols *Symbols, samples schemav1.Samples) (*typesv1.FunctionTable, error) {
+		return symbols.FunctionTable(ctx, samples, byLine)
+	})
+}
+
+// FunctionLines returns the self and total values of every source line of
+// the function with the given name, found in the stack traces. The total
+// value of the table is the sum of all the sample values.
+func (r *Resolver) FunctionLines(function string) (*typesv1.FunctionTable, error) {
+	span, ctx := opentracing.StartSpanFromContext(r.ctx, "Resolver.FunctionLines")
+	defer span.Finish()
+	return r.functionTable(ctx, func(ctx context.Context, symbols *Symbols, samples schemav1.Samples) (*typesv1.FunctionTable, error) {
+		return symbols.FunctionLines(ctx, samples, function)
+	})
+}
+
+func (r *Resolver) functionTable(
+	ctx context.Context,
+	fn func(context.Context, *Symbols, schemav1.Samples) (*typesv1.FunctionTable, error),
+) (*typesv1.FunctionTable, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(r.c)
+
+	m := model.NewFunctionTableMerger()
+
+	for _, p := range r.p {
+		p := p
+		g.Go(func() error {
+			defer close(p.done)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case symbols := <-p.c:
+				samples, err := r.samples(ctx, symbols, p.samples)
+				if err != nil {
+					return err
+				}
+				ft, err := fn(ctx, symbols, samples)
+				if err != nil {
+					return err
+				}
+				m.MergeFunctionTable(ft)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return m.FunctionTable(0, false), nil
+}
+
+// samples returns the partition samples that match
+// the stack trace selector, if one is specified.
+func (r *Resolver) samples(ctx context.Context, symbols *Symbols, m map[uint32]int64) (schemav1.Samples, error) {
+	samples := schemav1.NewSamplesFromMap(m)
+	if !IsStackTraceSelectorEmpty(r.sts) {
+		return symbols.SelectSamples(ctx, r.sts, samples)
+	}
+	return samples, nil
+}
+
 func (r *Symbols) Tree(ctx context.Context, samples schemav1.Samples) (*model.Tree, error) {
 	t := treeSymbolsFromPool()
 	defer t.reset()
@@ -234,6 +325,148 @@ func (r *treeSymbols) InsertStacktrace(_ uint32, locations []int32) {
 	r.cur++
 }
 
+func (r *Symbols) FunctionTable(ctx context.Context, samples schemav1.Samples, byLine bool) (*typesv1.FunctionTable, error) {
+	t := functionTableSymbols{
+		symbols: r,
+		samples: &samples,
+		byLine:  byLine,
+		index:   make(map[functionTableKey]int),
+	}
+	if err := r.Stacktraces.ResolveStacktraceLocations(ctx, &t, samples.StacktraceIDs); err != nil {
+		return nil, err
+	}
+	return t.functionTable(), nil
+}
+
+func (r *Symbols) FunctionLines(ctx context.Context, samples schemav1.Samples, function string) (*typesv1.FunctionTable, error) {
+	t := functionTableSymbols{
+		symbols:   r,
+		samples:   &samples,
+		byLine:    true,
+		functions: r.functionsByName(function),
+		index:     make(map[functionTableKey]int),
+	}
+	if len(t.functions) == 0 {
+		// None of the stack traces can contain the function:
+		// there is no need to resolve them.
+		return &typesv1.FunctionTable{Total: int64(samples.Sum())}, nil
+	}
+	if err := r.Stacktraces.ResolveStacktraceLocations(ctx, &t, samples.StacktraceIDs); err != nil {
+		return nil, err
+	}
+	return t.functionTable(), nil
+}
+
+// functionsByName returns the IDs of the functions with the given name.
+func (r *Symbols) functionsByName(name string) map[uint32]struct{} {
+	ids := make(map[uint32]struct{})
+	for i, f := range r.Functions {
+		if r.Strings[f.Name] == name {
+			ids[uint32(i)] = struct{}{}
+		}
+	}
+	return ids
+}
+
+// functionTableKey identifies a function table entry within a partition:
+// functions with the same name and file name share the entry, regardless
+// of their IDs.
+type functionTableKey struct {
+	name uint32
+	file uint32
+	line int32
+}
+
+type functionTableEntry struct {
+	key   functionTableKey
+	self  int64
+	total int64
+	// The last stack trace the entry was accounted in,
+	// which prevents recursive calls from being counted
+	// more than once in the total value.
+	stack int
+}
+
+type functionTableSymbols struct {
+	symbols *Symbols
+	samples *schemav1.Samples
+	byLine  bool
+	// If not nil, only the lines of the
+	// functions are accounted.
+	functions map[uint32]struct{}
+	cur       int
+	total     int64
+	index     map[functionTableKey]int
+	entries   []functionTableEntry
+}
+
+func (r *functionTableSymbols) InsertStacktrace(_ uint32, locations []int32) {
+	v := int64(r.samples.Values[r.cur])
+	r.cur++
+	r.total += v
+	leaf := true
+	// The first location is the leaf of the call stack, as well as
+	// the first line of a location: the rest of the lines represent
+	// the callers into which the preceding function was inlined.
+	for _, loc := range locations {
+		for _, line := range r.symbols.Locations[loc].Line {
+			if !r.accounted(line.FunctionId) {
+				leaf = false
+				continue
+			}
+			e := &r.entries[r.entry(line)]
+			if leaf {
+				e.self += v
+				leaf = false
+			}
+			if e.stack != r.cur {
+				e.stack = r.cur
+				e.total += v
+			}
+		}
+	}
+}
+
+func (r *functionTableSymbols) accounted(function uint32) bool {
+	if r.functions == nil {
+		return true
+	}
+	_, ok := r.functions[function]
+	return ok
+}
+
+func (r *functionTableSymbols) entry(line schemav1.InMemoryLine) int {
+	f := r.symbols.Functions[line.FunctionId]
+	k := functionTableKey{name: f.Name, file: f.Filename}
+	if r.byLine {
+		k.line = line.Line
+	}
+	i, ok := r.index[k]
+	if !ok {
+		i = len(r.entries)
+		r.index[k] = i
+		r.entries = append(r.entries, functionTableEntry{key: k})
+	}
+	return i
+}
+
+func (r *functionTableSymbols) functionTable() *typesv1.FunctionTable {
+	t := &typesv1.FunctionTable{
+		Entries: make([]*typesv1.FunctionTableEntry, len(r.entries)),
+		Total:   r.total,
+	}
+	for i, e := range r.entries {
+		t.Entries[i] = &typesv1.FunctionTableEntry{
+			FunctionName: r.symbols.Strings[e.key.name],
+			FileName:     r.symbols.Strings[e.key.file],
+			Line:         int64(e.key.line),
+			Self:         e.self,
+			Total:        e.total,
+		}
+	}
+	return t
+}
+
 func (r *Symbols) Profile(ctx context.Context, samples schemav1.Samples) (*profile.Profile, error) {
 	t := pprofResolveFromPool()
 	defer t.reset()
@@ -47,6 +47,113 @@ func Test_memory_Resolver_ResolveTree(t *testing.T) {
 	require.Equal(t, expectedFingerprint, treeFingerprint(resolved))
 }
 
+func Test_memory_Resolver_ResolveFunctionTable(t *testing.T) {
+	s := newMemSuite(t, [][]string{{"testdata/profile.pb.gz"}})
+	r := NewResolver(context.Background(), s.db)
+	defer r.Release()
+	r.AddSamples(0, s.indexed[0][0].Samples)
+	p, err := r.Profile()
+	require.NoError(t, err)
+
+	type key struct{ name, file string }
+	type values struct{ self, total int64 }
+	expected := make(map[key]values)
+	var total int64
+	for _, sample := range p.Sample {
+		v := sample.Value[0]
+		total += v
+		seen := make(map[key]struct{})
+		for i, loc := range sample.Location {
+			for j, line := range loc.Line {
+				k := key{line.Function.Name, line.Function.Filename}
+				x := expected[k]
+				if i == 0 && j == 0 {
+					x.self += v
+				}
+				if _, ok := seen[k]; !ok {
+					seen[k] = struct{}{}
+					x.total += v
+				}
+				expected[k] = x
+			}
+		}
+	}
+
+	r = NewResolver(context.Background(), s.db)
+	defer r.Release()
+	r.AddSamples(0, s.indexed[0][0].Samples)
+	ft, err := r.FunctionTable(false)
+	require.NoError(t, err)
+	require.Equal(t, total, ft.Total)
+	actual := make(map[key]values, len(ft.Entries))
+	for _, e := range ft.Entries {
+		require.Zero(t, e.Line)
+		actual[key{e.FunctionName, e.FileName}] = values{e.Self, e.Total}
+	}
+	require.Equal(t, expected, actual)
+}
+
+func Test_memory_Resolver_ResolveFunctionLines(t *testing.T) {
+	s := newMemSuite(t, [][]string{{"testdata/profile.pb.gz"}})
+	r := NewResolver(context.Background(), s.db)
+	defer r.Release()
+	r.AddSamples(0, s.indexed[0][0].Samples)
+	p, err := r.Profile()
+	require.NoError(t, err)
+
+	function := p.Sample[0].Location[0].Line[0].Function.Name
+	type key struct {
+		file string
+		line int64
+	}
+	type values struct{ self, total int64 }
+	expected := make(map[key]values)
+	var total int64
+	for _, sample := range p.Sample {
+		v := sample.Value[0]
+		total += v
+		seen := make(map[key]struct{})
+		for i, loc := range sample.Location {
+			for j, line := range loc.Line {
+				if line.Function.Name != function {
+					continue
+				}
+				k := key{line.Function.Filename, line.Line}
+				x := expected[k]
+				if i == 0 && j == 0 {
+					x.self += v
+				}
+				if _, ok := seen[k]; !ok {
+					seen[k] = struct{}{}
+					x.total += v
+				}
+				expected[k] = x
+			}
+		}
+	}
+
+	r = NewResolver(context.Background(), s.db)
+	defer r.Release()
+	r.AddSamples(0, s.indexed[0][0].Samples)
+	ft, err := r.FunctionLines(function)
+	require.NoError(t, err)
+	require.Equal(t, total, ft.Total)
+	actual := make(map[key]values, len(ft.Entries))
+	for _, e := range ft.Entries {
+		require.Equal(t, function, e.FunctionName)
+		actual[key{e.FileName, e.Line}] = values{e.Self, e.Total}
+	}
+	require.Equal(t, expected, actual)
+
+	r = NewResolver(context.Background(), s.db)
+	defer r.Release()
+	r.AddSamples(0, s.indexed[0][0].Samples)
+	ft, err = r.FunctionLines("not-a-function")
+	require.NoError(t, err)
+	require.Equal(t, total, ft.Total)
+	require.Empty(t, ft.Entries)
+}
+
 func Test_block_Resolver_ResolveProfile(t *testing.T) {
 	s := newBlockSuite(t, [][]string{{"testdata/profile.pb.gz"}})
 	defer s.teardown()
@@ -0,0 +1,90 @@
+package symdb
+
+import (
+	"context"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
+)
+
+// IsStackTraceSelectorEmpty reports whether the selector matches
+// any stack trace, and therefore can be ignored.
+func IsStackTraceSelectorEmpty(sts *typesv1.StackTraceSelector) bool {
+	return sts == nil || len(sts.CallSite) == 0
+}
+
+// SelectStacktraces returns the set of stack traces that contain the call
+// site of the selector. The stack traces must be ordered in the ascending
+// order; the slice is not modified.
+func (r *Symbols) SelectStacktraces(ctx context.Context, sts *typesv1.StackTraceSelector, stacktraces []uint32) (map[uint32]struct{}, error) {
+	s := stacktraceSelector{
+		symbols:  r,
+		callSite: make([]string, len(sts.GetCallSite())),
+		selected: make(map[uint32]struct{}),
+	}
+	for i, loc := range sts.GetCallSite() {
+		s.callSite[i] = loc.Name
+	}
+	ids := make([]uint32, len(stacktraces))
+	copy(ids, stacktraces)
+	if err := r.Stacktraces.ResolveStacktraceLocations(ctx, &s, ids); err != nil {
+		return nil, err
+	}
+	return s.selected, nil
+}
+
+// SelectSamples returns the samples with stack traces matching the selector.
+func (r *Symbols) SelectSamples(ctx context.Context, sts *typesv1.StackTraceSelector, samples schemav1.Samples) (schemav1.Samples, error) {
+	selected, err := r.SelectStacktraces(ctx, sts, samples.StacktraceIDs)
+	if err != nil {
+		return schemav1.Samples{}, err
+	}
+	result := schemav1.NewSamples(len(selected))
+	for i, sid := range samples.StacktraceIDs {
+		if _, ok := selected[sid]; ok {
+			result.StacktraceIDs = append(result.StacktraceIDs, sid)
+			result.Values = append(result.Values, samples.Values[i])
+		}
+	}
+	return result, nil
+}
+
+type stacktraceSelector struct {
+	symbols  *Symbols
+	callSite []string
+	selected map[uint32]struct{}
+	frames   []string
+}
+
+func (s *stacktraceSelector) InsertStacktrace(stacktraceID uint32, locations []int32) {
+	// Frames are ordered from the root, the same way as in the call site.
+	s.frames = s.frames[:0]
+	for i := len(locations) - 1; i >= 0; i-- {
+		lines := s.symbols.Locations[locations[i]].Line
+		for j := len(lines) - 1; j >= 0; j-- {
+			f := s.symbols.Functions[lines[j].FunctionId]
+			s.frames = append(s.frames, s.symbols.Strings[f.Name])
+		}
+	}
+	if containsCallSite(s.frames, s.callSite) {
+		s.selected[stacktraceID] = struct{}{}
+	}
+}
+
+// containsCallSite reports whether the call site
+// is present in the frames as a contiguous sequence.
+func containsCallSite(frames, callSite []string) bool {
+	for i := 0; i+len(callSite) <= len(frames); i++ {
+		match := true
+		for j := range callSite {
+			if frames[i+j] != callSite[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
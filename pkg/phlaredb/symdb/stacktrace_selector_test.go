@@ -0,0 +1,68 @@
+package symdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+func Test_containsCallSite(t *testing.T) {
+	frames := []string{"main", "a", "b", "c"}
+	for _, tc := range []struct {
+		callSite []string
+		expected bool
+	}{
+		{callSite: []string{"main"}, expected: true},
+		{callSite: []string{"b"}, expected: true},
+		{callSite: []string{"main", "a", "b", "c"}, expected: true},
+		{callSite: []string{"a", "b"}, expected: true},
+		{callSite: []string{"a", "c"}, expected: false},
+		{callSite: []string{"c", "d"}, expected: false},
+		{callSite: []string{"main", "a", "b", "c", "d"}, expected: false},
+		{callSite: []string{"x"}, expected: false},
+	} {
+		require.Equal(t, tc.expected, containsCallSite(frames, tc.callSite), tc.callSite)
+	}
+}
+
+func Test_memory_Resolver_StackTraceSelector(t *testing.T) {
+	s := newMemSuite(t, [][]string{{"testdata/profile.pb.gz"}})
+	r := NewResolver(context.Background(), s.db)
+	defer r.Release()
+	r.AddSamples(0, s.indexed[0][0].Samples)
+	p, err := r.Profile()
+	require.NoError(t, err)
+
+	// Select the stack traces that have the leaf function
+	// of the first sample anywhere in the call path.
+	name := p.Sample[0].Location[0].Line[0].Function.Name
+	var expected int64
+	for _, sample := range p.Sample {
+	match:
+		for _, loc := range sample.Location {
+			for _, line := range loc.Line {
+				if line.Function.Name == name {
+					expected += sample.Value[0]
+					break match
+				}
+			}
+		}
+	}
+
+	sts := &typesv1.StackTraceSelector{CallSite: []*typesv1.Location{{Name: name}}}
+	r = NewResolver(context.Background(), s.db, WithStackTraceSelector(sts))
+	defer r.Release()
+	r.AddSamples(0, s.indexed[0][0].Samples)
+	selected, err := r.Profile()
+	require.NoError(t, err)
+	require.NotEmpty(t, selected.Sample)
+	require.Less(t, len(selected.Sample), len(p.Sample))
+	var actual int64
+	for _, sample := range selected.Sample {
+		actual += sample.Value[0]
+	}
+	require.Equal(t, expected, actual)
+}
@@ -0,0 +1,65 @@
+package querier
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+// SelectCallGraph returns the call graph of the merged profile of the
+// matching series. Unlike flame graphs, the graph has a node per function,
+// which makes it possible to see all the callers of a function at once.
+func (q *Querier) SelectCallGraph(ctx context.Context, req *connect.Request[querierv1.SelectCallGraphRequest]) (*connect.Response[querierv1.SelectCallGraphResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectCallGraph")
+	defer func() {
+		sp.LogFields(
+			otlog.String("start", model.Time(req.Msg.Start).Time().String()),
+			otlog.String("end", model.Time(req.Msg.End).Time().String()),
+			otlog.String("selector", req.Msg.LabelSelector),
+			otlog.String("profile_id", req.Msg.ProfileTypeID),
+		)
+		sp.Finish()
+	}()
+
+	opts := phlaremodel.CallGraphOptions{
+		NodeFraction: phlaremodel.DefaultCallGraphNodeFraction,
+		EdgeFraction: phlaremodel.DefaultCallGraphEdgeFraction,
+	}
+	if req.Msg.NodeFraction != nil {
+		opts.NodeFraction = *req.Msg.NodeFraction
+	}
+	if req.Msg.EdgeFraction != nil {
+		opts.EdgeFraction = *req.Msg.EdgeFraction
+	}
+	if opts.NodeFraction < 0 || opts.NodeFraction > 1 || opts.EdgeFraction < 0 || opts.EdgeFraction > 1 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("node and edge fractions must be in the [0, 1] range"))
+	}
+
+	profile, err := q.SelectMergeProfile(ctx, connect.NewRequest(&querierv1.SelectMergeProfileRequest{
+		ProfileTypeID: req.Msg.ProfileTypeID,
+		LabelSelector: req.Msg.LabelSelector,
+		Start:         req.Msg.Start,
+		End:           req.Msg.End,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	g := phlaremodel.NewCallGraph(profile.Msg, opts)
+	var dot bytes.Buffer
+	if err = phlaremodel.WriteCallGraphDOT(&dot, req.Msg.ProfileTypeID, g); err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	return connect.NewResponse(&querierv1.SelectCallGraphResponse{
+		Graph: g,
+		Dot:   dot.String(),
+	}), nil
+}
@@ -0,0 +1,244 @@
+package querier
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/go-kit/log/level"
+	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/tenant"
+	"github.com/grafana/pyroscope/pkg/util/spanlogger"
+)
+
+const defaultCardinalityLimit = 10
+
+// Cardinality returns the cardinality statistics of the series matching
+// the request. The ingesters and the store-gateways count the series of
+// their blocks, and only the counts are merged. As series can't be
+// deduplicated across the instances, the series counts are estimates:
+//
+//   - A series is replicated to multiple ingesters, therefore the sum of
+//     the ingester counts is scaled down by the replication factor.
+//   - The blocks of the store-gateways cover different time ranges that
+//     mostly contain the same series, therefore the highest count is used.
+//
+// The higher of the two estimates is returned for every statistic.
+func (q *Querier) Cardinality(ctx context.Context, req *connect.Request[querierv1.CardinalityRequest]) (*connect.Response[querierv1.CardinalityResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "Cardinality")
+	defer sp.Finish()
+
+	sp.LogFields(
+		otlog.String("matchers", strings.Join(req.Msg.Matchers, ",")),
+		otlog.Int64("start", req.Msg.Start),
+		otlog.Int64("end", req.Msg.End),
+		otlog.Int32("limit", req.Msg.Limit),
+	)
+
+	if req.Msg.Start == 0 || req.Msg.End == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start and end time are required"))
+	}
+
+	queryIngesters, queryStoreGateways := true, false
+	if q.storeGatewayQuerier != nil {
+		storeQueries := splitQueryToStores(model.Time(req.Msg.Start), model.Time(req.Msg.End), model.Now(), q.cfg.QueryStoreAfter)
+		if !storeQueries.ingester.shouldQuery && !storeQueries.storeGateway.shouldQuery {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start and end time are outside of the ingester and store gateway retention"))
+		}
+		storeQueries.Log(level.Debug(spanlogger.FromContext(ctx, q.logger)))
+		queryIngesters, queryStoreGateways = storeQueries.ingester.shouldQuery, storeQueries.storeGateway.shouldQuery
+	}
+
+	countsReq := &ingestv1.CardinalityRequest{
+		Matchers: req.Msg.Matchers,
+		Start:    req.Msg.Start,
+		End:      req.Msg.End,
+	}
+	ingesterCounts := newCardinalityCounts()
+	storeGatewayCounts := newCardinalityCounts()
+	group, gCtx := errgroup.WithContext(ctx)
+	if queryIngesters {
+		group.Go(func() error {
+			return q.cardinalityFromIngesters(gCtx, countsReq, ingesterCounts)
+		})
+	}
+	if queryStoreGateways {
+		group.Go(func() error {
+			return q.cardinalityFromStoreGateway(gCtx, countsReq, storeGatewayCounts)
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	limit := int(req.Msg.Limit)
+	if limit <= 0 {
+		limit = defaultCardinalityLimit
+	}
+	ingesterCounts.max(storeGatewayCounts)
+	return connect.NewResponse(ingesterCounts.response(limit)), nil
+}
+
+func (q *Querier) cardinalityFromIngesters(ctx context.Context, req *ingestv1.CardinalityRequest, counts *cardinalityCounts) error {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "Cardinality Ingesters")
+	defer sp.Finish()
+
+	responses, err := forAllIngesters(ctx, q.ingesterQuerier, func(childCtx context.Context, ic IngesterQueryClient) (*ingestv1.CardinalityResponse, error) {
+		res, err := ic.Cardinality(childCtx, connect.NewRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		return res.Msg, nil
+	})
+	if err != nil {
+		return connect.NewError(connect.CodeInternal, err)
+	}
+	scale := ingesterReplicaScale(len(responses), q.ingesterQuerier.ring.InstancesCount(), q.ingesterQuerier.ring.ReplicationFactor())
+	for _, r := range responses {
+		counts.add(r.response, scale)
+	}
+	return nil
+}
+
+func (q *Querier) cardinalityFromStoreGateway(ctx context.Context, req *ingestv1.CardinalityRequest, counts *cardinalityCounts) error {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "Cardinality StoreGateway")
+	defer sp.Finish()
+
+	tenantID, err := tenant.ExtractTenantIDFromContext(ctx)
+	if err != nil {
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	responses, err := forAllStoreGateways(ctx, tenantID, q.storeGatewayQuerier, func(ctx context.Context, ic StoreGatewayQueryClient) (*ingestv1.CardinalityResponse, error) {
+		res, err := ic.Cardinality(ctx, connect.NewRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		return res.Msg, nil
+	})
+	if err != nil {
+		return connect.NewError(connect.CodeInternal, err)
+	}
+	for _, r := range responses {
+		c := newCardinalityCounts()
+		c.add(r.response, 1)
+		counts.max(c)
+	}
+	return nil
+}
+
+// ingesterReplicaScale returns the factor to apply to the sum of the
+// series counts of the responding ingesters. Every series is stored in
+// replicationFactor out of the instances, thus it is expected to be
+// counted responses*replicationFactor/instances times.
+func ingesterReplicaScale(responses, instances, replicationFactor int) float64 {
+	if responses == 0 {
+		return 0
+	}
+	if instances < responses {
+		instances = responses
+	}
+	if replicationFactor > instances {
+		replicationFactor = instances
+	}
+	if replicationFactor < 1 {
+		replicationFactor = 1
+	}
+	return float64(instances) / float64(responses*replicationFactor)
+}
+
+type cardinalityLabelPair struct{ name, value string }
+
+// cardinalityCounts is the number of series, in total and per label pair.
+// The counts are fractional, as they are estimated from the counts of
+// multiple instances.
+type cardinalityCounts struct {
+	total  float64
+	series map[cardinalityLabelPair]float64
+}
+
+func newCardinalityCounts() *cardinalityCounts {
+	return &cardinalityCounts{series: make(map[cardinalityLabelPair]float64)}
+}
+
+// add adds the scaled counts of the response.
+func (c *cardinalityCounts) add(r *ingestv1.CardinalityResponse, scale float64) {
+	c.total += float64(r.TotalSeries) * scale
+	for _, p := range r.SeriesCountByLabelPair {
+		c.series[cardinalityLabelPair{name: p.Name, value: p.Value}] += float64(p.SeriesCount) * scale
+	}
+}
+
+// max keeps the highest of the counts.
+func (c *cardinalityCounts) max(o *cardinalityCounts) {
+	c.total = math.Max(c.total, o.total)
+	for p, n := range o.series {
+		c.series[p] = math.Max(c.series[p], n)
+	}
+}
+
+// response computes the statistics of the counts. Private labels are
+// excluded from the label statistics; profile types are reported
+// separately.
+func (c *cardinalityCounts) response(limit int) *querierv1.CardinalityResponse {
+	var (
+		valueCountByName = make(map[string]uint64)
+		seriesByPair     = make(map[string]uint64)
+		seriesBySvc      = make(map[string]uint64)
+		seriesByPType    = make(map[string]uint64)
+	)
+	for p, x := range c.series {
+		n := uint64(math.Round(x))
+		if n == 0 {
+			continue
+		}
+		switch p.name {
+		case phlaremodel.LabelNameServiceName:
+			seriesBySvc[p.value] = n
+		case phlaremodel.LabelNameProfileType:
+			seriesByPType[p.value] = n
+		}
+		if strings.HasPrefix(p.name, "__") {
+			continue
+		}
+		valueCountByName[p.name]++
+		seriesByPair[p.name+"="+p.value] = n
+	}
+
+	return &querierv1.CardinalityResponse{
+		TotalSeries:                 uint64(math.Round(c.total)),
+		LabelValueCountByLabelName:  topCardinalityStats(valueCountByName, limit),
+		SeriesCountByLabelValuePair: topCardinalityStats(seriesByPair, limit),
+		SeriesCountByServiceName:    topCardinalityStats(seriesBySvc, limit),
+		SeriesCountByProfileType:    topCardinalityStats(seriesByPType, limit),
+	}
+}
+
+// topCardinalityStats returns up to limit entries with the highest
+// values, ordered by value in descending order and then by name.
+func topCardinalityStats(m map[string]uint64, limit int) []*querierv1.CardinalityStat {
+	stats := make([]*querierv1.CardinalityStat, 0, len(m))
+	for name, value := range m {
+		stats = append(stats, &querierv1.CardinalityStat{Name: name, Value: value})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Value != stats[j].Value {
+			return stats[i].Value > stats[j].Value
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	if len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats
+}
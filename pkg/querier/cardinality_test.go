@@ -0,0 +1,123 @@
+package querier
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/ring/client"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/pkg/clientpool"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/testhelper"
+)
+
+func Test_Cardinality(t *testing.T) {
+	// Every ingester holds all the series: 3 instances, replication factor 3.
+	ingesterResponse := connect.NewResponse(&ingestv1.CardinalityResponse{
+		TotalSeries: 5,
+		SeriesCountByLabelPair: []*ingestv1.LabelPairSeriesCount{
+			{Name: phlaremodel.LabelNameProfileType, Value: "cpu", SeriesCount: 4},
+			{Name: phlaremodel.LabelNameProfileType, Value: "memory", SeriesCount: 1},
+			{Name: "pod", Value: "api-1", SeriesCount: 2},
+			{Name: "pod", Value: "api-2", SeriesCount: 1},
+			{Name: "pod", Value: "api-3", SeriesCount: 1},
+			{Name: "pod", Value: "db-1", SeriesCount: 1},
+			{Name: phlaremodel.LabelNameServiceName, Value: "api", SeriesCount: 4},
+			{Name: phlaremodel.LabelNameServiceName, Value: "db", SeriesCount: 1},
+		},
+	})
+	querier, err := New(Config{
+		PoolConfig: clientpool.PoolConfig{ClientCleanupPeriod: 1 * time.Millisecond},
+	}, testhelper.NewMockRing([]ring.InstanceDesc{
+		{Addr: "1"},
+		{Addr: "2"},
+		{Addr: "3"},
+	}, 3), &poolFactory{func(addr string) (client.PoolClient, error) {
+		q := newFakeQuerier()
+		q.On("Cardinality", mock.Anything, mock.Anything).Return(ingesterResponse, nil)
+		return q, nil
+	}}, nil, nil, log.NewLogfmtLogger(os.Stdout))
+	require.NoError(t, err)
+
+	out, err := querier.Cardinality(context.Background(), connect.NewRequest(&querierv1.CardinalityRequest{
+		Matchers: []string{`{}`},
+		Start:    1,
+		End:      2,
+		Limit:    2,
+	}))
+	require.NoError(t, err)
+	require.Equal(t, &querierv1.CardinalityResponse{
+		TotalSeries: 5,
+		LabelValueCountByLabelName: []*querierv1.CardinalityStat{
+			{Name: "pod", Value: 4},
+			{Name: "service_name", Value: 2},
+		},
+		SeriesCountByLabelValuePair: []*querierv1.CardinalityStat{
+			{Name: "service_name=api", Value: 4},
+			{Name: "pod=api-1", Value: 2},
+		},
+		SeriesCountByServiceName: []*querierv1.CardinalityStat{
+			{Name: "api", Value: 4},
+			{Name: "db", Value: 1},
+		},
+		SeriesCountByProfileType: []*querierv1.CardinalityStat{
+			{Name: "cpu", Value: 4},
+			{Name: "memory", Value: 1},
+		},
+	}, out.Msg)
+}
+
+func Test_cardinalityCounts(t *testing.T) {
+	response := func(total uint64, pods ...uint64) *ingestv1.CardinalityResponse {
+		r := &ingestv1.CardinalityResponse{TotalSeries: total}
+		for i, n := range pods {
+			r.SeriesCountByLabelPair = append(r.SeriesCountByLabelPair, &ingestv1.LabelPairSeriesCount{
+				Name: "pod", Value: string(rune('a' + i)), SeriesCount: n,
+			})
+		}
+		return r
+	}
+
+	// 4 ingesters, replication factor 2: the series of the 3
+	// responding ingesters are expected to be counted 1.5 times.
+	ingesters := newCardinalityCounts()
+	scale := ingesterReplicaScale(3, 4, 2)
+	for _, r := range []*ingestv1.CardinalityResponse{
+		response(4, 2, 2),
+		response(2, 2),
+		response(3, 2, 1),
+	} {
+		ingesters.add(r, scale)
+	}
+
+	storeGateways := newCardinalityCounts()
+	for _, r := range []*ingestv1.CardinalityResponse{
+		response(5, 1, 1, 3),
+		response(3, 1, 2),
+	} {
+		c := newCardinalityCounts()
+		c.add(r, 1)
+		storeGateways.max(c)
+	}
+
+	ingesters.max(storeGateways)
+	actual := ingesters.response(10)
+	require.Equal(t, uint64(6), actual.TotalSeries)
+	require.Equal(t, []*querierv1.CardinalityStat{
+		{Name: "pod=a", Value: 4},
+		{Name: "pod=c", Value: 3},
+		{Name: "pod=b", Value: 2},
+	}, actual.SeriesCountByLabelValuePair)
+	require.Equal(t, []*querierv1.CardinalityStat{
+		{Name: "pod", Value: 3},
+	}, actual.LabelValueCountByLabelName)
+}
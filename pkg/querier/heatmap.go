@@ -0,0 +1,93 @@
+package querier
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+// SelectHeatmap returns the heatmap of the values of the matching profiles:
+// unlike the series, where the values of the profiles within a step are
+// aggregated, the heatmap shows how the values are distributed.
+func (q *Querier) SelectHeatmap(ctx context.Context, req *connect.Request[querierv1.SelectHeatmapRequest]) (*connect.Response[querierv1.SelectHeatmapResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectHeatmap")
+	defer func() {
+		sp.LogFields(
+			otlog.String("start", model.Time(req.Msg.Start).Time().String()),
+			otlog.String("end", model.Time(req.Msg.End).Time().String()),
+			otlog.String("selector", req.Msg.LabelSelector),
+			otlog.String("profile_id", req.Msg.ProfileTypeID),
+			otlog.String("function", req.Msg.FunctionName),
+		)
+		sp.Finish()
+	}()
+
+	if _, err := parser.ParseMetricSelector(req.Msg.LabelSelector); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if req.Msg.Start >= req.Msg.End {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start must be before end"))
+	}
+	timeBuckets, valueBuckets, err := phlaremodel.HeatmapBuckets(req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if (req.Msg.MinValue == nil) != (req.Msg.MaxValue == nil) || req.Msg.GetMinValue() > req.Msg.GetMaxValue() {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid value range"))
+	}
+
+	var fs *typesv1.FunctionSeriesSelector
+	if req.Msg.FunctionName != "" {
+		fs = &typesv1.FunctionSeriesSelector{
+			Functions: []string{req.Msg.FunctionName},
+			ValueType: req.Msg.ValueType,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Points of profiles with the same timestamp are only kept apart,
+	// if the values can't be summed: the aggregation itself is not used.
+	aggregation := typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_MAX
+	responses, err := q.selectSeries(ctx, connect.NewRequest(&querierv1.SelectSeriesRequest{
+		ProfileTypeID: req.Msg.ProfileTypeID,
+		LabelSelector: req.Msg.LabelSelector,
+		Start:         req.Msg.Start,
+		End:           req.Msg.End,
+		Aggregation:   &aggregation,
+	}), fs)
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := selectMergeSeries(ctx, aggregation, responses)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	defer it.Close()
+
+	b := phlaremodel.NewHeatmapBuilder(req.Msg.Start, req.Msg.End, timeBuckets, valueBuckets)
+	if req.Msg.MinValue != nil {
+		b.SetValueRange(*req.Msg.MinValue, *req.Msg.MaxValue)
+	}
+	for it.Next() {
+		b.Add(it.At().Ts, int64(it.At().Value))
+	}
+	if it.Err() != nil {
+		return nil, connect.NewError(connect.CodeInternal, it.Err())
+	}
+
+	return connect.NewResponse(&querierv1.SelectHeatmapResponse{
+		Heatmap: b.Build(),
+	}), nil
+}
@@ -1,29 +1,38 @@
 package querier
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bufbuild/connect-go"
 	"github.com/gogo/status"
+	"github.com/google/pprof/profile"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql/parser"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
 	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
 	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
 	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
 	phlaremodel "github.com/grafana/pyroscope/pkg/model"
 	"github.com/grafana/pyroscope/pkg/og/structs/flamebearer"
 	"github.com/grafana/pyroscope/pkg/og/util/attime"
+	"github.com/grafana/pyroscope/pkg/pprof"
 	"github.com/grafana/pyroscope/pkg/querier/timeline"
 	httputil "github.com/grafana/pyroscope/pkg/util/http"
+	"github.com/grafana/pyroscope/pkg/util/math"
+	"github.com/grafana/pyroscope/public"
 )
 
 func NewHTTPHandlers(client querierv1connect.QuerierServiceClient) *QueryHandlers {
@@ -75,37 +84,57 @@ func (q *QueryHandlers) RenderDiff(w http.ResponseWriter, req *http.Request) {
 		httputil.Error(w, connect.NewError(connect.CodeInvalidArgument, err))
 		return
 	}
-
-	// Left
-	leftSelectParams, leftProfileType, err := parseSelectProfilesRequest(renderRequestFieldNames{
-		query: "leftQuery",
-		from:  "leftFrom",
-		until: "leftUntil",
-	}, req)
+	format, err := parseRenderFormat(req.Form.Get("format"))
 	if err != nil {
 		httputil.Error(w, connect.NewError(connect.CodeInvalidArgument, err))
 		return
 	}
 
-	rightSelectParams, rightProfileType, err := parseSelectProfilesRequest(renderRequestFieldNames{
-		query: "rightQuery",
-		from:  "rightFrom",
-		until: "rightUntil",
+	// Left
+	leftSelectParams, leftProfileType, err := parseSelectProfilesRequest(renderRequestFieldNames{
+		query:  "leftQuery",
+		from:   "leftFrom",
+		until:  "leftUntil",
+		offset: "leftOffset",
 	}, req)
 	if err != nil {
 		httputil.Error(w, connect.NewError(connect.CodeInvalidArgument, err))
 		return
 	}
-	// TODO: check profile types?
-	if leftProfileType.ID != rightProfileType.ID {
-		httputil.Error(w, connect.NewError(connect.CodeInvalidArgument, errors.New("profile types must match")))
+
+	diffRequest := &querierv1.DiffRequest{Left: leftSelectParams}
+	// If the right query is not specified, the right side is the
+	// left one shifted back by the offset: e.g., now vs yesterday.
+	if req.Form.Get("rightQuery") == "" && req.Form.Get("offset") != "" {
+		if diffRequest.Offset, err = parseOffset(req.Form.Get("offset")); err != nil {
+			httputil.Error(w, connect.NewError(connect.CodeInvalidArgument, err))
+			return
+		}
+	} else {
+		rightSelectParams, rightProfileType, err := parseSelectProfilesRequest(renderRequestFieldNames{
+			query:  "rightQuery",
+			from:   "rightFrom",
+			until:  "rightUntil",
+			offset: "rightOffset",
+		}, req)
+		if err != nil {
+			httputil.Error(w, connect.NewError(connect.CodeInvalidArgument, err))
+			return
+		}
+		// TODO: check profile types?
+		if leftProfileType.ID != rightProfileType.ID {
+			httputil.Error(w, connect.NewError(connect.CodeInvalidArgument, errors.New("profile types must match")))
+			return
+		}
+		diffRequest.Right = rightSelectParams
+	}
+
+	if format != renderFormatJSON {
+		q.renderDiffProfiles(w, req, format, leftProfileType, diffRequest)
 		return
 	}
 
-	res, err := q.client.Diff(req.Context(), connect.NewRequest(&querierv1.DiffRequest{
-		Left:  leftSelectParams,
-		Right: rightSelectParams,
-	}))
+	res, err := q.client.Diff(req.Context(), connect.NewRequest(diffRequest))
 	if err != nil {
 		httputil.Error(w, err)
 		return
@@ -118,6 +147,15 @@ func (q *QueryHandlers) RenderDiff(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// Render renders the flame graph and the timeline of the query. The query
+// is either a profile type selector, or an arithmetic expression of profile
+// types with their own label matchers, e.g.:
+//
+//	memory:alloc_space:bytes:space:bytes{service_name="app"} / memory:alloc_objects:count:space:bytes{service_name="app"}
+//
+// The expression is evaluated per point of the timeline, and for the total
+// and self values of each node of the flame graph, see SelectMergeStacktraces.
+// Expressions are only supported in the json format.
 func (q *QueryHandlers) Render(w http.ResponseWriter, req *http.Request) {
 	if err := req.ParseForm(); err != nil {
 		httputil.Error(w, connect.NewError(connect.CodeInvalidArgument, err))
@@ -128,6 +166,20 @@ func (q *QueryHandlers) Render(w http.ResponseWriter, req *http.Request) {
 		httputil.Error(w, connect.NewError(connect.CodeInvalidArgument, err))
 		return
 	}
+	format, err := parseRenderFormat(req.Form.Get("format"))
+	if err != nil {
+		httputil.Error(w, connect.NewError(connect.CodeInvalidArgument, err))
+		return
+	}
+	if format != renderFormatJSON {
+		p, err := q.selectMergeProfile(req.Context(), selectParams)
+		if err != nil {
+			httputil.Error(w, err)
+			return
+		}
+		writeProfile(w, format, profileType, req.Form.Get("query"), selectParams.GetMaxNodes(), p, nil)
+		return
+	}
 
 	groupBy := req.URL.Query()["groupBy"]
 
@@ -151,11 +203,33 @@ func (q *QueryHandlers) Render(w http.ResponseWriter, req *http.Request) {
 				End:           selectParams.End,
 				Step:          timelineStep,
 				GroupBy:       groupBy,
+				Offset:        selectParams.Offset,
 			}))
 
 		return err
 	})
 
+	var resHeatmap *connect.Response[querierv1.SelectHeatmapResponse]
+	if req.Form.Get("heatmap") == "true" {
+		g.Go(func() error {
+			var err error
+			// The heatmap of the offset range is
+			// shifted to the requested time range.
+			resHeatmap, err = q.client.SelectHeatmap(ctx,
+				connect.NewRequest(&querierv1.SelectHeatmapRequest{
+					ProfileTypeID: selectParams.ProfileTypeID,
+					LabelSelector: selectParams.LabelSelector,
+					Start:         selectParams.Start - selectParams.Offset,
+					End:           selectParams.End - selectParams.Offset,
+				}))
+			if err == nil && resHeatmap.Msg.Heatmap != nil {
+				resHeatmap.Msg.Heatmap.Start += selectParams.Offset
+				resHeatmap.Msg.Heatmap.End += selectParams.Offset
+			}
+			return err
+		})
+	}
+
 	err = g.Wait()
 	if err != nil {
 		httputil.Error(w, err)
@@ -185,6 +259,10 @@ func (q *QueryHandlers) Render(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	if resHeatmap != nil {
+		fb.Heatmap = phlaremodel.ExportHeatmapToFlamebearer(resHeatmap.Msg.Heatmap)
+	}
+
 	w.Header().Add("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(fb); err != nil {
 		httputil.Error(w, err)
@@ -192,19 +270,222 @@ func (q *QueryHandlers) Render(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// renderDiffProfiles renders the left and right sides of the diff request
+// in the given format, with the left side as the base.
+func (q *QueryHandlers) renderDiffProfiles(w http.ResponseWriter, req *http.Request, format string, profileType *typesv1.ProfileType, diffRequest *querierv1.DiffRequest) {
+	right, err := phlaremodel.DiffRight(diffRequest)
+	if err != nil {
+		httputil.Error(w, connect.NewError(connect.CodeInvalidArgument, err))
+		return
+	}
+	var leftProfile, rightProfile *profilev1.Profile
+	g, ctx := errgroup.WithContext(req.Context())
+	g.Go(func() error {
+		var err error
+		leftProfile, err = q.selectMergeProfile(ctx, diffRequest.Left)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		rightProfile, err = q.selectMergeProfile(ctx, right)
+		return err
+	})
+	if err = g.Wait(); err != nil {
+		httputil.Error(w, err)
+		return
+	}
+	title := req.Form.Get("leftQuery")
+	if rq := req.Form.Get("rightQuery"); rq != "" && rq != title {
+		title += " vs " + rq
+	}
+	maxNodes := math.Max(diffRequest.Left.GetMaxNodes(), right.GetMaxNodes())
+	writeProfile(w, format, profileType, title, maxNodes, rightProfile, leftProfile)
+}
+
+// selectMergeProfile returns the merged profile of the request. Unlike the
+// flame graph of SelectMergeStacktraces, the profile is not truncated.
+func (q *QueryHandlers) selectMergeProfile(ctx context.Context, req *querierv1.SelectMergeStacktracesRequest) (*profilev1.Profile, error) {
+	if phlaremodel.IsProfileTypeExpression(req.ProfileTypeID) {
+		return nil, connect.NewError(connect.CodeInvalidArgument,
+			errors.New("profile type expressions are only supported in the json format"))
+	}
+	res, err := q.client.SelectMergeProfile(ctx, connect.NewRequest(&querierv1.SelectMergeProfileRequest{
+		ProfileTypeID:      req.ProfileTypeID,
+		LabelSelector:      req.LabelSelector,
+		Start:              req.Start,
+		End:                req.End,
+		StackTraceSelector: req.StackTraceSelector,
+		Offset:             req.Offset,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return res.Msg, nil
+}
+
+const (
+	renderFormatJSON       = "json"
+	renderFormatCollapsed  = "collapsed"
+	renderFormatFolded     = "folded"
+	renderFormatSpeedscope = "speedscope"
+	renderFormatPprof      = "pprof"
+	renderFormatHTML       = "html"
+	renderFormatSVG        = "svg"
+)
+
+// parseRenderFormat validates the format of the render response.
+// Flamebearer JSON is returned by default.
+func parseRenderFormat(format string) (string, error) {
+	switch format {
+	case "":
+		return renderFormatJSON, nil
+	case renderFormatJSON,
+		renderFormatCollapsed,
+		renderFormatFolded,
+		renderFormatSpeedscope,
+		renderFormatPprof,
+		renderFormatHTML,
+		renderFormatSVG:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// writeProfile writes the profile in the given format. If the base profile
+// is not nil, the output represents the difference between the base and
+// the profile. The max nodes limit only applies to the HTML flame graph.
+func writeProfile(w http.ResponseWriter, format string, profileType *typesv1.ProfileType, title string, maxNodes int64, p, base *profilev1.Profile) {
+	if maxNodes <= 0 {
+		maxNodes = phlaremodel.MaxNodes
+	}
+	var (
+		buf         bytes.Buffer
+		err         error
+		contentType string
+	)
+	t := phlaremodel.TreeFromProfile(p)
+	var baseTree *phlaremodel.Tree
+	if base != nil {
+		baseTree = phlaremodel.TreeFromProfile(base)
+	}
+	switch format {
+	case renderFormatCollapsed, renderFormatFolded:
+		contentType = "text/plain; charset=utf-8"
+		if base != nil {
+			phlaremodel.WriteCollapsedDiff(&buf, baseTree, t)
+		} else {
+			_, err = buf.WriteString(phlaremodel.ExportToOgTree(t).Collapsed())
+		}
+	case renderFormatSpeedscope:
+		contentType = "application/json"
+		profiles := []phlaremodel.SpeedscopeProfile{{Name: title, Tree: t}}
+		if base != nil {
+			profiles = []phlaremodel.SpeedscopeProfile{{Name: "left", Tree: baseTree}, {Name: "right", Tree: t}}
+		}
+		err = phlaremodel.WriteSpeedscope(&buf, profileType, profiles...)
+	case renderFormatPprof:
+		contentType = "application/octet-stream"
+		if base != nil {
+			err = writePprofDiff(&buf, base, p)
+		} else {
+			_, err = pprof.RawFromProto(p).WriteTo(&buf)
+		}
+	case renderFormatHTML:
+		contentType = "text/html; charset=utf-8"
+		err = writeFlamebearerHTML(&buf, profileType, int(maxNodes), t, baseTree)
+	case renderFormatSVG:
+		contentType = "image/svg+xml"
+		err = phlaremodel.WriteFlameGraphSVG(&buf, title, t, baseTree)
+	}
+	if err != nil {
+		httputil.Error(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if format == renderFormatPprof {
+		w.Header().Set("Content-Disposition", `attachment; filename="profile.pb.gz"`)
+	}
+	_, _ = w.Write(buf.Bytes())
+}
+
+// writePprofDiff writes the profile with the base profile values
+// subtracted, the same way "pprof -diff_base" does it.
+func writePprofDiff(w io.Writer, base, p *profilev1.Profile) error {
+	profiles := make([]*profile.Profile, 0, 2)
+	for i, x := range []*profilev1.Profile{p, base} {
+		if len(x.Sample) == 0 {
+			continue
+		}
+		b, err := x.MarshalVT()
+		if err != nil {
+			return err
+		}
+		pp, err := profile.ParseData(b)
+		if err != nil {
+			return err
+		}
+		if i == 1 {
+			pp.Scale(-1)
+			for _, s := range pp.Sample {
+				if s.Label == nil {
+					s.Label = make(map[string][]string)
+				}
+				s.Label["pprof::base"] = []string{"true"}
+			}
+		}
+		profiles = append(profiles, pp)
+	}
+	if len(profiles) == 0 {
+		_, err := pprof.RawFromProto(p).WriteTo(w)
+		return err
+	}
+	merged, err := profile.Merge(profiles)
+	if err != nil {
+		return err
+	}
+	return merged.Write(w)
+}
+
+// writeFlamebearerHTML writes the standalone HTML flame graph of the tree.
+// If the base tree is not nil, the flame graph is differential.
+func writeFlamebearerHTML(w io.Writer, profileType *typesv1.ProfileType, maxNodes int, t, base *phlaremodel.Tree) error {
+	assets, err := public.Assets()
+	if err != nil {
+		return err
+	}
+	cfg := flamebearer.ProfileConfig{
+		Name:     profileType.SampleType,
+		MaxNodes: maxNodes,
+		Metadata: phlaremodel.FlamebearerMetadata(profileType),
+		Tree:     phlaremodel.ExportToOgTree(t),
+	}
+	fb := flamebearer.NewProfile(cfg)
+	if base != nil {
+		baseCfg := cfg
+		baseCfg.Tree = phlaremodel.ExportToOgTree(base)
+		if fb, err = flamebearer.NewCombinedProfile(baseCfg, cfg); err != nil {
+			return err
+		}
+	}
+	return flamebearer.FlamebearerToStandaloneHTML(&fb, assets, w)
+}
+
 type renderRequestFieldNames struct {
-	query string
-	from  string
-	until string
+	query  string
+	from   string
+	until  string
+	offset string
 }
 
 // render/render?format=json&from=now-12h&until=now&query=pyroscope.server.cpu
 func parseSelectProfilesRequest(fieldNames renderRequestFieldNames, req *http.Request) (*querierv1.SelectMergeStacktracesRequest, *typesv1.ProfileType, error) {
 	if fieldNames == (renderRequestFieldNames{}) {
 		fieldNames = renderRequestFieldNames{
-			query: "query",
-			from:  "from",
-			until: "until",
+			query:  "query",
+			from:   "from",
+			until:  "until",
+			offset: "offset",
 		}
 	}
 	selector, ptype, err := parseQuery(fieldNames.query, req)
@@ -218,11 +499,17 @@ func parseSelectProfilesRequest(fieldNames renderRequestFieldNames, req *http.Re
 	start := model.TimeFromUnixNano(attime.Parse(v.Get(fieldNames.from)).UnixNano())
 	end := model.TimeFromUnixNano(attime.Parse(v.Get(fieldNames.until)).UnixNano())
 
+	offset, err := parseOffset(v.Get(fieldNames.offset))
+	if err != nil {
+		return nil, nil, err
+	}
+
 	p := &querierv1.SelectMergeStacktracesRequest{
 		Start:         int64(start),
 		End:           int64(end),
 		LabelSelector: selector,
 		ProfileTypeID: ptype.ID,
+		Offset:        offset,
 	}
 
 	var mn int64
@@ -237,6 +524,18 @@ func parseSelectProfilesRequest(fieldNames renderRequestFieldNames, req *http.Re
 	return p, ptype, nil
 }
 
+// parseOffset parses the duration, e.g. "1h" or "7d", into milliseconds.
+func parseOffset(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := model.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse offset: %w", err)
+	}
+	return time.Duration(d).Milliseconds(), nil
+}
+
 func parseQuery(fieldName string, req *http.Request) (string, *typesv1.ProfileType, error) {
 	q := req.Form.Get(fieldName)
 	if q == "" {
@@ -245,6 +544,11 @@ func parseQuery(fieldName string, req *http.Request) (string, *typesv1.ProfileTy
 
 	parsedSelector, err := parser.ParseMetricSelector(q)
 	if err != nil {
+		// The query may combine several profile types, in which case
+		// the label matchers are specified per profile type.
+		if phlaremodel.IsProfileTypeExpression(q) {
+			return "{}", &typesv1.ProfileType{ID: q}, nil
+		}
 		return "", nil, status.Error(codes.InvalidArgument, fmt.Sprintf("failed to parse '%s'", fieldName))
 	}
 
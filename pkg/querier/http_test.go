@@ -1,15 +1,18 @@
 package querier
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
 	"time"
 
+	"github.com/google/pprof/profile"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
 	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
 )
 
@@ -40,3 +43,87 @@ func Test_ParseQuery(t *testing.T) {
 
 	require.Equal(t, `{foo="bar",bar=~"buzz"}`, queryRequest.LabelSelector)
 }
+
+func Test_ParseQuery_Expression(t *testing.T) {
+	expr := `memory:alloc_space:bytes:space:bytes{foo="bar"} / memory:alloc_objects:count:space:bytes{foo="bar"}`
+	q := url.Values{
+		"query": []string{expr},
+		"from":  []string{"now-6h"},
+		"until": []string{"now"},
+	}
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost/render/render?%s", q.Encode()), nil)
+	require.NoError(t, err)
+	require.NoError(t, req.ParseForm())
+
+	queryRequest, ptype, err := parseSelectProfilesRequest(renderRequestFieldNames{}, req)
+	require.NoError(t, err)
+	require.Equal(t, &typesv1.ProfileType{ID: expr}, ptype)
+	require.Equal(t, expr, queryRequest.ProfileTypeID)
+	require.Equal(t, "{}", queryRequest.LabelSelector)
+}
+
+func Test_ParseQuery_Offset(t *testing.T) {
+	q := url.Values{
+		"query":  []string{`memory:alloc_space:bytes:space:bytes{foo="bar"}`},
+		"from":   []string{"now-6h"},
+		"until":  []string{"now"},
+		"offset": []string{"1d"},
+	}
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost/render/render?%s", q.Encode()), nil)
+	require.NoError(t, err)
+	require.NoError(t, req.ParseForm())
+
+	queryRequest, _, err := parseSelectProfilesRequest(renderRequestFieldNames{}, req)
+	require.NoError(t, err)
+	require.Equal(t, (24 * time.Hour).Milliseconds(), queryRequest.Offset)
+
+	q.Set("offset", "yesterday")
+	req, err = http.NewRequest("GET", fmt.Sprintf("http://localhost/render/render?%s", q.Encode()), nil)
+	require.NoError(t, err)
+	require.NoError(t, req.ParseForm())
+	_, _, err = parseSelectProfilesRequest(renderRequestFieldNames{}, req)
+	require.Error(t, err)
+}
+
+func Test_parseRenderFormat(t *testing.T) {
+	format, err := parseRenderFormat("")
+	require.NoError(t, err)
+	require.Equal(t, renderFormatJSON, format)
+
+	format, err = parseRenderFormat("folded")
+	require.NoError(t, err)
+	require.Equal(t, renderFormatFolded, format)
+
+	_, err = parseRenderFormat("png")
+	require.Error(t, err)
+}
+
+func Test_writePprofDiff(t *testing.T) {
+	newProfile := func(v int64) *profilev1.Profile {
+		return &profilev1.Profile{
+			StringTable: []string{"", "main", "cpu", "nanoseconds"},
+			SampleType:  []*profilev1.ValueType{{Type: 2, Unit: 3}},
+			PeriodType:  &profilev1.ValueType{Type: 2, Unit: 3},
+			Function:    []*profilev1.Function{{Id: 1, Name: 1}},
+			Location:    []*profilev1.Location{{Id: 1, Line: []*profilev1.Line{{FunctionId: 1}}}},
+			Sample:      []*profilev1.Sample{{LocationId: []uint64{1}, Value: []int64{v}}},
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writePprofDiff(&buf, newProfile(3), newProfile(5)))
+	p, err := profile.Parse(&buf)
+	require.NoError(t, err)
+	var total int64
+	for _, s := range p.Sample {
+		total += s.Value[0]
+	}
+	require.Equal(t, int64(2), total)
+	require.Len(t, p.Sample, 2)
+
+	buf.Reset()
+	require.NoError(t, writePprofDiff(&buf, &profilev1.Profile{}, newProfile(5)))
+	p, err = profile.Parse(&buf)
+	require.NoError(t, err)
+	require.Len(t, p.Sample, 1)
+}
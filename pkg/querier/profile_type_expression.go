@@ -0,0 +1,68 @@
+package querier
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+// selectSeriesExpression combines the series of the profile types
+// of the expression. The limit only applies to the resulting series.
+func (q *Querier) selectSeriesExpression(ctx context.Context, req *connect.Request[querierv1.SelectSeriesRequest]) (*connect.Response[querierv1.SelectSeriesResponse], error) {
+	e, err := phlaremodel.ParseProfileTypeExpression(req.Msg.ProfileTypeID, req.Msg.LabelSelector)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	series, err := e.SelectSeries(ctx, func(ctx context.Context, op phlaremodel.ProfileTypeOperand) ([]*typesv1.Series, error) {
+		resp, err := q.SelectSeries(ctx, connect.NewRequest(&querierv1.SelectSeriesRequest{
+			ProfileTypeID:      op.ProfileTypeID,
+			LabelSelector:      op.LabelSelector,
+			Start:              req.Msg.Start,
+			End:                req.Msg.End,
+			GroupBy:            req.Msg.GroupBy,
+			Step:               req.Msg.Step,
+			Aggregation:        req.Msg.Aggregation,
+			StackTraceSelector: req.Msg.StackTraceSelector,
+			Offset:             req.Msg.Offset,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return resp.Msg.Series, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&querierv1.SelectSeriesResponse{
+		Series: phlaremodel.TopSeries(series, int(req.Msg.GetLimit()), req.Msg.IncludeOther),
+	}), nil
+}
+
+// selectMergeStacktracesExpression combines the trees
+// of the profile types of the expression per node.
+func (q *Querier) selectMergeStacktracesExpression(ctx context.Context, req *connect.Request[querierv1.SelectMergeStacktracesRequest]) (*connect.Response[querierv1.SelectMergeStacktracesResponse], error) {
+	e, err := phlaremodel.ParseProfileTypeExpression(req.Msg.ProfileTypeID, req.Msg.LabelSelector)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	t, err := e.SelectTree(ctx, func(ctx context.Context, op phlaremodel.ProfileTypeOperand) (*phlaremodel.Tree, error) {
+		return q.selectTree(ctx, &querierv1.SelectMergeStacktracesRequest{
+			ProfileTypeID:      op.ProfileTypeID,
+			LabelSelector:      op.LabelSelector,
+			Start:              req.Msg.Start,
+			End:                req.Msg.End,
+			StackTraceSelector: req.Msg.StackTraceSelector,
+			Offset:             req.Msg.Offset,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&querierv1.SelectMergeStacktracesResponse{
+		Flamegraph: phlaremodel.NewFlameGraph(t, req.Msg.GetMaxNodes()),
+	}), nil
+}
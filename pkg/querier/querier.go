@@ -11,6 +11,8 @@ import (
 	"github.com/bufbuild/connect-go"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/google/pprof/profile"
+	"github.com/google/uuid"
 	"github.com/grafana/dskit/ring"
 	ring_client "github.com/grafana/dskit/ring/client"
 	"github.com/grafana/dskit/services"
@@ -31,6 +33,7 @@ import (
 	"github.com/grafana/pyroscope/pkg/clientpool"
 	"github.com/grafana/pyroscope/pkg/iter"
 	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/pprof"
 	"github.com/grafana/pyroscope/pkg/util"
 	"github.com/grafana/pyroscope/pkg/util/math"
 	"github.com/grafana/pyroscope/pkg/util/spanlogger"
@@ -148,23 +151,68 @@ func (q *Querier) ProfileTypes(ctx context.Context, req *connect.Request[querier
 
 func (q *Querier) LabelValues(ctx context.Context, req *connect.Request[typesv1.LabelValuesRequest]) (*connect.Response[typesv1.LabelValuesResponse], error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "LabelValues")
-	defer func() {
-		sp.LogFields(
-			otlog.String("name", req.Msg.Name),
-		)
-		sp.Finish()
-	}()
-	responses, err := forAllIngesters(ctx, q.ingesterQuerier, func(childCtx context.Context, ic IngesterQueryClient) ([]string, error) {
-		res, err := ic.LabelValues(childCtx, connect.NewRequest(&typesv1.LabelValuesRequest{
-			Name:     req.Msg.Name,
-			Matchers: req.Msg.Matchers,
-		}))
+	defer sp.Finish()
+
+	sp.LogFields(
+		otlog.String("name", req.Msg.Name),
+		otlog.String("matchers", strings.Join(req.Msg.Matchers, ",")),
+		otlog.Int64("start", req.Msg.Start),
+		otlog.Int64("end", req.Msg.End),
+	)
+
+	// Some clients may not be sending us timestamps. If start or end are 0, then
+	// mark this a legacy request. Legacy requests only query the ingesters.
+	legacyRequest := req.Msg.Start == 0 || req.Msg.End == 0
+	sp.LogFields(otlog.Bool("legacy_request", legacyRequest))
+	if q.storeGatewayQuerier == nil || legacyRequest {
+		responses, err := q.labelValuesFromIngesters(ctx, req.Msg)
 		if err != nil {
 			return nil, err
 		}
-		return res.Msg.Names, nil
-	})
-	if err != nil {
+		return connect.NewResponse(&typesv1.LabelValuesResponse{
+			Names: uniqueSortedStrings(responses),
+		}), nil
+	}
+
+	storeQueries := splitQueryToStores(model.Time(req.Msg.Start), model.Time(req.Msg.End), model.Now(), q.cfg.QueryStoreAfter)
+	if !storeQueries.ingester.shouldQuery && !storeQueries.storeGateway.shouldQuery {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start and end time are outside of the ingester and store gateway retention"))
+	}
+	storeQueries.Log(level.Debug(spanlogger.FromContext(ctx, q.logger)))
+
+	var responses []ResponseFromReplica[[]string]
+	var lock sync.Mutex
+	group, ctx := errgroup.WithContext(ctx)
+
+	if storeQueries.ingester.shouldQuery {
+		group.Go(func() error {
+			ir, err := q.labelValuesFromIngesters(ctx, storeQueries.ingester.LabelValuesRequest(req.Msg))
+			if err != nil {
+				return err
+			}
+
+			lock.Lock()
+			responses = append(responses, ir...)
+			lock.Unlock()
+			return nil
+		})
+	}
+
+	if storeQueries.storeGateway.shouldQuery {
+		group.Go(func() error {
+			ir, err := q.labelValuesFromStoreGateway(ctx, storeQueries.storeGateway.LabelValuesRequest(req.Msg))
+			if err != nil {
+				return err
+			}
+
+			lock.Lock()
+			responses = append(responses, ir...)
+			lock.Unlock()
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
 		return nil, err
 	}
 
@@ -176,16 +224,66 @@ func (q *Querier) LabelValues(ctx context.Context, req *connect.Request[typesv1.
 func (q *Querier) LabelNames(ctx context.Context, req *connect.Request[typesv1.LabelNamesRequest]) (*connect.Response[typesv1.LabelNamesResponse], error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "LabelNames")
 	defer sp.Finish()
-	responses, err := forAllIngesters(ctx, q.ingesterQuerier, func(childCtx context.Context, ic IngesterQueryClient) ([]string, error) {
-		res, err := ic.LabelNames(childCtx, connect.NewRequest(&typesv1.LabelNamesRequest{
-			Matchers: req.Msg.Matchers,
-		}))
+
+	sp.LogFields(
+		otlog.String("matchers", strings.Join(req.Msg.Matchers, ",")),
+		otlog.Int64("start", req.Msg.Start),
+		otlog.Int64("end", req.Msg.End),
+	)
+
+	// Some clients may not be sending us timestamps. If start or end are 0, then
+	// mark this a legacy request. Legacy requests only query the ingesters.
+	legacyRequest := req.Msg.Start == 0 || req.Msg.End == 0
+	sp.LogFields(otlog.Bool("legacy_request", legacyRequest))
+	if q.storeGatewayQuerier == nil || legacyRequest {
+		responses, err := q.labelNamesFromIngesters(ctx, req.Msg)
 		if err != nil {
 			return nil, err
 		}
-		return res.Msg.Names, nil
-	})
-	if err != nil {
+		return connect.NewResponse(&typesv1.LabelNamesResponse{
+			Names: uniqueSortedStrings(responses),
+		}), nil
+	}
+
+	storeQueries := splitQueryToStores(model.Time(req.Msg.Start), model.Time(req.Msg.End), model.Now(), q.cfg.QueryStoreAfter)
+	if !storeQueries.ingester.shouldQuery && !storeQueries.storeGateway.shouldQuery {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start and end time are outside of the ingester and store gateway retention"))
+	}
+	storeQueries.Log(level.Debug(spanlogger.FromContext(ctx, q.logger)))
+
+	var responses []ResponseFromReplica[[]string]
+	var lock sync.Mutex
+	group, ctx := errgroup.WithContext(ctx)
+
+	if storeQueries.ingester.shouldQuery {
+		group.Go(func() error {
+			ir, err := q.labelNamesFromIngesters(ctx, storeQueries.ingester.LabelNamesRequest(req.Msg))
+			if err != nil {
+				return err
+			}
+
+			lock.Lock()
+			responses = append(responses, ir...)
+			lock.Unlock()
+			return nil
+		})
+	}
+
+	if storeQueries.storeGateway.shouldQuery {
+		group.Go(func() error {
+			ir, err := q.labelNamesFromStoreGateway(ctx, storeQueries.storeGateway.LabelNamesRequest(req.Msg))
+			if err != nil {
+				return err
+			}
+
+			lock.Lock()
+			responses = append(responses, ir...)
+			lock.Unlock()
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
 		return nil, err
 	}
 
@@ -300,15 +398,20 @@ func (q *Querier) Diff(ctx context.Context, req *connect.Request[querierv1.DiffR
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "Diff")
 	defer func() {
 		sp.LogFields(
-			otlog.String("leftStart", model.Time(req.Msg.Left.Start).Time().String()),
-			otlog.String("leftEnd", model.Time(req.Msg.Left.End).Time().String()),
+			otlog.String("leftStart", model.Time(req.Msg.Left.GetStart()).Time().String()),
+			otlog.String("leftEnd", model.Time(req.Msg.Left.GetEnd()).Time().String()),
 			// Assume are the same
-			otlog.String("selector", req.Msg.Left.LabelSelector),
-			otlog.String("profile_id", req.Msg.Left.ProfileTypeID),
+			otlog.String("selector", req.Msg.Left.GetLabelSelector()),
+			otlog.String("profile_id", req.Msg.Left.GetProfileTypeID()),
 		)
 		sp.Finish()
 	}()
 
+	right, err := phlaremodel.DiffRight(req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
 	var leftTree, rightTree *phlaremodel.Tree
 	g, gCtx := errgroup.WithContext(ctx)
 
@@ -323,7 +426,7 @@ func (q *Querier) Diff(ctx context.Context, req *connect.Request[querierv1.DiffR
 	})
 
 	g.Go(func() error {
-		res, err := q.selectTree(gCtx, req.Msg.Right)
+		res, err := q.selectTree(gCtx, right)
 		if err != nil {
 			return err
 		}
@@ -345,6 +448,155 @@ func (q *Querier) Diff(ctx context.Context, req *connect.Request[querierv1.DiffR
 	}), nil
 }
 
+const defaultDiffReportLimit = 20
+
+// DiffReport returns the functions whose share of the total has changed
+// the most between the baseline and the comparison selections.
+func (q *Querier) DiffReport(ctx context.Context, req *connect.Request[querierv1.DiffReportRequest]) (*connect.Response[querierv1.DiffReportResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "DiffReport")
+	defer sp.Finish()
+
+	baseline, err := phlaremodel.DiffReportBaseline(req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	sp.LogFields(
+		otlog.String("start", model.Time(req.Msg.Comparison.Start).Time().String()),
+		otlog.String("end", model.Time(req.Msg.Comparison.End).Time().String()),
+		otlog.String("selector", req.Msg.Comparison.LabelSelector),
+		otlog.String("profile_id", req.Msg.Comparison.ProfileTypeID),
+	)
+
+	var b, c phlaremodel.DiffReportSelection
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		b, err = q.selectDiffReportSelection(gCtx, baseline)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		c, err = q.selectDiffReportSelection(gCtx, req.Msg.Comparison)
+		return err
+	})
+	if err = g.Wait(); err != nil {
+		return nil, err
+	}
+
+	limit := int(req.Msg.GetLimit())
+	if limit <= 0 {
+		limit = defaultDiffReportLimit
+	}
+	r := phlaremodel.NewDiffReport(b, c, limit)
+	if len(r.Entries) == 0 {
+		return connect.NewResponse(r), nil
+	}
+
+	// The per-profile values are only needed for the reported functions.
+	functions := make([]string, len(r.Entries))
+	for i, e := range r.Entries {
+		functions[i] = e.FunctionName
+	}
+	g, gCtx = errgroup.WithContext(ctx)
+	for _, x := range []struct {
+		req       *querierv1.SelectMergeStacktracesRequest
+		valueType typesv1.FunctionValueType
+		squares   *map[string]float64
+	}{
+		{baseline, typesv1.FunctionValueType_FUNCTION_VALUE_TYPE_SELF, &b.SelfSquares},
+		{baseline, typesv1.FunctionValueType_FUNCTION_VALUE_TYPE_TOTAL, &b.TotalSquares},
+		{req.Msg.Comparison, typesv1.FunctionValueType_FUNCTION_VALUE_TYPE_SELF, &c.SelfSquares},
+		{req.Msg.Comparison, typesv1.FunctionValueType_FUNCTION_VALUE_TYPE_TOTAL, &c.TotalSquares},
+	} {
+		x := x
+		g.Go(func() error {
+			var err error
+			*x.squares, err = q.selectFunctionSquares(gCtx, x.req, functions, x.valueType)
+			return err
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return nil, err
+	}
+	phlaremodel.ScoreDiffReport(r, b, c)
+	return connect.NewResponse(r), nil
+}
+
+// selectDiffReportSelection returns the merged tree of the selection and
+// the number of profiles it is made of.
+func (q *Querier) selectDiffReportSelection(ctx context.Context, req *querierv1.SelectMergeStacktracesRequest) (phlaremodel.DiffReportSelection, error) {
+	var s phlaremodel.DiffReportSelection
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		s.Tree, err = q.selectTree(gCtx, req)
+		return err
+	})
+	g.Go(func() error {
+		// A single step covering the whole time range.
+		aggregation := typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_COUNT
+		resp, err := q.SelectSeries(gCtx, connect.NewRequest(&querierv1.SelectSeriesRequest{
+			ProfileTypeID: req.ProfileTypeID,
+			LabelSelector: req.LabelSelector,
+			Start:         req.Start,
+			End:           req.End,
+			Offset:        req.Offset,
+			Step:          math.Max(float64(req.End-req.Start)/1000, 1),
+			Aggregation:   &aggregation,
+		}))
+		if err != nil {
+			return err
+		}
+		for _, series := range resp.Msg.Series {
+			for _, p := range series.Points {
+				s.Profiles += int64(p.Value)
+			}
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		return phlaremodel.DiffReportSelection{}, err
+	}
+	return s, nil
+}
+
+// selectFunctionSquares returns the sums of squares of the per-profile
+// values of the functions in the selection.
+func (q *Querier) selectFunctionSquares(ctx context.Context, req *querierv1.SelectMergeStacktracesRequest, functions []string, valueType typesv1.FunctionValueType) (map[string]float64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Without the step, the points of the profiles are kept apart.
+	aggregation := typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_MAX
+	responses, err := q.selectSeries(ctx, connect.NewRequest(&querierv1.SelectSeriesRequest{
+		ProfileTypeID:      req.ProfileTypeID,
+		LabelSelector:      req.LabelSelector,
+		Start:              req.Start - req.Offset,
+		End:                req.End - req.Offset,
+		Aggregation:        &aggregation,
+		StackTraceSelector: req.StackTraceSelector,
+	}), &typesv1.FunctionSeriesSelector{
+		Functions: functions,
+		ValueType: valueType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	it, err := selectMergeSeries(ctx, aggregation, responses)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	defer it.Close()
+	squares := make(map[string]float64, len(functions))
+	for it.Next() {
+		p := it.At()
+		squares[p.Labels().Get(phlaremodel.LabelNameFunction)] += p.Value * p.Value
+	}
+	if it.Err() != nil {
+		return nil, connect.NewError(connect.CodeInternal, it.Err())
+	}
+	return squares, nil
+}
+
 func (q *Querier) SelectMergeStacktraces(ctx context.Context, req *connect.Request[querierv1.SelectMergeStacktracesRequest]) (*connect.Response[querierv1.SelectMergeStacktracesResponse], error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectMergeStacktraces")
 	level.Info(spanlogger.FromContext(ctx, q.logger)).Log(
@@ -362,6 +614,10 @@ func (q *Querier) SelectMergeStacktraces(ctx context.Context, req *connect.Reque
 		req.Msg.MaxNodes = &mn
 	}
 
+	if phlaremodel.IsProfileTypeExpression(req.Msg.ProfileTypeID) {
+		return q.selectMergeStacktracesExpression(ctx, req)
+	}
+
 	t, err := q.selectTree(ctx, req.Msg)
 	if err != nil {
 		return nil, err
@@ -373,6 +629,13 @@ func (q *Querier) SelectMergeStacktraces(ctx context.Context, req *connect.Reque
 }
 
 func (q *Querier) selectTree(ctx context.Context, req *querierv1.SelectMergeStacktracesRequest) (*phlaremodel.Tree, error) {
+	if req.Offset != 0 {
+		req = req.CloneVT()
+		req.Start -= req.Offset
+		req.End -= req.Offset
+		req.Offset = 0
+	}
+
 	// no store gateways configured so just query the ingesters
 	if q.storeGatewayQuerier == nil {
 		return q.selectTreeFromIngesters(ctx, req)
@@ -417,6 +680,217 @@ func (q *Querier) selectTree(ctx context.Context, req *querierv1.SelectMergeStac
 	return storegatewayTree, nil
 }
 
+func (q *Querier) SelectMergeSpanProfile(ctx context.Context, req *connect.Request[querierv1.SelectMergeSpanProfileRequest]) (*connect.Response[querierv1.SelectMergeSpanProfileResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectMergeSpanProfile")
+	level.Info(spanlogger.FromContext(ctx, q.logger)).Log(
+		"start", model.Time(req.Msg.Start).Time().String(),
+		"end", model.Time(req.Msg.End).Time().String(),
+		"selector", req.Msg.LabelSelector,
+		"profile_id", req.Msg.ProfileTypeID,
+		"spans", len(req.Msg.SpanSelector),
+	)
+	defer func() {
+		sp.Finish()
+	}()
+
+	if _, err := phlaremodel.NewSpanSelector(req.Msg.SpanSelector); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if req.Msg.MaxNodes == nil || *req.Msg.MaxNodes == 0 {
+		mn := maxNodesDefault
+		req.Msg.MaxNodes = &mn
+	}
+
+	t, err := q.selectSpanProfile(ctx, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&querierv1.SelectMergeSpanProfileResponse{
+		Flamegraph: phlaremodel.NewFlameGraph(t, req.Msg.GetMaxNodes()),
+	}), nil
+}
+
+func (q *Querier) selectSpanProfile(ctx context.Context, req *querierv1.SelectMergeSpanProfileRequest) (*phlaremodel.Tree, error) {
+	if len(req.SpanSelector) == 0 {
+		return new(phlaremodel.Tree), nil
+	}
+	if q.storeGatewayQuerier == nil {
+		return q.selectSpanProfileFromIngesters(ctx, req)
+	}
+
+	storeQueries := splitQueryToStores(model.Time(req.Start), model.Time(req.End), model.Now(), q.cfg.QueryStoreAfter)
+	if !storeQueries.ingester.shouldQuery && !storeQueries.storeGateway.shouldQuery {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start and end time are outside of the ingester and store gateway retention"))
+	}
+	storeQueries.Log(level.Debug(spanlogger.FromContext(ctx, q.logger)))
+
+	var m sync.Mutex
+	t := new(phlaremodel.Tree)
+	g, gCtx := errgroup.WithContext(ctx)
+	if storeQueries.ingester.shouldQuery {
+		g.Go(func() error {
+			r, err := q.selectSpanProfileFromIngesters(gCtx, storeQueries.ingester.MergeSpanProfileRequest(req))
+			if err != nil {
+				return err
+			}
+			m.Lock()
+			t.Merge(r)
+			m.Unlock()
+			return nil
+		})
+	}
+	if storeQueries.storeGateway.shouldQuery {
+		g.Go(func() error {
+			r, err := q.selectSpanProfileFromStoreGateway(gCtx, storeQueries.storeGateway.MergeSpanProfileRequest(req))
+			if err != nil {
+				return err
+			}
+			m.Lock()
+			t.Merge(r)
+			m.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (q *Querier) SelectFunctionTable(ctx context.Context, req *connect.Request[querierv1.SelectFunctionTableRequest]) (*connect.Response[querierv1.SelectFunctionTableResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectFunctionTable")
+	level.Info(spanlogger.FromContext(ctx, q.logger)).Log(
+		"start", model.Time(req.Msg.Start).Time().String(),
+		"end", model.Time(req.Msg.End).Time().String(),
+		"selector", req.Msg.LabelSelector,
+		"profile_id", req.Msg.ProfileTypeID,
+		"by_line", req.Msg.ByLine,
+	)
+	defer func() {
+		sp.Finish()
+	}()
+
+	m := phlaremodel.NewFunctionTableMerger()
+	if q.storeGatewayQuerier == nil {
+		t, err := q.selectFunctionTableFromIngesters(ctx, req.Msg)
+		if err != nil {
+			return nil, err
+		}
+		m.MergeFunctionTable(t)
+		return connect.NewResponse(&querierv1.SelectFunctionTableResponse{
+			FunctionTable: m.FunctionTable(req.Msg.GetLimit(), req.Msg.OrderByTotal),
+		}), nil
+	}
+
+	storeQueries := splitQueryToStores(model.Time(req.Msg.Start), model.Time(req.Msg.End), model.Now(), q.cfg.QueryStoreAfter)
+	if !storeQueries.ingester.shouldQuery && !storeQueries.storeGateway.shouldQuery {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start and end time are outside of the ingester and store gateway retention"))
+	}
+	storeQueries.Log(level.Debug(spanlogger.FromContext(ctx, q.logger)))
+
+	g, gCtx := errgroup.WithContext(ctx)
+	if storeQueries.ingester.shouldQuery {
+		g.Go(func() error {
+			t, err := q.selectFunctionTableFromIngesters(gCtx, storeQueries.ingester.SelectFunctionTableRequest(req.Msg))
+			if err != nil {
+				return err
+			}
+			m.MergeFunctionTable(t)
+			return nil
+		})
+	}
+	if storeQueries.storeGateway.shouldQuery {
+		g.Go(func() error {
+			t, err := q.selectFunctionTableFromStoreGateway(gCtx, storeQueries.storeGateway.SelectFunctionTableRequest(req.Msg))
+			if err != nil {
+				return err
+			}
+			m.MergeFunctionTable(t)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&querierv1.SelectFunctionTableResponse{
+		FunctionTable: m.FunctionTable(req.Msg.GetLimit(), req.Msg.OrderByTotal),
+	}), nil
+}
+
+func (q *Querier) SelectFunctionLines(ctx context.Context, req *connect.Request[querierv1.SelectFunctionLinesRequest]) (*connect.Response[querierv1.SelectFunctionLinesResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectFunctionLines")
+	level.Info(spanlogger.FromContext(ctx, q.logger)).Log(
+		"start", model.Time(req.Msg.Start).Time().String(),
+		"end", model.Time(req.Msg.End).Time().String(),
+		"selector", req.Msg.LabelSelector,
+		"profile_id", req.Msg.ProfileTypeID,
+		"function", req.Msg.FunctionName,
+	)
+	defer func() {
+		sp.Finish()
+	}()
+
+	if req.Msg.FunctionName == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("function name is required"))
+	}
+
+	m := phlaremodel.NewFunctionTableMerger()
+	if q.storeGatewayQuerier == nil {
+		t, err := q.selectFunctionLinesFromIngesters(ctx, req.Msg)
+		if err != nil {
+			return nil, err
+		}
+		m.MergeFunctionTable(t)
+		return connect.NewResponse(&querierv1.SelectFunctionLinesResponse{
+			FunctionTable: functionLines(m),
+		}), nil
+	}
+
+	storeQueries := splitQueryToStores(model.Time(req.Msg.Start), model.Time(req.Msg.End), model.Now(), q.cfg.QueryStoreAfter)
+	if !storeQueries.ingester.shouldQuery && !storeQueries.storeGateway.shouldQuery {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start and end time are outside of the ingester and store gateway retention"))
+	}
+	storeQueries.Log(level.Debug(spanlogger.FromContext(ctx, q.logger)))
+
+	g, gCtx := errgroup.WithContext(ctx)
+	if storeQueries.ingester.shouldQuery {
+		g.Go(func() error {
+			t, err := q.selectFunctionLinesFromIngesters(gCtx, storeQueries.ingester.SelectFunctionLinesRequest(req.Msg))
+			if err != nil {
+				return err
+			}
+			m.MergeFunctionTable(t)
+			return nil
+		})
+	}
+	if storeQueries.storeGateway.shouldQuery {
+		g.Go(func() error {
+			t, err := q.selectFunctionLinesFromStoreGateway(gCtx, storeQueries.storeGateway.SelectFunctionLinesRequest(req.Msg))
+			if err != nil {
+				return err
+			}
+			m.MergeFunctionTable(t)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&querierv1.SelectFunctionLinesResponse{
+		FunctionTable: functionLines(m),
+	}), nil
+}
+
+// functionLines returns the merged function lines in the source order.
+func functionLines(m *phlaremodel.FunctionTableMerger) *typesv1.FunctionTable {
+	t := m.FunctionTable(0, false)
+	phlaremodel.SortFunctionTableEntriesByLine(t.Entries)
+	return t
+}
+
 type storeQuery struct {
 	start, end  model.Time
 	shouldQuery bool
@@ -424,15 +898,64 @@ type storeQuery struct {
 
 func (sq storeQuery) MergeStacktracesRequest(req *querierv1.SelectMergeStacktracesRequest) *querierv1.SelectMergeStacktracesRequest {
 	return &querierv1.SelectMergeStacktracesRequest{
+		Start:              int64(sq.start),
+		End:                int64(sq.end),
+		LabelSelector:      req.LabelSelector,
+		ProfileTypeID:      req.ProfileTypeID,
+		MaxNodes:           req.MaxNodes,
+		StackTraceSelector: req.StackTraceSelector,
+	}
+}
+
+func (sq storeQuery) MergeSpanProfileRequest(req *querierv1.SelectMergeSpanProfileRequest) *querierv1.SelectMergeSpanProfileRequest {
+	return &querierv1.SelectMergeSpanProfileRequest{
 		Start:         int64(sq.start),
 		End:           int64(sq.end),
 		LabelSelector: req.LabelSelector,
 		ProfileTypeID: req.ProfileTypeID,
+		SpanSelector:  req.SpanSelector,
 		MaxNodes:      req.MaxNodes,
 	}
 }
 
-func (sq storeQuery) MergeSeriesRequest(req *querierv1.SelectSeriesRequest, profileType *typesv1.ProfileType) *ingestv1.MergeProfilesLabelsRequest {
+func (sq storeQuery) SelectFunctionTableRequest(req *querierv1.SelectFunctionTableRequest) *querierv1.SelectFunctionTableRequest {
+	return &querierv1.SelectFunctionTableRequest{
+		Start:         int64(sq.start),
+		End:           int64(sq.end),
+		LabelSelector: req.LabelSelector,
+		ProfileTypeID: req.ProfileTypeID,
+		ByLine:        req.ByLine,
+	}
+}
+
+func (sq storeQuery) SelectFunctionLinesRequest(req *querierv1.SelectFunctionLinesRequest) *querierv1.SelectFunctionLinesRequest {
+	return &querierv1.SelectFunctionLinesRequest{
+		Start:         int64(sq.start),
+		End:           int64(sq.end),
+		LabelSelector: req.LabelSelector,
+		ProfileTypeID: req.ProfileTypeID,
+		FunctionName:  req.FunctionName,
+	}
+}
+
+func (sq storeQuery) LabelValuesRequest(req *typesv1.LabelValuesRequest) *typesv1.LabelValuesRequest {
+	return &typesv1.LabelValuesRequest{
+		Name:     req.Name,
+		Matchers: req.Matchers,
+		Start:    int64(sq.start),
+		End:      int64(sq.end),
+	}
+}
+
+func (sq storeQuery) LabelNamesRequest(req *typesv1.LabelNamesRequest) *typesv1.LabelNamesRequest {
+	return &typesv1.LabelNamesRequest{
+		Matchers: req.Matchers,
+		Start:    int64(sq.start),
+		End:      int64(sq.end),
+	}
+}
+
+func (sq storeQuery) MergeSeriesRequest(req *querierv1.SelectSeriesRequest, profileType *typesv1.ProfileType, fs *typesv1.FunctionSeriesSelector) *ingestv1.MergeProfilesLabelsRequest {
 	return &ingestv1.MergeProfilesLabelsRequest{
 		By: req.GroupBy,
 		Request: &ingestv1.SelectProfilesRequest{
@@ -441,6 +964,19 @@ func (sq storeQuery) MergeSeriesRequest(req *querierv1.SelectSeriesRequest, prof
 			Start:         int64(sq.start),
 			End:           int64(sq.end),
 		},
+		Aggregation:        req.Aggregation,
+		MaxExemplars:       req.MaxExemplars,
+		StackTraceSelector: req.StackTraceSelector,
+		FunctionSeries:     fs,
+	}
+}
+
+func (sq storeQuery) SelectProfileByIDRequest(req *querierv1.SelectProfileByIDRequest, profileType *typesv1.ProfileType) *ingestv1.SelectProfileByIDRequest {
+	return &ingestv1.SelectProfileByIDRequest{
+		ProfileId: req.ProfileId,
+		Type:      profileType,
+		Start:     int64(sq.start),
+		End:       int64(sq.end),
 	}
 }
 
@@ -498,6 +1034,11 @@ func (q *Querier) SelectMergeProfile(ctx context.Context, req *connect.Request[q
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
+	if req.Msg.Offset != 0 {
+		req.Msg.Start -= req.Msg.Offset
+		req.Msg.End -= req.Msg.Offset
+		req.Msg.Offset = 0
+	}
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -519,6 +1060,7 @@ func (q *Querier) SelectMergeProfile(ctx context.Context, req *connect.Request[q
 					End:           req.Msg.End,
 					Type:          profileType,
 				},
+				StackTraceSelector: req.Msg.StackTraceSelector,
 			})
 		}))
 	}
@@ -536,6 +1078,94 @@ func (q *Querier) SelectMergeProfile(ctx context.Context, req *connect.Request[q
 	return connect.NewResponse(profile), nil
 }
 
+func (q *Querier) SelectProfileByID(ctx context.Context, req *connect.Request[querierv1.SelectProfileByIDRequest]) (*connect.Response[googlev1.Profile], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectProfileByID")
+	defer func() {
+		sp.LogFields(
+			otlog.String("start", model.Time(req.Msg.Start).Time().String()),
+			otlog.String("end", model.Time(req.Msg.End).Time().String()),
+			otlog.String("profile_id", req.Msg.ProfileId),
+			otlog.String("profile_type", req.Msg.ProfileTypeID),
+		)
+		sp.Finish()
+	}()
+
+	if _, err := uuid.Parse(req.Msg.ProfileId); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.Wrap(err, "invalid profile ID"))
+	}
+	profileType, err := phlaremodel.ParseProfileTypeSelector(req.Msg.ProfileTypeID)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	var responses []ResponseFromReplica[[]byte]
+	if q.storeGatewayQuerier == nil {
+		responses, err = q.selectProfileByIDFromIngesters(ctx, &ingestv1.SelectProfileByIDRequest{
+			ProfileId: req.Msg.ProfileId,
+			Type:      profileType,
+			Start:     req.Msg.Start,
+			End:       req.Msg.End,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		storeQueries := splitQueryToStores(model.Time(req.Msg.Start), model.Time(req.Msg.End), model.Now(), q.cfg.QueryStoreAfter)
+		if !storeQueries.ingester.shouldQuery && !storeQueries.storeGateway.shouldQuery {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start and end time are outside of the ingester and store gateway retention"))
+		}
+		storeQueries.Log(level.Debug(spanlogger.FromContext(ctx, q.logger)))
+
+		var lock sync.Mutex
+		group, gCtx := errgroup.WithContext(ctx)
+		if storeQueries.ingester.shouldQuery {
+			group.Go(func() error {
+				ir, err := q.selectProfileByIDFromIngesters(gCtx, storeQueries.ingester.SelectProfileByIDRequest(req.Msg, profileType))
+				if err != nil {
+					return err
+				}
+				lock.Lock()
+				responses = append(responses, ir...)
+				lock.Unlock()
+				return nil
+			})
+		}
+		if storeQueries.storeGateway.shouldQuery {
+			group.Go(func() error {
+				ir, err := q.selectProfileByIDFromStoreGateway(gCtx, storeQueries.storeGateway.SelectProfileByIDRequest(req.Msg, profileType))
+				if err != nil {
+					return err
+				}
+				lock.Lock()
+				responses = append(responses, ir...)
+				lock.Unlock()
+				return nil
+			})
+		}
+		if err = group.Wait(); err != nil {
+			return nil, err
+		}
+	}
+
+	// The profile is stored in a single block, but the block may be
+	// replicated, therefore any of the non-empty results can be used.
+	for _, r := range responses {
+		if len(r.response) == 0 {
+			continue
+		}
+		p, err := profile.ParseUncompressed(r.response)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, err)
+		}
+		result, err := pprof.FromProfile(p)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, err)
+		}
+		return connect.NewResponse(result), nil
+	}
+	return nil, connect.NewError(connect.CodeNotFound, errors.Errorf("profile %s not found", req.Msg.ProfileId))
+}
+
 func (q *Querier) SelectSeries(ctx context.Context, req *connect.Request[querierv1.SelectSeriesRequest]) (*connect.Response[querierv1.SelectSeriesResponse], error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectSeries")
 	defer func() {
@@ -546,6 +1176,9 @@ func (q *Querier) SelectSeries(ctx context.Context, req *connect.Request[querier
 			otlog.String("profile_id", req.Msg.ProfileTypeID),
 			otlog.String("group_by", strings.Join(req.Msg.GroupBy, ",")),
 			otlog.Float64("step", req.Msg.Step),
+			otlog.String("aggregation", req.Msg.GetAggregation().String()),
+			otlog.Int64("limit", req.Msg.GetLimit()),
+			otlog.Int64("max_exemplars", req.Msg.MaxExemplars),
 		)
 		sp.Finish()
 	}()
@@ -563,31 +1196,125 @@ func (q *Querier) SelectSeries(ctx context.Context, req *connect.Request[querier
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("step must be non-zero"))
 	}
 
+	if _, ok := typesv1.TimeSeriesAggregationType_name[int32(req.Msg.GetAggregation())]; !ok {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.Errorf("unknown aggregation type: %d", req.Msg.GetAggregation()))
+	}
+
+	if phlaremodel.IsProfileTypeExpression(req.Msg.ProfileTypeID) {
+		return q.selectSeriesExpression(ctx, req)
+	}
+
+	// The time range is shifted back by the offset, and the
+	// timestamps of the resulting points are shifted forward.
+	offset := req.Msg.Offset
+	req.Msg.Start -= offset
+	req.Msg.End -= offset
+	req.Msg.Offset = 0
+
 	stepMs := time.Duration(req.Msg.Step * float64(time.Second)).Milliseconds()
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	responses, err := q.selectSeries(ctx, req)
+	responses, err := q.selectSeries(ctx, req, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	it, err := selectMergeSeries(ctx, responses)
+	it, err := selectMergeSeries(ctx, req.Msg.GetAggregation(), responses)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInternal, err)
 	}
 
-	result := rangeSeries(it, req.Msg.Start, req.Msg.End, stepMs)
+	result := rangeSeries(it, req.Msg.Start, req.Msg.End, stepMs, req.Msg.GetAggregation(), int(req.Msg.MaxExemplars))
 	if it.Err() != nil {
 		return nil, connect.NewError(connect.CodeInternal, it.Err())
 	}
+	result = phlaremodel.TopSeries(result, int(req.Msg.GetLimit()), req.Msg.IncludeOther)
+	result = phlaremodel.ShiftSeries(result, offset)
 
 	return connect.NewResponse(&querierv1.SelectSeriesResponse{
 		Series: result,
 	}), nil
 }
 
-func (q *Querier) selectSeries(ctx context.Context, req *connect.Request[querierv1.SelectSeriesRequest]) ([]ResponseFromReplica[clientpool.BidiClientMergeProfilesLabels], error) {
+func (q *Querier) SelectFunctionSeries(ctx context.Context, req *connect.Request[querierv1.SelectFunctionSeriesRequest]) (*connect.Response[querierv1.SelectSeriesResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectFunctionSeries")
+	defer func() {
+		sp.LogFields(
+			otlog.String("start", model.Time(req.Msg.Start).Time().String()),
+			otlog.String("end", model.Time(req.Msg.End).Time().String()),
+			otlog.String("selector", req.Msg.LabelSelector),
+			otlog.String("profile_id", req.Msg.ProfileTypeID),
+			otlog.String("group_by", strings.Join(req.Msg.GroupBy, ",")),
+			otlog.Float64("step", req.Msg.Step),
+			otlog.String("functions", strings.Join(req.Msg.Functions, ",")),
+			otlog.String("value_type", req.Msg.ValueType.String()),
+		)
+		sp.Finish()
+	}()
+
+	if len(req.Msg.Functions) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("at least one function is required"))
+	}
+
+	_, err := parser.ParseMetricSelector(req.Msg.LabelSelector)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	if req.Msg.Start > req.Msg.End {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start must be before end"))
+	}
+
+	if req.Msg.Step == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("step must be non-zero"))
+	}
+
+	if _, ok := typesv1.FunctionValueType_name[int32(req.Msg.ValueType)]; !ok {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.Errorf("unknown function value type: %d", req.Msg.ValueType))
+	}
+
+	stepMs := time.Duration(req.Msg.Step * float64(time.Second)).Milliseconds()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The values of the profiles within a step are summed.
+	responses, err := q.selectSeries(ctx, connect.NewRequest(&querierv1.SelectSeriesRequest{
+		ProfileTypeID: req.Msg.ProfileTypeID,
+		LabelSelector: req.Msg.LabelSelector,
+		Start:         req.Msg.Start,
+		End:           req.Msg.End,
+		GroupBy:       req.Msg.GroupBy,
+		Step:          req.Msg.Step,
+	}), &typesv1.FunctionSeriesSelector{
+		// Duplicates would result in series with the same labels.
+		Functions: lo.Uniq(req.Msg.Functions),
+		ValueType: req.Msg.ValueType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	aggregation := typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_SUM
+	it, err := selectMergeSeries(ctx, aggregation, responses)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	result := rangeSeries(it, req.Msg.Start, req.Msg.End, stepMs, aggregation, 0)
+	if it.Err() != nil {
+		return nil, connect.NewError(connect.CodeInternal, it.Err())
+	}
+
+	return connect.NewResponse(&querierv1.SelectSeriesResponse{
+		Series: result,
+	}), nil
+}
+
+// selectSeries queries the series from the ingesters and store-gateways.
+// If the function series selector is specified, the series are split
+// by function.
+func (q *Querier) selectSeries(ctx context.Context, req *connect.Request[querierv1.SelectSeriesRequest], fs *typesv1.FunctionSeriesSelector) ([]ResponseFromReplica[clientpool.BidiClientMergeProfilesLabels], error) {
 	stepMs := time.Duration(req.Msg.Step * float64(time.Second)).Milliseconds()
 	sort.Strings(req.Msg.GroupBy)
 
@@ -608,7 +1335,11 @@ func (q *Querier) selectSeries(ctx context.Context, req *connect.Request[querier
 				End:           req.Msg.End,
 				Type:          profileType,
 			},
-			By: req.Msg.GroupBy,
+			By:                 req.Msg.GroupBy,
+			Aggregation:        req.Msg.Aggregation,
+			MaxExemplars:       req.Msg.MaxExemplars,
+			StackTraceSelector: req.Msg.StackTraceSelector,
+			FunctionSeries:     fs,
 		})
 	}
 
@@ -622,7 +1353,7 @@ func (q *Querier) selectSeries(ctx context.Context, req *connect.Request[querier
 
 	// todo in parallel
 	if storeQueries.ingester.shouldQuery {
-		ir, err := q.selectSeriesFromIngesters(ctx, storeQueries.ingester.MergeSeriesRequest(req.Msg, profileType))
+		ir, err := q.selectSeriesFromIngesters(ctx, storeQueries.ingester.MergeSeriesRequest(req.Msg, profileType, fs))
 		if err != nil {
 			return nil, err
 		}
@@ -630,7 +1361,7 @@ func (q *Querier) selectSeries(ctx context.Context, req *connect.Request[querier
 	}
 
 	if storeQueries.storeGateway.shouldQuery {
-		ir, err := q.selectSeriesFromStoreGateway(ctx, storeQueries.storeGateway.MergeSeriesRequest(req.Msg, profileType))
+		ir, err := q.selectSeriesFromStoreGateway(ctx, storeQueries.storeGateway.MergeSeriesRequest(req.Msg, profileType, fs))
 		if err != nil {
 			return nil, err
 		}
@@ -642,51 +1373,48 @@ func (q *Querier) selectSeries(ctx context.Context, req *connect.Request[querier
 // rangeSeries aggregates profiles into series.
 // Series contains points spaced by step from start to end.
 // Profiles from the same step are aggregated into one point.
-func rangeSeries(it iter.Iterator[ProfileValue], start, end, step int64) []*typesv1.Series {
+// Each point keeps up to maxExemplars exemplars with the largest values.
+func rangeSeries(it iter.Iterator[ProfileValue], start, end, step int64, aggregation typesv1.TimeSeriesAggregationType, maxExemplars int) []*typesv1.Series {
 	defer it.Close()
 	seriesMap := make(map[uint64]*typesv1.Series)
+	aggregators := make(map[uint64]phlaremodel.TimeSeriesAggregator)
+	exemplars := make(map[uint64][]*typesv1.Exemplar)
+	// Series that have values in the current step.
+	var current []uint64
 
 	if !it.Next() {
 		return nil
 	}
+	more := true
 	// advance from the start to the end, adding each step results to the map.
-Outer:
-	for currentStep := start; currentStep <= end; currentStep += step {
-		for {
-			if it.At().Ts > currentStep {
-				break // no more profiles for the currentStep
-			}
+	for currentStep := start; more && currentStep <= end; currentStep += step {
+		for more && it.At().Ts <= currentStep {
 			// find or create series
-			series, ok := seriesMap[it.At().LabelsHash]
+			a, ok := aggregators[it.At().LabelsHash]
 			if !ok {
-				seriesMap[it.At().LabelsHash] = &typesv1.Series{
-					Labels: it.At().Lbs,
-					Points: []*typesv1.Point{
-						{Value: it.At().Value, Timestamp: currentStep},
-					},
-				}
-				if !it.Next() {
-					break Outer
-				}
-				continue
+				a = phlaremodel.NewTimeSeriesAggregator(aggregation, step)
+				aggregators[it.At().LabelsHash] = a
+				seriesMap[it.At().LabelsHash] = &typesv1.Series{Labels: it.At().Lbs}
 			}
-			// Aggregate point if it is in the current step.
-			if series.Points[len(series.Points)-1].Timestamp == currentStep {
-				series.Points[len(series.Points)-1].Value += it.At().Value
-				if !it.Next() {
-					break Outer
-				}
-				continue
+			if a.IsEmpty() {
+				current = append(current, it.At().LabelsHash)
 			}
-			// Next step is missing
+			a.Add(it.At().Value)
+			if maxExemplars > 0 {
+				exemplars[it.At().LabelsHash] = append(exemplars[it.At().LabelsHash], it.At().Exemplars...)
+			}
+			more = it.Next()
+		}
+		for _, h := range current {
+			series := seriesMap[h]
 			series.Points = append(series.Points, &typesv1.Point{
-				Value:     it.At().Value,
+				Value:     aggregators[h].GetAndReset(),
 				Timestamp: currentStep,
+				Exemplars: phlaremodel.TopExemplars(exemplars[h], maxExemplars),
 			})
-			if !it.Next() {
-				break Outer
-			}
+			delete(exemplars, h)
 		}
+		current = current[:0]
 	}
 	series := lo.Values(seriesMap)
 	sort.Slice(series, func(i, j int) bool {
@@ -567,6 +567,38 @@ func (f *fakeQuerierIngester) Series(ctx context.Context, req *connect.Request[i
 	return res, err
 }
 
+func (f *fakeQuerierIngester) Cardinality(ctx context.Context, req *connect.Request[ingestv1.CardinalityRequest]) (*connect.Response[ingestv1.CardinalityResponse], error) {
+	var (
+		args = f.Called(ctx, req)
+		res  *connect.Response[ingestv1.CardinalityResponse]
+		err  error
+	)
+	if args[0] != nil {
+		res = args[0].(*connect.Response[ingestv1.CardinalityResponse])
+	}
+	if args[1] != nil {
+		err = args.Get(1).(error)
+	}
+
+	return res, err
+}
+
+func (f *fakeQuerierIngester) SelectProfileByID(ctx context.Context, req *connect.Request[ingestv1.SelectProfileByIDRequest]) (*connect.Response[ingestv1.SelectProfileByIDResponse], error) {
+	var (
+		args = f.Called(ctx, req)
+		res  *connect.Response[ingestv1.SelectProfileByIDResponse]
+		err  error
+	)
+	if args[0] != nil {
+		res = args[0].(*connect.Response[ingestv1.SelectProfileByIDResponse])
+	}
+	if args[1] != nil {
+		err = args.Get(1).(error)
+	}
+
+	return res, err
+}
+
 type testProfile struct {
 	Ts     int64
 	Labels *typesv1.Labels
@@ -788,9 +820,10 @@ func (f *fakeQuerierIngester) MergeProfilesPprof(ctx context.Context) clientpool
 
 func TestRangeSeries(t *testing.T) {
 	for _, tc := range []struct {
-		name string
-		in   []ProfileValue
-		out  []*typesv1.Series
+		name        string
+		in          []ProfileValue
+		out         []*typesv1.Series
+		aggregation typesv1.TimeSeriesAggregationType
 	}{
 		{
 			name: "single series",
@@ -847,15 +880,85 @@ func TestRangeSeries(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "max",
+			aggregation: typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_MAX,
+			in: []ProfileValue{
+				{Ts: 1, Value: 1, Lbs: foobarlabels, LabelsHash: foobarlabels.Hash()},
+				{Ts: 1, Value: 3, Lbs: foobarlabels, LabelsHash: foobarlabels.Hash()},
+				{Ts: 1, Value: 2, Lbs: foobuzzlabels, LabelsHash: foobuzzlabels.Hash()},
+				{Ts: 3, Value: 1, Lbs: foobuzzlabels, LabelsHash: foobuzzlabels.Hash()},
+				{Ts: 3, Value: 5, Lbs: foobuzzlabels, LabelsHash: foobuzzlabels.Hash()},
+				{Ts: 5, Value: 5, Lbs: foobarlabels, LabelsHash: foobarlabels.Hash()},
+			},
+			out: []*typesv1.Series{
+				{
+					Labels: foobarlabels,
+					Points: []*typesv1.Point{
+						{Timestamp: 1, Value: 3},
+						{Timestamp: 5, Value: 5},
+					},
+				},
+				{
+					Labels: foobuzzlabels,
+					Points: []*typesv1.Point{
+						{Timestamp: 1, Value: 2},
+						{Timestamp: 3, Value: 5},
+					},
+				},
+			},
+		},
+		{
+			name:        "count",
+			aggregation: typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_COUNT,
+			in: []ProfileValue{
+				{Ts: 1, Value: 1},
+				{Ts: 1, Value: 1},
+				{Ts: 2, Value: 2},
+				{Ts: 4, Value: 4},
+				{Ts: 4, Value: 4},
+				{Ts: 4, Value: 4},
+			},
+			out: []*typesv1.Series{
+				{
+					Points: []*typesv1.Point{
+						{Timestamp: 1, Value: 2},
+						{Timestamp: 2, Value: 1},
+						{Timestamp: 4, Value: 3},
+					},
+				},
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			in := iter.NewSliceIterator(tc.in)
-			out := rangeSeries(in, 1, 5, 1)
+			out := rangeSeries(in, 1, 5, 1, tc.aggregation, 0)
 			testhelper.EqualProto(t, tc.out, out)
 		})
 	}
 }
 
+func TestRangeSeriesExemplars(t *testing.T) {
+	exemplar := func(id string, v int64) []*typesv1.Exemplar {
+		return []*typesv1.Exemplar{{ProfileId: id, Value: v}}
+	}
+	in := iter.NewSliceIterator([]ProfileValue{
+		{Ts: 1, Value: 1, Exemplars: exemplar("a", 1)},
+		{Ts: 1, Value: 3, Exemplars: exemplar("b", 3)},
+		{Ts: 1, Value: 2, Exemplars: exemplar("c", 2)},
+		{Ts: 3, Value: 4, Exemplars: exemplar("d", 4)},
+	})
+	out := rangeSeries(in, 1, 5, 1, typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_SUM, 2)
+	testhelper.EqualProto(t, []*typesv1.Series{
+		{
+			Points: []*typesv1.Point{
+				{Timestamp: 1, Value: 6, Exemplars: []*typesv1.Exemplar{{ProfileId: "b", Value: 3}, {ProfileId: "c", Value: 2}}},
+				{Timestamp: 3, Value: 4, Exemplars: []*typesv1.Exemplar{{ProfileId: "d", Value: 4}}},
+			},
+		},
+	}, out)
+}
+
 func Test_splitQueryToStores(t *testing.T) {
 	for _, tc := range []struct {
 		name            string
@@ -22,6 +22,7 @@ import (
 	"github.com/grafana/pyroscope/pkg/iter"
 	phlaremodel "github.com/grafana/pyroscope/pkg/model"
 	"github.com/grafana/pyroscope/pkg/pprof"
+	"github.com/grafana/pyroscope/pkg/querier/stats"
 	"github.com/grafana/pyroscope/pkg/util"
 	"github.com/grafana/pyroscope/pkg/util/loser"
 )
@@ -193,6 +194,11 @@ func (s *mergeIterator[R, Req, Res]) Result() (R, error) {
 		s.err = err
 		return *new(R), err
 	}
+	if stats.IsEnabled(s.ctx) {
+		// The stream ends after the result: the query statistics
+		// are only received in the trailer, once it is exhausted.
+		_, _ = s.bidi.Receive()
+	}
 	switch result := any(res).(type) {
 	case *ingestv1.MergeProfilesStacktracesResponse:
 		return any(result.Result).(R), nil
@@ -334,6 +340,59 @@ func selectMergeTree(ctx context.Context, responses []ResponseFromReplica[client
 	return m.Tree(), nil
 }
 
+// selectMergeFunctionTable selects the profile from each ingester by deduping
+// them and returns the function table of the merged stacktrace samples.
+func selectMergeFunctionTable(ctx context.Context, responses []ResponseFromReplica[clientpool.BidiClientMergeProfilesStacktraces]) (*typesv1.FunctionTable, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "selectMergeFunctionTable")
+	defer span.Finish()
+
+	mergeResults := make([]MergeResult[*ingestv1.MergeProfilesStacktracesResult], len(responses))
+	iters := make([]MergeIterator, len(responses))
+	var wg sync.WaitGroup
+	for i, resp := range responses {
+		wg.Add(1)
+		go func(i int, resp ResponseFromReplica[clientpool.BidiClientMergeProfilesStacktraces]) {
+			defer wg.Done()
+			it := NewMergeIterator[*ingestv1.MergeProfilesStacktracesResult](
+				ctx, ResponseFromReplica[BidiClientMerge[*ingestv1.MergeProfilesStacktracesRequest, *ingestv1.MergeProfilesStacktracesResponse]]{
+					addr:     resp.addr,
+					response: resp.response,
+				})
+			iters[i] = it
+			mergeResults[i] = it
+		}(i, resp)
+	}
+	wg.Wait()
+
+	if err := skipDuplicates(ctx, iters); err != nil {
+		return nil, err
+	}
+
+	// Collects the results in parallel.
+	span.LogFields(otlog.String("msg", "collecting merge results"))
+	g, _ := errgroup.WithContext(ctx)
+	m := phlaremodel.NewFunctionTableMerger()
+	for _, iter := range mergeResults {
+		iter := iter
+		g.Go(util.RecoverPanic(func() error {
+			result, err := iter.Result()
+			if err != nil || result == nil {
+				return err
+			}
+			if result.Format != ingestv1.StacktracesMergeFormat_MERGE_FORMAT_FUNCTION_TABLE {
+				return fmt.Errorf("unexpected merge result format: %v", result.Format)
+			}
+			m.MergeFunctionTable(result.FunctionTable)
+			return nil
+		}))
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return m.FunctionTable(0, false), nil
+}
+
 // selectMergePprofProfile selects the  profile from each ingester by deduping them and request merges of stacktraces in the pprof format.
 func selectMergePprofProfile(ctx context.Context, ty *typesv1.ProfileType, responses []ResponseFromReplica[clientpool.BidiClientMergeProfilesPprof]) (*googlev1.Profile, error) {
 	mergeResults := make([]MergeResult[[]byte], len(responses))
@@ -400,6 +459,7 @@ type ProfileValue struct {
 	Lbs        []*typesv1.LabelPair
 	LabelsHash uint64
 	Value      float64
+	Exemplars  []*typesv1.Exemplar
 }
 
 func (p ProfileValue) Labels() phlaremodel.Labels {
@@ -411,7 +471,7 @@ func (p ProfileValue) Timestamp() model.Time {
 }
 
 // selectMergeSeries selects the  profile from each ingester by deduping them and request merges of total values.
-func selectMergeSeries(ctx context.Context, responses []ResponseFromReplica[clientpool.BidiClientMergeProfilesLabels]) (iter.Iterator[ProfileValue], error) {
+func selectMergeSeries(ctx context.Context, aggregation typesv1.TimeSeriesAggregationType, responses []ResponseFromReplica[clientpool.BidiClientMergeProfilesLabels]) (iter.Iterator[ProfileValue], error) {
 	mergeResults := make([]MergeResult[[]*typesv1.Series], len(responses))
 	iters := make([]MergeIterator, len(responses))
 	var wg sync.WaitGroup
@@ -454,7 +514,7 @@ func selectMergeSeries(ctx context.Context, responses []ResponseFromReplica[clie
 	if err := g.Wait(); err != nil {
 		return nil, err
 	}
-	series := phlaremodel.SumSeries(results...)
+	series := phlaremodel.MergeSeries(aggregation, results...)
 	seriesIters := make([]iter.Iterator[ProfileValue], 0, len(series))
 	for _, s := range series {
 		s := s
@@ -488,6 +548,7 @@ func (s *seriesIterator) Next() bool {
 	s.point = s.point[1:]
 	s.curr.Ts = p.Timestamp
 	s.curr.Value = p.Value
+	s.curr.Exemplars = p.Exemplars
 	return true
 }
 
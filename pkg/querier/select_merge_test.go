@@ -171,7 +171,7 @@ func TestSelectMergeByLabels(t *testing.T) {
 		Points: []*typesv1.Point{{Timestamp: 5, Value: 5.0}, {Timestamp: 6, Value: 6.0}},
 	})
 
-	res, err := selectMergeSeries(context.Background(), []ResponseFromReplica[clientpool.BidiClientMergeProfilesLabels]{
+	res, err := selectMergeSeries(context.Background(), typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_SUM, []ResponseFromReplica[clientpool.BidiClientMergeProfilesLabels]{
 		{
 			response: resp1,
 		},
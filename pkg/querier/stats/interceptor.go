@@ -0,0 +1,174 @@
+package stats
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1/ingesterv1connect"
+	"github.com/grafana/pyroscope/api/gen/proto/go/storegateway/v1/storegatewayv1connect"
+)
+
+// HeaderName is the name of the request header used to request
+// the query statistics, and of the response trailer the statistics
+// are returned in, encoded as JSON.
+const HeaderName = "X-Pyroscope-Query-Stats"
+
+var (
+	ingesterProcedurePrefix     = "/" + ingesterv1connect.IngesterServiceName + "/"
+	storeGatewayProcedurePrefix = "/" + storegatewayv1connect.StoreGatewayServiceName + "/"
+)
+
+// NewInterceptor creates a new interceptor that propagates the query
+// statistics between the services.
+//
+// For the client:
+//
+// If the statistics are tracked in the context, the interceptor requests
+// them from the server and merges the statistics returned in the response
+// trailer into the context ones. The time spent in calls to ingesters and
+// store-gateways is accounted as well.
+//
+// For the server:
+//
+// The statistics are tracked if requested by the client, or if the
+// interceptor is enabled, and are returned in the response trailer.
+func NewInterceptor(enabled bool) connect.Interceptor {
+	return &interceptor{enabled: enabled}
+}
+
+type interceptor struct {
+	enabled bool
+}
+
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			stats := FromContext(ctx)
+			if stats == nil {
+				return next(ctx, req)
+			}
+			req.Header().Set(HeaderName, "true")
+			start := time.Now()
+			resp, err := next(ctx, req)
+			stats.addCallTime(req.Spec().Procedure, time.Since(start))
+			if err == nil {
+				stats.mergeHeader(resp.Trailer())
+			}
+			return resp, err
+		}
+		if !i.shouldTrack(ctx, req.Header()) {
+			return next(ctx, req)
+		}
+		stats, ctx := ContextWithEmptyStats(ctx)
+		resp, err := next(ctx, req)
+		if err == nil {
+			setHeader(resp.Trailer(), stats)
+		}
+		return resp, err
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, s connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, s)
+		stats := FromContext(ctx)
+		if stats == nil {
+			return conn
+		}
+		conn.RequestHeader().Set(HeaderName, "true")
+		return &streamingClientConn{
+			StreamingClientConn: conn,
+			stats:               stats,
+			start:               time.Now(),
+		}
+	}
+}
+
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if !i.shouldTrack(ctx, conn.RequestHeader()) {
+			return next(ctx, conn)
+		}
+		stats, ctx := ContextWithEmptyStats(ctx)
+		err := next(ctx, conn)
+		if err == nil {
+			setHeader(conn.ResponseTrailer(), stats)
+		}
+		return err
+	}
+}
+
+// shouldTrack reports whether the server should track the statistics of
+// the request. If the statistics are already tracked in the context, they
+// are returned by the interceptor that has started tracking.
+func (i *interceptor) shouldTrack(ctx context.Context, header http.Header) bool {
+	if IsEnabled(ctx) {
+		return false
+	}
+	return i.enabled || header.Get(HeaderName) != ""
+}
+
+// streamingClientConn merges the statistics returned by the server,
+// once the response stream is exhausted or closed.
+type streamingClientConn struct {
+	connect.StreamingClientConn
+	stats *Stats
+	start time.Time
+	once  sync.Once
+}
+
+func (c *streamingClientConn) Receive(msg any) error {
+	err := c.StreamingClientConn.Receive(msg)
+	if err != nil {
+		// Trailers are only available once the stream is exhausted.
+		c.done()
+	}
+	return err
+}
+
+func (c *streamingClientConn) CloseResponse() error {
+	c.done()
+	return c.StreamingClientConn.CloseResponse()
+}
+
+func (c *streamingClientConn) done() {
+	c.once.Do(func() {
+		c.stats.addCallTime(c.Spec().Procedure, time.Since(c.start))
+		c.stats.mergeHeader(c.ResponseTrailer())
+	})
+}
+
+func (s *Stats) addCallTime(procedure string, d time.Duration) {
+	switch {
+	case strings.HasPrefix(procedure, ingesterProcedurePrefix):
+		s.AddIngesterTime(d)
+	case strings.HasPrefix(procedure, storeGatewayProcedurePrefix):
+		s.AddStoreGatewayTime(d)
+	}
+}
+
+func (s *Stats) mergeHeader(h http.Header) {
+	v := h.Get(HeaderName)
+	if v == "" {
+		return
+	}
+	var other Stats
+	if err := protojson.Unmarshal([]byte(v), &other); err != nil {
+		return
+	}
+	s.Merge(&other)
+}
+
+func setHeader(h http.Header, s *Stats) {
+	b, err := protojson.Marshal(s)
+	if err != nil {
+		return
+	}
+	h.Set(HeaderName, string(b))
+}
@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats_HeaderRoundTrip(t *testing.T) {
+	server, _ := ContextWithEmptyStats(context.Background())
+	server.AddProfilesScanned(100)
+	server.AddBlocksQueried(2)
+	server.AddIngesterTime(time.Second)
+
+	h := make(http.Header)
+	setHeader(h, server)
+	require.NotEmpty(t, h.Get(HeaderName))
+
+	client, _ := ContextWithEmptyStats(context.Background())
+	client.AddProfilesScanned(10)
+	client.mergeHeader(h)
+	client.mergeHeader(make(http.Header))
+
+	assert.Equal(t, uint64(110), client.LoadProfilesScanned())
+	assert.Equal(t, uint64(2), client.LoadBlocksQueried())
+	assert.Equal(t, time.Second, client.LoadIngesterTime())
+}
+
+func TestStats_AddCallTime(t *testing.T) {
+	stats, _ := ContextWithEmptyStats(context.Background())
+	stats.addCallTime("/ingester.v1.IngesterService/MergeProfilesStacktraces", time.Second)
+	stats.addCallTime("/storegateway.v1.StoreGatewayService/MergeProfilesStacktraces", 2*time.Second)
+	stats.addCallTime("/querier.v1.QuerierService/SelectMergeStacktraces", 3*time.Second)
+
+	assert.Equal(t, time.Second, stats.LoadIngesterTime())
+	assert.Equal(t, 2*time.Second, stats.LoadStoreGatewayTime())
+}
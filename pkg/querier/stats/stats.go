@@ -155,6 +155,134 @@ func (s *Stats) LoadSplitQueries() uint32 {
 	return atomic.LoadUint32(&s.SplitQueries)
 }
 
+func (s *Stats) AddProfilesScanned(profiles uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.ProfilesScanned, profiles)
+}
+
+func (s *Stats) LoadProfilesScanned() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.ProfilesScanned)
+}
+
+func (s *Stats) AddSeriesMatched(series uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.SeriesMatched, series)
+}
+
+func (s *Stats) LoadSeriesMatched() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.SeriesMatched)
+}
+
+func (s *Stats) AddBlocksQueried(blocks uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.BlocksQueried, blocks)
+}
+
+func (s *Stats) LoadBlocksQueried() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.BlocksQueried)
+}
+
+func (s *Stats) AddRowGroupsTouched(rowGroups uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.RowGroupsTouched, rowGroups)
+}
+
+func (s *Stats) LoadRowGroupsTouched() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.RowGroupsTouched)
+}
+
+func (s *Stats) AddFetchedSymbolBytes(bytes uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.FetchedSymbolBytes, bytes)
+}
+
+func (s *Stats) LoadFetchedSymbolBytes() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.FetchedSymbolBytes)
+}
+
+func (s *Stats) AddSymdbPartitionsResolved(partitions uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.SymdbPartitionsResolved, partitions)
+}
+
+func (s *Stats) LoadSymdbPartitionsResolved() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.SymdbPartitionsResolved)
+}
+
+func (s *Stats) AddIngesterTime(t time.Duration) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddInt64(&s.IngesterTime, int64(t))
+}
+
+func (s *Stats) LoadIngesterTime() time.Duration {
+	if s == nil {
+		return 0
+	}
+
+	return time.Duration(atomic.LoadInt64(&s.IngesterTime))
+}
+
+func (s *Stats) AddStoreGatewayTime(t time.Duration) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddInt64(&s.StoreGatewayTime, int64(t))
+}
+
+func (s *Stats) LoadStoreGatewayTime() time.Duration {
+	if s == nil {
+		return 0
+	}
+
+	return time.Duration(atomic.LoadInt64(&s.StoreGatewayTime))
+}
+
 // Merge the provided Stats into this one.
 func (s *Stats) Merge(other *Stats) {
 	if s == nil || other == nil {
@@ -168,6 +296,14 @@ func (s *Stats) Merge(other *Stats) {
 	s.AddShardedQueries(other.LoadShardedQueries())
 	s.AddSplitQueries(other.LoadSplitQueries())
 	s.AddFetchedIndexBytes(other.LoadFetchedIndexBytes())
+	s.AddProfilesScanned(other.LoadProfilesScanned())
+	s.AddSeriesMatched(other.LoadSeriesMatched())
+	s.AddBlocksQueried(other.LoadBlocksQueried())
+	s.AddRowGroupsTouched(other.LoadRowGroupsTouched())
+	s.AddFetchedSymbolBytes(other.LoadFetchedSymbolBytes())
+	s.AddSymdbPartitionsResolved(other.LoadSymdbPartitionsResolved())
+	s.AddIngesterTime(other.LoadIngesterTime())
+	s.AddStoreGatewayTime(other.LoadStoreGatewayTime())
 }
 
 func ShouldTrackHTTPGRPCResponse(r *httpgrpc.HTTPResponse) bool {
@@ -44,6 +44,24 @@ type Stats struct {
 	SplitQueries uint32 `protobuf:"varint,6,opt,name=split_queries,json=splitQueries,proto3" json:"split_queries,omitempty"`
 	// The number of index bytes fetched on the store-gateway for the query
 	FetchedIndexBytes uint64 `protobuf:"varint,7,opt,name=fetched_index_bytes,json=fetchedIndexBytes,proto3" json:"fetched_index_bytes,omitempty"`
+	// The number of profiles read for the query.
+	ProfilesScanned uint64 `protobuf:"varint,8,opt,name=profiles_scanned,json=profilesScanned,proto3" json:"profiles_scanned,omitempty"`
+	// The number of series matching the query selector, summed across blocks.
+	SeriesMatched uint64 `protobuf:"varint,9,opt,name=series_matched,json=seriesMatched,proto3" json:"series_matched,omitempty"`
+	// The number of blocks queried, including the ingester heads.
+	BlocksQueried uint64 `protobuf:"varint,10,opt,name=blocks_queried,json=blocksQueried,proto3" json:"blocks_queried,omitempty"`
+	// The number of row groups of the profile tables with rows matching the query.
+	RowGroupsTouched uint64 `protobuf:"varint,11,opt,name=row_groups_touched,json=rowGroupsTouched,proto3" json:"row_groups_touched,omitempty"`
+	// The number of bytes of the symbols (stack traces, locations, functions, mappings
+	// and strings) fetched from the block storage. Pages of the parquet profile tables
+	// are read by block readers shared among the queries, and are not accounted.
+	FetchedSymbolBytes uint64 `protobuf:"varint,12,opt,name=fetched_symbol_bytes,json=fetchedSymbolBytes,proto3" json:"fetched_symbol_bytes,omitempty"`
+	// The number of symbol partitions resolved.
+	SymdbPartitionsResolved uint64 `protobuf:"varint,13,opt,name=symdb_partitions_resolved,json=symdbPartitionsResolved,proto3" json:"symdb_partitions_resolved,omitempty"`
+	// The sum of all time spent in calls to ingesters.
+	IngesterTime int64 `protobuf:"varint,14,opt,name=ingester_time,json=ingesterTime,proto3" json:"ingester_time,omitempty"`
+	// The sum of all time spent in calls to store-gateways.
+	StoreGatewayTime int64 `protobuf:"varint,15,opt,name=store_gateway_time,json=storeGatewayTime,proto3" json:"store_gateway_time,omitempty"`
 }
 
 func (x *Stats) Reset() {
@@ -127,12 +145,68 @@ func (x *Stats) GetFetchedIndexBytes() uint64 {
 	return 0
 }
 
+func (x *Stats) GetProfilesScanned() uint64 {
+	if x != nil {
+		return x.ProfilesScanned
+	}
+	return 0
+}
+
+func (x *Stats) GetSeriesMatched() uint64 {
+	if x != nil {
+		return x.SeriesMatched
+	}
+	return 0
+}
+
+func (x *Stats) GetBlocksQueried() uint64 {
+	if x != nil {
+		return x.BlocksQueried
+	}
+	return 0
+}
+
+func (x *Stats) GetRowGroupsTouched() uint64 {
+	if x != nil {
+		return x.RowGroupsTouched
+	}
+	return 0
+}
+
+func (x *Stats) GetFetchedSymbolBytes() uint64 {
+	if x != nil {
+		return x.FetchedSymbolBytes
+	}
+	return 0
+}
+
+func (x *Stats) GetSymdbPartitionsResolved() uint64 {
+	if x != nil {
+		return x.SymdbPartitionsResolved
+	}
+	return 0
+}
+
+func (x *Stats) GetIngesterTime() int64 {
+	if x != nil {
+		return x.IngesterTime
+	}
+	return 0
+}
+
+func (x *Stats) GetStoreGatewayTime() int64 {
+	if x != nil {
+		return x.StoreGatewayTime
+	}
+	return 0
+}
+
 var File_querier_stats_stats_proto protoreflect.FileDescriptor
 
 var file_querier_stats_stats_proto_rawDesc = []byte{
 	0x0a, 0x19, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x72, 0x2f, 0x73, 0x74, 0x61, 0x74, 0x73, 0x2f,
 	0x73, 0x74, 0x61, 0x74, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x73, 0x74, 0x61,
-	0x74, 0x73, 0x22, 0xb6, 0x02, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x1b, 0x0a, 0x09,
+	0x74, 0x73, 0x22, 0x9e, 0x05, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x1b, 0x0a, 0x09,
 	0x77, 0x61, 0x6c, 0x6c, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
 	0x08, 0x77, 0x61, 0x6c, 0x6c, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x30, 0x0a, 0x14, 0x66, 0x65, 0x74,
 	0x63, 0x68, 0x65, 0x64, 0x5f, 0x73, 0x65, 0x72, 0x69, 0x65, 0x73, 0x5f, 0x63, 0x6f, 0x75, 0x6e,
@@ -151,15 +225,39 @@ var file_querier_stats_stats_proto_rawDesc = []byte{
 	0x70, 0x6c, 0x69, 0x74, 0x51, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x12, 0x2e, 0x0a, 0x13, 0x66,
 	0x65, 0x74, 0x63, 0x68, 0x65, 0x64, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x62, 0x79, 0x74,
 	0x65, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x11, 0x66, 0x65, 0x74, 0x63, 0x68, 0x65,
-	0x64, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x42, 0x79, 0x74, 0x65, 0x73, 0x42, 0x7b, 0x0a, 0x09, 0x63,
-	0x6f, 0x6d, 0x2e, 0x73, 0x74, 0x61, 0x74, 0x73, 0x42, 0x0a, 0x53, 0x74, 0x61, 0x74, 0x73, 0x50,
-	0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
-	0x6f, 0x6d, 0x2f, 0x67, 0x72, 0x61, 0x66, 0x61, 0x6e, 0x61, 0x2f, 0x70, 0x79, 0x72, 0x6f, 0x73,
-	0x63, 0x6f, 0x70, 0x65, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x72,
-	0x2f, 0x73, 0x74, 0x61, 0x74, 0x73, 0xa2, 0x02, 0x03, 0x53, 0x58, 0x58, 0xaa, 0x02, 0x05, 0x53,
-	0x74, 0x61, 0x74, 0x73, 0xca, 0x02, 0x05, 0x53, 0x74, 0x61, 0x74, 0x73, 0xe2, 0x02, 0x11, 0x53,
-	0x74, 0x61, 0x74, 0x73, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61,
-	0xea, 0x02, 0x05, 0x53, 0x74, 0x61, 0x74, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x64, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x29, 0x0a, 0x10, 0x70,
+	0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x5f, 0x73, 0x63, 0x61, 0x6e, 0x6e, 0x65, 0x64, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x53,
+	0x63, 0x61, 0x6e, 0x6e, 0x65, 0x64, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x65, 0x72, 0x69, 0x65, 0x73,
+	0x5f, 0x6d, 0x61, 0x74, 0x63, 0x68, 0x65, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0d,
+	0x73, 0x65, 0x72, 0x69, 0x65, 0x73, 0x4d, 0x61, 0x74, 0x63, 0x68, 0x65, 0x64, 0x12, 0x25, 0x0a,
+	0x0e, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x5f, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x64, 0x18,
+	0x0a, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0d, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x51, 0x75, 0x65,
+	0x72, 0x69, 0x65, 0x64, 0x12, 0x2c, 0x0a, 0x12, 0x72, 0x6f, 0x77, 0x5f, 0x67, 0x72, 0x6f, 0x75,
+	0x70, 0x73, 0x5f, 0x74, 0x6f, 0x75, 0x63, 0x68, 0x65, 0x64, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x10, 0x72, 0x6f, 0x77, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x54, 0x6f, 0x75, 0x63, 0x68,
+	0x65, 0x64, 0x12, 0x30, 0x0a, 0x14, 0x66, 0x65, 0x74, 0x63, 0x68, 0x65, 0x64, 0x5f, 0x73, 0x79,
+	0x6d, 0x62, 0x6f, 0x6c, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x12, 0x66, 0x65, 0x74, 0x63, 0x68, 0x65, 0x64, 0x53, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x42,
+	0x79, 0x74, 0x65, 0x73, 0x12, 0x3a, 0x0a, 0x19, 0x73, 0x79, 0x6d, 0x64, 0x62, 0x5f, 0x70, 0x61,
+	0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x5f, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65,
+	0x64, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x04, 0x52, 0x17, 0x73, 0x79, 0x6d, 0x64, 0x62, 0x50, 0x61,
+	0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64,
+	0x12, 0x23, 0x0a, 0x0d, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x65, 0x72, 0x5f, 0x74, 0x69, 0x6d,
+	0x65, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x65,
+	0x72, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x2c, 0x0a, 0x12, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x5f, 0x67,
+	0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x0f, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x10, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x47, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x54,
+	0x69, 0x6d, 0x65, 0x42, 0x88, 0x01, 0x0a, 0x09, 0x63, 0x6f, 0x6d, 0x2e, 0x73, 0x74, 0x61, 0x74,
+	0x73, 0x42, 0x0a, 0x53, 0x74, 0x61, 0x74, 0x73, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a,
+	0x3b, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x72, 0x61, 0x66,
+	0x61, 0x6e, 0x61, 0x2f, 0x70, 0x79, 0x72, 0x6f, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x2f, 0x70, 0x6b,
+	0x67, 0x2f, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x72, 0x2f, 0x73, 0x74, 0x61, 0x74, 0x73, 0x3b,
+	0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x72, 0x73, 0x74, 0x61, 0x74, 0x73, 0xa2, 0x02, 0x03, 0x53,
+	0x58, 0x58, 0xaa, 0x02, 0x05, 0x53, 0x74, 0x61, 0x74, 0x73, 0xca, 0x02, 0x05, 0x53, 0x74, 0x61,
+	0x74, 0x73, 0xe2, 0x02, 0x11, 0x53, 0x74, 0x61, 0x74, 0x73, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65,
+	0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x05, 0x53, 0x74, 0x61, 0x74, 0x73, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -124,6 +124,8 @@ func TestStats_Merge(t *testing.T) {
 		stats1.AddFetchedChunks(10)
 		stats1.AddShardedQueries(20)
 		stats1.AddSplitQueries(10)
+		stats1.AddProfilesScanned(100)
+		stats1.AddIngesterTime(time.Second)
 
 		stats2 := &Stats{}
 		stats2.AddWallTime(time.Second)
@@ -132,6 +134,8 @@ func TestStats_Merge(t *testing.T) {
 		stats2.AddFetchedChunks(11)
 		stats2.AddShardedQueries(21)
 		stats2.AddSplitQueries(11)
+		stats2.AddProfilesScanned(200)
+		stats2.AddStoreGatewayTime(time.Second)
 
 		stats1.Merge(stats2)
 
@@ -141,6 +145,9 @@ func TestStats_Merge(t *testing.T) {
 		assert.Equal(t, uint64(21), stats1.LoadFetchedChunks())
 		assert.Equal(t, uint32(41), stats1.LoadShardedQueries())
 		assert.Equal(t, uint32(21), stats1.LoadSplitQueries())
+		assert.Equal(t, uint64(300), stats1.LoadProfilesScanned())
+		assert.Equal(t, time.Second, stats1.LoadIngesterTime())
+		assert.Equal(t, time.Second, stats1.LoadStoreGatewayTime())
 	})
 
 	t.Run("merge two nil stats objects", func(t *testing.T) {
@@ -48,6 +48,46 @@ func (m *Stats) MarshalToSizedBufferVT(dAtA []byte) (int, error) {
 		i -= len(m.unknownFields)
 		copy(dAtA[i:], m.unknownFields)
 	}
+	if m.StoreGatewayTime != 0 {
+		i = encodeVarint(dAtA, i, uint64(m.StoreGatewayTime))
+		i--
+		dAtA[i] = 0x78
+	}
+	if m.IngesterTime != 0 {
+		i = encodeVarint(dAtA, i, uint64(m.IngesterTime))
+		i--
+		dAtA[i] = 0x70
+	}
+	if m.SymdbPartitionsResolved != 0 {
+		i = encodeVarint(dAtA, i, uint64(m.SymdbPartitionsResolved))
+		i--
+		dAtA[i] = 0x68
+	}
+	if m.FetchedSymbolBytes != 0 {
+		i = encodeVarint(dAtA, i, uint64(m.FetchedSymbolBytes))
+		i--
+		dAtA[i] = 0x60
+	}
+	if m.RowGroupsTouched != 0 {
+		i = encodeVarint(dAtA, i, uint64(m.RowGroupsTouched))
+		i--
+		dAtA[i] = 0x58
+	}
+	if m.BlocksQueried != 0 {
+		i = encodeVarint(dAtA, i, uint64(m.BlocksQueried))
+		i--
+		dAtA[i] = 0x50
+	}
+	if m.SeriesMatched != 0 {
+		i = encodeVarint(dAtA, i, uint64(m.SeriesMatched))
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.ProfilesScanned != 0 {
+		i = encodeVarint(dAtA, i, uint64(m.ProfilesScanned))
+		i--
+		dAtA[i] = 0x40
+	}
 	if m.FetchedIndexBytes != 0 {
 		i = encodeVarint(dAtA, i, uint64(m.FetchedIndexBytes))
 		i--
@@ -124,6 +164,30 @@ func (m *Stats) SizeVT() (n int) {
 	if m.FetchedIndexBytes != 0 {
 		n += 1 + sov(uint64(m.FetchedIndexBytes))
 	}
+	if m.ProfilesScanned != 0 {
+		n += 1 + sov(uint64(m.ProfilesScanned))
+	}
+	if m.SeriesMatched != 0 {
+		n += 1 + sov(uint64(m.SeriesMatched))
+	}
+	if m.BlocksQueried != 0 {
+		n += 1 + sov(uint64(m.BlocksQueried))
+	}
+	if m.RowGroupsTouched != 0 {
+		n += 1 + sov(uint64(m.RowGroupsTouched))
+	}
+	if m.FetchedSymbolBytes != 0 {
+		n += 1 + sov(uint64(m.FetchedSymbolBytes))
+	}
+	if m.SymdbPartitionsResolved != 0 {
+		n += 1 + sov(uint64(m.SymdbPartitionsResolved))
+	}
+	if m.IngesterTime != 0 {
+		n += 1 + sov(uint64(m.IngesterTime))
+	}
+	if m.StoreGatewayTime != 0 {
+		n += 1 + sov(uint64(m.StoreGatewayTime))
+	}
 	n += len(m.unknownFields)
 	return n
 }
@@ -296,6 +360,158 @@ func (m *Stats) UnmarshalVT(dAtA []byte) error {
 					break
 				}
 			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ProfilesScanned", wireType)
+			}
+			m.ProfilesScanned = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ProfilesScanned |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SeriesMatched", wireType)
+			}
+			m.SeriesMatched = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SeriesMatched |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlocksQueried", wireType)
+			}
+			m.BlocksQueried = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BlocksQueried |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RowGroupsTouched", wireType)
+			}
+			m.RowGroupsTouched = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.RowGroupsTouched |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FetchedSymbolBytes", wireType)
+			}
+			m.FetchedSymbolBytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FetchedSymbolBytes |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SymdbPartitionsResolved", wireType)
+			}
+			m.SymdbPartitionsResolved = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SymdbPartitionsResolved |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IngesterTime", wireType)
+			}
+			m.IngesterTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.IngesterTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StoreGatewayTime", wireType)
+			}
+			m.StoreGatewayTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StoreGatewayTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skip(dAtA[iNdEx:])
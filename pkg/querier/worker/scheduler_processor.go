@@ -233,7 +233,9 @@ func (sp *schedulerProcessor) runRequest(ctx context.Context, logger log.Logger,
 		stats, ctx = querier_stats.ContextWithEmptyStats(ctx)
 	}
 
+	start := time.Now()
 	response, err := sp.handler.Handle(ctx, request)
+	stats.AddWallTime(time.Since(start))
 	if err != nil {
 		var ok bool
 		response, ok = httpgrpc.HTTPResponseFromError(err)
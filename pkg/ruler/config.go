@@ -0,0 +1,57 @@
+package ruler
+
+import (
+	"flag"
+	"time"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	EvaluationInterval time.Duration     `yaml:"evaluation_interval" category:"experimental"`
+	EvaluationDelay    time.Duration     `yaml:"evaluation_delay" category:"experimental"`
+	PollInterval       time.Duration     `yaml:"poll_interval" category:"experimental"`
+	QueryAddress       string            `yaml:"query_address" category:"experimental"`
+	RemoteWrite        RemoteWriteConfig `yaml:"remote_write"`
+}
+
+type RemoteWriteConfig struct {
+	URL            string         `yaml:"url" category:"experimental"`
+	Timeout        time.Duration  `yaml:"timeout" category:"experimental"`
+	BasicAuthUser  string         `yaml:"basic_auth_username" category:"experimental"`
+	BasicAuthPass  flagext.Secret `yaml:"basic_auth_password" category:"experimental"`
+	AddOrgIDHeader bool           `yaml:"add_org_id_header" category:"experimental"`
+}
+
+func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
+	f.DurationVar(&cfg.EvaluationInterval, "ruler.evaluation-interval", time.Minute, "How frequently the recording rules are evaluated. The rules are evaluated over the time range of the interval.")
+	f.DurationVar(&cfg.EvaluationDelay, "ruler.evaluation-delay", time.Minute, "How long to wait after the end of a time range before evaluating the rules over it. Profiles that arrive later than the delay are not included in the results.")
+	f.DurationVar(&cfg.PollInterval, "ruler.poll-interval", time.Minute, "How frequently the rules are reloaded from the object storage.")
+	f.StringVar(&cfg.QueryAddress, "ruler.query-address", "", "The HTTP address of the query-frontend or querier the rules are evaluated with. If empty, the local server is used.")
+	cfg.RemoteWrite.RegisterFlags(f)
+}
+
+func (cfg *RemoteWriteConfig) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&cfg.URL, "ruler.remote-write.url", "", "The URL of the Prometheus remote-write endpoint the results of the rules are written to.")
+	f.DurationVar(&cfg.Timeout, "ruler.remote-write.timeout", 30*time.Second, "The timeout of the remote-write requests.")
+	f.StringVar(&cfg.BasicAuthUser, "ruler.remote-write.basic-auth-username", "", "The username for the basic authentication of the remote-write requests.")
+	f.Var(&cfg.BasicAuthPass, "ruler.remote-write.basic-auth-password", "The password for the basic authentication of the remote-write requests.")
+	f.BoolVar(&cfg.AddOrgIDHeader, "ruler.remote-write.add-org-id-header", false, "Set the X-Scope-OrgID header of the remote-write requests to the tenant of the rules.")
+}
+
+func (cfg *Config) Validate() error {
+	if cfg.RemoteWrite.URL == "" {
+		return errors.New("the remote-write URL of the ruler is required")
+	}
+	if cfg.EvaluationInterval <= 0 {
+		return errors.New("the ruler evaluation interval must be positive")
+	}
+	if cfg.EvaluationDelay < 0 {
+		return errors.New("the ruler evaluation delay must not be negative")
+	}
+	if cfg.PollInterval <= 0 {
+		return errors.New("the ruler poll interval must be positive")
+	}
+	return nil
+}
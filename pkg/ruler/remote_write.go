@@ -0,0 +1,70 @@
+package ruler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/grafana/dskit/user"
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/grafana/pyroscope/pkg/util"
+)
+
+const maxErrMsgLen = 512
+
+// RemoteWriter writes samples to a Prometheus remote-write endpoint.
+type RemoteWriter struct {
+	cfg    RemoteWriteConfig
+	client *http.Client
+}
+
+func NewRemoteWriter(cfg RemoteWriteConfig) *RemoteWriter {
+	return &RemoteWriter{
+		cfg: cfg,
+		// The endpoint is not necessarily HTTP/2 capable.
+		client: &http.Client{
+			Transport: util.WrapWithInstrumentedHTTPTransport(http.DefaultTransport),
+			Timeout:   cfg.Timeout,
+		},
+	}
+}
+
+// Write sends the time series of the tenant in a single request.
+func (w *RemoteWriter) Write(ctx context.Context, tenant string, series []prompb.TimeSeries) error {
+	if len(series) == 0 {
+		return nil
+	}
+	b, err := (&prompb.WriteRequest{Timeseries: series}).Marshal()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(snappy.Encode(nil, b)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "pyroscope-ruler")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if w.cfg.BasicAuthUser != "" {
+		req.SetBasicAuth(w.cfg.BasicAuthUser, w.cfg.BasicAuthPass.String())
+	}
+	if w.cfg.AddOrgIDHeader {
+		req.Header.Set(user.OrgIDHeaderName, tenant)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrMsgLen))
+		return fmt.Errorf("remote write failed with status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
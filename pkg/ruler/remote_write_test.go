@@ -0,0 +1,63 @@
+package ruler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RemoteWriter(t *testing.T) {
+	var received prompb.WriteRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		require.Equal(t, "tenant-a", r.Header.Get("X-Scope-OrgID"))
+		u, p, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "user", u)
+		require.Equal(t, "pass", p)
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		b, err = snappy.Decode(nil, b)
+		require.NoError(t, err)
+		require.NoError(t, received.Unmarshal(b))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := NewRemoteWriter(RemoteWriteConfig{
+		URL:            server.URL,
+		Timeout:        time.Second,
+		BasicAuthUser:  "user",
+		BasicAuthPass:  flagext.SecretWithValue("pass"),
+		AddOrgIDHeader: true,
+	})
+	series := []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "a"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+	}}
+	require.NoError(t, w.Write(context.Background(), "tenant-a", series))
+	require.Equal(t, series, received.Timeseries)
+}
+
+func Test_RemoteWriter_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, r.Header.Get("X-Scope-OrgID"))
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	w := NewRemoteWriter(RemoteWriteConfig{URL: server.URL, Timeout: time.Second})
+	err := w.Write(context.Background(), "tenant-a", []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "a"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+	}})
+	require.EqualError(t, err, "remote write failed with status 400 Bad Request: out of order sample")
+}
@@ -0,0 +1,231 @@
+package ruler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/tenant"
+)
+
+// Querier evaluates the rules.
+type Querier interface {
+	SelectSeries(context.Context, *connect.Request[querierv1.SelectSeriesRequest]) (*connect.Response[querierv1.SelectSeriesResponse], error)
+}
+
+// Writer writes the results of the rules.
+type Writer interface {
+	Write(ctx context.Context, tenant string, series []prompb.TimeSeries) error
+}
+
+// Ruler periodically evaluates the per-tenant recording rules and writes
+// the results to the remote-write endpoint.
+//
+// Every evaluation covers the last complete evaluation interval; rules
+// are not sharded, therefore a single ruler replica is expected to run.
+type Ruler struct {
+	services.Service
+
+	cfg     Config
+	logger  log.Logger
+	store   *RuleStore
+	querier Querier
+	writer  Writer
+	metrics *metrics
+
+	mu    sync.Mutex
+	rules map[string]*RuleGroups
+}
+
+type metrics struct {
+	evaluations        *prometheus.CounterVec
+	evaluationFailures *prometheus.CounterVec
+	samplesWritten     *prometheus.CounterVec
+	writeFailures      *prometheus.CounterVec
+	loadFailures       prometheus.Counter
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	return &metrics{
+		evaluations: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_ruler_rule_evaluations_total",
+			Help: "The total number of rule evaluations.",
+		}, []string{"tenant"}),
+		evaluationFailures: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_ruler_rule_evaluation_failures_total",
+			Help: "The total number of failed rule evaluations.",
+		}, []string{"tenant"}),
+		samplesWritten: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_ruler_samples_written_total",
+			Help: "The total number of samples written to the remote-write endpoint.",
+		}, []string{"tenant"}),
+		writeFailures: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_ruler_remote_write_failures_total",
+			Help: "The total number of failed remote-write requests.",
+		}, []string{"tenant"}),
+		loadFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "pyroscope_ruler_rules_load_failures_total",
+			Help: "The total number of failures to load the rules from the object storage.",
+		}),
+	}
+}
+
+func New(cfg Config, store *RuleStore, querier Querier, writer Writer, logger log.Logger, reg prometheus.Registerer) *Ruler {
+	r := &Ruler{
+		cfg:     cfg,
+		logger:  logger,
+		store:   store,
+		querier: querier,
+		writer:  writer,
+		metrics: newMetrics(reg),
+		rules:   make(map[string]*RuleGroups),
+	}
+	r.Service = services.NewBasicService(r.starting, r.running, nil)
+	return r
+}
+
+func (r *Ruler) starting(ctx context.Context) error {
+	r.loadRules(ctx)
+	return nil
+}
+
+func (r *Ruler) running(ctx context.Context) error {
+	evaluation := time.NewTicker(r.cfg.EvaluationInterval)
+	defer evaluation.Stop()
+	poll := time.NewTicker(r.cfg.PollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll.C:
+			r.loadRules(ctx)
+		case t := <-evaluation.C:
+			start, end := r.evaluationWindow(t)
+			r.evaluate(ctx, start, end)
+		}
+	}
+}
+
+// evaluationWindow returns the latest time range that closed at least
+// the evaluation delay before t: profiles are ingested with a delay,
+// and the most recent ones are not queryable yet.
+func (r *Ruler) evaluationWindow(t time.Time) (start, end model.Time) {
+	end = model.TimeFromUnixNano(t.Add(-r.cfg.EvaluationDelay).Truncate(r.cfg.EvaluationInterval).UnixNano())
+	return end.Add(-r.cfg.EvaluationInterval), end
+}
+
+// loadRules replaces the rules of all the tenants. If the rules of
+// a tenant can't be loaded, the previously loaded ones are kept.
+func (r *Ruler) loadRules(ctx context.Context) {
+	tenants, err := r.store.Tenants(ctx)
+	if err != nil {
+		r.metrics.loadFailures.Inc()
+		level.Error(r.logger).Log("msg", "failed to list the tenants rules", "err", err)
+		return
+	}
+	r.mu.Lock()
+	previous := r.rules
+	r.mu.Unlock()
+	rules := make(map[string]*RuleGroups, len(tenants))
+	for _, tenantID := range tenants {
+		groups, err := r.store.Load(ctx, tenantID)
+		if err != nil {
+			r.metrics.loadFailures.Inc()
+			level.Error(r.logger).Log("msg", "failed to load the tenant rules", "tenant", tenantID, "err", err)
+			if groups = previous[tenantID]; groups == nil {
+				continue
+			}
+		}
+		rules[tenantID] = groups
+	}
+	r.mu.Lock()
+	r.rules = rules
+	r.mu.Unlock()
+}
+
+func (r *Ruler) evaluate(ctx context.Context, start, end model.Time) {
+	r.mu.Lock()
+	rules := r.rules
+	r.mu.Unlock()
+	for tenantID, groups := range rules {
+		series := r.evaluateTenant(ctx, tenantID, groups, start, end)
+		if len(series) == 0 {
+			continue
+		}
+		if err := r.writer.Write(ctx, tenantID, series); err != nil {
+			r.metrics.writeFailures.WithLabelValues(tenantID).Inc()
+			level.Error(r.logger).Log("msg", "failed to write the rules results", "tenant", tenantID, "err", err)
+			continue
+		}
+		r.metrics.samplesWritten.WithLabelValues(tenantID).Add(float64(len(series)))
+	}
+}
+
+func (r *Ruler) evaluateTenant(ctx context.Context, tenantID string, groups *RuleGroups, start, end model.Time) []prompb.TimeSeries {
+	ctx = tenant.InjectTenantID(ctx, tenantID)
+	var series []prompb.TimeSeries
+	for _, group := range groups.Groups {
+		for _, rule := range group.Rules {
+			r.metrics.evaluations.WithLabelValues(tenantID).Inc()
+			resp, err := r.querier.SelectSeries(ctx, connect.NewRequest(rule.SelectSeriesRequest(start, end)))
+			if err != nil {
+				r.metrics.evaluationFailures.WithLabelValues(tenantID).Inc()
+				level.Warn(r.logger).Log("msg", "failed to evaluate rule", "tenant", tenantID, "group", group.Name, "record", rule.Record, "err", err)
+				continue
+			}
+			series = append(series, recordedSeries(rule, resp.Msg.Series, end)...)
+		}
+	}
+	return series
+}
+
+// recordedSeries converts the query result to the samples of the recorded
+// metric at the end of the evaluated time range. The rule labels override
+// the series ones.
+func recordedSeries(rule Rule, result []*typesv1.Series, end model.Time) []prompb.TimeSeries {
+	series := make([]prompb.TimeSeries, 0, len(result))
+	for _, s := range result {
+		var point *typesv1.Point
+		for _, p := range s.Points {
+			if p.Timestamp == int64(end) {
+				point = p
+			}
+		}
+		if point == nil {
+			continue
+		}
+		labels := make(map[string]string, len(s.Labels)+len(rule.Labels)+1)
+		for _, l := range s.Labels {
+			labels[l.Name] = l.Value
+		}
+		for name, value := range rule.Labels {
+			labels[name] = value
+		}
+		labels[model.MetricNameLabel] = rule.Record
+		ts := prompb.TimeSeries{
+			Labels:  make([]prompb.Label, 0, len(labels)),
+			Samples: []prompb.Sample{{Value: point.Value, Timestamp: point.Timestamp}},
+		}
+		for name, value := range labels {
+			ts.Labels = append(ts.Labels, prompb.Label{Name: name, Value: value})
+		}
+		sort.Slice(ts.Labels, func(i, j int) bool {
+			return ts.Labels[i].Name < ts.Labels[j].Name
+		})
+		series = append(series, ts)
+	}
+	return series
+}
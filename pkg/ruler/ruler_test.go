@@ -0,0 +1,220 @@
+package ruler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1/ingesterv1connect"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/clientpool"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/objstore/providers/filesystem"
+	phlarecontext "github.com/grafana/pyroscope/pkg/phlare/context"
+	"github.com/grafana/pyroscope/pkg/phlaredb"
+	pproftesthelper "github.com/grafana/pyroscope/pkg/pprof/testhelper"
+	"github.com/grafana/pyroscope/pkg/querier"
+	"github.com/grafana/pyroscope/pkg/testhelper"
+)
+
+type fakeQuerier struct {
+	requests []*querierv1.SelectSeriesRequest
+	tenants  []string
+}
+
+func (q *fakeQuerier) SelectSeries(ctx context.Context, req *connect.Request[querierv1.SelectSeriesRequest]) (*connect.Response[querierv1.SelectSeriesResponse], error) {
+	tenantID, err := user.ExtractOrgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q.tenants = append(q.tenants, tenantID)
+	q.requests = append(q.requests, req.Msg)
+	return connect.NewResponse(&querierv1.SelectSeriesResponse{
+		Series: []*typesv1.Series{
+			{
+				Labels: []*typesv1.LabelPair{{Name: "pod", Value: "a"}, {Name: "team", Value: "x"}},
+				Points: []*typesv1.Point{{Timestamp: req.Msg.Start, Value: 1}, {Timestamp: req.Msg.End, Value: 2}},
+			},
+			{
+				// No point at the end of the time range.
+				Labels: []*typesv1.LabelPair{{Name: "pod", Value: "b"}},
+				Points: []*typesv1.Point{{Timestamp: req.Msg.Start, Value: 3}},
+			},
+		},
+	}), nil
+}
+
+type fakeWriter struct {
+	series map[string][]prompb.TimeSeries
+}
+
+func (w *fakeWriter) Write(_ context.Context, tenant string, series []prompb.TimeSeries) error {
+	w.series[tenant] = append(w.series[tenant], series...)
+	return nil
+}
+
+func Test_Ruler_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	bucket, err := filesystem.NewBucket(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, bucket.Upload(ctx, "rules/tenant-a.yaml", strings.NewReader(`
+groups:
+  - name: runtime
+    rules:
+      - record: gc_cpu
+        profile_type: process_cpu:cpu:nanoseconds:cpu:nanoseconds
+        function: runtime.gcBgMarkWorker
+        group_by: [pod, team]
+        labels:
+          team: platform
+`)))
+	require.NoError(t, bucket.Upload(ctx, "rules/README.md", strings.NewReader("")))
+
+	q := &fakeQuerier{}
+	w := &fakeWriter{series: make(map[string][]prompb.TimeSeries)}
+	cfg := Config{EvaluationInterval: time.Minute, PollInterval: time.Minute}
+	r := New(cfg, NewRuleStore(bucket), q, w, log.NewNopLogger(), prometheus.NewRegistry())
+
+	r.loadRules(ctx)
+	r.evaluate(ctx, 60000, 120000)
+
+	require.Equal(t, []string{"tenant-a"}, q.tenants)
+	require.Len(t, q.requests, 1)
+	require.Equal(t, int64(60000), q.requests[0].Start)
+	require.Equal(t, int64(120000), q.requests[0].End)
+	require.Equal(t, map[string][]prompb.TimeSeries{
+		"tenant-a": {{
+			Labels: []prompb.Label{
+				{Name: "__name__", Value: "gc_cpu"},
+				{Name: "pod", Value: "a"},
+				{Name: "team", Value: "platform"},
+			},
+			Samples: []prompb.Sample{{Value: 2, Timestamp: 120000}},
+		}},
+	}, w.series)
+}
+
+func Test_Ruler_EvaluationWindow(t *testing.T) {
+	r := &Ruler{cfg: Config{EvaluationInterval: time.Minute, EvaluationDelay: 30 * time.Second}}
+	for _, tc := range []struct {
+		now        time.Time
+		start, end model.Time
+	}{
+		{now: time.UnixMilli(180000), start: 60000, end: 120000},
+		{now: time.UnixMilli(150000), start: 60000, end: 120000},
+		{now: time.UnixMilli(149999), start: 0, end: 60000},
+	} {
+		start, end := r.evaluationWindow(tc.now)
+		require.Equal(t, tc.start, start)
+		require.Equal(t, tc.end, end)
+	}
+}
+
+// ingesterHandler serves the queries of the querier from the database.
+type ingesterHandler struct {
+	ingesterv1connect.UnimplementedIngesterServiceHandler
+	db *phlaredb.PhlareDB
+}
+
+func (h *ingesterHandler) MergeProfilesLabels(ctx context.Context, stream *connect.BidiStream[ingestv1.MergeProfilesLabelsRequest, ingestv1.MergeProfilesLabelsResponse]) error {
+	return h.db.MergeProfilesLabels(ctx, stream)
+}
+
+type noLimit struct{}
+
+func (noLimit) AllowProfile(model.Fingerprint, phlaremodel.Labels, int64) error { return nil }
+
+func (noLimit) Stop() {}
+
+func Test_Ruler_Evaluate_Querier(t *testing.T) {
+	ctx := phlarecontext.WithLogger(context.Background(), log.NewNopLogger())
+	ctx = phlarecontext.WithRegistry(ctx, prometheus.NewRegistry())
+	dataPath := t.TempDir()
+	bucket, err := filesystem.NewBucket(dataPath)
+	require.NoError(t, err)
+
+	db, err := phlaredb.New(ctx, phlaredb.Config{
+		DataPath:         dataPath,
+		MaxBlockDuration: time.Hour,
+	}, noLimit{}, bucket)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	// The profiles at 60s and 125s are outside of the evaluated time range.
+	for _, p := range []struct {
+		ts    int64
+		pod   string
+		value int64
+	}{
+		{ts: 60, pod: "a", value: 1},
+		{ts: 90, pod: "a", value: 2},
+		{ts: 100, pod: "a", value: 3},
+		{ts: 110, pod: "b", value: 4},
+		{ts: 125, pod: "a", value: 5},
+	} {
+		b := pproftesthelper.NewProfileBuilder(p.ts*1e9).CPUProfile().WithLabels("pod", p.pod)
+		b.ForStacktraceString("runtime.gcDrain", "runtime.gcBgMarkWorker").AddSamples(p.value)
+		b.ForStacktraceString("main.work", "main.main").AddSamples(100)
+		require.NoError(t, db.Ingest(ctx, b.Profile, b.UUID, b.Labels...))
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(ingesterv1connect.NewIngesterServiceHandler(&ingesterHandler{db: db}))
+	// The queries are bidirectional streams that require HTTP/2.
+	server := httptest.NewUnstartedServer(h2c.NewHandler(mux, &http2.Server{}))
+	server.Start()
+	defer server.Close()
+	q, err := querier.New(querier.Config{
+		PoolConfig: clientpool.PoolConfig{ClientCleanupPeriod: time.Minute},
+	}, testhelper.NewMockRing([]ring.InstanceDesc{
+		// The ring tolerates an instance failure: the querier only waits
+		// for all the responses if there are two instances.
+		{Addr: strings.TrimPrefix(server.URL, "http://")},
+		{Addr: strings.TrimPrefix(server.URL, "http://")},
+	}, 2), nil, nil, prometheus.NewRegistry(), log.NewNopLogger())
+	require.NoError(t, err)
+
+	require.NoError(t, bucket.Upload(ctx, "rules/tenant-a.yaml", strings.NewReader(`
+groups:
+  - name: runtime
+    rules:
+      - record: gc_cpu
+        profile_type: process_cpu:cpu:nanoseconds:cpu:nanoseconds
+        function: runtime.gcBgMarkWorker
+        group_by: [pod]
+`)))
+	w := &fakeWriter{series: make(map[string][]prompb.TimeSeries)}
+	r := New(Config{EvaluationInterval: time.Minute, PollInterval: time.Minute}, NewRuleStore(bucket), q, w, log.NewNopLogger(), prometheus.NewRegistry())
+	r.loadRules(ctx)
+	r.evaluate(ctx, 60000, 120000)
+
+	require.Equal(t, map[string][]prompb.TimeSeries{
+		"tenant-a": {
+			{
+				Labels:  []prompb.Label{{Name: "__name__", Value: "gc_cpu"}, {Name: "pod", Value: "a"}},
+				Samples: []prompb.Sample{{Value: 5, Timestamp: 120000}},
+			},
+			{
+				Labels:  []prompb.Label{{Name: "__name__", Value: "gc_cpu"}, {Name: "pod", Value: "b"}},
+				Samples: []prompb.Sample{{Value: 4, Timestamp: 120000}},
+			},
+		},
+	}, w.series)
+}
@@ -0,0 +1,159 @@
+package ruler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+// RuleGroups is the content of the rules file of a tenant.
+//
+//	groups:
+//	  - name: runtime
+//	    rules:
+//	      - record: go_gc_cpu_nanoseconds
+//	        profile_type: process_cpu:cpu:nanoseconds:cpu:nanoseconds
+//	        selector: '{service_name="checkout"}'
+//	        function: runtime.gcBgMarkWorker
+//	        group_by: [pod]
+type RuleGroups struct {
+	Groups []RuleGroup `yaml:"groups"`
+}
+
+type RuleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule is a recording rule: the result of the SelectSeries query
+// of the rule is written as the series of the recorded metric.
+type Rule struct {
+	// The name of the recorded metric.
+	Record      string `yaml:"record"`
+	ProfileType string `yaml:"profile_type"`
+	// The label selector of the profile series, all series by default.
+	Selector string   `yaml:"selector,omitempty"`
+	GroupBy  []string `yaml:"group_by,omitempty"`
+	// The aggregation of the profile values within the evaluation
+	// interval: sum (default), average, min, max, count, p50, p95,
+	// p99, or rate.
+	Aggregation string `yaml:"aggregation,omitempty"`
+	// Account only the samples with the function in their stack traces.
+	Function string `yaml:"function,omitempty"`
+	// Account only the samples with the call site in their stack traces.
+	// The call site is a sequence of consecutive functions, ordered from
+	// the root. Mutually exclusive with the function.
+	CallSite []string `yaml:"call_site,omitempty"`
+	// Labels added to the recorded series.
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// ParseRuleGroups parses and validates the rules file.
+func ParseRuleGroups(b []byte) (*RuleGroups, error) {
+	var g RuleGroups
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&g); err != nil {
+		return nil, err
+	}
+	return &g, g.Validate()
+}
+
+func (g *RuleGroups) Validate() error {
+	names := make(map[string]struct{}, len(g.Groups))
+	for _, group := range g.Groups {
+		if group.Name == "" {
+			return errors.New("rule group name is required")
+		}
+		if _, ok := names[group.Name]; ok {
+			return fmt.Errorf("duplicate rule group %q", group.Name)
+		}
+		names[group.Name] = struct{}{}
+		for i, r := range group.Rules {
+			if err := r.Validate(); err != nil {
+				return errors.Wrapf(err, "group %q, rule %d", group.Name, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Rule) Validate() error {
+	if !model.IsValidMetricName(model.LabelValue(r.Record)) {
+		return fmt.Errorf("invalid recorded metric name %q", r.Record)
+	}
+	if _, err := phlaremodel.ParseProfileTypeSelector(r.ProfileType); err != nil {
+		return err
+	}
+	if _, err := parser.ParseMetricSelector(r.selector()); err != nil {
+		return errors.Wrap(err, "invalid selector")
+	}
+	if _, err := parseAggregation(r.Aggregation); err != nil {
+		return err
+	}
+	if r.Function != "" && len(r.CallSite) > 0 {
+		return errors.New("function and call site are mutually exclusive")
+	}
+	for name := range r.Labels {
+		if !model.LabelName(name).IsValid() || name == model.MetricNameLabel {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) selector() string {
+	if r.Selector == "" {
+		return "{}"
+	}
+	return r.Selector
+}
+
+// SelectSeriesRequest returns the query of the rule over the time range.
+// The step is the time range duration: a single point per series
+// is expected at the end of the time range.
+func (r *Rule) SelectSeriesRequest(start, end model.Time) *querierv1.SelectSeriesRequest {
+	aggregation, _ := parseAggregation(r.Aggregation)
+	req := &querierv1.SelectSeriesRequest{
+		ProfileTypeID: r.ProfileType,
+		LabelSelector: r.selector(),
+		Start:         int64(start),
+		End:           int64(end),
+		GroupBy:       r.GroupBy,
+		Step:          end.Sub(start).Seconds(),
+		Aggregation:   &aggregation,
+	}
+	callSite := r.CallSite
+	if r.Function != "" {
+		callSite = []string{r.Function}
+	}
+	if len(callSite) > 0 {
+		req.StackTraceSelector = &typesv1.StackTraceSelector{
+			CallSite: make([]*typesv1.Location, len(callSite)),
+		}
+		for i, name := range callSite {
+			req.StackTraceSelector.CallSite[i] = &typesv1.Location{Name: name}
+		}
+	}
+	return req
+}
+
+func parseAggregation(s string) (typesv1.TimeSeriesAggregationType, error) {
+	if s == "" {
+		return typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_SUM, nil
+	}
+	v, ok := typesv1.TimeSeriesAggregationType_value["TIME_SERIES_AGGREGATION_TYPE_"+strings.ToUpper(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown aggregation %q", s)
+	}
+	return typesv1.TimeSeriesAggregationType(v), nil
+}
@@ -0,0 +1,101 @@
+package ruler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+func Test_ParseRuleGroups(t *testing.T) {
+	g, err := ParseRuleGroups([]byte(`
+groups:
+  - name: runtime
+    rules:
+      - record: go_gc_cpu_nanoseconds
+        profile_type: process_cpu:cpu:nanoseconds:cpu:nanoseconds
+        selector: '{service_name="checkout"}'
+        function: runtime.gcBgMarkWorker
+        group_by: [pod]
+        labels:
+          team: platform
+      - record: cpu_nanoseconds
+        profile_type: process_cpu:cpu:nanoseconds:cpu:nanoseconds
+        aggregation: average
+`))
+	require.NoError(t, err)
+	require.Len(t, g.Groups, 1)
+	require.Len(t, g.Groups[0].Rules, 2)
+
+	req := g.Groups[0].Rules[0].SelectSeriesRequest(1000, 61000)
+	sum := typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_SUM
+	require.Equal(t, &querierv1.SelectSeriesRequest{
+		ProfileTypeID: "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+		LabelSelector: `{service_name="checkout"}`,
+		Start:         1000,
+		End:           61000,
+		GroupBy:       []string{"pod"},
+		Step:          60,
+		Aggregation:   &sum,
+		StackTraceSelector: &typesv1.StackTraceSelector{
+			CallSite: []*typesv1.Location{{Name: "runtime.gcBgMarkWorker"}},
+		},
+	}, req)
+
+	req = g.Groups[0].Rules[1].SelectSeriesRequest(1000, 61000)
+	require.Equal(t, "{}", req.LabelSelector)
+	require.Nil(t, req.StackTraceSelector)
+	require.Equal(t, typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_AVERAGE, req.GetAggregation())
+}
+
+func Test_ParseRuleGroups_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rules string
+	}{
+		{
+			name:  "unknown field",
+			rules: "groups: [{name: a, rules: [{record: a, profile_type: 'process_cpu:cpu:nanoseconds:cpu:nanoseconds', query: x}]}]",
+		},
+		{
+			name:  "missing group name",
+			rules: "groups: [{rules: []}]",
+		},
+		{
+			name:  "duplicate group",
+			rules: "groups: [{name: a}, {name: a}]",
+		},
+		{
+			name:  "invalid metric name",
+			rules: "groups: [{name: a, rules: [{record: 'a-b', profile_type: 'process_cpu:cpu:nanoseconds:cpu:nanoseconds'}]}]",
+		},
+		{
+			name:  "invalid profile type",
+			rules: "groups: [{name: a, rules: [{record: a, profile_type: 'cpu'}]}]",
+		},
+		{
+			name:  "invalid selector",
+			rules: "groups: [{name: a, rules: [{record: a, profile_type: 'process_cpu:cpu:nanoseconds:cpu:nanoseconds', selector: '{'}]}]",
+		},
+		{
+			name:  "unknown aggregation",
+			rules: "groups: [{name: a, rules: [{record: a, profile_type: 'process_cpu:cpu:nanoseconds:cpu:nanoseconds', aggregation: median}]}]",
+		},
+		{
+			name:  "function and call site",
+			rules: "groups: [{name: a, rules: [{record: a, profile_type: 'process_cpu:cpu:nanoseconds:cpu:nanoseconds', function: f, call_site: [main, f]}]}]",
+		},
+		{
+			name:  "metric name label",
+			rules: "groups: [{name: a, rules: [{record: a, profile_type: 'process_cpu:cpu:nanoseconds:cpu:nanoseconds', labels: {__name__: b}}]}]",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseRuleGroups([]byte(tc.rules))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package ruler
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	phlareobj "github.com/grafana/pyroscope/pkg/objstore"
+)
+
+// RulesPrefix is the object storage directory the rules files are
+// stored in: the rules of a tenant are stored in "rules/<tenant>.yaml".
+const RulesPrefix = "rules"
+
+const rulesFileExt = ".yaml"
+
+// RuleStore loads the per-tenant rules from the object storage.
+type RuleStore struct {
+	bucket phlareobj.BucketReader
+}
+
+func NewRuleStore(bucket phlareobj.BucketReader) *RuleStore {
+	return &RuleStore{bucket: bucket}
+}
+
+// Tenants returns the tenants that have a rules file.
+func (s *RuleStore) Tenants(ctx context.Context) ([]string, error) {
+	var tenants []string
+	err := s.bucket.Iter(ctx, RulesPrefix+"/", func(name string) error {
+		name = path.Base(name)
+		if tenant := strings.TrimSuffix(name, rulesFileExt); tenant != name && tenant != "" {
+			tenants = append(tenants, tenant)
+		}
+		return nil
+	})
+	return tenants, err
+}
+
+// Load returns the parsed rules of the tenant.
+func (s *RuleStore) Load(ctx context.Context, tenant string) (*RuleGroups, error) {
+	r, err := s.bucket.Get(ctx, path.Join(RulesPrefix, tenant+rulesFileExt))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	g, err := ParseRuleGroups(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid rules of tenant %q", tenant)
+	}
+	return g, nil
+}
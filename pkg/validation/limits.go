@@ -7,6 +7,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/relabel"
 	"gopkg.in/yaml.v3"
 )
 
@@ -33,6 +34,10 @@ type Limits struct {
 	MaxProfileStacktraceDepth        int `yaml:"max_profile_stacktrace_depth" json:"max_profile_stacktrace_depth"`
 	MaxProfileSymbolValueLength      int `yaml:"max_profile_symbol_value_length" json:"max_profile_symbol_value_length"`
 
+	// Distributor relabeling rules.
+	IngestionRelabelingRules       []*relabel.Config `yaml:"ingestion_relabeling_rules" json:"ingestion_relabeling_rules" doc:"nocli|description=List of Prometheus relabeling rules applied to the series labels of ingested profiles, after the profiles are split by the sample labels and before the labels are validated. Series dropped by the rules are discarded."`
+	IngestionSampleRelabelingRules []*relabel.Config `yaml:"ingestion_sample_relabeling_rules" json:"ingestion_sample_relabeling_rules" doc:"nocli|description=List of Prometheus relabeling rules applied to the string labels of the samples of ingested pprof profiles, before the profiles are split by the sample labels. Samples dropped by the rules are discarded."`
+
 	// The tenant shard size determines the how many ingesters a particular
 	// tenant will be sharded to. Needs to be specified on distributors for
 	// correct distribution and on ingesters so that the local ingestion limit
@@ -52,7 +57,8 @@ type Limits struct {
 	StoreGatewayTenantShardSize int `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
 
 	// Query frontend.
-	QuerySplitDuration model.Duration `yaml:"split_queries_by_interval" json:"split_queries_by_interval"`
+	QuerySplitDuration       model.Duration `yaml:"split_queries_by_interval" json:"split_queries_by_interval"`
+	QueryShardingTotalShards int            `yaml:"query_sharding_total_shards" json:"query_sharding_total_shards"`
 
 	// Ensure profiles are dated within the IngestionWindow of the distributor.
 	RejectOlderThan model.Duration `yaml:"reject_older_than" json:"reject_older_than"`
@@ -91,6 +97,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 
 	_ = l.QuerySplitDuration.Set("0s")
 	f.Var(&l.QuerySplitDuration, "querier.split-queries-by-interval", "Split queries by a time interval and execute in parallel. The value 0 disables splitting by time")
+	f.IntVar(&l.QueryShardingTotalShards, "query-frontend.query-sharding-total-shards", 0, "The number of series shards each split query interval is fanned out to by the query-frontend. The value 0 disables query sharding.")
 
 	f.IntVar(&l.MaxQueryParallelism, "querier.max-query-parallelism", 0, "Maximum number of queries that will be scheduled in parallel by the frontend.")
 
@@ -232,6 +239,16 @@ func (o *Overrides) MaxProfileSymbolValueLength(tenantID string) int {
 	return o.getOverridesForTenant(tenantID).MaxProfileSymbolValueLength
 }
 
+// IngestionRelabelingRules returns the relabeling rules applied to the series labels.
+func (o *Overrides) IngestionRelabelingRules(tenantID string) []*relabel.Config {
+	return o.getOverridesForTenant(tenantID).IngestionRelabelingRules
+}
+
+// IngestionSampleRelabelingRules returns the relabeling rules applied to the profile sample labels.
+func (o *Overrides) IngestionSampleRelabelingRules(tenantID string) []*relabel.Config {
+	return o.getOverridesForTenant(tenantID).IngestionSampleRelabelingRules
+}
+
 // MaxSessionsPerSeries returns the maximum number of sessions per single series.
 func (o *Overrides) MaxSessionsPerSeries(tenantID string) int {
 	return o.getOverridesForTenant(tenantID).MaxSessionsPerSeries
@@ -275,6 +292,12 @@ func (o *Overrides) QuerySplitDuration(tenantID string) time.Duration {
 	return time.Duration(o.getOverridesForTenant(tenantID).QuerySplitDuration)
 }
 
+// QueryShardingTotalShards returns the number of series shards the query
+// frontend splits the queries of the tenant into.
+func (o *Overrides) QueryShardingTotalShards(tenantID string) int {
+	return o.getOverridesForTenant(tenantID).QueryShardingTotalShards
+}
+
 // MaxQueriersPerTenant returns the limit to the number of queriers that can be used
 // Shuffle sharding will be used to distribute queries across queriers.
 // 0 means no limit. Currently disabled.
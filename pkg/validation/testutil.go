@@ -3,13 +3,14 @@ package validation
 import "time"
 
 type MockLimits struct {
-	QuerySplitDurationValue     time.Duration
-	MaxQueryParallelismValue    int
-	MaxQueryLengthValue         time.Duration
-	MaxQueryLookbackValue       time.Duration
-	MaxLabelNameLengthValue     int
-	MaxLabelValueLengthValue    int
-	MaxLabelNamesPerSeriesValue int
+	QuerySplitDurationValue       time.Duration
+	MaxQueryParallelismValue      int
+	QueryShardingTotalShardsValue int
+	MaxQueryLengthValue           time.Duration
+	MaxQueryLookbackValue         time.Duration
+	MaxLabelNameLengthValue       int
+	MaxLabelValueLengthValue      int
+	MaxLabelNamesPerSeriesValue   int
 
 	RejectOlderThanValue time.Duration
 	RejectNewerThanValue time.Duration
@@ -23,6 +24,7 @@ type MockLimits struct {
 
 func (m MockLimits) QuerySplitDuration(string) time.Duration        { return m.QuerySplitDurationValue }
 func (m MockLimits) MaxQueryParallelism(string) int                 { return m.MaxQueryParallelismValue }
+func (m MockLimits) QueryShardingTotalShards(string) int            { return m.QueryShardingTotalShardsValue }
 func (m MockLimits) MaxQueryLength(tenantID string) time.Duration   { return m.MaxQueryLengthValue }
 func (m MockLimits) MaxQueryLookback(tenantID string) time.Duration { return m.MaxQueryLookbackValue }
 func (m MockLimits) MaxLabelNameLength(userID string) int           { return m.MaxLabelNameLengthValue }
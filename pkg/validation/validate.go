@@ -51,6 +51,9 @@ const (
 	ProfileSizeLimit  Reason = "profile_size_limit"
 	SampleLabelsLimit Reason = "sample_labels_limit"
 	MalformedProfile  Reason = "malformed_profile"
+	// DroppedByRelabelRules is a reason for discarding profiles
+	// whose series are dropped by the tenant relabeling rules.
+	DroppedByRelabelRules Reason = "dropped_by_relabel_rules"
 
 	SeriesLimitErrorMsg                = "Maximum active series limit exceeded (%d/%d), reduce the number of active streams (reduce labels or reduce label values), or contact your administrator to see if the limit can be increased"
 	MissingLabelsErrorMsg              = "error at least one label pair is required per profile"
@@ -32,13 +32,15 @@ export function queryToMatchers(query: string) {
   return [`{__profile_type__=\"${query}\"}`];
 }
 
-export async function fetchTags(query: string, _from: number, _until: number) {
+export async function fetchTags(query: string, from: number, until: number) {
   const response = await requestWithOrgID(
     '/querier.v1.QuerierService/LabelNames',
     {
       method: 'POST',
       body: JSON.stringify({
         matchers: queryToMatchers(query),
+        start: from * 1000,
+        end: until * 1000,
       }),
       headers: {
         'content-type': 'application/json',
@@ -58,8 +60,8 @@ export async function fetchTags(query: string, _from: number, _until: number) {
 export async function fetchLabelValues(
   label: string,
   query: string,
-  _from: number,
-  _until: number
+  from: number,
+  until: number
 ) {
   const response = await requestWithOrgID(
     '/querier.v1.QuerierService/LabelValues',
@@ -68,6 +70,8 @@ export async function fetchLabelValues(
       body: JSON.stringify({
         matchers: queryToMatchers(query),
         name: label,
+        start: from * 1000,
+        end: until * 1000,
       }),
       headers: {
         'content-type': 'application/json',
@@ -0,0 +1,23 @@
+import React from 'react';
+import ReactDOM from 'react-dom/client';
+import { FlamegraphRenderer } from '@pyroscope/legacy/flamegraph/FlamegraphRenderer';
+import type { Profile } from '@pyroscope/legacy/models';
+import './sass/profile.scss';
+
+// The flame graph is embedded into the page by the server,
+// see pkg/og/structs/flamebearer/html.go.
+const { flamegraph } = window as unknown as { flamegraph?: Profile };
+
+function run() {
+  if (!flamegraph) {
+    throw new Error(`'flamegraph' is required`);
+  }
+  const container = document.getElementById('reactRoot') as HTMLElement;
+  ReactDOM.createRoot(container).render(
+    <FlamegraphRenderer profile={flamegraph} showCredit={false} />
+  );
+}
+
+// The scripts are inlined into the head, therefore
+// the DOM has to be loaded before rendering.
+window.addEventListener('DOMContentLoaded', run, false);
@@ -0,0 +1,33 @@
+const HtmlWebpackPlugin = require('html-webpack-plugin');
+const fs = require('fs');
+const path = require('path');
+
+// The standalone flame graph page is exported by the server as a single
+// HTML file (see pkg/og/structs/flamebearer/html.go), therefore the
+// scripts and the styles of the entry are inlined into the page.
+module.exports = function standalonePage() {
+  return new HtmlWebpackPlugin({
+    filename: path.resolve(__dirname, '../../public/build/standalone.html'),
+    chunks: ['standalone'],
+    inject: false,
+    // The server replaces a comment of the template with the flame graph.
+    minify: false,
+    templateContent: ({ compilation }) => {
+      const template = fs.readFileSync(
+        path.resolve(__dirname, '../../public/templates/standalone.html'),
+        'utf8'
+      );
+      const files = compilation.entrypoints.get('standalone').getFiles();
+      const source = (file) => compilation.assets[file].source().toString();
+      const tags = [
+        ...files
+          .filter((file) => file.endsWith('.css'))
+          .map((file) => `<style>${source(file)}</style>`),
+        ...files
+          .filter((file) => file.endsWith('.js'))
+          .map((file) => `<script>${source(file)}</script>`),
+      ];
+      return template.replace('</head>', `${tags.join('\n')}\n  </head>`);
+    },
+  });
+};
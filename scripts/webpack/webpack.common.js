@@ -17,6 +17,7 @@ module.exports = {
   target: 'web',
   entry: {
     app: './public/app/app.tsx',
+    standalone: './public/app/standalone.tsx',
   },
   output: {
     clean: true,
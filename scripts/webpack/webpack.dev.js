@@ -1,6 +1,7 @@
 const { merge } = require('webpack-merge');
 const HtmlWebpackPlugin = require('html-webpack-plugin');
 const common = require('./webpack.common');
+const standalonePage = require('./standalone');
 const webpack = require('webpack');
 const path = require('path');
 
@@ -26,7 +27,9 @@ module.exports = merge(common, {
     new HtmlWebpackPlugin({
       filename: path.resolve(__dirname, '../../public/build/index.html'),
       template: path.resolve(__dirname, '../../public/templates/index.html'),
+      chunks: ['app'],
       chunksSortMode: 'none',
     }),
+    standalonePage(),
   ],
 });
@@ -3,6 +3,7 @@ const webpack = require('webpack');
 const HtmlWebpackPlugin = require('html-webpack-plugin');
 const path = require('path');
 const common = require('./webpack.common');
+const standalonePage = require('./standalone');
 
 module.exports = merge(common, {
   mode: 'production',
@@ -20,7 +21,9 @@ module.exports = merge(common, {
     new HtmlWebpackPlugin({
       filename: path.resolve(__dirname, '../../public/build/index.html'),
       template: path.resolve(__dirname, '../../public/templates/index.html'),
+      chunks: ['app'],
       chunksSortMode: 'none',
     }),
+    standalonePage(),
   ],
 });